@@ -10,6 +10,10 @@
 //
 //   c, err := api.NewAPIClient("your_station_id", "your_station_secret")
 //
+// or, with an API token created on your user page:
+//
+//   c, err := api.NewAPIClientWithToken("your_api_token")
+//
 // Fetch some packets:
 //
 //   packets, err := c.GetLatestPackets("your_satellite_id", 3)
@@ -34,11 +38,14 @@ import "bytes"
 
 const defaultApiHost = "api.carpcomm.com:5051"
 const jsonMimeType = "application/json"
+const authorizationPrefix = "Bearer "
 
 type APIClient struct {
 	host string
 	client http.Client
 	station_id, station_secret string
+	// Sent instead of the station secret if set.
+	api_token string
 }
 
 func NewAPIClient(station_id, station_secret string) (*APIClient, error) {
@@ -49,6 +56,26 @@ func NewAPIClient(station_id, station_secret string) (*APIClient, error) {
 		return nil, errors.New("station_secret is empty")
 	}
 
+	c := newAPIClient()
+	c.station_id = station_id
+	c.station_secret = station_secret
+	return c, nil
+}
+
+// NewAPIClientWithToken authenticates with an API token instead of the
+// station secret. The token acts for its station so no station_id is
+// needed.
+func NewAPIClientWithToken(api_token string) (*APIClient, error) {
+	if api_token == "" {
+		return nil, errors.New("api_token is empty")
+	}
+
+	c := newAPIClient()
+	c.api_token = api_token
+	return c, nil
+}
+
+func newAPIClient() *APIClient {
 	// FIXME(tstranex): Change InsecureSkipVerify to false once the server
 	// certificate is registered.
 	tr := &http.Transport{
@@ -60,10 +87,14 @@ func NewAPIClient(station_id, station_secret string) (*APIClient, error) {
 	var c APIClient
 	c.host = defaultApiHost
 	c.client = http.Client{Transport: tr}
-	c.station_id = station_id
-	c.station_secret = station_secret
+	return &c
+}
 
-	return &c, nil
+func (c *APIClient) do(req *http.Request) (*http.Response, error) {
+	if c.api_token != "" {
+		req.Header.Set("Authorization", authorizationPrefix+c.api_token)
+	}
+	return c.client.Do(req)
 }
 
 
@@ -105,8 +136,12 @@ func (c *APIClient) PostPacket(satellite_id string, p Packet) error {
 	fmt.Printf("url: %s\n", u.String())
 	fmt.Printf("body: %s\n", body)
 
-	resp, err := c.client.Post(
-		u.String(), jsonMimeType, bytes.NewBuffer(body))
+	hreq, err := http.NewRequest("POST", u.String(), bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	hreq.Header.Set("Content-Type", jsonMimeType)
+	resp, err := c.do(hreq)
 	if err != nil {
 		return err
 	}
@@ -133,8 +168,10 @@ func (c *APIClient) GetLatestPackets(satellite_id string, limit int) (
 	[]Packet, error) {
 
 	v := make(url.Values)
-	v.Set("station_id", c.station_id)
-	v.Set("station_secret", c.station_secret)
+	if c.api_token == "" {
+		v.Set("station_id", c.station_id)
+		v.Set("station_secret", c.station_secret)
+	}
 	v.Set("satellite_id", satellite_id)
 	v.Set("limit", fmt.Sprintf("%d", limit))
 
@@ -144,7 +181,11 @@ func (c *APIClient) GetLatestPackets(satellite_id string, limit int) (
 	u.Path = "/GetLatestPackets"
 	u.RawQuery = v.Encode()
 
-	resp, err := c.client.Get(u.String())
+	hreq, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(hreq)
 	if err != nil {
 		return nil, err
 	}
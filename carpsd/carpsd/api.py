@@ -25,6 +25,11 @@ class APIClient:
         self._station_id = config.get(client.Client.__name__, 'id')
         self._secret = config.get(client.Client.__name__, 'secret')
         self._ca_certs = config.get(client.Client.__name__, 'ca_certificate')
+        # A scoped API token is sent instead of the station secret if it's
+        # configured.
+        self._api_token = None
+        if config.has_option(client.Client.__name__, 'api_token'):
+            self._api_token = config.get(client.Client.__name__, 'api_token')
 
         # We should consider setting default values here.
         self.SetServer(None, None)
@@ -49,13 +54,23 @@ class APIClient:
         # UGLY. We have to open the socket ourselves because HTTPSConnection
         # doesn't allow us to specify all SSL parameters.
         c.sock = s
-        c.request(method, path, body)
+        c.request(method, path, body, self._Headers())
         r = c.getresponse()
         if r.status != httplib.OK:
             return False, (r.status, r.reason), None
 
         return True, (r.status, r.reason), r.read()
 
+    def _Headers(self):
+        if self._api_token:
+            return {'Authorization': 'Bearer %s' % self._api_token}
+        return {}
+
+    def _RemoveSecret(self, req):
+        if self._api_token:
+            del req['station_secret']
+        return req
+
     def PostPacket(self, satellite_id, timestamp, frame):
         req = {
             'station_id': self._station_id,
@@ -65,7 +80,7 @@ class APIClient:
             'format': 'FRAME',
             'frame_base64': base64.b64encode(frame),
             }
-        body = json.dumps(req)
+        body = json.dumps(self._RemoveSecret(req))
         ok, status, body = self._SendRequest('POST', '/PostPacket', body)
         return ok, status
 
@@ -76,7 +91,7 @@ class APIClient:
             'satellite_id': satellite_id,
             'limit': limit,
             }
-        params = urllib.urlencode(req)
+        params = urllib.urlencode(self._RemoveSecret(req))
         ok, status, body = self._SendRequest(
             'GET', '/GetLatestPackets?%s' % params, '')
         packets = []
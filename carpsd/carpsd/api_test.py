@@ -4,10 +4,12 @@
 # Author: Timothy Stranex <tstranex@carpcomm.com>
 
 import api
+import client
 import config
 import testing
 
 import datetime
+import json
 import unittest
 
 
@@ -76,6 +78,25 @@ class APIClientTest(unittest.TestCase):
              (datetime.datetime(2012, 11, 1, 3, 35, 40),
               'wu\xec\x06p\xf2~g\xa9\xef\x10\xb7\x07T\x15\xde\x8e\x97')])
 
+    def testAPIToken(self):
+        conf = testing.GetConfigForTesting()
+        conf.set(client.Client.__name__, 'api_token', '123.abc')
+        c = api.APIClient(conf)
+        self.assertEquals({'Authorization': 'Bearer 123.abc'}, c._Headers())
+
+        def SendRequest(method, path, body):
+            req = json.loads(body)
+            self.assertEquals('test_station_id', req['station_id'])
+            self.assertFalse('station_secret' in req)
+            return True, (200, ''), ''
+
+        c._SendRequest = SendRequest
+        ok, status = c.PostPacket('test_satellite', 569, '\xab\x00\x5a')
+        self.assertEquals(True, ok)
+
+    def testNoAPIToken(self):
+        self.assertEquals({}, self.create()._Headers())
+
 
 def liveTest():
     conf = testing.GetConfigForTesting()
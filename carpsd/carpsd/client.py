@@ -13,27 +13,132 @@ import platform
 import random
 import time
 import StringIO
+import Queue
+import struct
+import threading
+import control
 import signalling
 
 VERSION = '0.19'
 CLIENT_NAME = 'carpsd'
 
 PING_TIMEOUT = 3600  # [seconds]
-RECONNECT_DELAY = 600  # [seconds]
+
+# Reconnection attempts back off exponentially between these delays.
+MIN_RECONNECT_DELAY = 5  # [seconds]
+MAX_RECONNECT_DELAY = 600  # [seconds]
+
+
+def NextReconnectDelay(delay):
+    """Returns the delay before the reconnection attempt after this one."""
+    return min(2 * delay, MAX_RECONNECT_DELAY)
+
+
+def _HandlerResult(r):
+    """Converts a handler return value to (code, content_type, data)."""
+    if r == True:
+        return 200, 'text/plain', ''
+    elif r == False:
+        return 500, 'text/plain', ''
+    else:
+        return r
+
+
+# Framed protocol: each frame is a big endian uint32 length of the rest of the
+# frame, a uint32 request id and then the HTTP request or response. This lets
+# the server send several requests at once and we can answer them in any
+# order.
+
+def ReadFrame(f):
+    """Returns (request_id, payload) or None if the connection was closed."""
+    header = f.read(8)
+    if len(header) < 8:
+        return None
+    length, request_id = struct.unpack('>II', header)
+    payload = f.read(length - 4)
+    if len(payload) < length - 4:
+        return None
+    return request_id, payload
+
+
+def WriteFrame(f, request_id, payload):
+    f.write(struct.pack('>II', len(payload) + 4, request_id) + payload)
+    f.flush()
+
+
+def ParseRequest(payload):
+    """Returns the path, params and body of an HTTP GET or POST request."""
+    head, _, body = payload.partition('\r\n\r\n')
+    request_line = head.split('\r\n', 1)[0]
+    method, target, version = request_line.split(' ', 2)
+    url = urlparse.urlparse(target)
+    params = urlparse.parse_qs(url.query, keep_blank_values=True)
+    return url.path, params, body
+
+
+def FormatResponse(code, content_type, data):
+    reason = BaseHTTPServer.BaseHTTPRequestHandler.responses.get(
+        code, ('',))[0]
+    return ('HTTP/1.1 %d %s\r\n'
+            'Content-Type: %s\r\n'
+            'Content-Length: %d\r\n'
+            '\r\n' % (code, reason, content_type, len(data))) + data
+
+
+def _Lane(path, params, body=''):
+    """Requests for the same device are handled one at a time."""
+    if path == '/Control':
+        try:
+            return control.Lane(json.loads(body))
+        except (ValueError, AttributeError):
+            return ''
+    for prefix in ['/Receiver', '/TNC', '/Motor']:
+        if path.startswith(prefix):
+            return prefix
+    return ''
+
+
+class _LaneThread(threading.Thread):
+    """Handles the requests for one lane in order."""
+
+    def __init__(self, client, write_frame):
+        threading.Thread.__init__(self)
+        self.daemon = True
+        self._client = client
+        self._write_frame = write_frame
+        self.queue = Queue.Queue()
+
+    def run(self):
+        while True:
+            item = self.queue.get()
+            if item is None:
+                return
+            request_id, path, params, body = item
+            try:
+                r = _HandlerResult(
+                    self._client._Dispatch(path, params, body))
+            except Exception:
+                logging.exception('Error handling %s:', path)
+                r = 500, 'text/plain', ''
+            try:
+                self._write_frame(request_id, FormatResponse(*r))
+            except (socket.error, ValueError):
+                logging.exception('Error writing response:')
 
 
 class _HTTPHandler(BaseHTTPServer.BaseHTTPRequestHandler):
     def do_GET(self):
+        self._Handle('')
+
+    def do_POST(self):
+        length = int(self.headers.getheader('Content-Length') or 0)
+        self._Handle(self.rfile.read(length))
+
+    def _Handle(self, body):
         url = urlparse.urlparse(self.path)
         params = urlparse.parse_qs(url.query, keep_blank_values=True)
-        r = self.server._Dispatch(url.path, params)
-
-        if r == True:
-            code, content_type, data = 200, 'text/plain', ''
-        elif r == False:
-            code, content_type, data = 500, 'text/plain', ''
-        else:
-            code, content_type, data = r
+        r = self.server._Dispatch(url.path, params, body)
+        code, content_type, data = _HandlerResult(r)
 
         self.send_response(code)
         self.send_header('Content-Type', content_type)
@@ -54,6 +159,18 @@ class Client(object):
         self.RegisterHandler('/Identify', self._IdentifyHandler)
         self.RegisterHandler('/Disconnect', self._DisconnectHandler)
         self.RegisterHandler('/Ping', self._PingHandler)
+        self.RegisterHandler('/Session', self._SessionHandler)
+        self.RegisterHandler('/StartFraming', self._StartFramingHandler)
+
+        self._control = control.Control()
+
+        # The server gives us a session token after authenticating us. If we
+        # reconnect soon enough, we can resume the session.
+        self._session = None
+        self._session_started = False
+
+        # Set once the server asks us to switch to the framed protocol.
+        self._framing = False
 
         self._station_id = config.get(Client.__name__, 'id')
         self._secret = config.get(Client.__name__, 'secret')
@@ -75,6 +192,7 @@ class Client(object):
             ca_certs=self._ca_certs)
 
     def ServeUntilDisconnected(self):
+        self._framing = False
         while not self._disconnected:
             h = _HTTPHandler(self._socket, self._server, self)
             if not h.raw_requestline:
@@ -82,25 +200,75 @@ class Client(object):
                 # should check the timer to be sure.
                 logging.info('Connection closed or timed out.')
                 break
+            if self._framing:
+                self._ServeFramed()
+                break
         self._socket.close()
 
+    def _ServeFramed(self):
+        logging.info('Switching to the framed protocol.')
+        rfile = self._socket.makefile('rb', 0)
+        wfile = self._socket.makefile('wb', 0)
+        write_lock = threading.Lock()
+        def Write(request_id, payload):
+            with write_lock:
+                WriteFrame(wfile, request_id, payload)
+
+        lanes = {}
+        try:
+            while not self._disconnected:
+                frame = ReadFrame(rfile)
+                if frame is None:
+                    logging.info('Connection closed or timed out.')
+                    break
+                request_id, payload = frame
+                path, params, body = ParseRequest(payload)
+                lane = _Lane(path, params, body)
+                if lane not in lanes:
+                    lanes[lane] = _LaneThread(self, Write)
+                    lanes[lane].start()
+                lanes[lane].queue.put((request_id, path, params, body))
+        finally:
+            for t in lanes.itervalues():
+                t.queue.put(None)
+
     def ConnectAndServeForever(self):
+        delay = MIN_RECONNECT_DELAY
         while True:
+            self._session_started = False
             try:
                 self.Connect()
                 self.ServeUntilDisconnected()
             except socket.error:
                 logging.exception('Connection error:')
 
-            # TODO: exponential backoff
-            delay = RECONNECT_DELAY + int(RECONNECT_DELAY * random.random())
-            logging.info('Reconnecting in %d seconds', delay)
-            time.sleep(delay)
+            if self._session_started:
+                # We were connected so try to resume the session quickly.
+                delay = MIN_RECONNECT_DELAY
+            wait = delay + int(delay * random.random())
+            logging.info('Reconnecting in %d seconds', wait)
+            time.sleep(wait)
+            delay = NextReconnectDelay(delay)
 
     def RegisterHandler(self, path, handler):
         self._handlers[path] = handler
 
-    def _Dispatch(self, path, params):
+    def RegisterCommand(self, request_type, handler):
+        """Registers a handler for a typed StationRequest."""
+        self._control.RegisterCommand(request_type, handler)
+
+    def RegisterStatus(self, name, handler):
+        """Registers a function returning part of the StationStatus."""
+        self._control.RegisterStatus(name, handler)
+
+    def SetDiskPath(self, path):
+        """Sets the directory whose free space is reported in the health."""
+        self._control.SetDiskPath(path)
+
+    def _Dispatch(self, path, params, body=''):
+        if path == '/Control':
+            # Typed requests are POSTed as JSON.
+            return self._ControlHandler(body)
         if path in self._handlers:
             return self._handlers[path](params)
         else:
@@ -132,6 +300,12 @@ class Client(object):
             'secret': self._secret,
             'platform': arch,
             'config': self._config,
+            'session': self._session or '',
+            'protocols': ['framed'],
+            'protocol_version': control.PROTOCOL_VERSION,
+            'features': self._control.Features(),
+            # Lets the server estimate our clock offset.
+            'station_time': time.time(),
             }
         return 200, 'text/plain', json.dumps(data)
 
@@ -140,6 +314,27 @@ class Client(object):
         self._disconnected = True
         return True
 
+    def _SessionHandler(self, params):
+        self._session = params['token'][0]
+        self._session_started = True
+        return True
+
+    def _StartFramingHandler(self, params):
+        # The response is still sent using plain HTTP.
+        self._framing = True
+        return True
+
+    def _ControlHandler(self, body):
+        try:
+            request = json.loads(body)
+        except ValueError:
+            return 400, 'text/plain', 'Invalid request'
+        if not isinstance(request, dict):
+            return 400, 'text/plain', 'Invalid request'
+        response = self._control.Handle(request)
+        return 200, 'application/json', json.dumps(response)
+
     def _PingHandler(self, params):
         signalling.Get().SignalPing()
-        return 200, 'text/plain', 'pong'
+        # Older servers only check the status code.
+        return 200, 'text/plain', 'pong %.6f' % time.time()
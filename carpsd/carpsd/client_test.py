@@ -0,0 +1,127 @@
+#!/usr/bin/python
+
+# Copyright 2012 Carpcomm GmbH
+# Author: Timothy Stranex <tstranex@carpcomm.com>
+
+import client
+import control
+import testing
+
+import json
+import StringIO
+import unittest
+
+
+class ClientTest(unittest.TestCase):
+
+    def testNextReconnectDelay(self):
+        delay = client.MIN_RECONNECT_DELAY
+        delays = []
+        for i in xrange(20):
+            delays.append(delay)
+            delay = client.NextReconnectDelay(delay)
+        self.assertEquals(2 * client.MIN_RECONNECT_DELAY, delays[1])
+        self.assertEquals(client.MAX_RECONNECT_DELAY, delays[-1])
+
+    def testSession(self):
+        conf = testing.GetConfigForTesting()
+        conf.set(client.Client.__name__, 'server', 'localhost:1234')
+        c = client.Client(conf)
+
+        code, _, data = c._Dispatch('/Identify', {})
+        self.assertEquals(200, code)
+        self.assertEquals('', json.loads(data)['session'])
+
+        self.assertTrue(c._Dispatch('/Session', {'token': ['abc']}))
+        code, _, data = c._Dispatch('/Identify', {})
+        self.assertEquals('abc', json.loads(data)['session'])
+
+    def testClock(self):
+        conf = testing.GetConfigForTesting()
+        conf.set(client.Client.__name__, 'server', 'localhost:1234')
+        c = client.Client(conf)
+
+        code, _, data = c._Dispatch('/Identify', {})
+        self.assertTrue(json.loads(data)['station_time'] > 0)
+
+        code, _, data = c._Dispatch('/Ping', {})
+        self.assertEquals(200, code)
+        self.assertTrue(float(data.split()[1]) > 0)
+
+    def testFrames(self):
+        f = StringIO.StringIO()
+        client.WriteFrame(f, 7, 'hello')
+        client.WriteFrame(f, 8, '')
+        f.seek(0)
+        self.assertEquals((7, 'hello'), client.ReadFrame(f))
+        self.assertEquals((8, ''), client.ReadFrame(f))
+        self.assertEquals(None, client.ReadFrame(f))
+
+    def testParseRequest(self):
+        path, params, body = client.ParseRequest(
+            'GET /ReceiverSetFrequency?hz=437000000 HTTP/1.1\r\n'
+            'Host: station\r\n\r\n')
+        self.assertEquals('/ReceiverSetFrequency', path)
+        self.assertEquals({'hz': ['437000000']}, params)
+        self.assertEquals('', body)
+
+        path, params, body = client.ParseRequest(
+            'POST /Control HTTP/1.1\r\n'
+            'Host: station\r\n'
+            'Content-Length: 16\r\n\r\n'
+            '{"type": "PING"}')
+        self.assertEquals('/Control', path)
+        self.assertEquals({}, params)
+        self.assertEquals('{"type": "PING"}', body)
+
+    def testFormatResponse(self):
+        self.assertEquals(
+            'HTTP/1.1 200 OK\r\n'
+            'Content-Type: text/plain\r\n'
+            'Content-Length: 2\r\n'
+            '\r\n'
+            'ok', client.FormatResponse(200, 'text/plain', 'ok'))
+
+    def testLane(self):
+        self.assertEquals('/Receiver', client._Lane('/ReceiverStart', {}))
+        self.assertEquals(client._Lane('/ReceiverStop', {}),
+                          client._Lane('/ReceiverGetState', {}))
+        self.assertNotEquals(client._Lane('/ReceiverStop', {}),
+                             client._Lane('/MotorStop', {}))
+        self.assertEquals('', client._Lane('/Ping', {}))
+
+    def testStartFraming(self):
+        conf = testing.GetConfigForTesting()
+        conf.set(client.Client.__name__, 'server', 'localhost:1234')
+        c = client.Client(conf)
+
+        code, _, data = c._Dispatch('/Identify', {})
+        self.assertEquals(['framed'], json.loads(data)['protocols'])
+        self.assertTrue(c._Dispatch('/StartFraming', {}))
+        self.assertTrue(c._framing)
+
+    def testControl(self):
+        conf = testing.GetConfigForTesting()
+        conf.set(client.Client.__name__, 'server', 'localhost:1234')
+        c = client.Client(conf)
+        c.RegisterCommand('TNC_START', lambda request: True)
+        c.RegisterCommand('TNC_STOP', lambda request: True)
+
+        code, _, data = c._Dispatch('/Identify', {})
+        identity = json.loads(data)
+        self.assertEquals(control.PROTOCOL_VERSION,
+                          identity['protocol_version'])
+        self.assertTrue(identity['features']['tnc'])
+
+        request = json.dumps({'protocol_version': 1, 'type': 'TNC_STOP'})
+        code, _, data = c._Dispatch('/Control', {}, request)
+        self.assertEquals(200, code)
+        self.assertEquals('OK', json.loads(data)['status'])
+        self.assertEquals('/TNC', client._Lane('/Control', {}, request))
+
+        code, _, _ = c._Dispatch('/Control', {}, '[')
+        self.assertEquals(400, code)
+
+
+if __name__ == '__main__':
+    unittest.main()
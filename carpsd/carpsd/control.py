@@ -0,0 +1,145 @@
+#!/usr/bin/python
+
+# Copyright 2012 Carpcomm GmbH
+# Author: Timothy Stranex <tstranex@carpcomm.com>
+
+"""Typed control protocol.
+
+The messages are defined by StationRequest and StationResponse in
+carpcomm/pb/station_control.proto. They are POSTed to /Control
+JSON-encoded.
+"""
+
+import logging
+import os
+import time
+
+
+# Increment when adding request types or changing their meaning. The server
+# sends plain URL actions to stations that don't report a version or report
+# a version before 4, when requests were moved from the query string to the
+# body.
+PROTOCOL_VERSION = 4
+
+OK = 'OK'
+ERROR = 'ERROR'
+UNSUPPORTED = 'UNSUPPORTED'
+
+
+class Control(object):
+
+    def __init__(self):
+        # Request type -> function taking the request dict and returning
+        # True on success.
+        self._commands = {}
+        # StationStatus field -> function returning a dict.
+        self._status = {}
+        # Where recordings are written. Its free space is reported.
+        self._disk_path = None
+
+    def RegisterCommand(self, request_type, handler):
+        self._commands[request_type] = handler
+
+    def RegisterStatus(self, name, handler):
+        self._status[name] = handler
+
+    def SetDiskPath(self, path):
+        self._disk_path = path
+
+    def Features(self):
+        """Returns the StationFeatures dict for Identify."""
+        return {
+            'receiver': 'RECEIVER_START' in self._commands,
+            'tnc': 'TNC_START' in self._commands,
+            'motor': 'MOTOR_START' in self._commands,
+            }
+
+    def _Response(self, status, error=None):
+        r = {
+            'protocol_version': PROTOCOL_VERSION,
+            'status': status,
+            }
+        if error:
+            r['error'] = error
+        return r
+
+    def Status(self):
+        """Returns the StationStatus dict.
+
+        A device whose status can't be read is reported as faulted.
+        """
+        status = {}
+        for name, f in self._status.iteritems():
+            try:
+                status[name] = f()
+            except Exception, e:
+                logging.exception('Error getting %s status:', name)
+                status[name] = {'fault': str(e) or e.__class__.__name__}
+        return status
+
+    def Health(self):
+        """Returns the StationHealth dict."""
+        health = {
+            'status': self.Status(),
+            'station_time': time.time(),
+            }
+        if hasattr(os, 'getloadavg'):
+            try:
+                health['load_average'] = os.getloadavg()[0]
+            except OSError:
+                pass
+        if self._disk_path and hasattr(os, 'statvfs'):
+            try:
+                s = os.statvfs(self._disk_path)
+                health['disk_free_bytes'] = s.f_bavail * s.f_frsize
+            except OSError:
+                logging.exception('Error getting free disk space:')
+        return health
+
+    def Handle(self, request):
+        """Handles a StationRequest dict and returns a StationResponse dict."""
+        t = request.get('type')
+        if t == 'PING':
+            return self._Response(OK)
+        if t == 'GET_STATUS':
+            r = self._Response(OK)
+            r['station_status'] = self.Status()
+            return r
+        if t == 'GET_HEALTH':
+            r = self._Response(OK)
+            r['health'] = self.Health()
+            return r
+
+        if t not in self._commands:
+            return self._Response(UNSUPPORTED, 'Unknown request type: %s' % t)
+        try:
+            ok = self._commands[t](request)
+        except (KeyError, ValueError, TypeError), e:
+            logging.exception('Invalid %s request:', t)
+            return self._Response(ERROR, 'Invalid request: %s' % e)
+        if ok:
+            return self._Response(OK)
+        else:
+            return self._Response(ERROR, '%s failed' % t)
+
+
+def ProgramOffset(coordinate, now):
+    """Returns the seconds from now of a program coordinate.
+
+    The server sets station_timestamp using our clock offset if it knows
+    it, which also accounts for the time the request took to arrive.
+    """
+    if 'station_timestamp' in coordinate:
+        return float(coordinate['station_timestamp']) - now
+    return float(coordinate['offset_s'])
+
+
+def Lane(request):
+    """Returns the device that handles the request for the framed protocol."""
+    t = request.get('type', '')
+    for prefix, lane in [('RECEIVER_', '/Receiver'),
+                         ('TNC_', '/TNC'),
+                         ('MOTOR_', '/Motor')]:
+        if t.startswith(prefix):
+            return lane
+    return ''
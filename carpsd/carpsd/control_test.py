@@ -0,0 +1,84 @@
+#!/usr/bin/python
+
+# Copyright 2012 Carpcomm GmbH
+# Author: Timothy Stranex <tstranex@carpcomm.com>
+
+import control
+
+import unittest
+
+
+class ControlTest(unittest.TestCase):
+
+    def setUp(self):
+        self.c = control.Control()
+        self.started = []
+        def Start(request):
+            self.started.append(request['stream_url'])
+            return True
+        self.c.RegisterCommand('RECEIVER_START', Start)
+        self.c.RegisterCommand('RECEIVER_STOP', lambda request: False)
+        self.c.RegisterStatus('receiver', lambda: {'started': True})
+
+    def testCommand(self):
+        r = self.c.Handle({'protocol_version': 1,
+                           'type': 'RECEIVER_START',
+                           'stream_url': 'http://example.com/'})
+        self.assertEquals(control.OK, r['status'])
+        self.assertEquals(control.PROTOCOL_VERSION, r['protocol_version'])
+        self.assertEquals(['http://example.com/'], self.started)
+
+    def testCommandFailed(self):
+        r = self.c.Handle({'type': 'RECEIVER_STOP'})
+        self.assertEquals(control.ERROR, r['status'])
+
+    def testInvalidRequest(self):
+        r = self.c.Handle({'type': 'RECEIVER_START'})
+        self.assertEquals(control.ERROR, r['status'])
+
+    def testUnsupported(self):
+        r = self.c.Handle({'type': 'MOTOR_START'})
+        self.assertEquals(control.UNSUPPORTED, r['status'])
+        r = self.c.Handle({})
+        self.assertEquals(control.UNSUPPORTED, r['status'])
+
+    def testStatus(self):
+        r = self.c.Handle({'type': 'GET_STATUS'})
+        self.assertEquals(control.OK, r['status'])
+        self.assertEquals({'receiver': {'started': True}},
+                          r['station_status'])
+
+    def testHealth(self):
+        def Broken():
+            raise IOError('device unplugged')
+        self.c.RegisterStatus('motor', Broken)
+        self.c.SetDiskPath('.')
+        r = self.c.Handle({'type': 'GET_HEALTH'})
+        self.assertEquals(control.OK, r['status'])
+        health = r['health']
+        self.assertEquals({'started': True}, health['status']['receiver'])
+        self.assertEquals('device unplugged',
+                          health['status']['motor']['fault'])
+        self.assertTrue(health['station_time'] > 0)
+        self.assertTrue(health['disk_free_bytes'] >= 0)
+
+    def testFeatures(self):
+        self.assertEquals({'receiver': True, 'tnc': False, 'motor': False},
+                          self.c.Features())
+
+    def testProgramOffset(self):
+        self.assertEquals(
+            2.5, control.ProgramOffset({'offset_s': 2.5}, 1000.0))
+        self.assertEquals(
+            10.0, control.ProgramOffset(
+                {'offset_s': 2.5, 'station_timestamp': 1010.0}, 1000.0))
+
+    def testLane(self):
+        self.assertEquals('/Receiver',
+                          control.Lane({'type': 'RECEIVER_START'}))
+        self.assertEquals('/Motor', control.Lane({'type': 'MOTOR_STOP'}))
+        self.assertEquals('', control.Lane({'type': 'PING'}))
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -41,6 +41,20 @@ class DummyReceiverTest(unittest.TestCase):
         #self.assertTrue(isinstance(img, Image.Image))
         self.assertTrue(img is not None)
 
+    def testFrequencyProgram(self):
+        r = self.Create()
+
+        self.assertFalse(r.StartFrequencyProgram([]))
+
+        self.assertTrue(r.StartFrequencyProgram(
+                [(0.0, 145000000), (0.01, 145001000)]))
+        r._frequency_program_thread.join()
+        self.assertEqual(145001000, r.GetHardwareTunerHz())
+
+        self.assertTrue(r.StartFrequencyProgram([(10.0, 146000000)]))
+        r.StopFrequencyProgram()
+        self.assertEqual(145001000, r.GetHardwareTunerHz())
+
 
 if __name__ == '__main__':
     unittest.main()
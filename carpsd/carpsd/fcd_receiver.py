@@ -143,6 +143,9 @@ class FCDReceiver(receiver.Receiver):
         return upload.UploadAndDeleteFile(
             self._output_path, self._stream_url, self._sample_rate, 'SINT16')
 
+    def RecordingDir(self):
+        return self._dir
+
     def IsStarted(self):
         if self._fcd_pipe is None:
             return False
@@ -7,6 +7,7 @@ import time
 import json
 import cStringIO
 import base64
+import control
 import signalling
 
 
@@ -26,25 +27,43 @@ class ReceiverHandlers(object):
         client.RegisterHandler('/ReceiverStop', self.ReceiverStop)
         client.RegisterHandler('/ReceiverSetFrequency',
                                self.ReceiverSetFrequency)
+        client.RegisterHandler('/ReceiverFrequencyProgram',
+                               self.ReceiverFrequencyProgram)
         client.RegisterHandler('/ReceiverWaterfallPNG',
                                self.ReceiverWaterfallPNG)
 
+        client.RegisterCommand('RECEIVER_SET_FREQUENCY',
+                               self.ControlSetFrequency)
+        client.RegisterCommand('RECEIVER_START', self.ControlStart)
+        client.RegisterCommand('RECEIVER_STOP', self.ControlStop)
+        client.RegisterCommand('RECEIVER_FREQUENCY_PROGRAM',
+                               self.ControlFrequencyProgram)
+        client.RegisterStatus('receiver', self.Status)
+        if self.r.RecordingDir():
+            client.SetDiskPath(self.r.RecordingDir())
+
     def ReceiverGetInfo(self, params):
         return OK, 'application/json', json.dumps(self.r.GetInfoDict())
 
     def ReceiverGetState(self, params):
         return OK, 'application/json', json.dumps(self.r.GetStateDict())
 
-    def ReceiverStart(self, params):
+    def _Start(self, stream_url):
         signalling.Get().SignalReceiverStart()
-        stream_url = params['stream_url'][0]
         return self.r.Start(stream_url)
 
-    def ReceiverStop(self, params):
+    def _Stop(self):
         signalling.Get().SignalReceiverStop()
+        self.r.StopFrequencyProgram()
         self.r.Stop()
         return True
 
+    def ReceiverStart(self, params):
+        return self._Start(params['stream_url'][0])
+
+    def ReceiverStop(self, params):
+        return self._Stop()
+
     def ReceiverSetFrequency(self, params):
         if 'hz' not in params:
             return False
@@ -54,6 +73,35 @@ class ReceiverHandlers(object):
             return False
         return self.r.SetHardwareTunerHz(freq_hz)
 
+    def ReceiverFrequencyProgram(self, params):
+        if 'program' not in params:
+            return False
+        try:
+            program = json.loads(params['program'][0])
+        except ValueError:
+            return False
+        return self.r.StartFrequencyProgram(program)
+
+    def ControlSetFrequency(self, request):
+        return self.r.SetHardwareTunerHz(int(request['frequency_hz']))
+
+    def ControlStart(self, request):
+        return self._Start(request['stream_url'])
+
+    def ControlStop(self, request):
+        return self._Stop()
+
+    def ControlFrequencyProgram(self, request):
+        now = time.time()
+        program = [[control.ProgramOffset(c, now), int(c['frequency_hz'])]
+                   for c in request['frequency_program']]
+        return self.r.StartFrequencyProgram(program)
+
+    def Status(self):
+        status = dict(self.r.GetStateDict())
+        status['driver'] = self.r.GetInfoDict()['driver']
+        return status
+
     def ReceiverWaterfallPNG(self, params):
         img = self.r.WaterfallImage()
         if img:
@@ -75,6 +123,10 @@ class MotorHandlers(object):
         client.RegisterHandler('/MotorStart', self.MotorStart)
         client.RegisterHandler('/MotorStop', self.MotorStop)
 
+        client.RegisterCommand('MOTOR_START', self.ControlStart)
+        client.RegisterCommand('MOTOR_STOP', self.ControlStop)
+        client.RegisterStatus('motor', self.Status)
+
     def MotorGetInfo(self, params):
         return OK, 'application/json', json.dumps(self.m.GetInfoDict())
 
@@ -88,6 +140,22 @@ class MotorHandlers(object):
     def MotorStop(self, params):
         return self.m.Stop()
 
+    def ControlStart(self, request):
+        now = time.time()
+        program = [[control.ProgramOffset(c, now),
+                    float(c['azimuth_degrees']),
+                    float(c['altitude_degrees'])]
+                   for c in request['motor_program']]
+        return self.m.Start(program)
+
+    def ControlStop(self, request):
+        return self.m.Stop()
+
+    def Status(self):
+        status = dict(self.m.GetStateDict())
+        status['driver'] = self.m.GetInfoDict()['driver']
+        return status
+
 
 class TNCHandlers(object):
 
@@ -100,6 +168,10 @@ class TNCHandlers(object):
         client.RegisterHandler('/TNCGetLatestFrames', self.TNCGetLatestFrames)
         client.RegisterHandler('/TNCGetState', self.TNCGetState)
 
+        client.RegisterCommand('TNC_START', self.ControlStart)
+        client.RegisterCommand('TNC_STOP', self.ControlStop)
+        client.RegisterStatus('tnc', self.t.GetStateDict)
+
     def TNCStart(self, params):
         api_host = params['api_host'][0]
         try:
@@ -112,6 +184,14 @@ class TNCHandlers(object):
     def TNCStop(self, params):
         return self.t.Stop()
 
+    def ControlStart(self, request):
+        return self.t.Start(request['api_host'],
+                            int(request['api_port']),
+                            request['satellite_id'])
+
+    def ControlStop(self, request):
+        return self.t.Stop()
+
     def TNCGetLatestFrames(self, params):
         # This is mostly for interactive console use.
         ok, frames = self.t.GetLatestFrames()
@@ -3,9 +3,64 @@
 # Copyright 2012 Carpcomm GmbH
 # Author: Timothy Stranex <tstranex@carpcomm.com>
 
+import logging
+import threading
+import time
+
+
+class _FrequencyProgramThread(threading.Thread):
+
+    def __init__(self, program, receiver):
+        threading.Thread.__init__(self)
+
+        self.program = program
+        self.receiver = receiver
+        self.start_time = time.time()
+        self.should_stop = False
+
+    def run(self):
+        for t, freq_hz in self.program:
+            dt = self.start_time + t - time.time()
+            if dt < 0.0:
+                continue
+            time.sleep(dt)
+            if self.should_stop:
+                return
+            self.receiver.SetHardwareTunerHz(int(freq_hz))
+
+    def Stop(self):
+        self.should_stop = True
+
+
 class Receiver(object):
     """Interface to a software-defined radio."""
 
+    _frequency_program_thread = None
+
+    def StartFrequencyProgram(self, program):
+        """Retune the hardware tuner according to the given program.
+
+        program is a list of tuples: (time_seconds, freq_hz).
+        They should be sorted in ascending order by time.
+        The times are relative to when the method is called.
+
+        Any previous program is stopped. Returns True on success.
+        """
+        if not program:
+            return False
+        self.StopFrequencyProgram()
+        self._frequency_program_thread = _FrequencyProgramThread(
+            program, self)
+        self._frequency_program_thread.start()
+        logging.info('Started frequency program thread.')
+        return True
+
+    def StopFrequencyProgram(self):
+        """Stop any ongoing frequency program."""
+        if self._frequency_program_thread is not None:
+            self._frequency_program_thread.Stop()
+            self._frequency_program_thread = None
+
     def SetHardwareTunerHz(self, freq_hz):
         """Set the center frequency of the hardware tuner.
 
@@ -33,6 +88,10 @@ class Receiver(object):
         """Stop receiving data."""
         raise NotImplementedError()
 
+    def RecordingDir(self):
+        """Returns the directory where data is recorded or None."""
+        return None
+
     def IsStarted(self):
         """Returns true if we currently receiving data from the radio."""
         raise NotImplementedError()
@@ -100,6 +100,9 @@ class RTLSDRReceiver(receiver.Receiver):
         return upload.UploadAndDeleteFile(
             self._output_path, self._stream_url, 250977, 'UINT8')
 
+    def RecordingDir(self):
+        return self._dir
+
     def IsStarted(self):
         if self._pipe is None:
             return False
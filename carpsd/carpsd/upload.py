@@ -12,15 +12,27 @@ import threading
 import subprocess
 import os
 import base64
+import hashlib
 import json
 import httplib
 import socket
 import ssl
 import signalling
+import time
 import urllib
+import urlparse
 import datetime
 
 
+# Chunks are small so that little is lost when the connection drops.
+CHUNK_SIZE = 1024 * 1024
+
+# How many times the whole upload is retried and how long we wait between
+# attempts. Every attempt only sends the chunks the server is missing.
+MAX_ATTEMPTS = 20
+RETRY_DELAY_S = 30
+
+
 class _PipeWaitThread(threading.Thread):
     """Thread that calls wait() on a pipe."""
 
@@ -41,7 +53,146 @@ class _PipeWaitThread(threading.Thread):
             logging.exception('Error removing file: %s', self.path)
 
 
+class _Unsupported(Exception):
+    """The streamer doesn't support resumable uploads."""
+
+
+class _Rejected(Exception):
+    """The streamer refused the upload. Retrying won't help."""
+
+
+def _MissingRanges(received, size, chunk_size):
+    """Returns the (offset, length) chunks not covered by received."""
+    missing = []
+    pos = 0
+    for r in sorted(received, key=lambda r: r['start']) + [
+        {'start': size, 'end': size}]:
+        while pos < r['start']:
+            n = min(chunk_size, r['start'] - pos)
+            missing.append((pos, n))
+            pos += n
+        pos = max(pos, r['end'])
+    return missing
+
+
+class ResumableUpload(object):
+    """Uploads a file in chunks using the streamer's resumable protocol."""
+
+    def __init__(self, path, stream_url, rate, dtype):
+        self._path = path
+        self._stream_url = stream_url
+        self._rate = rate
+        self._dtype = dtype
+
+    def _SendRequest(self, method, path, body):
+        """Returns the status and body of the response."""
+        u = urlparse.urlparse(self._stream_url)
+        c = httplib.HTTPConnection(u.netloc, timeout=60)
+        c.request(method, u.path + path, body)
+        r = c.getresponse()
+        return r.status, r.read()
+
+    def _Request(self, method, path, body=''):
+        """Returns the response body. Raises HTTPException on errors."""
+        status, data = self._SendRequest(method, path, body)
+        if status not in (httplib.OK, httplib.NO_CONTENT):
+            raise httplib.HTTPException('%s %s: %d %s' % (
+                method, path, status, data))
+        return data
+
+    def _CreateSession(self, size):
+        status, data = self._SendRequest(
+            'POST', '/upload?' + urllib.urlencode([
+                    ('size', size),
+                    ('rate', self._rate),
+                    ('type', self._dtype)]), '')
+        if status == httplib.CONFLICT:
+            raise _Rejected(data)
+        if status in (httplib.BAD_REQUEST, httplib.METHOD_NOT_ALLOWED):
+            # Older streamers only accept PUT.
+            raise _Unsupported()
+        if status != httplib.OK:
+            raise httplib.HTTPException(
+                'Creating upload session: %d %s' % (status, data))
+        return json.loads(data)
+
+    def _Attempt(self, f):
+        size = os.path.getsize(self._path)
+        session = self._CreateSession(size)
+        missing = _MissingRanges(session['received'], size, CHUNK_SIZE)
+        logging.info('Uploading %d chunks of %d bytes', len(missing), size)
+        for offset, n in missing:
+            f.seek(offset)
+            chunk = f.read(n)
+            self._Request('PUT', '/upload?' + urllib.urlencode([
+                        ('offset', offset),
+                        ('sha256', hashlib.sha256(chunk).hexdigest())]),
+                          chunk)
+        self._Request('POST', '/upload/finalize')
+
+    def Run(self, sleep=time.sleep):
+        """Returns True if the upload succeeded.
+
+        Raises _Unsupported if the streamer is too old.
+        """
+        for attempt in range(MAX_ATTEMPTS):
+            try:
+                with open(self._path, 'rb') as f:
+                    self._Attempt(f)
+                return True
+            except _Rejected, e:
+                logging.error('Upload rejected: %s', e)
+                return False
+            except (socket.error, httplib.HTTPException, ValueError), e:
+                logging.warning('Upload attempt %d failed: %s',
+                                attempt + 1, e)
+                sleep(RETRY_DELAY_S)
+        return False
+
+
+class _ResumableUploadThread(threading.Thread):
+
+    def __init__(self, upload, fallback):
+        threading.Thread.__init__(self)
+        self.upload = upload
+        self.fallback = fallback
+
+    def run(self):
+        signalling.Get().SignalUploadStart()
+        try:
+            ok = self.upload.Run()
+        except _Unsupported:
+            logging.info('Resumable upload not supported.')
+            signalling.Get().SignalUploadStop()
+            self.fallback()
+            return
+        except Exception:
+            logging.exception('Error during upload')
+            ok = False
+        if ok:
+            logging.info('Upload complete.')
+        else:
+            logging.error('Upload failed.')
+        signalling.Get().SignalUploadStop()
+        try:
+            os.remove(self.upload._path)
+        except OSError:
+            logging.exception('Error removing file: %s', self.upload._path)
+
+
 def UploadAndDeleteFile(path, stream_url, rate, dtype):
+    """Upload the finalized file in another thread.
+
+    The resumable protocol is used unless the streamer doesn't support it.
+    """
+    u = ResumableUpload(path, stream_url, rate, dtype)
+    t = _ResumableUploadThread(
+        u, lambda: _CurlUploadAndDeleteFile(path, stream_url, rate, dtype))
+    t.start()
+    return True
+
+
+def _CurlUploadAndDeleteFile(path, stream_url, rate, dtype):
     """Upload the finalized file in another process."""
 
     query = '?rate=%d&type=%s' % (rate, dtype)
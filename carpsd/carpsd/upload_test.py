@@ -5,10 +5,15 @@
 
 import upload
 
+import hashlib
+import httplib
+import json
+import socket
 import unittest
 import subprocess
 import tempfile
 import os.path
+import urlparse
 
 
 class UploadTest(unittest.TestCase):
@@ -26,6 +31,88 @@ class UploadTest(unittest.TestCase):
 
         self.assertFalse(os.path.exists(path))
 
+    def testMissingRanges(self):
+        self.assertEquals([(0, 4), (4, 4), (8, 2)],
+                          upload._MissingRanges([], 10, 4))
+        self.assertEquals([(0, 2), (5, 4), (9, 1)],
+                          upload._MissingRanges(
+                [{'start': 2, 'end': 5}], 10, 4))
+        self.assertEquals([], upload._MissingRanges(
+                [{'start': 0, 'end': 10}], 10, 4))
+
+
+class _FakeStreamer(object):
+    """Implements the resumable upload protocol and drops some requests."""
+
+    def __init__(self, drop_every):
+        self.data = None
+        self.received = []
+        self.finalized = None
+        self.requests = 0
+        self.drop_every = drop_every
+
+    def SendRequest(self, method, path, body):
+        self.requests += 1
+        if self.requests % self.drop_every == 0:
+            raise socket.error('connection dropped')
+
+        u = urlparse.urlparse(path)
+        q = dict(urlparse.parse_qsl(u.query))
+        if method == 'POST' and u.path == '/upload':
+            if self.data is None:
+                self.data = ['\0'] * int(q['size'])
+            return httplib.OK, json.dumps({'received': self.received})
+        if method == 'PUT' and u.path == '/upload':
+            if hashlib.sha256(body).hexdigest() != q['sha256']:
+                return httplib.BAD_REQUEST, 'checksum'
+            offset = int(q['offset'])
+            self.data[offset:offset + len(body)] = list(body)
+            self.received.append(
+                {'start': offset, 'end': offset + len(body)})
+            return httplib.OK, json.dumps({'received': self.received})
+        if method == 'POST' and u.path == '/upload/finalize':
+            self.finalized = ''.join(self.data)
+            return httplib.NO_CONTENT, ''
+        return httplib.NOT_FOUND, ''
+
+
+class ResumableUploadTest(unittest.TestCase):
+
+    def setUp(self):
+        self.orig_chunk_size = upload.CHUNK_SIZE
+        upload.CHUNK_SIZE = 3
+        f, self.path = tempfile.mkstemp()
+        os.write(f, 'abcdefghijk')
+        os.close(f)
+
+    def tearDown(self):
+        upload.CHUNK_SIZE = self.orig_chunk_size
+        os.remove(self.path)
+
+    def testResume(self):
+        streamer = _FakeStreamer(drop_every=3)
+        u = upload.ResumableUpload(
+            self.path, 'http://host/123', 250977, 'UINT8')
+        u._SendRequest = streamer.SendRequest
+        sleeps = []
+        self.assertTrue(u.Run(sleep=sleeps.append))
+        self.assertEquals('abcdefghijk', streamer.finalized)
+        self.assertTrue(sleeps)
+
+    def testRejected(self):
+        u = upload.ResumableUpload(
+            self.path, 'http://host/123', 250977, 'UINT8')
+        u._SendRequest = lambda method, path, body: (
+            httplib.CONFLICT, 'IQ data already uploaded.')
+        self.assertFalse(u.Run(sleep=lambda s: None))
+
+    def testUnsupported(self):
+        u = upload.ResumableUpload(
+            self.path, 'http://host/123', 250977, 'UINT8')
+        u._SendRequest = lambda method, path, body: (
+            httplib.BAD_REQUEST, 'Expected PUT method.')
+        self.assertRaises(upload._Unsupported, u.Run)
+
 
 if __name__ == '__main__':
     unittest.main()
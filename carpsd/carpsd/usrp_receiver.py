@@ -94,6 +94,9 @@ class USRPReceiver(receiver.Receiver):
         return upload.UploadAndDeleteFile(
             self._output_path, self._stream_url, self._sample_rate_hz, 'SINT16')
 
+    def RecordingDir(self):
+        return self._dir
+
     def IsStarted(self):
         if self._pipe is None:
             return False
@@ -0,0 +1,89 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package archive
+
+import "errors"
+import "flag"
+import "fmt"
+import "io"
+import "os"
+
+var archive_backend = flag.String("archive_backend", "s3",
+	"Storage backend for IQ recordings: s3 or local")
+var local_archive_dir = flag.String("local_archive_dir",
+	"/tmp/carpcomm_archive",
+	"Directory holding the recordings for the local archive_backend")
+
+// Archive is durable storage for IQ recordings and other large files that
+// don't fit in the database.
+type Archive interface {
+	// Create starts writing the file at path. Data is uploaded in chunks
+	// as it's written so there's no limit on the size. The file only
+	// appears in the archive once the Writer is closed successfully.
+	Create(path string) (Writer, error)
+
+	// Open returns the contents of the file at path.
+	Open(path string) (io.ReadCloser, error)
+
+	Delete(path string) error
+}
+
+type Writer interface {
+	io.Writer
+
+	// Close finishes the upload.
+	Close() error
+
+	// Abort discards the upload. The Writer can't be used afterwards.
+	Abort() error
+}
+
+// New opens the archive selected by the --archive_backend flag.
+func New() (Archive, error) {
+	switch *archive_backend {
+	case "s3":
+		return NewS3Archive()
+	case "local":
+		return NewLocalArchive(*local_archive_dir)
+	}
+	return nil, errors.New(
+		fmt.Sprintf("Unknown archive_backend: %s", *archive_backend))
+}
+
+// IQPath returns the path of a contact's IQ recording in the archive.
+func IQPath(contact_id string) string {
+	return "iq/" + contact_id
+}
+
+// UploadIQPath returns the path for one upload of a contact's IQ recording.
+// Each upload has its own path so that concurrent uploads can't overwrite
+// the recording that the contact refers to.
+func UploadIQPath(contact_id, nonce string) string {
+	return IQPath(contact_id) + "." + nonce
+}
+
+// SpectrogramPath returns the path of a contact's spectrogram PNG in the
+// archive.
+func SpectrogramPath(contact_id string) string {
+	return "spectrogram/" + contact_id + ".png"
+}
+
+// Fetch copies the file at path from the archive to local_path.
+func Fetch(a Archive, path, local_path string) (int64, error) {
+	r, err := a.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	f, err := os.Create(local_path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
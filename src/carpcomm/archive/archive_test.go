@@ -0,0 +1,143 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package archive
+
+import "launchpad.net/goamz/s3"
+
+import "bytes"
+import "errors"
+import "io"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "testing"
+
+func TestLocalArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive_test")
+	if err != nil {
+		t.Fatalf("TempDir error: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	a, err := NewLocalArchive(dir)
+	if err != nil {
+		t.Fatalf("NewLocalArchive error: %s", err.Error())
+	}
+
+	w, err := a.Create(IQPath("123"))
+	if err != nil {
+		t.Fatalf("Create error: %s", err.Error())
+	}
+	io.WriteString(w, "hello ")
+	if _, err := a.Open(IQPath("123")); err == nil {
+		t.Errorf("File visible before Close")
+	}
+	io.WriteString(w, "world")
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close error: %s", err.Error())
+	}
+
+	local_path := filepath.Join(dir, "fetched")
+	n, err := Fetch(a, IQPath("123"), local_path)
+	if err != nil {
+		t.Fatalf("Fetch error: %s", err.Error())
+	}
+	data, _ := ioutil.ReadFile(local_path)
+	if n != 11 || string(data) != "hello world" {
+		t.Errorf("Fetched %d bytes: %q", n, data)
+	}
+
+	w, _ = a.Create(IQPath("456"))
+	io.WriteString(w, "discarded")
+	if err := w.Abort(); err != nil {
+		t.Errorf("Abort error: %s", err.Error())
+	}
+	if _, err := a.Open(IQPath("456")); err == nil {
+		t.Errorf("Aborted file is visible")
+	}
+
+	if err := a.Delete(IQPath("123")); err != nil {
+		t.Errorf("Delete error: %s", err.Error())
+	}
+	if _, err := a.Open(IQPath("123")); err == nil {
+		t.Errorf("Deleted file is visible")
+	}
+
+	if _, err := a.Create("../escape"); err == nil {
+		t.Errorf("Accepted path outside the archive")
+	}
+}
+
+type fakeMulti struct {
+	parts [][]byte
+	fail_part int
+	completed []s3.Part
+	aborted bool
+}
+
+func (m *fakeMulti) PutPart(n int, r io.ReadSeeker) (s3.Part, error) {
+	if n == m.fail_part {
+		return s3.Part{}, errors.New("PutPart failed")
+	}
+	data, _ := ioutil.ReadAll(r)
+	m.parts = append(m.parts, data)
+	return s3.Part{N: n, Size: int64(len(data))}, nil
+}
+
+func (m *fakeMulti) Complete(parts []s3.Part) error {
+	m.completed = parts
+	return nil
+}
+
+func (m *fakeMulti) Abort() error {
+	m.aborted = true
+	return nil
+}
+
+func TestS3WriterParts(t *testing.T) {
+	m := &fakeMulti{}
+	w := newS3Writer(m, 4)
+	io.WriteString(w, "ab")
+	io.WriteString(w, "cdefghij")
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close error: %s", err.Error())
+	}
+
+	expected := []string{"abcd", "efgh", "ij"}
+	if len(m.parts) != len(expected) || len(m.completed) != len(expected) {
+		t.Fatalf("Wrong parts: %q, completed %v", m.parts, m.completed)
+	}
+	for i, p := range m.parts {
+		if !bytes.Equal(p, []byte(expected[i])) {
+			t.Errorf("Part %d: %q, expected %q", i, p, expected[i])
+		}
+		if m.completed[i].N != i+1 {
+			t.Errorf("Part %d has number %d", i, m.completed[i].N)
+		}
+	}
+}
+
+func TestS3WriterEmpty(t *testing.T) {
+	m := &fakeMulti{}
+	if err := newS3Writer(m, 4).Close(); err != nil {
+		t.Fatalf("Close error: %s", err.Error())
+	}
+	if len(m.completed) != 1 {
+		t.Errorf("Expected one empty part, got %v", m.completed)
+	}
+}
+
+func TestS3WriterFailure(t *testing.T) {
+	m := &fakeMulti{fail_part: 2}
+	w := newS3Writer(m, 4)
+	if _, err := io.WriteString(w, "abcdefghij"); err == nil {
+		t.Errorf("Expected write error")
+	}
+	if err := w.Close(); err == nil {
+		t.Errorf("Expected close error")
+	}
+	if !m.aborted || m.completed != nil {
+		t.Errorf("Upload wasn't aborted")
+	}
+}
@@ -0,0 +1,87 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package archive
+
+import "errors"
+import "io"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "strings"
+
+// LocalArchive stores files in a local directory. It allows the servers to
+// run without AWS, e.g. for development and tests.
+type LocalArchive struct {
+	dir string
+}
+
+func NewLocalArchive(dir string) (*LocalArchive, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &LocalArchive{dir}, nil
+}
+
+func (a *LocalArchive) localPath(path string) (string, error) {
+	if path == "" || strings.Contains(path, "..") ||
+		filepath.IsAbs(path) {
+		return "", errors.New("Invalid archive path: " + path)
+	}
+	return filepath.Join(a.dir, filepath.FromSlash(path)), nil
+}
+
+// Data is written to a temporary file which is renamed when it's closed so
+// that readers never see a partial file.
+type localWriter struct {
+	f *os.File
+	path string
+}
+
+func (a *LocalArchive) Create(path string) (Writer, error) {
+	p, err := a.localPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(p), ".upload-")
+	if err != nil {
+		return nil, err
+	}
+	return &localWriter{f, p}, nil
+}
+
+func (w *localWriter) Write(b []byte) (int, error) {
+	return w.f.Write(b)
+}
+
+func (w *localWriter) Close() error {
+	if err := w.f.Close(); err != nil {
+		os.Remove(w.f.Name())
+		return err
+	}
+	return os.Rename(w.f.Name(), w.path)
+}
+
+func (w *localWriter) Abort() error {
+	w.f.Close()
+	return os.Remove(w.f.Name())
+}
+
+func (a *LocalArchive) Open(path string) (io.ReadCloser, error) {
+	p, err := a.localPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (a *LocalArchive) Delete(path string) error {
+	p, err := a.localPath(path)
+	if err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
@@ -0,0 +1,140 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package archive
+
+import "launchpad.net/goamz/aws"
+import "launchpad.net/goamz/s3"
+
+import "bytes"
+import "errors"
+import "flag"
+import "fmt"
+import "io"
+import "log"
+
+var archive_s3_bucket = flag.String("archive_s3_bucket", "carpcomm-iq",
+	"S3 bucket for the s3 archive_backend")
+var archive_s3_region = flag.String("archive_s3_region", "us-east-1",
+	"AWS region of the archive bucket")
+var archive_s3_endpoint = flag.String("archive_s3_endpoint", "",
+	"Endpoint of an S3-compatible service to use instead of AWS")
+
+// S3 requires every part except the last to be at least 5 MB.
+const kS3PartSize = 5 * 1024 * 1024
+
+// S3Archive stores files in an S3 bucket using multipart uploads.
+type S3Archive struct {
+	bucket *s3.Bucket
+}
+
+// NewS3Archive opens the bucket given by the flags. The credentials are taken
+// from the environment.
+func NewS3Archive() (*S3Archive, error) {
+	auth, err := aws.EnvAuth()
+	if err != nil {
+		log.Printf("AWS auth error: %s", err.Error())
+		return nil, err
+	}
+	region, ok := aws.Regions[*archive_s3_region]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf(
+			"Unknown AWS region: %s", *archive_s3_region))
+	}
+	if *archive_s3_endpoint != "" {
+		region.S3Endpoint = *archive_s3_endpoint
+	}
+	return &S3Archive{s3.New(auth, region).Bucket(*archive_s3_bucket)}, nil
+}
+
+// The parts of s3.Multi that we use.
+type multipartUpload interface {
+	PutPart(n int, r io.ReadSeeker) (s3.Part, error)
+	Complete(parts []s3.Part) error
+	Abort() error
+}
+
+// Buffers one part at a time.
+type s3Writer struct {
+	multi multipartUpload
+	part_size int
+	buf []byte
+	parts []s3.Part
+	err error
+}
+
+func newS3Writer(multi multipartUpload, part_size int) *s3Writer {
+	return &s3Writer{
+		multi: multi,
+		part_size: part_size,
+		buf: make([]byte, 0, part_size)}
+}
+
+func (a *S3Archive) Create(path string) (Writer, error) {
+	multi, err := a.bucket.InitMulti(
+		path, "application/octet-stream", s3.Private)
+	if err != nil {
+		return nil, err
+	}
+	return newS3Writer(multi, kS3PartSize), nil
+}
+
+func (w *s3Writer) flush() error {
+	part, err := w.multi.PutPart(
+		len(w.parts)+1, bytes.NewReader(w.buf))
+	if err != nil {
+		return err
+	}
+	w.parts = append(w.parts, part)
+	w.buf = w.buf[:0]
+	return nil
+}
+
+func (w *s3Writer) Write(b []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	written := 0
+	for len(b) > 0 {
+		n := w.part_size - len(w.buf)
+		if n > len(b) {
+			n = len(b)
+		}
+		w.buf = append(w.buf, b[:n]...)
+		b = b[n:]
+		written += n
+		if len(w.buf) == w.part_size {
+			if w.err = w.flush(); w.err != nil {
+				return written, w.err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (w *s3Writer) Close() error {
+	if w.err != nil {
+		w.multi.Abort()
+		return w.err
+	}
+	// A multipart upload needs at least one part, even if it's empty.
+	if len(w.buf) > 0 || len(w.parts) == 0 {
+		if err := w.flush(); err != nil {
+			w.multi.Abort()
+			return err
+		}
+	}
+	return w.multi.Complete(w.parts)
+}
+
+func (w *s3Writer) Abort() error {
+	return w.multi.Abort()
+}
+
+func (a *S3Archive) Open(path string) (io.ReadCloser, error) {
+	return a.bucket.GetReader(path)
+}
+
+func (a *S3Archive) Delete(path string) error {
+	return a.bucket.Del(path)
+}
@@ -0,0 +1,163 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package db
+
+import "carpcomm/pb"
+import "code.google.com/p/goprotobuf/proto"
+
+import "crypto/subtle"
+import "reflect"
+import "strings"
+import "time"
+
+type APITokenDB struct {
+	table Table
+}
+
+const kAPITokenColumn = "pb.APIToken"
+const kAPITokenKeyUserId = "userid"
+
+func NewAPITokenDB(table Table) *APITokenDB {
+	return &APITokenDB{table}
+}
+
+// NewAPIToken creates a token acting for the station. It also returns the
+// token to give to the user. Only its hash is stored so this is the only
+// chance to show it. A zero expires means that the token doesn't expire.
+func NewAPIToken(userid, station_id, name string,
+	scopes []pb.APIToken_Scope, satellite_ids []string,
+	now, expires time.Time) (*pb.APIToken, string, error) {
+	id, err := CryptoRandId()
+	if err != nil {
+		return nil, "", err
+	}
+	secret, salt, err := randomSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	t := &pb.APIToken{
+		Id: proto.String(id),
+		Userid: proto.String(userid),
+		StationId: proto.String(station_id),
+		Name: proto.String(name),
+		Scope: scopes,
+		SatelliteId: satellite_ids,
+		Salt: salt,
+		Hash: hashSecret(salt, secret),
+		Created: proto.Int64(now.Unix()),
+	}
+	if !expires.IsZero() {
+		t.Expires = proto.Int64(expires.Unix())
+	}
+	return t, id + "." + secret, nil
+}
+
+// APITokenAllows returns whether the token may be used for the scope and
+// satellite.
+func APITokenAllows(t *pb.APIToken,
+	scope pb.APIToken_Scope, satellite_id string) bool {
+	found := false
+	for _, s := range t.Scope {
+		if s == scope {
+			found = true
+		}
+	}
+	if !found {
+		return false
+	}
+	if len(t.SatelliteId) == 0 {
+		return true
+	}
+	for _, id := range t.SatelliteId {
+		if id == satellite_id {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *APITokenDB) Store(t *pb.APIToken) error {
+	values, err := encodeItem(kAPITokenColumn, t)
+	if err != nil {
+		return err
+	}
+	if t.Userid != nil {
+		values[kAPITokenKeyUserId] = *t.Userid
+	}
+	return db.table.put(*t.Id, values)
+}
+
+// Returns nil, nil if id was not found.
+func (db *APITokenDB) Lookup(id string) (*pb.APIToken, error) {
+	t := &pb.APIToken{}
+	found, err := db.table.getProto(id, kAPITokenColumn, t)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+	return t, nil
+}
+
+func (db *APITokenDB) UserTokens(userid string) ([]*pb.APIToken, error) {
+	result, err := lookupByKeyValue(db.table, kAPITokenColumn,
+		kAPITokenKeyUserId, userid, reflect.TypeOf(pb.APIToken{}))
+	if err != nil {
+		return nil, err
+	}
+	conv := make([]*pb.APIToken, len(result))
+	for i, v := range result {
+		conv[i] = v.(*pb.APIToken)
+	}
+	return conv, nil
+}
+
+// Revoke stops the token from working. The token is kept so that it still
+// shows up in the audit log.
+func (db *APITokenDB) Revoke(t *pb.APIToken, now time.Time) error {
+	t.Revoked = proto.Int64(now.Unix())
+	return db.Store(t)
+}
+
+// Authenticate looks up the token and checks its secret, revocation and
+// expiry. The attempt is recorded in the audit log. Returns nil, nil if the
+// token isn't valid.
+func (db *APITokenDB) Authenticate(token, source string) (
+	*pb.APIToken, error) {
+	id := token
+	secret := ""
+	if i := strings.Index(token, "."); i >= 0 {
+		id, secret = token[:i], token[i+1:]
+	}
+
+	t, err := db.Lookup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := "ok"
+	if t == nil {
+		result = "denied (unknown token)"
+	} else if subtle.ConstantTimeCompare(
+		t.Hash, hashSecret(t.Salt, secret)) != 1 {
+		result = "denied"
+	} else if t.Revoked != nil {
+		result = "denied (revoked)"
+	} else if t.Expires != nil && time.Now().Unix() >= t.GetExpires() {
+		result = "denied (expired)"
+	}
+	logAuth("api token auth: token=%q station=%q source=%q result=%s",
+		id, t.GetStationId(), source, result)
+	if result != "ok" {
+		return nil, nil
+	}
+	return t, nil
+}
+
+// Create the database table.
+func (db *APITokenDB) Create() error {
+	return db.table.create()
+}
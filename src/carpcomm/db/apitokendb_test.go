@@ -0,0 +1,91 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package db
+
+import "carpcomm/pb"
+import "os"
+import "strings"
+import "testing"
+import "time"
+
+func TestAPITokenDB(t *testing.T) {
+	d, dir := newTestLocalDomain(t)
+	defer os.RemoveAll(dir)
+	tokendb := d.NewAPITokenDB()
+
+	now := time.Now()
+	tok, token, err := NewAPIToken("u1", "s1", "test",
+		[]pb.APIToken_Scope{pb.APIToken_READ_PACKETS}, nil,
+		now, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("NewAPIToken: %s", err.Error())
+	}
+	if !strings.HasPrefix(token, tok.GetId()+".") {
+		t.Errorf("Token should start with its id: %s", token)
+	}
+	if err := tokendb.Store(tok); err != nil {
+		t.Fatalf("Store: %s", err.Error())
+	}
+
+	got, err := tokendb.Authenticate(token, "test")
+	if err != nil || got == nil || got.GetStationId() != "s1" {
+		t.Errorf("Token rejected: %v, %v", got, err)
+	}
+	for _, bad := range []string{
+		tok.GetId(), tok.GetId() + ".x", token + "x", "missing.x"} {
+		if got, _ := tokendb.Authenticate(bad, "test"); got != nil {
+			t.Errorf("Bad token accepted: %s", bad)
+		}
+	}
+
+	tokens, err := tokendb.UserTokens("u1")
+	if err != nil || len(tokens) != 1 {
+		t.Errorf("Wrong user tokens: %v, %v", tokens, err)
+	}
+
+	if err := tokendb.Revoke(tok, now); err != nil {
+		t.Fatalf("Revoke: %s", err.Error())
+	}
+	// A fresh table must see the revocation.
+	if got, _ := d.NewAPITokenDB().Authenticate(token, "test"); got != nil {
+		t.Errorf("Revoked token accepted")
+	}
+	got, err = tokendb.Lookup(tok.GetId())
+	if err != nil || got == nil || got.GetRevoked() != now.Unix() {
+		t.Errorf("Revoked token not stored: %v, %v", got, err)
+	}
+}
+
+func TestAPITokenExpired(t *testing.T) {
+	d, dir := newTestLocalDomain(t)
+	defer os.RemoveAll(dir)
+	tokendb := d.NewAPITokenDB()
+
+	now := time.Now()
+	tok, token, _ := NewAPIToken("u1", "s1", "test",
+		[]pb.APIToken_Scope{pb.APIToken_READ_PACKETS}, nil,
+		now.Add(-2*time.Hour), now.Add(-time.Hour))
+	tokendb.Store(tok)
+	if got, _ := tokendb.Authenticate(token, "test"); got != nil {
+		t.Errorf("Expired token accepted")
+	}
+}
+
+func TestAPITokenAllows(t *testing.T) {
+	tok := &pb.APIToken{
+		Scope: []pb.APIToken_Scope{pb.APIToken_POST_PACKETS},
+	}
+	if !APITokenAllows(tok, pb.APIToken_POST_PACKETS, "sat") {
+		t.Errorf("Scope not allowed")
+	}
+	if APITokenAllows(tok, pb.APIToken_READ_IQ, "sat") {
+		t.Errorf("Wrong scope allowed")
+	}
+
+	tok.SatelliteId = []string{"sat"}
+	if !APITokenAllows(tok, pb.APIToken_POST_PACKETS, "sat") ||
+		APITokenAllows(tok, pb.APIToken_POST_PACKETS, "other") {
+		t.Errorf("Wrong satellite restriction")
+	}
+}
@@ -10,7 +10,7 @@ import "code.google.com/p/goprotobuf/proto"
 import "reflect"
 
 type CommentDB struct {
-	table *SDBTable
+	table Table
 }
 
 const kCommentColumn = "pb.Comment"
@@ -18,7 +18,7 @@ const kCommentKeyUserId = "user_id"
 const kCommentKeyObjectId = "object_id"
 const kCommentKeyTimestamp = "timestamp"
 
-func NewCommentDB(table *SDBTable) *CommentDB {
+func NewCommentDB(table Table) *CommentDB {
 	return &CommentDB{table}
 }
 
@@ -57,15 +57,12 @@ func (db *CommentDB) Lookup(id string) (*pb.Comment, error) {
 // Results are sorted by timestamp (newest first).
 func (db *CommentDB) SearchByObjectId(object_id string) (
 	[]*pb.Comment, error) {
-	query := fmt.Sprintf(
-		"select * from `%s` where `%s` = '%s' and `%s` is not null order by `%s` desc",
-		db.table.domain.Name,
-		kCommentKeyObjectId,
-		object_id,
-		kCommentKeyTimestamp,
-		kCommentKeyTimestamp)
+	q := query{
+		key: kCommentKeyObjectId,
+		value: object_id,
+		order_by: kCommentKeyTimestamp}
 	result, err := db.table.search(
-		query, kCommentColumn, reflect.TypeOf(pb.Comment{}))
+		q, kCommentColumn, reflect.TypeOf(pb.Comment{}))
 	if err != nil {
 		return nil, err
 	}
@@ -77,7 +74,7 @@ func (db *CommentDB) SearchByObjectId(object_id string) (
 }
 
 func (db *CommentDB) GetAll() ([]*pb.Comment, error) {
-	result, err := db.table.getAll(kCommentColumn,
+	result, err := getAll(db.table, kCommentColumn,
 		reflect.TypeOf(pb.Comment{}))
 	if err != nil {
 		return nil, err
@@ -11,8 +11,8 @@ import "code.google.com/p/goprotobuf/proto"
 import "encoding/base64"
 import "fmt"
 import "reflect"
-import "errors"
 import "strconv"
+import "strings"
 import "math/rand"
 import "math"
 import "log"
@@ -21,6 +21,7 @@ import "time"
 const maxRetries = 5
 
 
+// SDBTable is a Table stored in an Amazon SimpleDB domain.
 type SDBTable struct {
 	s *sdb.SDB
 	domain *sdb.Domain
@@ -33,14 +34,17 @@ func NewSDBTable(auth *aws.Auth, region *aws.Region, domain string) *SDBTable {
 	return t
 }
 
-// Keep in sync with _DecodeItem in carpcomm/tools/table.py.
-func decodeItem(attrs []sdb.Attr, column string, p proto.Message) (
-	found bool, err error) {
-
+func attrMap(attrs []sdb.Attr) map[string]string {
 	table := make(map[string]string)
 	for _, v := range(attrs) {
 		table[v.Name] = v.Value
 	}
+	return table
+}
+
+// Keep in sync with _DecodeItem in carpcomm/tools/table.py.
+func decodeItem(table map[string]string, column string, p proto.Message) (
+	found bool, err error) {
 
 	column_count := table[column + ".v2"]
 	encoded_data := ""
@@ -116,7 +120,7 @@ func (table *SDBTable) getProto(id, column string, p proto.Message) (
 	if err != nil {
 		return false, err
 	}
-	return decodeItem(resp.Attrs, column, p)
+	return decodeItem(attrMap(resp.Attrs), column, p)
 }
 
 func (table *SDBTable) put(id string, values map[string]string) error {
@@ -142,12 +146,30 @@ func (table *SDBTable) put(id string, values map[string]string) error {
 	return err
 }
 
-func (table *SDBTable) setProto(id, column string, p proto.Message) error {
-	values, err := encodeItem(column, p)
+func (table *SDBTable) putIf(id string, values map[string]string,
+	key, expected string) (bool, error) {
+	var attrs sdb.PutAttrs
+	for k, v := range values {
+		attrs.Replace(k, v)
+	}
+	if expected == "" {
+		attrs.IfMissing(key)
+	} else {
+		attrs.IfValue(key, expected)
+	}
+
+	// Unlike put, we don't retry since the first attempt may have
+	// succeeded. The caller has to read the item again anyway.
+	_, err := table.domain.Item(id).PutAttrs(&attrs)
+	e, ok := err.(*sdb.Error)
+	if ok && (e.Code == "ConditionalCheckFailed" ||
+		e.Code == "AttributeDoesNotExist") {
+		return false, nil
+	}
 	if err != nil {
-		return err
+		return false, err
 	}
-	return table.put(id, values)
+	return true, nil
 }
 
 func (table *SDBTable) delete(id, column string) error {
@@ -157,9 +179,37 @@ func (table *SDBTable) delete(id, column string) error {
 	return nil
 }
 
-func (table *SDBTable) search(query, column string, t reflect.Type) (
+// Translate a query into a SimpleDB select expression.
+func (table *SDBTable) selectExpression(q query, column string) string {
+	if q.order_by == "" {
+		s := fmt.Sprintf("select `%s` from `%s`",
+			column, table.domain.Name)
+		if q.key != "" {
+			s += fmt.Sprintf(" where `%s` = '%s'", q.key, q.value)
+		}
+		return s
+	}
+
+	s := fmt.Sprintf("select * from `%s` where ", table.domain.Name)
+	if q.key != "" {
+		s += fmt.Sprintf("`%s` = '%s' and ", q.key, q.value)
+	}
+	order := "desc"
+	if q.ascending {
+		order = "asc"
+	}
+	s += fmt.Sprintf("`%s` is not null order by `%s` %s",
+		q.order_by, q.order_by, order)
+	if q.limit > 0 {
+		s += fmt.Sprintf(" limit %d", q.limit)
+	}
+	return s
+}
+
+func (table *SDBTable) search(q query, column string, t reflect.Type) (
 	[]proto.Message, error) {
-	resp, err := table.domain.Select(query, true)//, nil)
+	resp, err := table.domain.Select(
+		table.selectExpression(q, column), true)//, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -168,7 +218,7 @@ func (table *SDBTable) search(query, column string, t reflect.Type) (
 	i := 0
 	for _, v := range(resp.Items) {
 		p := reflect.New(t).Interface().(proto.Message)
-		found, err := decodeItem(v.Attrs, column, p)
+		found, err := decodeItem(attrMap(v.Attrs), column, p)
 		if err != nil {
 			return nil, err
 		}
@@ -181,98 +231,56 @@ func (table *SDBTable) search(query, column string, t reflect.Type) (
 	return result[:i], nil
 }
 
-// Create the database table.
-func (table *SDBTable) create() error {
-	_, err := table.domain.CreateDomain()
-	return err
-}
-
-
-type Iterator struct {
-	table *SDBTable
-	query string
-	consistent bool
-	column string
-	resp *sdb.SelectResp
-	i int
-	fetchError error
-}
-
-func (it *Iterator) fetch() error {
-	/*
-var nextToken *string = nil
-	if it.resp != nil {
-		nextToken = &it.resp.NextToken
-	}
-*/
-	resp, err := it.table.domain.Select(it.query, it.consistent)//, nextToken)
-	if err != nil {
-		it.fetchError = err
-		return err
-	}
-	it.resp = resp
-	it.i = 0
-	return nil
-}
+// SimpleDB returns at most this many items per select.
+const kSDBPageSize = 100
 
-func (it *Iterator) Done() bool {
-	//return it.resp.NextToken == "" && it.i == len(it.resp.Items)
-	return it.i == len(it.resp.Items)
+// Quotes a string for use in a select expression.
+func sdbQuote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
 }
 
-func (it *Iterator) Get() ([]byte, error) {
-	if it.fetchError != nil {
-		return nil, it.fetchError
-	}
-	if it.Done() {
-		return nil, errors.New("Already done")
-	}
+// The library doesn't support NextToken so instead each page continues
+// after the id of the last item of the previous one.
+func (table *SDBTable) iterate(q query, column string, t reflect.Type,
+	f func(p proto.Message) error) error {
+	last := ""
+	for {
+		s := fmt.Sprintf("select `%s` from `%s` where ",
+			column, table.domain.Name)
+		if q.key != "" {
+			s += fmt.Sprintf("`%s` = %s and ",
+				q.key, sdbQuote(q.value))
+		}
+		s += fmt.Sprintf(
+			"itemName() > %s order by itemName() limit %d",
+			sdbQuote(last), kSDBPageSize)
 
-	for _, v := range it.resp.Items[it.i].Attrs {
-		if v.Name == it.column {
-			data, err := base64.StdEncoding.DecodeString(v.Value)
+		resp, err := table.domain.Select(s, true)
+		if err != nil {
+			return err
+		}
+		for _, v := range resp.Items {
+			last = v.Name
+			p := reflect.New(t).Interface().(proto.Message)
+			found, err := decodeItem(attrMap(v.Attrs), column, p)
 			if err != nil {
-				return nil, err
+				return err
 			}
-			return data, nil
-		 }
-	}
-
-	return nil, errors.New(fmt.Sprintf("Column missing: %s", it.column))
-}
-
-func (it *Iterator) Next() {
-	it.i++
-	if it.Done() {
-		return
-	}
-	if it.i == len(it.resp.Items) {
-		it.fetch()
+			if !found {
+				continue
+			}
+			if err := f(p); err != nil {
+				return err
+			}
+		}
+		if len(resp.Items) < kSDBPageSize {
+			return nil
+		}
 	}
 }
 
-func (table *SDBTable) searchIterator(query, column string) (Iterator, error) {
-	it := Iterator{table, query, true, column, nil, 0, nil}
-	err := it.fetch()
-	return it, err
-}
-
-
-
-func (table *SDBTable) getAll(column string, t reflect.Type) (
-	[]proto.Message, error) {
-	query := fmt.Sprintf("select `%s` from `%s`", column, table.domain.Name)
-	return table.search(query, column, t)
-}
-
-func (table *SDBTable) GetAllIterator(column string) (Iterator, error) {
-	query := fmt.Sprintf("select `%s` from `%s`", column, table.domain.Name)
-	return table.searchIterator(query, column)
-}
-
-func (table *SDBTable) lookupByKeyValue(
-	column, key, value string, t reflect.Type) ([]proto.Message, error) {
-	query := fmt.Sprintf("select `%s` from `%s` where `%s` = '%s'",
-		column, table.domain.Name, key, value)
-	return table.search(query, column, t)
+// Create the database table.
+func (table *SDBTable) create() error {
+	_, err := table.domain.CreateDomain()
+	return err
 }
@@ -96,6 +96,8 @@ func RestoreContactsTable(input *db.RecordReader, output *db.ContactDB) error {
 			return err
 		}
 
+		// The restored table is empty so the versions start again.
+		c.Version = nil
 		err = output.Store(c)
 		if err != nil {
 			log.Printf("Error writing record: %s", err.Error())
@@ -171,6 +173,15 @@ func restore(domain* db.Domain) {
 	if err := contactdb.Create(); err != nil {
 		log.Fatalf("Error creating contact table: %s", err.Error())
 	}
+	// There's no backup of the API tokens since users can create new
+	// ones.
+	if err := domain.NewAPITokenDB().Create(); err != nil {
+		log.Fatalf("Error creating API token table: %s", err.Error())
+	}
+	// Jobs for contacts that were waiting to be processed are lost.
+	if err := domain.NewIQJobDB().Create(); err != nil {
+		log.Fatalf("Error creating IQ job table: %s", err.Error())
+	}
 	
 	if err := RestoreUserTable(user_rr, userdb); err != nil {
 		log.Fatalf("Error restoring user table: %s", err.Error())
@@ -250,6 +261,31 @@ func store(domain* db.Domain) {
 	}
 }
 
+// Replace the plaintext station secrets with their hashes.
+func migrateSecrets(domain* db.Domain) {
+	stationdb := domain.NewStationDB()
+	stations, err := stationdb.AllStations()
+	if err != nil {
+		log.Fatalf("Error reading stations: %s", err.Error())
+	}
+
+	n := 0
+	for _, s := range stations {
+		migrated, err := db.MigrateStationSecret(s)
+		if err != nil {
+			log.Fatalf("Error hashing secret: %s", err.Error())
+		}
+		if !migrated {
+			continue
+		}
+		if err = stationdb.Store(s); err != nil {
+			log.Fatalf("Error storing station: %s", err.Error())
+		}
+		n++
+	}
+	log.Printf("Migrated %d of %d stations.", n, len(stations))
+}
+
 func main() {
 	flag.Parse()
 	if len(flag.Args()) == 0 {
@@ -268,6 +304,8 @@ func main() {
 		lookup(domain)
 	} else if cmd == "store" {
 		store(domain)
+	} else if cmd == "migrate_secrets" {
+		migrateSecrets(domain)
 	} else {
 		log.Fatalf("Unknown command: %s", cmd)
 	}
@@ -5,15 +5,40 @@ package db
 
 import "launchpad.net/goamz/aws"
 
+import "errors"
+import "flag"
+import "fmt"
 import "log"
+import "os"
+import "path/filepath"
 
-// A group of tables in a single region that make up the full database.
+var db_backend = flag.String("db_backend", "sdb",
+	"Storage backend for the database: sdb or local")
+var local_db_dir = flag.String("local_db_dir", "/tmp/carpcomm_db",
+	"Directory holding the table files for the local db_backend")
+
+// A group of tables that make up the full database.
 type Domain struct {
 	db_prefix string
-	auth aws.Auth
+	newTable func(name string) Table
 }
 
+// NewDomain opens the database using the backend selected by the
+// --db_backend flag.
 func NewDomain(db_prefix string) (*Domain, error) {
+	switch *db_backend {
+	case "sdb":
+		return NewSDBDomain(db_prefix)
+	case "local":
+		return NewLocalDomain(*local_db_dir, db_prefix)
+	}
+	return nil, errors.New(
+		fmt.Sprintf("Unknown db_backend: %s", *db_backend))
+}
+
+// NewSDBDomain opens a database stored in Amazon SimpleDB in the US East
+// region. The credentials are taken from the environment.
+func NewSDBDomain(db_prefix string) (*Domain, error) {
 	auth, err := aws.EnvAuth()
 	if err != nil {
 		log.Printf("AWS auth error: %s", err.Error())
@@ -22,25 +47,48 @@ func NewDomain(db_prefix string) (*Domain, error) {
 
 	var d Domain
 	d.db_prefix = db_prefix
-	d.auth = auth
+	d.newTable = func(name string) Table {
+		return NewSDBTable(&auth, &aws.USEast, name)
+	}
+	return &d, nil
+}
+
+// NewLocalDomain opens a database stored in local files in dir. Several
+// processes on the same machine may share the same directory.
+func NewLocalDomain(dir, db_prefix string) (*Domain, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		log.Printf("Error creating local db dir: %s", err.Error())
+		return nil, err
+	}
+
+	var d Domain
+	d.db_prefix = db_prefix
+	d.newTable = func(name string) Table {
+		return NewLocalTable(filepath.Join(dir, name + ".rec"))
+	}
 	return &d, nil
 }
 
 func (d *Domain) NewUserDB() *UserDB {
-	return NewUserDB(NewSDBTable(&d.auth, &aws.USEast, d.db_prefix+"users"))
+	return NewUserDB(d.newTable(d.db_prefix+"users"))
 }
 
 func (d *Domain) NewStationDB() *StationDB {
-	return NewStationDB(
-		NewSDBTable(&d.auth, &aws.USEast, d.db_prefix+"stations"))
+	return NewStationDB(d.newTable(d.db_prefix+"stations"))
 }
 
 func (d *Domain) NewContactDB() *ContactDB {
-	return NewContactDB(
-		NewSDBTable(&d.auth, &aws.USEast, d.db_prefix+"contacts"))
+	return NewContactDB(d.newTable(d.db_prefix+"contacts"))
 }
 
 func (d *Domain) NewCommentDB() *CommentDB {
-	return NewCommentDB(
-		NewSDBTable(&d.auth, &aws.USEast, d.db_prefix+"comments"))
-}
\ No newline at end of file
+	return NewCommentDB(d.newTable(d.db_prefix+"comments"))
+}
+
+func (d *Domain) NewAPITokenDB() *APITokenDB {
+	return NewAPITokenDB(d.newTable(d.db_prefix+"api_tokens"))
+}
+
+func (d *Domain) NewIQJobDB() *IQJobDB {
+	return NewIQJobDB(d.newTable(d.db_prefix+"iq_jobs"))
+}
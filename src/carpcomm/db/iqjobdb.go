@@ -0,0 +1,98 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package db
+
+import "carpcomm/pb"
+import "code.google.com/p/goprotobuf/proto"
+
+import "fmt"
+import "reflect"
+
+type IQJobDB struct {
+	table Table
+}
+
+const kIQJobColumn = "pb.IQJob"
+const kIQJobKeyStatus = "status"
+const kIQJobKeyUpdated = "updated"
+
+func NewIQJobDB(table Table) *IQJobDB {
+	return &IQJobDB{table}
+}
+
+// Update stores the job unless another worker has stored it since it was
+// read, in which case it returns false. The job's version is incremented.
+func (db *IQJobDB) Update(j *pb.IQJob) (bool, error) {
+	version, saved := j.GetVersion(), j.Version
+	j.Version = proto.Int64(version + 1)
+	values, err := encodeItem(kIQJobColumn, j)
+	if err != nil {
+		j.Version = saved
+		return false, err
+	}
+	values[kIQJobKeyStatus] = j.GetStatus().String()
+	values[kIQJobKeyUpdated] = fmt.Sprintf("%016x", j.GetUpdated())
+
+	ok, err := putVersioned(db.table, j.GetId(), values, version)
+	if !ok || err != nil {
+		j.Version = saved
+	}
+	return ok, err
+}
+
+// Returns nil, nil if id was not found.
+func (db *IQJobDB) Lookup(id string) (*pb.IQJob, error) {
+	j := &pb.IQJob{}
+	found, err := db.table.getProto(id, kIQJobColumn, j)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+	return j, nil
+}
+
+func (db *IQJobDB) search(q query) ([]*pb.IQJob, error) {
+	q.order_by = kIQJobKeyUpdated
+	result, err := db.table.search(
+		q, kIQJobColumn, reflect.TypeOf(pb.IQJob{}))
+	if err != nil {
+		return nil, err
+	}
+	conv := make([]*pb.IQJob, len(result))
+	for i, v := range result {
+		conv[i] = v.(*pb.IQJob)
+	}
+	return conv, nil
+}
+
+// Results are sorted by update time (newest first).
+func (db *IQJobDB) SearchByStatus(status pb.IQJob_Status, limit int) (
+	[]*pb.IQJob, error) {
+	return db.search(query{
+		key: kIQJobKeyStatus,
+		value: status.String(),
+		limit: limit})
+}
+
+// Results are sorted by update time (oldest first).
+func (db *IQJobDB) OldestByStatus(status pb.IQJob_Status, limit int) (
+	[]*pb.IQJob, error) {
+	return db.search(query{
+		key: kIQJobKeyStatus,
+		value: status.String(),
+		ascending: true,
+		limit: limit})
+}
+
+// Returns the most recently updated jobs of any status.
+func (db *IQJobDB) Recent(limit int) ([]*pb.IQJob, error) {
+	return db.search(query{limit: limit})
+}
+
+// Create the database table.
+func (db *IQJobDB) Create() error {
+	return db.table.create()
+}
@@ -0,0 +1,68 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package db
+
+import "carpcomm/pb"
+import "code.google.com/p/goprotobuf/proto"
+import "os"
+import "testing"
+
+func TestIQJobDB(t *testing.T) {
+	d, dir := newTestLocalDomain(t)
+	defer os.RemoveAll(dir)
+	jobdb := d.NewIQJobDB()
+
+	for i, id := range []string{"1", "2", "3"} {
+		j := &pb.IQJob{
+			Id: proto.String(id),
+			Status: pb.IQJob_QUEUED.Enum(),
+			Updated: proto.Int64(int64(100 + i)),
+		}
+		if ok, err := jobdb.Update(j); !ok || err != nil {
+			t.Fatalf("Update: %v, %v", ok, err)
+		}
+	}
+	j, _ := jobdb.Lookup("2")
+	stale, _ := jobdb.Lookup("2")
+	j.Status = pb.IQJob_RUNNING.Enum()
+	j.Updated = proto.Int64(200)
+	if ok, err := jobdb.Update(j); !ok || err != nil {
+		t.Fatalf("Update: %v, %v", ok, err)
+	}
+	if j.GetVersion() != 2 {
+		t.Errorf("Wrong version: %d", j.GetVersion())
+	}
+	// Another worker's copy of the job is out of date.
+	stale.Status = pb.IQJob_DONE.Enum()
+	if ok, err := jobdb.Update(stale); ok || err != nil {
+		t.Errorf("Stale update succeeded: %v, %v", ok, err)
+	}
+	if stale.GetVersion() != 1 {
+		t.Errorf("Version of failed update changed: %d",
+			stale.GetVersion())
+	}
+
+	queued, err := jobdb.SearchByStatus(pb.IQJob_QUEUED, 0)
+	if err != nil {
+		t.Fatalf("SearchByStatus: %s", err.Error())
+	}
+	if len(queued) != 2 || queued[0].GetId() != "3" ||
+		queued[1].GetId() != "1" {
+		t.Errorf("Wrong queued jobs: %v", queued)
+	}
+
+	oldest, err := jobdb.OldestByStatus(pb.IQJob_QUEUED, 1)
+	if err != nil || len(oldest) != 1 || oldest[0].GetId() != "1" {
+		t.Errorf("Wrong oldest jobs: %v, %v", oldest, err)
+	}
+
+	recent, err := jobdb.Recent(1)
+	if err != nil || len(recent) != 1 || recent[0].GetId() != "2" {
+		t.Errorf("Wrong recent jobs: %v, %v", recent, err)
+	}
+
+	if j, err := jobdb.Lookup("4"); j != nil || err != nil {
+		t.Errorf("Lookup of missing job: %v, %v", j, err)
+	}
+}
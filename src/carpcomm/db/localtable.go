@@ -0,0 +1,326 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package db
+
+import "code.google.com/p/goprotobuf/proto"
+
+import "encoding/json"
+import "io"
+import "io/ioutil"
+import "log"
+import "os"
+import "path/filepath"
+import "reflect"
+import "sort"
+import "strings"
+import "sync"
+
+// LocalTable is an embedded Table stored in a local file. It allows the
+// servers to run without AWS, e.g. for development and tests.
+//
+// The file is an append-only log of item updates in the RecordWriter
+// format. Items are held in memory and the log is replayed before every
+// operation. Each update is appended with a single write so several
+// processes on the same machine can share a table. Conditional updates are
+// evaluated when they're replayed so every process agrees on their outcome.
+type LocalTable struct {
+	path string
+
+	lock sync.Mutex
+	items map[string]map[string]string
+	offset int64  // Position in the file up to which we have replayed.
+}
+
+// One entry in the log.
+type localRecord struct {
+	Id string
+	Values map[string]string `json:",omitempty"`
+	DeleteColumn string `json:",omitempty"`
+	// The update is only applied if the IfKey attribute equals IfValue,
+	// or is missing if IfValue is empty.
+	IfKey string `json:",omitempty"`
+	IfValue string `json:",omitempty"`
+}
+
+func NewLocalTable(path string) *LocalTable {
+	return &LocalTable{
+		path: path,
+		items: make(map[string]map[string]string)}
+}
+
+// Returns false if the record's condition doesn't hold.
+func (t *LocalTable) apply(r localRecord) bool {
+	if r.IfKey != "" {
+		v, ok := t.items[r.Id][r.IfKey]
+		if (r.IfValue == "" && ok) ||
+			(r.IfValue != "" && v != r.IfValue) {
+			return false
+		}
+	}
+
+	item := t.items[r.Id]
+	if item == nil {
+		item = make(map[string]string)
+		t.items[r.Id] = item
+	}
+	for k, v := range r.Values {
+		item[k] = v
+	}
+	if r.DeleteColumn != "" {
+		// Remove both the v1 and v2 encodings of the column.
+		for k := range item {
+			if k == r.DeleteColumn ||
+				strings.HasPrefix(k, r.DeleteColumn + ".") {
+				delete(item, k)
+			}
+		}
+	}
+	if len(item) == 0 {
+		delete(t.items, r.Id)
+	}
+	return true
+}
+
+// Replay records that were appended since the last call.
+// t.lock must be held.
+func (t *LocalTable) refresh() error {
+	_, err := t.replay(-1)
+	return err
+}
+
+// Like refresh but also returns whether the record at offset watch was
+// applied.
+func (t *LocalTable) replay(watch int64) (applied bool, err error) {
+	f, err := os.Open(t.path)
+	if os.IsNotExist(err) {
+		// The table is empty.
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	var rr *RecordReader
+	offset := t.offset
+	if offset == 0 {
+		rr, err = NewRecordReader(f)
+		if err != nil {
+			return false, err
+		}
+		offset = int64(len(kRecordWriterV0Header))
+	} else {
+		if _, err := f.Seek(offset, 0); err != nil {
+			return false, err
+		}
+		rr = &RecordReader{f}
+	}
+
+	for {
+		rec, err := rr.ReadRecord()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// A partial record means that another process is
+			// busy appending it. We'll read it next time.
+			break
+		} else if err != nil {
+			return false, err
+		}
+
+		var lr localRecord
+		if err := json.Unmarshal(rec, &lr); err != nil {
+			log.Printf("%s: Corrupt record at offset %d: %s",
+				t.path, offset, err.Error())
+			return false, err
+		}
+		ok := t.apply(lr)
+		if offset == watch {
+			applied = ok
+		}
+		offset += int64(len(encodeRecord(rec)))
+	}
+
+	t.offset = offset
+	return applied, nil
+}
+
+// Returns whether the record was applied.
+// t.lock must be held.
+func (t *LocalTable) append(r localRecord) (bool, error) {
+	if err := t.create(); err != nil {
+		return false, err
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return false, err
+	}
+	encoded := encodeRecord(data)
+
+	f, err := os.OpenFile(t.path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return false, err
+	}
+	_, err = f.Write(encoded)
+	var end int64
+	if err == nil {
+		end, err = f.Seek(0, 1)
+	}
+	f.Close()
+	if err != nil {
+		return false, err
+	}
+
+	// Read back our own record along with any records appended by
+	// other processes.
+	return t.replay(end - int64(len(encoded)))
+}
+
+func (t *LocalTable) getProto(id, column string, p proto.Message) (
+	found bool, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if err := t.refresh(); err != nil {
+		return false, err
+	}
+	item := t.items[id]
+	if item == nil {
+		return false, nil
+	}
+	return decodeItem(item, column, p)
+}
+
+func (t *LocalTable) put(id string, values map[string]string) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	_, err := t.append(localRecord{Id: id, Values: values})
+	return err
+}
+
+func (t *LocalTable) putIf(id string, values map[string]string,
+	key, expected string) (bool, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.append(localRecord{
+		Id: id, Values: values, IfKey: key, IfValue: expected})
+}
+
+func (t *LocalTable) delete(id, column string) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	_, err := t.append(localRecord{Id: id, DeleteColumn: column})
+	return err
+}
+
+type localItem struct {
+	id string
+	values map[string]string
+}
+
+type localItemList struct {
+	items []localItem
+	order_by string
+	ascending bool
+}
+
+func (l localItemList) Len() int {
+	return len(l.items)
+}
+func (l localItemList) Less(i, j int) bool {
+	a, b := l.items[i], l.items[j]
+	if l.order_by != "" && a.values[l.order_by] != b.values[l.order_by] {
+		// Same as SimpleDB's "order by ... desc" or "... asc".
+		if l.ascending {
+			return a.values[l.order_by] < b.values[l.order_by]
+		}
+		return a.values[l.order_by] > b.values[l.order_by]
+	}
+	return a.id < b.id
+}
+func (l localItemList) Swap(i, j int) {
+	l.items[i], l.items[j] = l.items[j], l.items[i]
+}
+
+func (t *LocalTable) search(q query, column string, typ reflect.Type) (
+	[]proto.Message, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if err := t.refresh(); err != nil {
+		return nil, err
+	}
+
+	selected := localItemList{nil, q.order_by, q.ascending}
+	for id, values := range t.items {
+		if q.key != "" && values[q.key] != q.value {
+			continue
+		}
+		if _, ok := values[q.order_by]; q.order_by != "" && !ok {
+			continue
+		}
+		selected.items = append(selected.items, localItem{id, values})
+	}
+	sort.Sort(selected)
+
+	result := make([]proto.Message, 0)
+	for _, item := range selected.items {
+		if q.limit > 0 && len(result) >= q.limit {
+			break
+		}
+		p := reflect.New(typ).Interface().(proto.Message)
+		found, err := decodeItem(item.values, column, p)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// The items are held in memory anyway so they're simply searched by id.
+func (t *LocalTable) iterate(q query, column string, typ reflect.Type,
+	f func(p proto.Message) error) error {
+	items, err := t.search(query{key: q.key, value: q.value}, column, typ)
+	if err != nil {
+		return err
+	}
+	for _, p := range items {
+		if err := f(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create the file if it doesn't exist yet.
+func (t *LocalTable) create() error {
+	if _, err := os.Stat(t.path); err == nil {
+		return nil
+	}
+
+	// Write the header to a temporary file and then link it into place.
+	// This ensures that other processes never see a file without a
+	// header.
+	tmp, err := ioutil.TempFile(filepath.Dir(t.path), ".create")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = NewRecordWriter(tmp)
+	tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Link(tmp.Name(), t.path)
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
+	return nil
+}
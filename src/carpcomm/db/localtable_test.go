@@ -0,0 +1,321 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package db
+
+import "carpcomm/pb"
+import "code.google.com/p/goprotobuf/proto"
+import "errors"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "reflect"
+import "strings"
+import "testing"
+
+var errStop = errors.New("stop")
+
+func newTestLocalDomain(t *testing.T) (*Domain, string) {
+	dir, err := ioutil.TempDir("", "localtable_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewLocalDomain(dir, "test_")
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return d, dir
+}
+
+func TestLocalTableStation(t *testing.T) {
+	d, dir := newTestLocalDomain(t)
+	defer os.RemoveAll(dir)
+	stationdb := d.NewStationDB()
+
+	s, err := stationdb.Lookup("missing")
+	if err != nil || s != nil {
+		t.Errorf("Lookup of missing station: %v, %v", s, err)
+	}
+
+	s = &pb.Station{
+		Id: proto.String("s1"),
+		Userid: proto.String("u1"),
+		Name: proto.String("Station 1")}
+	if err := stationdb.Store(s); err != nil {
+		t.Fatal(err)
+	}
+	// A second table on the same file must see the update.
+	s2, err := d.NewStationDB().Lookup("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s2 == nil || !proto.Equal(s, s2) {
+		t.Errorf("Wrong station: %v", s2)
+	}
+
+	if err := stationdb.Delete("s1"); err != nil {
+		t.Fatal(err)
+	}
+	s2, err = d.NewStationDB().Lookup("s1")
+	if err != nil || s2 != nil {
+		t.Errorf("Lookup of deleted station: %v, %v", s2, err)
+	}
+}
+
+func TestLocalTableLargeItem(t *testing.T) {
+	d, dir := newTestLocalDomain(t)
+	defer os.RemoveAll(dir)
+	userdb := d.NewUserDB()
+
+	// Large enough to be split across several attributes.
+	u := &pb.User{
+		Id: proto.String("u1"),
+		DisplayName: proto.String(strings.Repeat("x", 5000))}
+	if err := userdb.Store(u); err != nil {
+		t.Fatal(err)
+	}
+	u2, err := userdb.Lookup("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u2 == nil || !proto.Equal(u, u2) {
+		t.Errorf("Wrong user: %v", u2)
+	}
+}
+
+func TestLocalTableSearch(t *testing.T) {
+	d, dir := newTestLocalDomain(t)
+	defer os.RemoveAll(dir)
+	contactdb := d.NewContactDB()
+
+	contacts := []*pb.Contact{
+		&pb.Contact{
+			Id: proto.String("c1"),
+			StationId: proto.String("s1"),
+			StartTimestamp: proto.Int64(100)},
+		&pb.Contact{
+			Id: proto.String("c2"),
+			StationId: proto.String("s2"),
+			StartTimestamp: proto.Int64(200)},
+		&pb.Contact{
+			Id: proto.String("c3"),
+			StationId: proto.String("s1"),
+			StartTimestamp: proto.Int64(300)},
+		&pb.Contact{
+			Id: proto.String("c4"),
+			StationId: proto.String("s1"),
+			StartTimestamp: proto.Int64(50)},
+	}
+	for _, c := range contacts {
+		if err := contactdb.Store(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := contactdb.SearchByStationId("s1", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 2 {
+		t.Fatalf("Wrong number of results: %d", len(result))
+	}
+	if *result[0].Id != "c3" || *result[1].Id != "c1" {
+		t.Errorf("Wrong order: %s, %s", *result[0].Id, *result[1].Id)
+	}
+
+	all, err := contactdb.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != len(contacts) {
+		t.Errorf("Wrong number of contacts: %d", len(all))
+	}
+}
+
+func TestContactDBCaptures(t *testing.T) {
+	d, dir := newTestLocalDomain(t)
+	defer os.RemoveAll(dir)
+	contactdb := d.NewContactDB()
+
+	capture := func(planned int64, status pb.Contact_Capture_Status) (
+		*pb.Contact_Capture) {
+		return &pb.Contact_Capture{
+			PlannedStartTimestamp: proto.Int64(planned),
+			Status: status.Enum()}
+	}
+	contacts := []*pb.Contact{
+		&pb.Contact{
+			Id: proto.String("c1"),
+			StationId: proto.String("s1"),
+			StartTimestamp: proto.Int64(100),
+			Capture: capture(90, pb.Contact_Capture_SUCCESS)},
+		&pb.Contact{
+			// Not a capture.
+			Id: proto.String("c2"),
+			StationId: proto.String("s1"),
+			StartTimestamp: proto.Int64(200)},
+		&pb.Contact{
+			Id: proto.String("c3"),
+			StationId: proto.String("s1"),
+			StartTimestamp: proto.Int64(300),
+			Capture: capture(310, pb.Contact_Capture_PENDING)},
+		&pb.Contact{
+			Id: proto.String("c4"),
+			StationId: proto.String("s2"),
+			StartTimestamp: proto.Int64(400),
+			Capture: capture(400, pb.Contact_Capture_NO_SIGNAL)},
+	}
+	for _, c := range contacts {
+		if err := contactdb.Store(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The status attribute must be updated when the capture finishes.
+	contacts[2].Capture.Status = pb.Contact_Capture_NO_SIGNAL.Enum()
+	if err := contactdb.Store(contacts[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := contactdb.SearchCapturesByStationId("s1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 2 || *result[0].Id != "c3" || *result[1].Id != "c1" {
+		t.Errorf("Wrong captures: %v", result)
+	}
+
+	stats := CaptureStats(result)
+	if stats[pb.Contact_Capture_SUCCESS] != 1 ||
+		stats[pb.Contact_Capture_NO_SIGNAL] != 1 ||
+		stats[pb.Contact_Capture_PENDING] != 0 {
+		t.Errorf("Wrong stats: %v", stats)
+	}
+
+	result, err = contactdb.SearchCapturesByStatus(
+		pb.Contact_Capture_NO_SIGNAL, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 2 || *result[0].Id != "c4" || *result[1].Id != "c3" {
+		t.Errorf("Wrong captures: %v", result)
+	}
+}
+
+func TestLocalTablePartialRecord(t *testing.T) {
+	d, dir := newTestLocalDomain(t)
+	defer os.RemoveAll(dir)
+	stationdb := d.NewStationDB()
+
+	s := &pb.Station{Id: proto.String("s1")}
+	if err := stationdb.Store(s); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate another process in the middle of appending a record.
+	path := filepath.Join(dir, "test_stations.rec")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 1})
+	f.Close()
+
+	s2, err := d.NewStationDB().Lookup("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s2 == nil || !proto.Equal(s, s2) {
+		t.Errorf("Wrong station: %v", s2)
+	}
+}
+
+func TestLocalTablePutIf(t *testing.T) {
+	d, dir := newTestLocalDomain(t)
+	defer os.RemoveAll(dir)
+	// Two tables on the same file behave like two processes.
+	a := d.newTable(d.db_prefix + "cas")
+	b := d.newTable(d.db_prefix + "cas")
+
+	put := func(table Table, value, expected string) bool {
+		ok, err := table.putIf(
+			"x", map[string]string{"v": value}, "v", expected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+	if !put(a, "1", "") {
+		t.Errorf("Conditional create failed")
+	}
+	if put(b, "2", "") {
+		t.Errorf("Conditional create of existing item succeeded")
+	}
+	if !put(b, "2", "1") {
+		t.Errorf("Conditional update failed")
+	}
+	// a hasn't seen b's update yet but the condition must still fail.
+	if put(a, "3", "1") {
+		t.Errorf("Conditional update with stale value succeeded")
+	}
+
+	for i, expected := range []bool{true, false} {
+		ok, err := putVersioned(a, "y", map[string]string{}, 0)
+		if err != nil || ok != expected {
+			t.Errorf("putVersioned %d: %v, %v", i, ok, err)
+		}
+	}
+	if ok, _ := putVersioned(b, "y", map[string]string{}, 1); !ok {
+		t.Errorf("putVersioned with current version failed")
+	}
+}
+
+func TestLocalTableIterate(t *testing.T) {
+	d, dir := newTestLocalDomain(t)
+	defer os.RemoveAll(dir)
+	table := d.newTable(d.db_prefix + "iterate")
+	if err := table.create(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"c", "a", "d", "b"} {
+		values, err := encodeItem(
+			"station", &pb.Station{Id: proto.String(id)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		values["kind"] = "x"
+		if id == "d" {
+			values["kind"] = "y"
+		}
+		if err := table.put(id, values); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ids []string
+	err := table.iterate(query{key: "kind", value: "x"}, "station",
+		reflect.TypeOf(pb.Station{}), func(p proto.Message) error {
+			ids = append(ids, p.(*pb.Station).GetId())
+			if len(ids) == 2 {
+				return errStop
+			}
+			return nil
+		})
+	if err != errStop {
+		t.Errorf("Expected iteration to stop: %v", err)
+	}
+	if strings.Join(ids, ",") != "a,b" {
+		t.Errorf("Wrong items: %v", ids)
+	}
+
+	all, err := collect(table, query{}, "station",
+		reflect.TypeOf(pb.Station{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 {
+		t.Errorf("Wrong number of items: %d", len(all))
+	}
+}
@@ -62,4 +62,33 @@ func (rr *RecordReader) ReadRecord() ([]byte, error) {
 		return nil, err
 	}
 	return rec, nil
-}
\ No newline at end of file
+}
+func encodeRecord(rec []byte) []byte {
+	// Big-endian size followed by the data. We build it in a single buffer
+	// so that it can be appended to a file with a single write.
+	n := uint32(len(rec))
+	b := make([]byte, 4+len(rec))
+	for i := 3; i >= 0; i-- {
+		b[i] = byte(n & 0xff)
+		n = n >> 8
+	}
+	copy(b[4:], rec)
+	return b
+}
+
+type RecordWriter struct {
+	w io.Writer
+}
+
+func NewRecordWriter(w io.Writer) (*RecordWriter, error) {
+	_, err := w.Write([]byte(kRecordWriterV0Header))
+	if err != nil {
+		return nil, err
+	}
+	return &RecordWriter{w}, nil
+}
+
+func (rw *RecordWriter) WriteRecord(rec []byte) error {
+	_, err := rw.w.Write(encodeRecord(rec))
+	return err
+}
@@ -9,6 +9,7 @@ import "log"
 import "flag"
 import "io/ioutil"
 import "sort"
+import "sync"
 
 var satellite_list = flag.String(
 	"satellite_list",
@@ -59,6 +60,18 @@ type SatelliteDB struct {
 	Map map[string]*pb.Satellite
 }
 
+func newSatelliteDB(list []*pb.Satellite) *SatelliteDB {
+	sort.Sort((satList)(list))
+
+	db := &SatelliteDB{}
+	db.List = list
+	db.Map = make(map[string]*pb.Satellite)
+	for _, s := range db.List {
+		db.Map[*s.Id] = s
+	}
+	return db
+}
+
 func LoadSatelliteDB(filename string) (db *SatelliteDB, err error) {
 	buf, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -69,16 +82,7 @@ func LoadSatelliteDB(filename string) (db *SatelliteDB, err error) {
 	if err != nil {
 		return nil, err
 	}
-
-	sort.Sort((satList)(sl.Satellite))
-
-	db = &SatelliteDB{}
-	db.List = sl.Satellite
-	db.Map = make(map[string]*pb.Satellite)
-	for _, s := range db.List {
-		db.Map[*s.Id] = s
-	}
-	return db, nil
+	return newSatelliteDB(sl.Satellite), nil
 }
 
 func loadGlobalDB() *SatelliteDB {
@@ -92,12 +96,25 @@ func loadGlobalDB() *SatelliteDB {
 }
 
 var globalDB *SatelliteDB = nil
+var globalDBLock sync.Mutex
 
-
+// GlobalSatelliteDB returns the current satellite database. It may be
+// replaced at any time (e.g. when new TLEs arrive) so callers should hold
+// on to the returned value rather than calling this repeatedly during one
+// operation. The returned value must not be modified.
 func GlobalSatelliteDB() *SatelliteDB {
-	if globalDB != nil {
-		return globalDB
+	globalDBLock.Lock()
+	defer globalDBLock.Unlock()
+
+	if globalDB == nil {
+		globalDB = loadGlobalDB()
 	}
-	globalDB = loadGlobalDB()
 	return globalDB
 }
+
+// SetGlobalSatelliteDB atomically replaces the global satellite database.
+func SetGlobalSatelliteDB(db *SatelliteDB) {
+	globalDBLock.Lock()
+	defer globalDBLock.Unlock()
+	globalDB = db
+}
@@ -0,0 +1,173 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package db
+
+import "carpcomm/pb"
+import "code.google.com/p/goprotobuf/proto"
+
+import crand "crypto/rand"
+import "crypto/sha256"
+import "crypto/subtle"
+import "encoding/base64"
+import "flag"
+import "io"
+import "log"
+import "os"
+import "sync"
+import "time"
+
+var station_auth_log = flag.String("station_auth_log", "",
+	"File to which station and API token authentication attempts are "+
+		"appended. "+
+		"They're written to the normal log if empty.")
+
+// How long the previous secret keeps working after a rotation so that the
+// owner has time to update the station's configuration.
+const kSecretRotationGracePeriod = 48 * time.Hour
+
+func hashSecret(salt []byte, secret string) []byte {
+	h := sha256.New()
+	h.Write(salt)
+	io.WriteString(h, secret)
+	return h.Sum(nil)
+}
+
+// Returns a random secret and a salt for hashing it.
+func randomSecret() (secret string, salt []byte, err error) {
+	b := make([]byte, 20)
+	if _, err := io.ReadFull(crand.Reader, b); err != nil {
+		return "", nil, err
+	}
+	salt = make([]byte, 16)
+	if _, err := io.ReadFull(crand.Reader, salt); err != nil {
+		return "", nil, err
+	}
+	return base64.StdEncoding.EncodeToString(b), salt, nil
+}
+
+// Returns the plaintext secret, which isn't stored anywhere.
+func newStationSecret(now time.Time) (string, *pb.StationSecret, error) {
+	plaintext, salt, err := randomSecret()
+	if err != nil {
+		return "", nil, err
+	}
+	return plaintext, &pb.StationSecret{
+		Salt: salt,
+		Hash: hashSecret(salt, plaintext),
+		Created: proto.Int64(now.Unix()),
+	}, nil
+}
+
+func secretExpired(s *pb.StationSecret, now time.Time) bool {
+	return s.Expires != nil && now.Unix() >= s.GetExpires()
+}
+
+// CheckStationSecret returns whether secret is one of the station's
+// unexpired secrets. Every secret is compared in constant time.
+func CheckStationSecret(s *pb.Station, secret string, now time.Time) bool {
+	ok := 0
+	if s.Secret != nil {
+		ok |= subtle.ConstantTimeCompare(
+			[]byte(s.GetSecret()), []byte(secret))
+	}
+	for _, h := range s.SecretHashes {
+		if secretExpired(h, now) {
+			continue
+		}
+		ok |= subtle.ConstantTimeCompare(
+			h.Hash, hashSecret(h.Salt, secret))
+	}
+	return ok == 1
+}
+
+// Replace the plaintext secret with its hash. Returns false if there was no
+// plaintext secret.
+func MigrateStationSecret(s *pb.Station) (bool, error) {
+	if s.Secret == nil {
+		return false, nil
+	}
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(crand.Reader, salt); err != nil {
+		return false, err
+	}
+	s.SecretHashes = append(s.SecretHashes, &pb.StationSecret{
+		Salt: salt,
+		Hash: hashSecret(salt, s.GetSecret()),
+		Created: s.Created,
+	})
+	s.Secret = nil
+	return true, nil
+}
+
+// RotateStationSecret adds a new secret to the station and returns it. The
+// existing secrets keep working for kSecretRotationGracePeriod. The caller
+// must store the station.
+func RotateStationSecret(s *pb.Station, now time.Time) (string, error) {
+	if _, err := MigrateStationSecret(s); err != nil {
+		return "", err
+	}
+	plaintext, secret, err := newStationSecret(now)
+	if err != nil {
+		return "", err
+	}
+
+	expires := now.Add(kSecretRotationGracePeriod).Unix()
+	kept := []*pb.StationSecret{}
+	for _, h := range s.SecretHashes {
+		if secretExpired(h, now) {
+			continue
+		}
+		if h.Expires == nil || h.GetExpires() > expires {
+			h.Expires = proto.Int64(expires)
+		}
+		kept = append(kept, h)
+	}
+	s.SecretHashes = append(kept, secret)
+	return plaintext, nil
+}
+
+var authLogOnce sync.Once
+var authLog *log.Logger
+
+// Record an authentication attempt in the audit log.
+func logAuth(format string, a ...interface{}) {
+	authLogOnce.Do(func() {
+		if *station_auth_log == "" {
+			return
+		}
+		f, err := os.OpenFile(*station_auth_log,
+			os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			log.Printf("Error opening station auth log: %s",
+				err.Error())
+			return
+		}
+		authLog = log.New(f, "", log.LstdFlags)
+	})
+	if authLog != nil {
+		authLog.Printf(format, a...)
+	} else {
+		log.Printf(format, a...)
+	}
+}
+
+// AuthenticateStation checks the secret of station s, which may be nil if
+// the station doesn't exist, and records the attempt in the audit log.
+// source describes where the attempt came from, e.g. the handler and remote
+// address.
+func AuthenticateStation(
+	s *pb.Station, station_id, secret, source string) bool {
+	ok := s != nil && s.GetId() == station_id &&
+		CheckStationSecret(s, secret, time.Now())
+
+	result := "denied"
+	if ok {
+		result = "ok"
+	} else if s == nil {
+		result = "denied (unknown station)"
+	}
+	logAuth("station auth: station=%q source=%q result=%s",
+		station_id, source, result)
+	return ok
+}
@@ -0,0 +1,93 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package db
+
+import "carpcomm/pb"
+import "code.google.com/p/goprotobuf/proto"
+import "testing"
+import "time"
+
+func TestNewStationSecret(t *testing.T) {
+	s, secret, err := NewStation("user")
+	if err != nil {
+		t.Fatalf("NewStation: %s", err.Error())
+	}
+	if s.Secret != nil || len(s.SecretHashes) != 1 {
+		t.Errorf("Secret should only be stored hashed: %v", s)
+	}
+	now := time.Now()
+	if !CheckStationSecret(s, secret, now) {
+		t.Errorf("Secret rejected")
+	}
+	if CheckStationSecret(s, secret+"x", now) ||
+		CheckStationSecret(s, "", now) {
+		t.Errorf("Wrong secret accepted")
+	}
+}
+
+func TestMigrateStationSecret(t *testing.T) {
+	s := &pb.Station{Secret: proto.String("old")}
+	now := time.Now()
+	if !CheckStationSecret(s, "old", now) {
+		t.Errorf("Plaintext secret rejected")
+	}
+	migrated, err := MigrateStationSecret(s)
+	if err != nil || !migrated {
+		t.Fatalf("MigrateStationSecret: %v, %v", migrated, err)
+	}
+	if s.Secret != nil || !CheckStationSecret(s, "old", now) {
+		t.Errorf("Migrated secret rejected: %v", s)
+	}
+	if migrated, _ := MigrateStationSecret(s); migrated {
+		t.Errorf("Already migrated")
+	}
+}
+
+func TestRotateStationSecret(t *testing.T) {
+	s := &pb.Station{Secret: proto.String("old")}
+	now := time.Unix(1000000, 0)
+	secret, err := RotateStationSecret(s, now)
+	if err != nil {
+		t.Fatalf("RotateStationSecret: %s", err.Error())
+	}
+	if s.Secret != nil {
+		t.Errorf("Plaintext secret should be hashed")
+	}
+
+	// Both secrets work during the grace period.
+	later := now.Add(kSecretRotationGracePeriod - time.Minute)
+	if !CheckStationSecret(s, "old", later) ||
+		!CheckStationSecret(s, secret, later) {
+		t.Errorf("Secret rejected during grace period")
+	}
+
+	later = now.Add(kSecretRotationGracePeriod)
+	if CheckStationSecret(s, "old", later) {
+		t.Errorf("Old secret accepted after grace period")
+	}
+	if !CheckStationSecret(s, secret, later) {
+		t.Errorf("New secret rejected")
+	}
+
+	// Expired secrets are dropped on the next rotation.
+	if _, err := RotateStationSecret(s, later); err != nil {
+		t.Fatalf("RotateStationSecret: %s", err.Error())
+	}
+	if len(s.SecretHashes) != 2 {
+		t.Errorf("Wrong number of secrets: %d", len(s.SecretHashes))
+	}
+}
+
+func TestAuthenticateStation(t *testing.T) {
+	s, secret, _ := NewStation("user")
+	if !AuthenticateStation(s, s.GetId(), secret, "test") {
+		t.Errorf("Station rejected")
+	}
+	if AuthenticateStation(s, "other", secret, "test") {
+		t.Errorf("Wrong station id accepted")
+	}
+	if AuthenticateStation(nil, "other", secret, "test") {
+		t.Errorf("Unknown station accepted")
+	}
+}
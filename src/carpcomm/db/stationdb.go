@@ -6,37 +6,35 @@ package db
 import "carpcomm/pb"
 import "code.google.com/p/goprotobuf/proto"
 
-import crand "crypto/rand"
-import "io"
 import "reflect"
-import "encoding/base64"
 import "time"
 import "log"
 
-func NewStation(userid string) (*pb.Station, error) {
+// Also returns the station's secret. Only its hash is stored so this is the
+// only chance to show it to the owner.
+func NewStation(userid string) (*pb.Station, string, error) {
 	var s pb.Station
 
 	s.Userid = proto.String(userid)
 
 	id, err := CryptoRandId()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	s.Id = proto.String(id)
 
-	secret := make([]byte, 20)
-	_, err = io.ReadFull(crand.Reader, secret)
+	now := time.Now()
+	plaintext, secret, err := newStationSecret(now)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	s.Secret = proto.String(base64.StdEncoding.EncodeToString(secret))
+	s.SecretHashes = []*pb.StationSecret{secret}
 
-	now := time.Now().Unix()
-	s.Created = &now
+	s.Created = proto.Int64(now.Unix())
 
 	s.Name = proto.String("Unnamed Station")
 
-	return &s, nil
+	return &s, plaintext, nil
 }
 
 /*
@@ -47,20 +45,20 @@ func DefaultCapabilities() *pb.Capabilities {
 
 
 type StationDB struct {
-	table *SDBTable
+	table Table
 	useridCache map[string]string
 }
 
 const kStationColumn = "pb.Station"
 
-func NewStationDB(table *SDBTable) *StationDB {
+func NewStationDB(table Table) *StationDB {
 	return &StationDB{
 		table,
 		make(map[string]string)}
 }
 
 func (db *StationDB) Store(s *pb.Station) error {
-	return db.table.setProto(*s.Id, kStationColumn, s)
+	return setProto(db.table, *s.Id, kStationColumn, s)
 }
 
 // Returns nil, nil if id was not found.
@@ -88,6 +86,10 @@ func (db *StationDB) GetStationUserId(id string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if s == nil {
+		// E.g. a deleted station still listed as authorized.
+		return "", nil
+	}
 	db.useridCache[id] = *s.Userid
 	return *s.Userid, nil
 }
@@ -110,7 +112,7 @@ func (db *StationDB) Delete(id string) error {
 }
 
 func (db *StationDB) AllStations() ([]*pb.Station, error) {
-	result, err := db.table.getAll(kStationColumn,
+	result, err := getAll(db.table, kStationColumn,
 		reflect.TypeOf(pb.Station{}))
 	if err != nil {
 		return nil, err
@@ -137,7 +139,7 @@ func (db *StationDB) UserStations(userid string) ([]*pb.Station, error) {
 
 	// FIXME: use a search query
 
-	result, err := db.table.getAll(kStationColumn,
+	result, err := getAll(db.table, kStationColumn,
 		reflect.TypeOf(pb.Station{}))
 	if err != nil {
 		return nil, err
@@ -3,12 +3,14 @@
 
 package db
 
+import "errors"
 import "fmt"
 import "carpcomm/pb"
+import "code.google.com/p/goprotobuf/proto"
 import "reflect"
 
 type ContactDB struct {
-	table *SDBTable
+	table Table
 }
 
 const kContactColumn = "pb.Contact"
@@ -16,8 +18,17 @@ const kContactKeyStationId = "station_id"
 const kContactKeyUserId = "user_id"
 const kContactKeySatelliteId = "satellite_id"
 const kContactKeyTimestamp = "timestamp"
+const kContactKeyCaptureTimestamp = "capture_timestamp"
+const kContactKeyCaptureStatus = "capture_status"
 
-func NewContactDB(table *SDBTable) *ContactDB {
+// Returned by Store if the contact was stored by someone else since it was
+// read.
+var ErrContactConflict = errors.New("Contact was modified concurrently")
+
+// How many times Update retries after a conflict.
+const kContactUpdateAttempts = 10
+
+func NewContactDB(table Table) *ContactDB {
 	return &ContactDB{table}
 }
 
@@ -28,9 +39,15 @@ func emptyIfUnknown(s *string) string {
 	return *s
 }
 
+// Store writes the contact unless it was stored by someone else since it was
+// read, in which case ErrContactConflict is returned. New contacts must not
+// have a version. The contact's version is incremented.
 func (db *ContactDB) Store(contact *pb.Contact) error {
+	version, saved := contact.GetVersion(), contact.Version
+	contact.Version = proto.Int64(version + 1)
 	values, err := encodeItem(kContactColumn, contact)
 	if err != nil {
+		contact.Version = saved
 		return err
 	}
 
@@ -40,8 +57,48 @@ func (db *ContactDB) Store(contact *pb.Contact) error {
 	values[kContactKeySatelliteId] = emptyIfUnknown(contact.SatelliteId)
 	values[kContactKeyTimestamp] = fmt.Sprintf(
 		"%016x", *contact.StartTimestamp)
+	if c := contact.Capture; c != nil {
+		// Only captures have these attributes so that they can be
+		// searched separately from other contacts.
+		values[kContactKeyCaptureTimestamp] = fmt.Sprintf(
+			"%016x", c.GetPlannedStartTimestamp())
+		values[kContactKeyCaptureStatus] = c.GetStatus().String()
+	}
 
-	return db.table.put(*contact.Id, values)
+	ok, err := putVersioned(db.table, *contact.Id, values, version)
+	if !ok || err != nil {
+		contact.Version = saved
+	}
+	if err == nil && !ok {
+		err = ErrContactConflict
+	}
+	return err
+}
+
+// Update looks up the contact, modifies it with f and stores it. If the
+// contact is stored concurrently, f is applied again to the new version so
+// f must only modify the contact. Errors from f are returned unchanged.
+// Returns nil, nil if the contact doesn't exist.
+func (db *ContactDB) Update(id string, f func(c *pb.Contact) error) (
+	*pb.Contact, error) {
+	for i := 0; i < kContactUpdateAttempts; i++ {
+		c, err := db.Lookup(id)
+		if err != nil || c == nil {
+			return nil, err
+		}
+		if err := f(c); err != nil {
+			return nil, err
+		}
+		err = db.Store(c)
+		if err == ErrContactConflict {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	return nil, ErrContactConflict
 }
 
 func (db *ContactDB) Lookup(id string) (*pb.Contact, error) {
@@ -58,16 +115,16 @@ func (db *ContactDB) Lookup(id string) (*pb.Contact, error) {
 
 // Results are sorted by timestamp (newest first).
 func (db *ContactDB) searchByKey(column, key string, limit int) ([]*pb.Contact, error) {
-	query := fmt.Sprintf(
-		"select * from `%s` where `%s` = '%s' and `%s` is not null order by `%s` desc limit %d",
-		db.table.domain.Name,
-		column,
-		key,
-		kContactKeyTimestamp,
-		kContactKeyTimestamp,
-		limit)
+	return db.search(query{
+		key: column,
+		value: key,
+		order_by: kContactKeyTimestamp,
+		limit: limit})
+}
+
+func (db *ContactDB) search(q query) ([]*pb.Contact, error) {
 	result, err := db.table.search(
-		query, kContactColumn, reflect.TypeOf(pb.Contact{}))
+		q, kContactColumn, reflect.TypeOf(pb.Contact{}))
 	if err != nil {
 		return nil, err
 	}
@@ -96,8 +153,41 @@ func (db *ContactDB) SearchByUserId(user_id string, limit int) (
 	return db.searchByKey(kContactKeyUserId, user_id, limit)
 }
 
+// Returns the contacts captured by the scheduler for the station.
+// Results are sorted by planned start time (newest first).
+func (db *ContactDB) SearchCapturesByStationId(station_id string, limit int) (
+	[]*pb.Contact, error) {
+	return db.search(query{
+		key: kContactKeyStationId,
+		value: station_id,
+		order_by: kContactKeyCaptureTimestamp,
+		limit: limit})
+}
+
+// Returns the captures of all stations with the given status.
+// Results are sorted by planned start time (newest first).
+func (db *ContactDB) SearchCapturesByStatus(
+	status pb.Contact_Capture_Status, limit int) ([]*pb.Contact, error) {
+	return db.search(query{
+		key: kContactKeyCaptureStatus,
+		value: status.String(),
+		order_by: kContactKeyCaptureTimestamp,
+		limit: limit})
+}
+
+// CaptureStats counts the captures of each status.
+func CaptureStats(captures []*pb.Contact) map[pb.Contact_Capture_Status]int {
+	stats := make(map[pb.Contact_Capture_Status]int)
+	for _, c := range captures {
+		if c.Capture != nil {
+			stats[c.Capture.GetStatus()]++
+		}
+	}
+	return stats
+}
+
 func (db *ContactDB) GetAll() ([]*pb.Contact, error) {
-	result, err := db.table.getAll(kContactColumn,
+	result, err := getAll(db.table, kContactColumn,
 		reflect.TypeOf(pb.Contact{}))
 	if err != nil {
 		return nil, err
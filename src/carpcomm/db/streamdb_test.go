@@ -0,0 +1,111 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package db
+
+import "carpcomm/pb"
+import "code.google.com/p/goprotobuf/proto"
+import "fmt"
+import "os"
+import "sync"
+import "testing"
+
+func TestContactDBStore(t *testing.T) {
+	d, dir := newTestLocalDomain(t)
+	defer os.RemoveAll(dir)
+	contactdb := d.NewContactDB()
+
+	c := &pb.Contact{
+		Id: proto.String("c1"),
+		StartTimestamp: proto.Int64(100),
+	}
+	if err := contactdb.Store(c); err != nil {
+		t.Fatalf("Store: %s", err.Error())
+	}
+	if c.GetVersion() != 1 {
+		t.Errorf("Wrong version: %d", c.GetVersion())
+	}
+	// Creating the contact again must not overwrite it.
+	dup := &pb.Contact{
+		Id: proto.String("c1"),
+		StartTimestamp: proto.Int64(200),
+	}
+	if err := contactdb.Store(dup); err != ErrContactConflict {
+		t.Errorf("Duplicate create: %v", err)
+	}
+
+	stale, _ := contactdb.Lookup("c1")
+	c.EndTimestamp = proto.Int64(150)
+	if err := contactdb.Store(c); err != nil {
+		t.Fatalf("Store: %s", err.Error())
+	}
+	stale.EndTimestamp = proto.Int64(160)
+	if err := contactdb.Store(stale); err != ErrContactConflict {
+		t.Errorf("Stale store: %v", err)
+	}
+	if stale.GetVersion() != 1 {
+		t.Errorf("Version of failed store changed: %d",
+			stale.GetVersion())
+	}
+
+	stored, _ := contactdb.Lookup("c1")
+	if stored.GetEndTimestamp() != 150 || stored.GetVersion() != 2 {
+		t.Errorf("Wrong stored contact: %v", stored)
+	}
+}
+
+func TestContactDBConcurrentUpdate(t *testing.T) {
+	d, dir := newTestLocalDomain(t)
+	defer os.RemoveAll(dir)
+
+	c := &pb.Contact{
+		Id: proto.String("c1"),
+		StartTimestamp: proto.Int64(100),
+	}
+	if err := d.NewContactDB().Store(c); err != nil {
+		t.Fatalf("Store: %s", err.Error())
+	}
+
+	// Each writer has its own table on the same file like separate
+	// processes.
+	const n = 8
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int, contactdb *ContactDB) {
+			defer wg.Done()
+			b := &pb.Contact_Blob{
+				InlineData: []byte(fmt.Sprintf("%d", i))}
+			_, err := contactdb.Update("c1",
+				func(c *pb.Contact) error {
+					c.Blob = append(c.Blob, b)
+					return nil
+				})
+			if err != nil {
+				t.Errorf("Update %d: %s", i, err.Error())
+			}
+		}(i, d.NewContactDB())
+	}
+	wg.Wait()
+
+	stored, err := d.NewContactDB().Lookup("c1")
+	if err != nil {
+		t.Fatalf("Lookup: %s", err.Error())
+	}
+	seen := make(map[string]bool)
+	for _, b := range stored.Blob {
+		seen[string(b.InlineData)] = true
+	}
+	if len(stored.Blob) != n || len(seen) != n {
+		t.Errorf("Blobs were lost: %v", stored.Blob)
+	}
+
+	missing, err := d.NewContactDB().Update("missing",
+		func(c *pb.Contact) error {
+			t.Errorf("Update of missing contact called f")
+			return nil
+		})
+	if missing != nil || err != nil {
+		t.Errorf("Update of missing contact: %v, %v", missing, err)
+	}
+}
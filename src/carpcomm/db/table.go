@@ -0,0 +1,105 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package db
+
+import "code.google.com/p/goprotobuf/proto"
+
+import "reflect"
+import "strconv"
+
+// A storage backend for a single table.
+// Items are identified by an id and consist of string attributes. Protos
+// are stored in a column using encodeItem and read back using decodeItem.
+type Table interface {
+	// Returns false, nil if there is no value for the id and column.
+	getProto(id, column string, p proto.Message) (found bool, err error)
+
+	// Replaces the given attributes of the item, creating it if necessary.
+	// Attributes that aren't in values are left unchanged.
+	put(id string, values map[string]string) error
+
+	// Like put but only if the key attribute of the item currently equals
+	// expected, or is missing if expected is empty. Returns false, nil if
+	// the condition doesn't hold.
+	putIf(id string, values map[string]string, key, expected string) (
+		bool, error)
+
+	delete(id, column string) error
+
+	// Items for which the column is missing are skipped.
+	search(q query, column string, t reflect.Type) ([]proto.Message, error)
+
+	// Calls f with every item selected by q in order of id. Unlike search,
+	// the items are fetched a page at a time so there's no limit on how
+	// many are returned. q.order_by and q.limit are ignored. Items for
+	// which the column is missing are skipped. Stops at the first error
+	// returned by f.
+	iterate(q query, column string, t reflect.Type,
+		f func(p proto.Message) error) error
+
+	// Create the database table.
+	create() error
+}
+
+// Selects the items whose key attribute equals value. All items are
+// selected if key is empty.
+// If order_by is set, only items which have the order_by attribute are
+// selected and they are sorted by it in descending order, or ascending
+// order if ascending is set.
+// A limit <= 0 means that there is no limit.
+type query struct {
+	key, value string
+	order_by string
+	ascending bool
+	limit int
+}
+
+func setProto(table Table, id, column string, p proto.Message) error {
+	values, err := encodeItem(column, p)
+	if err != nil {
+		return err
+	}
+	return table.put(id, values)
+}
+
+// The attribute holding the version of items that are updated with
+// putVersioned.
+const kVersionKey = "version"
+
+// putVersioned stores the values if the item's version is still version,
+// i.e. if nobody else updated it since it was read, and increments the
+// version. Items without a version attribute have version 0.
+func putVersioned(table Table, id string, values map[string]string,
+	version int64) (bool, error) {
+	expected := ""
+	if version > 0 {
+		expected = strconv.FormatInt(version, 10)
+	}
+	values[kVersionKey] = strconv.FormatInt(version+1, 10)
+	return table.putIf(id, values, kVersionKey, expected)
+}
+
+// Returns all the items selected by q.
+func collect(table Table, q query, column string, t reflect.Type) (
+	[]proto.Message, error) {
+	result := make([]proto.Message, 0)
+	err := table.iterate(q, column, t, func(p proto.Message) error {
+		result = append(result, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func getAll(table Table, column string, t reflect.Type) (
+	[]proto.Message, error) {
+	return collect(table, query{}, column, t)
+}
+
+func lookupByKeyValue(table Table,
+	column, key, value string, t reflect.Type) ([]proto.Message, error) {
+	return collect(table, query{key: key, value: value}, column, t)
+}
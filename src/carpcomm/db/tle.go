@@ -0,0 +1,236 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package db
+
+import "carpcomm/pb"
+import "carpcomm/sgp4"
+import "code.google.com/p/goprotobuf/proto"
+
+import "bufio"
+import "errors"
+import "flag"
+import "fmt"
+import "io"
+import "log"
+import "net/http"
+import "os"
+import "strings"
+import "sync"
+import "time"
+
+var tle_sources = flag.String(
+	"tle_sources",
+	"http://www.celestrak.com/NORAD/elements/cubesat.txt,"+
+		"http://www.celestrak.com/NORAD/elements/amateur.txt,"+
+		"http://www.celestrak.com/NORAD/elements/noaa.txt,"+
+		"http://www.celestrak.com/NORAD/elements/engineering.txt,"+
+		"http://www.celestrak.com/NORAD/elements/tle-new.txt,"+
+		"http://www.celestrak.com/NORAD/elements/stations.txt",
+	"Comma separated list of URLs or local paths of 3-line element "+
+		"set files used to refresh the satellite TLEs")
+var tle_refresh_interval = flag.Duration(
+	"tle_refresh_interval",
+	6*time.Hour,
+	"How often to refresh the satellite TLEs. Zero disables refreshing.")
+
+// ElementSet is one entry of a 3-line element set file.
+type ElementSet struct {
+	Label string  // The name line, without trailing spaces.
+	TLE *sgp4.TLE
+	Text string  // Name and element lines in the pb.Satellite.tle format.
+}
+
+func isElementLine(line string, n byte) bool {
+	return len(line) >= 69 && line[0] == n && line[1] == ' '
+}
+
+// ParseElementSets parses a file of 3-line element sets, as published by
+// Celestrak. Element sets with invalid checksums or fields are logged and
+// skipped.
+func ParseElementSets(r io.Reader) ([]ElementSet, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r\t")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var sets []ElementSet
+	for i := 0; i+2 < len(lines); i++ {
+		if isElementLine(lines[i], '1') ||
+			!isElementLine(lines[i+1], '1') ||
+			!isElementLine(lines[i+2], '2') {
+			continue
+		}
+		text := strings.Join(lines[i:i+3], "\n")
+		i += 2
+
+		if err := sgp4.VerifyChecksums(text); err != nil {
+			log.Printf("Skipping element set %s: %s",
+				lines[i-2], err.Error())
+			continue
+		}
+		tle, err := sgp4.ParseTLE(text)
+		if err != nil {
+			log.Printf("Skipping element set %s: %s",
+				lines[i-2], err.Error())
+			continue
+		}
+		sets = append(sets, ElementSet{tle.Name, tle, text})
+	}
+	return sets, nil
+}
+
+// Fetch and parse an element set file from a URL or local path.
+func loadElementSets(source string) ([]ElementSet, error) {
+	var r io.ReadCloser
+	if strings.HasPrefix(source, "http://") ||
+		strings.HasPrefix(source, "https://") {
+		resp, err := http.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, errors.New(fmt.Sprintf(
+				"Error fetching %s: %s", source, resp.Status))
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		r = f
+	}
+	defer r.Close()
+	return ParseElementSets(r)
+}
+
+// Return the element set for sat, if any. Satellites are matched by their
+// Celestrak label first and then by the NORAD catalog number of their
+// current TLE.
+func findElementSet(sat *pb.Satellite, current *sgp4.TLE,
+	by_label map[string]*ElementSet,
+	by_number map[int]*ElementSet) *ElementSet {
+	if sat.CelestrakTleLabel != nil {
+		if es := by_label[*sat.CelestrakTleLabel]; es != nil {
+			return es
+		}
+	}
+	if current != nil {
+		return by_number[current.SatelliteNumber]
+	}
+	return nil
+}
+
+// UpdateTLEs returns a copy of the database with the TLEs replaced by any
+// newer element sets, and the number of satellites that were updated.
+// Element sets with epochs that aren't newer than the current ones are
+// ignored. The receiver isn't modified.
+func (db *SatelliteDB) UpdateTLEs(sets []ElementSet) (*SatelliteDB, int) {
+	// Index the newest element set for each label and number.
+	by_label := make(map[string]*ElementSet)
+	by_number := make(map[int]*ElementSet)
+	newer := func(a, b *ElementSet) bool {
+		return b == nil || a.TLE.Epoch().After(b.TLE.Epoch())
+	}
+	for i := range sets {
+		es := &sets[i]
+		if newer(es, by_label[es.Label]) {
+			by_label[es.Label] = es
+		}
+		if newer(es, by_number[es.TLE.SatelliteNumber]) {
+			by_number[es.TLE.SatelliteNumber] = es
+		}
+	}
+
+	num_updated := 0
+	list := make([]*pb.Satellite, len(db.List))
+	for i, sat := range db.List {
+		list[i] = sat
+
+		var current *sgp4.TLE
+		if sat.Tle != nil {
+			current, _ = sgp4.ParseTLE(*sat.Tle)
+		}
+		es := findElementSet(sat, current, by_label, by_number)
+		if es == nil {
+			continue
+		}
+		if current != nil && !es.TLE.Epoch().After(current.Epoch()) {
+			continue
+		}
+
+		updated := proto.Clone(sat).(*pb.Satellite)
+		updated.Tle = proto.String(es.Text)
+		list[i] = updated
+		num_updated++
+	}
+
+	return newSatelliteDB(list), num_updated
+}
+
+var refreshLock sync.Mutex
+
+// RefreshTLEs loads element sets from the given sources and hot-swaps the
+// global satellite database if any TLEs changed. Sources may be URLs or
+// local paths. It returns the number of satellites updated. A source that
+// fails to load doesn't prevent updates from the others.
+func RefreshTLEs(sources []string) (int, error) {
+	refreshLock.Lock()
+	defer refreshLock.Unlock()
+
+	var sets []ElementSet
+	var loadErr error = nil
+	for _, source := range sources {
+		s, err := loadElementSets(source)
+		if err != nil {
+			log.Printf("Error loading TLEs from %s: %s",
+				source, err.Error())
+			loadErr = err
+			continue
+		}
+		sets = append(sets, s...)
+	}
+
+	updated, n := GlobalSatelliteDB().UpdateTLEs(sets)
+	if n > 0 {
+		SetGlobalSatelliteDB(updated)
+	}
+	log.Printf("Refreshed TLEs: %d element sets, %d satellites updated",
+		len(sets), n)
+	return n, loadErr
+}
+
+func tleSources() []string {
+	var sources []string
+	for _, s := range strings.Split(*tle_sources, ",") {
+		s = strings.TrimSpace(s)
+		if s != "" {
+			sources = append(sources, s)
+		}
+	}
+	return sources
+}
+
+// RefreshTLEsForever periodically refreshes the global satellite database
+// from the --tle_sources flag. It should be started in a goroutine by each
+// binary that uses GlobalSatelliteDB.
+func RefreshTLEsForever() {
+	sources := tleSources()
+	if *tle_refresh_interval <= 0 || len(sources) == 0 {
+		log.Printf("TLE refreshing is disabled.")
+		return
+	}
+	for {
+		RefreshTLEs(sources)
+		time.Sleep(*tle_refresh_interval)
+	}
+}
@@ -0,0 +1,146 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package db
+
+import "carpcomm/pb"
+import "code.google.com/p/goprotobuf/proto"
+import "io/ioutil"
+import "os"
+import "strings"
+import "testing"
+
+const swisscubeOld = "SWISSCUBE\n" +
+	"1 35932U 09051B   12110.66765508  .00000638  00000-0  15500-3 0  5172\n" +
+	"2 35932  98.3348 213.8703 0006768 284.4795  75.6141 14.52927878136365"
+const swisscubeNew = "SWISSCUBE\n" +
+	"1 35932U 09051B   12153.15748009  .00005321  00000-0  12986-2 0  5875\n" +
+	"2 35932  98.3384 256.1413 0008563 147.1867 212.9692 14.53018831142537"
+const tisatOld = "TISAT 1\n" +
+	"1 36799U 10035E   12203.03170004  .00001634  00000-0  21206-3 0  1761\n" +
+	"2 36799  98.0614 279.6142 0016277  65.8289 294.4603 14.82067936109505"
+
+// Same satellite under a different name.
+const tisatNew = "TISAT-1 (SO-67)\n" +
+	"1 36799U 10035E   12210.03170004  .00001634  00000-0  21206-3 0  1769\n" +
+	"2 36799  98.0614 279.6142 0016277  65.8289 294.4603 14.82067936109505"
+
+// Last digit of line 2 is wrong.
+const badChecksum = "MASAT 1\n" +
+	"1 38081U 12006E   12152.98871236  .00017916  00000-0  44342-3 0  2609\n" +
+	"2 38081  69.4881 352.7312 0749214 268.3793  83.1501 14.13682993 15298"
+
+func testSatelliteDB() *SatelliteDB {
+	return newSatelliteDB([]*pb.Satellite{
+		&pb.Satellite{
+			Id: proto.String("swisscube"),
+			Tle: proto.String(swisscubeOld),
+			CelestrakTleLabel: proto.String("SWISSCUBE")},
+		&pb.Satellite{
+			Id: proto.String("tisat1"),
+			Tle: proto.String(tisatOld)},
+		&pb.Satellite{
+			Id: proto.String("masat1"),
+			CelestrakTleLabel: proto.String("MASAT 1")},
+	})
+}
+
+func TestParseElementSets(t *testing.T) {
+	file := strings.Join([]string{
+		swisscubeNew, badChecksum, "garbage", tisatNew, ""}, "\r\n")
+	sets, err := ParseElementSets(strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if len(sets) != 2 {
+		t.Fatalf("Wrong number of element sets: %d", len(sets))
+	}
+	if sets[0].Label != "SWISSCUBE" || sets[0].Text != swisscubeNew {
+		t.Errorf("Wrong element set: %+v", sets[0])
+	}
+	if sets[1].Label != "TISAT-1 (SO-67)" ||
+		sets[1].TLE.SatelliteNumber != 36799 {
+		t.Errorf("Wrong element set: %+v", sets[1])
+	}
+}
+
+func TestUpdateTLEs(t *testing.T) {
+	sets, err := ParseElementSets(strings.NewReader(
+		swisscubeNew + "\n" + tisatNew + "\n" + badChecksum))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := testSatelliteDB()
+	updated, n := old.UpdateTLEs(sets)
+	if n != 2 {
+		t.Errorf("Wrong number of updates: %d", n)
+	}
+	// Matched by label.
+	if updated.Map["swisscube"].GetTle() != swisscubeNew {
+		t.Errorf("swisscube not updated: %s",
+			updated.Map["swisscube"].GetTle())
+	}
+	// Matched by NORAD catalog number.
+	if updated.Map["tisat1"].GetTle() != tisatNew {
+		t.Errorf("tisat1 not updated: %s",
+			updated.Map["tisat1"].GetTle())
+	}
+	if updated.Map["masat1"].Tle != nil {
+		t.Errorf("masat1 updated with a bad element set")
+	}
+	if len(updated.List) != len(old.List) {
+		t.Errorf("Wrong list length: %d", len(updated.List))
+	}
+
+	// The original must not be modified since other goroutines may be
+	// using it.
+	if old.Map["swisscube"].GetTle() != swisscubeOld ||
+		old.Map["tisat1"].GetTle() != tisatOld {
+		t.Errorf("Original database was modified")
+	}
+
+	// Older or identical elements are rejected.
+	sets, err = ParseElementSets(strings.NewReader(
+		swisscubeOld + "\n" + tisatNew))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, n := updated.UpdateTLEs(sets)
+	if n != 0 {
+		t.Errorf("Wrong number of updates: %d", n)
+	}
+	if again.Map["swisscube"].GetTle() != swisscubeNew {
+		t.Errorf("swisscube replaced by older elements")
+	}
+}
+
+func TestRefreshTLEs(t *testing.T) {
+	f, err := ioutil.TempFile("", "tle_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(swisscubeNew + "\n")
+	f.Close()
+
+	before := testSatelliteDB()
+	SetGlobalSatelliteDB(before)
+	defer SetGlobalSatelliteDB(nil)
+
+	n, err := RefreshTLEs([]string{f.Name(), f.Name() + ".missing"})
+	if err == nil {
+		t.Errorf("Expected an error for the missing file")
+	}
+	if n != 1 {
+		t.Errorf("Wrong number of updates: %d", n)
+	}
+	after := GlobalSatelliteDB()
+	if after == before {
+		t.Fatalf("Global database wasn't replaced")
+	}
+	if after.Map["swisscube"].GetTle() != swisscubeNew {
+		t.Errorf("swisscube not updated: %s",
+			after.Map["swisscube"].GetTle())
+	}
+}
@@ -10,18 +10,19 @@ import "reflect"
 import "log"
 
 type UserDB struct {
-	table *SDBTable
+	table Table
 }
 
 const kUserColumn = "pb.User"
 const kUserKeyGoogleKey = "google_key"
 
-func NewUserDB(table *SDBTable) *UserDB {
+func NewUserDB(table Table) *UserDB {
 	return &UserDB{table}
 }
 
 func (db *UserDB) lookupByGoogleKey(google_key string) (*pb.User, error) {
-	users, err := db.table.lookupByKeyValue(
+	users, err := lookupByKeyValue(
+		db.table,
 		kUserColumn,
 		kUserKeyGoogleKey,
 		google_key,
@@ -0,0 +1,119 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package cw
+
+import "math"
+
+// Each frame of this many samples is reduced to a single power value.
+const kCWFrameSize = 1024
+
+// The signal is searched for separately in each block of this many frames.
+const kCWBlockFrames = 2048
+
+const kCWBandwidthHz = 2000.0
+
+// Finds the CW signal in the spectrum and returns its power in each frame.
+type cwFilter struct {
+	// The bandwidth of the signal as a fraction of the sample rate.
+	filter_frac float64
+	block [][]float32
+	power []float64
+}
+
+func newCWFilter(filter_frac float64) *cwFilter {
+	return &cwFilter{filter_frac: filter_frac}
+}
+
+func (f *cwFilter) addFrame(logpower []float32) {
+	f.block = append(f.block, logpower)
+	if len(f.block) == kCWBlockFrames {
+		f.filterBlock()
+	}
+}
+
+// Returns the power of the signal in every frame.
+func (f *cwFilter) close() []float64 {
+	f.filterBlock()
+	return f.power
+}
+
+func argmax(a []float64) (r int) {
+	for i, v := range a {
+		if v > a[r] {
+			r = i
+		}
+	}
+	return r
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+func (f *cwFilter) filterBlock() {
+	if len(f.block) == 0 {
+		return
+	}
+	n := kCWFrameSize
+	num_frames := float64(len(f.block))
+
+	// The standard deviation of each frequency bin over time. It's
+	// unaffected by removing the continuous background streaks.
+	mean := make([]float64, n)
+	for _, row := range f.block {
+		for j, v := range row {
+			mean[j] += float64(v)
+		}
+	}
+	for j := range mean {
+		mean[j] /= num_frames
+	}
+	std := make([]float64, n)
+	for _, row := range f.block {
+		for j, v := range row {
+			d := float64(v) - mean[j]
+			std[j] += d * d
+		}
+	}
+	for j := range std {
+		std[j] = math.Sqrt(std[j] / num_frames)
+	}
+
+	delta := int(0.5 * float64(n) * f.filter_frac)
+	if delta < 1 {
+		delta = 1
+	}
+
+	// Assume the signal is not near the edge.
+	// FIXME: we should use a computed frequency hint instead
+	boundary := int(0.2 * float64(n))
+	center := argmax(std[boundary:n-boundary]) + boundary
+	max_variation := delta
+	if max_variation < 50 {
+		max_variation = 50
+	}
+	lo := clamp(center-max_variation, 0, n)
+	hi := clamp(center+max_variation, lo+1, n)
+
+	signal_pos := lo + argmax(std[lo:hi])
+	signal_pos = clamp(signal_pos, lo+delta, hi-delta)
+	band_lo := clamp(signal_pos-delta, 0, n-1)
+	band_hi := clamp(signal_pos+delta, band_lo+1, n)
+
+	// The power is measured above the background.
+	for _, row := range f.block {
+		p := math.Inf(-1)
+		for j := band_lo; j < band_hi; j++ {
+			p = math.Max(p, float64(row[j])-mean[j])
+		}
+		f.power = append(f.power, p)
+	}
+	f.block = f.block[:0]
+}
@@ -4,12 +4,10 @@ import "fmt"
 import "os"
 import "log"
 import "io"
-import "os/exec"
-import "bufio"
 import "strings"
 
+import "carpcomm/demod/dsp"
 import "carpcomm/pb"
-import "carpcomm/util/binary"
 
 func choose_threshold(a []float64) float64 {
 	return 2.0
@@ -247,61 +245,41 @@ func decode_cw(power[] float64, dot_len int) (words []string) {
 	return words
 }
 
+// A Decoder decodes Morse code from the IQ data written to it. The words are
+// only decoded after Close since the whole pass is optimized at once.
+type Decoder struct {
+	sample_rate float64
+	cw_params *pb.CWParams
+	spectrum *dsp.Spectrum
+	filter *cwFilter
+	blobs []pb.Contact_Blob
+}
 
-const cwFilterPath = "src/carpcomm/demod/cw/cw_filter.py"
-
-func DecodeCW(path string,
-	sample_rate float64,
-	sample_type pb.IQParams_Type,
-	cw_params *pb.CWParams) (
-	[]pb.Contact_Blob, error) {
-
-	const decimation = 1024 //512
-
-	// 1. CW Filter
-	filtered_path := fmt.Sprintf("%s_filtered", path)
-	c := exec.Command("python", cwFilterPath,
-		path,
-		sample_type.String(),
-		fmt.Sprintf("%f", sample_rate),
-		fmt.Sprintf("%d", decimation),
-		filtered_path)
-	err := c.Run()
-	if err != nil {
-		return nil, err
+func NewDecoder(sample_rate float64, cw_params *pb.CWParams) *Decoder {
+	f := newCWFilter(kCWBandwidthHz / sample_rate)
+	return &Decoder{
+		sample_rate: sample_rate,
+		cw_params: cw_params,
+		spectrum: dsp.NewSpectrum(kCWFrameSize, f.addFrame),
+		filter: f,
 	}
+}
 
-	file, err := os.Open(filtered_path)
-	if err != nil {
-		log.Printf("Error opening %s: %s", filtered_path, err.Error())
-		return nil, err
-	}
-	r := bufio.NewReader(file)
-	filtered := make([]float64, 0)
-	for {
-		f, err := binary.ReadFloat64LE(r)
-		if err != nil {
-			break
-		}
-		filtered = append(filtered, f)
-	}
-	file.Close()
+func (d *Decoder) Write(samples []complex64) error {
+	return d.spectrum.Write(samples)
+}
 
-	// Delete temporary file.
-	err = os.Remove(filtered_path)
-	if err != nil {
-		fmt.Printf("Error deleting file: %s", err.Error())
-		return nil, err
+func (d *Decoder) Close() error {
+	if err := d.spectrum.Close(); err != nil {
+		return err
 	}
+	filtered := d.filter.close()
 
+	frame_duration_s := float64(kCWFrameSize) / d.sample_rate
 
-	// 2. Optimize
-
-	frame_duration_s := float64(decimation) / sample_rate
+	log.Printf("%s\n", d.cw_params)
 
-	log.Printf("%s\n", cw_params)
-
-	dot_len := int(*cw_params.DotDurationS / frame_duration_s + 0.5)
+	dot_len := int(*d.cw_params.DotDurationS / frame_duration_s + 0.5)
 
 	log.Printf("duration: %f\ndot_len: %d\n", frame_duration_s, dot_len)
 
@@ -309,11 +287,16 @@ func DecodeCW(path string,
 	text := strings.Join(words, " ")
 
 	if text == "" {
-		return nil, nil
+		return nil
 	}
 
-	blobs := make([]pb.Contact_Blob, 1)
-	blobs[0].Format = pb.Contact_Blob_MORSE.Enum()
-	blobs[0].InlineData = ([]byte)(text)
-	return blobs, nil
-}
\ No newline at end of file
+	d.blobs = make([]pb.Contact_Blob, 1)
+	d.blobs[0].Format = pb.Contact_Blob_MORSE.Enum()
+	d.blobs[0].InlineData = ([]byte)(text)
+	return nil
+}
+
+// Blobs returns the decoded Morse message, if any, after Close.
+func (d *Decoder) Blobs() []pb.Contact_Blob {
+	return d.blobs
+}
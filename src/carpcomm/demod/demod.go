@@ -6,11 +6,72 @@ package demod
 import "carpcomm/pb"
 import "carpcomm/db"
 import "carpcomm/demod/cw"
+import "carpcomm/demod/doppler"
+import "carpcomm/demod/dsp"
 import "carpcomm/demod/packet"
 import "log"
 import "errors"
 import "fmt"
 
+// HasDecoder returns whether DecodeFromIQ can decode anything from the
+// satellite's channels.
+func HasDecoder(sat *pb.Satellite) bool {
+	for _, c := range sat.Channels {
+		if c.Modulation == nil {
+			continue
+		}
+		if *c.Modulation == pb.Channel_CW && c.CwParams != nil {
+			return true
+		}
+		if packet.CanDecode(*c) {
+			return true
+		}
+	}
+	return false
+}
+
+// A decoder produces blobs once all of the IQ data has been written to it.
+type decoder interface {
+	dsp.Sink
+	Blobs() []pb.Contact_Blob
+}
+
+// Estimates the Doppler corrections for each strategy used by the packet
+// channels. The IQ file is only read if some strategy depends on it.
+func analyzeDoppler(channels []*pb.Channel, path string,
+	sample_type pb.IQParams_Type) (
+	map[pb.Channel_DopplerStrategy]*doppler.Analyzer, error) {
+	analyzers := make(map[pb.Channel_DopplerStrategy]*doppler.Analyzer)
+	var sinks []dsp.Sink
+	for _, c := range channels {
+		if !packet.CanDecode(*c) {
+			continue
+		}
+		strategy := c.GetDopplerStrategy()
+		if analyzers[strategy] != nil {
+			continue
+		}
+		a, err := doppler.NewAnalyzer(strategy)
+		if err != nil {
+			return nil, err
+		}
+		analyzers[strategy] = a
+		if a.NeedsSamples() {
+			sinks = append(sinks, a)
+		}
+	}
+	if len(sinks) > 0 {
+		err := dsp.ReadIQFile(path, sample_type, dsp.Tee(sinks...))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return analyzers, nil
+}
+
+// DecodeFromIQ decodes the satellite's channels from the IQ file. The file is
+// read once for the Doppler analysis and once more by all the decoders
+// together.
 func DecodeFromIQ(satellite_id, path string,
 	sample_rate_hz float64, sample_type pb.IQParams_Type) (
 	blobs []pb.Contact_Blob, err error) {
@@ -22,42 +83,56 @@ func DecodeFromIQ(satellite_id, path string,
 		return nil, e
 	}
 
+	analyzers, err := analyzeDoppler(sat.Channels, path, sample_type)
+	if err != nil {
+		log.Printf("Error during Doppler analysis: %s", err.Error())
+		return nil, err
+	}
+
+	var decoders []decoder
+
 	// 1. Morse decoding
-	var cw_params *pb.CWParams
 	for _, c := range sat.Channels {
-		if c.Modulation != nil && *c.Modulation == pb.Channel_CW {
-			cw_params = c.CwParams
+		if c.Modulation != nil && *c.Modulation == pb.Channel_CW &&
+			c.CwParams != nil {
+			decoders = append(decoders,
+				cw.NewDecoder(sample_rate_hz, c.CwParams))
 			break
 		}
 	}
-	if cw_params != nil {
-		b, err := cw.DecodeCW(
-			path, sample_rate_hz, sample_type, cw_params)
-		blobs = append(blobs, b...)
-		if err != nil {
-			log.Printf("Error during DecodeCW: %s", err.Error())
-			return blobs, err
-		}
-	}
 
 	// 2. Frame decoding
 	for _, c := range sat.Channels {
-		if c.Modulation == nil {
+		if !packet.CanDecode(*c) {
 			continue
 		}
-		if *c.Modulation == pb.Channel_CW {
-			// CW is handled above.
+		a := analyzers[c.GetDopplerStrategy()]
+		corrections, e := a.Corrections()
+		if e != nil {
+			// The other channels may still decode.
+			log.Printf("Error during Doppler analysis: %s",
+				e.Error())
+			err = e
 			continue
 		}
-		b, err := packet.DecodePackets(
-			path, sample_rate_hz, sample_type, *c)
-		blobs = append(blobs, b...)
-		if err != nil {
-			log.Printf("Error during DecodePackets: %s",
-				err.Error())
-			return blobs, err
-		}
+		decoders = append(decoders,
+			packet.NewDecoder(*c, sample_rate_hz, corrections))
 	}
 
-	return blobs, nil
+	if len(decoders) == 0 {
+		return nil, err
+	}
+	sinks := make([]dsp.Sink, len(decoders))
+	for i, d := range decoders {
+		sinks[i] = d
+	}
+	e := dsp.ReadIQFile(path, sample_type, dsp.Tee(sinks...))
+	if e != nil {
+		log.Printf("Error during decoding: %s", e.Error())
+		err = e
+	}
+	for _, d := range decoders {
+		blobs = append(blobs, d.Blobs()...)
+	}
+	return blobs, err
 }
@@ -0,0 +1,48 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package main
+
+import "carpcomm/archive"
+import "carpcomm/db"
+import "carpcomm/streamer/jobs"
+import "flag"
+import "log"
+import "os"
+
+var db_prefix = flag.String("db_prefix", "r1-", "Database table prefix")
+var work_dir = flag.String("work_dir", "/tmp/demodd",
+	"Directory for the IQ data and intermediate files of running jobs")
+
+// demodd runs the IQ processing jobs queued by the streamer: it fetches the
+// IQ data from the archive, generates the spectrogram, demodulates and
+// decodes the data and stores the results in the contact. Any number of
+// instances can run against the same database.
+func main() {
+	flag.Parse()
+	log.Printf("Starting demodulation worker.")
+
+	domain, err := db.NewDomain(*db_prefix)
+	if err != nil {
+		log.Fatalf("Database error: %s", err.Error())
+	}
+	contactdb := domain.NewContactDB()
+	jobdb := domain.NewIQJobDB()
+
+	iq_archive, err := archive.New()
+	if err != nil {
+		log.Fatalf("Archive error: %s", err.Error())
+	}
+
+	if err := os.MkdirAll(*work_dir, 0700); err != nil {
+		log.Fatalf("Error creating work dir: %s", err.Error())
+	}
+
+	go db.RefreshTLEsForever()
+
+	queue := jobs.NewQueue(jobdb, func(j *jobs.Job) error {
+		return processNewIQData(j, contactdb, iq_archive)
+	})
+	queue.Start()
+	select {}
+}
@@ -0,0 +1,197 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package main
+
+import "log"
+import "errors"
+import "io"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "carpcomm/archive"
+import "carpcomm/db"
+import "carpcomm/demod"
+import "carpcomm/pb"
+import "carpcomm/streamer/contacts"
+import "carpcomm/streamer/jobs"
+import "code.google.com/p/goprotobuf/proto"
+
+// Stages that fail without failing the job since they may produce some
+// blobs anyway and retrying wouldn't help.
+func demodStage(satellite_id, local_path string, iq_params pb.IQParams) (
+	result []*pb.Contact_Blob, frames []pb.Contact_Blob, err error) {
+	log.Printf("Processing new IQ data %s for %s", local_path, satellite_id)
+
+	frames, err = demod.DecodeFromIQ(
+		satellite_id, local_path,
+		(float64)(*iq_params.SampleRate), *iq_params.Type)
+	if err != nil {
+		log.Printf("Error while processing IQ data: %s", err.Error())
+	}
+	log.Printf("Decoded %d blobs.", len(frames))
+	for _, b := range frames {
+		var cb pb.Contact_Blob = b
+		result = append(result, &cb)
+	}
+	return result, frames, err
+}
+
+func telemetryStage(satellite_id string, timestamp int64,
+	frames []pb.Contact_Blob) ([]*pb.Contact_Blob, error) {
+	decoded_blobs, err := contacts.DecodeBlobs(
+		satellite_id, timestamp, frames)
+	if err != nil {
+		log.Printf("Error decoding blobs: %s", err.Error())
+	}
+	log.Printf("Decoded %d telemetry blobs.", len(decoded_blobs))
+	return decoded_blobs, err
+}
+
+// Each job runs in its own directory under work_dir since the demodulators
+// write intermediate files next to the IQ data.
+func processNewIQData(j *jobs.Job, contactdb *db.ContactDB,
+	iq_archive archive.Archive) error {
+	contact_id := j.GetId()
+	log.Printf("%s: Processing IQ data", contact_id)
+
+	contact, err := contactdb.Lookup(contact_id)
+	if err != nil {
+		return err
+	}
+	if contact == nil {
+		return errors.New("Contact not found")
+	}
+
+	// Get IQParams and the archive path from the contact.
+	var iq_params *pb.IQParams
+	archive_path := ""
+	for _, b := range contact.Blob {
+		if b.Format != nil && *b.Format == pb.Contact_Blob_IQ {
+			if b.IqParams != nil {
+				iq_params = b.IqParams
+			}
+			if b.Path != nil {
+				archive_path = *b.Path
+			}
+		}
+	}
+	if iq_params == nil {
+		return errors.New("IQ blob missing")
+	}
+	if archive_path == "" {
+		return errors.New("IQ data not archived")
+	}
+
+	dir, err := ioutil.TempDir(*work_dir, contact_id+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	local_path := filepath.Join(dir, contact_id)
+	err = j.RunStage("fetch", func() (int, error) {
+		n, err := archive.Fetch(iq_archive, archive_path, local_path)
+		if err == nil {
+			log.Printf("%s: Fetched %d bytes from archive",
+				contact_id, n)
+		}
+		return 0, err
+	})
+	if err != nil {
+		return err
+	}
+
+	j.RunStage("spectrogram", func() (int, error) {
+		png_path := local_path + ".png"
+		err := demod.Spectrogram(local_path, *iq_params, png_path,
+			demod.SpectrogramTitle(*contact, *iq_params))
+		if err != nil {
+			return 0, err
+		}
+		return 0, archiveFile(iq_archive, png_path,
+			archive.SpectrogramPath(contact_id))
+	})
+
+	var new_blobs []*pb.Contact_Blob
+	var frames []pb.Contact_Blob
+	if contact.SatelliteId != nil {
+		j.RunStage("demod", func() (int, error) {
+			var blobs []*pb.Contact_Blob
+			blobs, frames, err = demodStage(*contact.SatelliteId,
+				local_path, *iq_params)
+			new_blobs = append(new_blobs, blobs...)
+			return len(blobs), err
+		})
+
+		j.RunStage("telemetry", func() (int, error) {
+			blobs, err := telemetryStage(*contact.SatelliteId,
+				*contact.StartTimestamp, frames)
+			new_blobs = append(new_blobs, blobs...)
+			return len(blobs), err
+		})
+	}
+
+	log.Printf("%s: Storing new blobs: %v", contact_id, new_blobs)
+
+	if j.Lost() {
+		return errors.New("Job taken over by another worker")
+	}
+
+	// The scheduler may have stored the end of the capture in the
+	// meantime so the results are added to the latest version. The job
+	// may be run more than once so the results of earlier runs are
+	// replaced.
+	add_results := func(c *pb.Contact) error {
+		c.Blob = append(undecodedBlobs(c.Blob), new_blobs...)
+		if c.Capture != nil {
+			c.Capture.FramesDecoded = proto.Int32(
+				int32(len(frames)))
+			contacts.UpdateCaptureStatus(c)
+		}
+		return nil
+	}
+	err = j.RunStage("store", func() (int, error) {
+		c, err := contactdb.Update(contact_id, add_results)
+		if err == nil && c == nil {
+			err = errors.New("Contact not found")
+		}
+		return 0, err
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("%s: Wrote updated contact to db.", contact_id)
+	return nil
+}
+
+// Returns the blobs that weren't produced by demodulating the IQ data, i.e.
+// the IQ data itself and anything entered by users.
+func undecodedBlobs(blobs []*pb.Contact_Blob) []*pb.Contact_Blob {
+	var result []*pb.Contact_Blob
+	for _, b := range blobs {
+		switch b.GetFormat() {
+		case pb.Contact_Blob_IQ, pb.Contact_Blob_FREEFORM:
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+func archiveFile(a archive.Archive, local_path, path string) error {
+	f, err := os.Open(local_path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, err := a.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, f); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Close()
+}
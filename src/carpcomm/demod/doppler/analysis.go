@@ -0,0 +1,284 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package doppler
+
+import "carpcomm/demod/dsp"
+import "carpcomm/pb"
+import "errors"
+import "fmt"
+import "math"
+
+const kFFTSize = 8192
+
+// The background is estimated separately for each block of FFT frames so
+// that memory use doesn't depend on the length of the recording.
+const kBlockFrames = 512
+
+// Transmissions are separated by at least this many FFT frames without a
+// signal.
+const kGapFrames = 250
+
+// The offset of the SNAPS FCD receiver's centre frequency.
+const kSNAPSOffsetFrac = 25000.0 / 192000.0
+
+// The strongest frequency bin of each FFT frame and its log power above the
+// background.
+type spectrumPeaks struct {
+	pos, val []float64
+}
+
+func (p *spectrumPeaks) addBlock(logpower [][]float32) {
+	if len(logpower) == 0 {
+		return
+	}
+
+	// Remove continuous background streaks.
+	mean := make([]float64, kFFTSize)
+	for _, row := range logpower {
+		for j, v := range row {
+			mean[j] += float64(v)
+		}
+	}
+	for j := range mean {
+		mean[j] /= float64(len(logpower))
+	}
+
+	for _, row := range logpower {
+		max_pos, max_val := 0, math.Inf(-1)
+		for j, v := range row {
+			if d := float64(v) - mean[j]; d > max_val {
+				max_pos, max_val = j, d
+			}
+		}
+		p.pos = append(p.pos, float64(max_pos))
+		p.val = append(p.val, max_val)
+	}
+}
+
+// A transmission spans the FFT frames [begin, end).
+type burst struct {
+	begin, end int
+}
+
+// Returns which frames contain a signal and the transmissions.
+func findBursts(val []float64) (signal []bool, bursts []burst) {
+	n := len(val)
+	if n == 0 {
+		return nil, nil
+	}
+	var sum, sum_sq float64
+	for _, v := range val {
+		sum += v
+		sum_sq += v * v
+	}
+	avg := sum / float64(n)
+	std := math.Sqrt(math.Max(sum_sq/float64(n)-avg*avg, 0))
+
+	signal = make([]bool, n)
+	for i, v := range val {
+		signal[i] = v > avg+2*std
+	}
+
+	// Find the gaps between transmissions.
+	var gaps []burst
+	zero_count := 0
+	begin := -1
+	for i := 0; i < n; i++ {
+		if !signal[i] {
+			if begin < 0 {
+				begin = i
+				zero_count = 0
+			}
+			zero_count++
+		} else {
+			if zero_count > kGapFrames {
+				gaps = append(gaps, burst{begin, i})
+			}
+			begin = -1
+			zero_count = 0
+		}
+	}
+	if begin >= 0 {
+		// The final gap doesn't need to exceed the threshold since we
+		// assume everything is quiet after the pass.
+		gaps = append(gaps, burst{begin, n})
+	}
+
+	for i := 0; i+1 < len(gaps); i++ {
+		bursts = append(bursts, burst{gaps[i].end, gaps[i+1].begin})
+	}
+	return signal, bursts
+}
+
+// Returns the least squares fit y = m*x + c.
+func linearFit(x, y []float64) (m, c float64) {
+	n := float64(len(x))
+	var sx, sy, sxx, sxy float64
+	for i := range x {
+		sx += x[i]
+		sy += y[i]
+		sxx += x[i] * x[i]
+		sxy += x[i] * y[i]
+	}
+	d := n*sxx - sx*sx
+	if d == 0 {
+		return 0, sy / n
+	}
+	m = (n*sxy - sx*sy) / d
+	return m, (sy - m*sx) / n
+}
+
+// HRBE transmits a single tone in the first half of each burst and shifts
+// between mark and space in the second half. The tone is fitted linearly.
+func hrbeLinearBurst(peaks *spectrumPeaks, signal []bool, b burst) (
+	float64, bool) {
+	lin_end := (b.begin + b.end) / 2
+	if lin_end < b.begin+1 {
+		lin_end = b.begin + 1
+	}
+	var x, y []float64
+	for i := b.begin; i < lin_end; i++ {
+		if signal[i] {
+			x = append(x, float64(i))
+			y = append(y, peaks.pos[i])
+		}
+	}
+	if len(x) == 0 {
+		return 0, false
+	}
+	m, c := linearFit(x, y)
+	return m*0.5*float64(b.begin+b.end) + c, true
+}
+
+// The average peak frequency of the burst without outliers.
+func constantBurst(peaks *spectrumPeaks, signal []bool, b burst) (
+	float64, bool) {
+	var x []float64
+	for i := b.begin; i < b.end; i++ {
+		if signal[i] {
+			x = append(x, peaks.pos[i])
+		}
+	}
+	if len(x) == 0 {
+		return 0, false
+	}
+	var sum, sum_sq float64
+	for _, v := range x {
+		sum += v
+		sum_sq += v * v
+	}
+	c := sum / float64(len(x))
+	s := math.Sqrt(math.Max(sum_sq/float64(len(x))-c*c, 0))
+
+	var y_sum float64
+	y_n := 0
+	for _, v := range x {
+		if math.Abs(v-c) < s {
+			y_sum += v
+			y_n++
+		}
+	}
+	if y_n > 0 {
+		c = y_sum / float64(y_n)
+	}
+	return c, true
+}
+
+type burstFit func(*spectrumPeaks, []bool, burst) (float64, bool)
+
+// Each burst's frequency applies from halfway between the previous burst and
+// itself until halfway to the next burst.
+func fitBursts(peaks *spectrumPeaks, fit burstFit) []Correction {
+	signal, bursts := findBursts(peaks.val)
+	var corrections []Correction
+	last_mid := 0
+	for i, b := range bursts {
+		next_begin := len(peaks.pos)
+		if i+1 < len(bursts) {
+			next_begin = bursts[i+1].begin
+		}
+		mid := (b.end + next_begin) / 2
+
+		pos, ok := fit(peaks, signal, b)
+		if ok {
+			corrections = append(corrections, Correction{
+				SampleNum: int64(last_mid) * kFFTSize,
+				DeltaFrac: (pos - kFFTSize/2) / kFFTSize,
+			})
+		}
+		last_mid = mid
+	}
+	return corrections
+}
+
+// An Analyzer estimates the frequency offset of the signal over time from
+// the IQ data written to it. The estimate depends on the whole pass so it's
+// only available after Close.
+type Analyzer struct {
+	fit burstFit
+	corrections []Correction
+
+	spectrum *dsp.Spectrum
+	block [][]float32
+	peaks spectrumPeaks
+}
+
+func NewAnalyzer(strategy pb.Channel_DopplerStrategy) (*Analyzer, error) {
+	a := &Analyzer{}
+	switch strategy {
+	case pb.Channel_DISABLED:
+		a.corrections = []Correction{{0, 0}}
+	case pb.Channel_SNAPS_FCD_OFFSET:
+		a.corrections = []Correction{{0, kSNAPSOffsetFrac}}
+	case pb.Channel_HRBE_LINEAR:
+		a.fit = hrbeLinearBurst
+	case pb.Channel_CONSTANT_BURST:
+		a.fit = constantBurst
+	default:
+		return nil, errors.New(fmt.Sprintf(
+			"Unknown doppler strategy: %s", strategy.String()))
+	}
+	if a.fit != nil {
+		a.spectrum = dsp.NewSpectrum(kFFTSize, a.addFrame)
+	}
+	return a, nil
+}
+
+// NeedsSamples returns false if the corrections don't depend on the IQ data.
+func (a *Analyzer) NeedsSamples() bool {
+	return a.fit != nil
+}
+
+func (a *Analyzer) addFrame(logpower []float32) {
+	a.block = append(a.block, logpower)
+	if len(a.block) == kBlockFrames {
+		a.peaks.addBlock(a.block)
+		a.block = a.block[:0]
+	}
+}
+
+func (a *Analyzer) Write(samples []complex64) error {
+	if !a.NeedsSamples() {
+		return nil
+	}
+	return a.spectrum.Write(samples)
+}
+
+func (a *Analyzer) Close() error {
+	if !a.NeedsSamples() {
+		return nil
+	}
+	a.peaks.addBlock(a.block)
+	a.block = nil
+	a.corrections = fitBursts(&a.peaks, a.fit)
+	return nil
+}
+
+// Corrections returns the estimated frequency offsets.
+func (a *Analyzer) Corrections() ([]Correction, error) {
+	if len(a.corrections) == 0 {
+		return nil, errors.New("No transmissions found")
+	}
+	return a.corrections, nil
+}
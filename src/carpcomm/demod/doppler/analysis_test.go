@@ -0,0 +1,114 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package doppler
+
+import "carpcomm/pb"
+import "math"
+import "math/cmplx"
+import "testing"
+
+func TestFitBursts(t *testing.T) {
+	// Two bursts separated by quiet periods. The frequency drifts up
+	// during the first and is constant during the second.
+	peaks := &spectrumPeaks{}
+	add := func(n int, pos func(i int) float64, val float64) {
+		for i := 0; i < n; i++ {
+			peaks.pos = append(peaks.pos, pos(i))
+			peaks.val = append(peaks.val, val)
+		}
+	}
+	noise := func(i int) float64 { return float64((i * 37) % kFFTSize) }
+	add(300, noise, 0)
+	add(20, func(i int) float64 { return 5000 + float64(i) }, 10)
+	add(300, noise, 0)
+	add(20, func(i int) float64 { return 4000 }, 10)
+	add(300, noise, 0)
+
+	c := fitBursts(peaks, hrbeLinearBurst)
+	if len(c) != 2 {
+		t.Fatalf("Wrong corrections: %v", c)
+	}
+	// The fit is evaluated at the middle of the burst.
+	if c[0].SampleNum != 0 ||
+		math.Abs(c[0].DeltaFrac-(5010.0-4096)/kFFTSize) > 1e-9 {
+		t.Errorf("Wrong first correction: %v", c[0])
+	}
+	if c[1].SampleNum != 470*kFFTSize ||
+		math.Abs(c[1].DeltaFrac-(4000.0-4096)/kFFTSize) > 1e-9 {
+		t.Errorf("Wrong second correction: %v", c[1])
+	}
+
+	c = fitBursts(peaks, constantBurst)
+	if len(c) != 2 ||
+		math.Abs(c[1].DeltaFrac-(4000.0-4096)/kFFTSize) > 1e-9 {
+		t.Errorf("Wrong constant corrections: %v", c)
+	}
+}
+
+func TestAnalyzerFixed(t *testing.T) {
+	a, err := NewAnalyzer(pb.Channel_SNAPS_FCD_OFFSET)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.NeedsSamples() {
+		t.Errorf("SNAPS_FCD_OFFSET needs samples")
+	}
+	c, err := a.Corrections()
+	if err != nil || len(c) != 1 || c[0].DeltaFrac != kSNAPSOffsetFrac {
+		t.Errorf("SNAPS_FCD_OFFSET: %v, %v", c, err)
+	}
+
+	a, err = NewAnalyzer(pb.Channel_HRBE_LINEAR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Write(make([]complex64, 3*kFFTSize))
+	a.Close()
+	if c, err := a.Corrections(); err == nil {
+		t.Errorf("Corrections found without a signal: %v", c)
+	}
+}
+
+type collector []complex64
+
+func (c *collector) Write(samples []complex64) error {
+	*c = append(*c, samples...)
+	return nil
+}
+
+func (c *collector) Close() error {
+	return nil
+}
+
+func TestCorrector(t *testing.T) {
+	// The tone moves from 0.1 to 0.2 of the sample rate at sample 100.
+	samples := make([]complex64, 300)
+	φ := 0.0
+	for i := range samples {
+		samples[i] = complex64(cmplx.Exp(complex(0, 2*math.Pi*φ)))
+		if i < 100 {
+			φ += 0.1
+		} else {
+			φ += 0.2
+		}
+	}
+
+	var out collector
+	c := NewCorrector([]Correction{{0, 0.1}, {100, 0.2}}, &out)
+	// Blocks don't line up with the corrections.
+	c.Write(samples[:70])
+	c.Write(samples[70:230])
+	c.Write(samples[230:])
+	c.Close()
+
+	if len(out) != len(samples) {
+		t.Fatalf("Wrong number of samples: %d", len(out))
+	}
+	for i, s := range out {
+		if cmplx.Abs(complex128(s)-1) > 1e-3 {
+			t.Errorf("Sample %d not shifted to DC: %v", i, s)
+			break
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package dsp
+
+// A Sink processes a stream of complex samples, which are written in blocks
+// so that recordings never need to fit into memory. Sinks must not modify
+// the blocks or keep references to them after Write returns since the same
+// block may be passed to several sinks. Close is called at the end of the
+// stream.
+type Sink interface {
+	Write(samples []complex64) error
+	Close() error
+}
+
+type tee []Sink
+
+// Tee returns a sink that writes to all the sinks so that they can share a
+// single read of the IQ data.
+func Tee(sinks ...Sink) Sink {
+	return tee(sinks)
+}
+
+func (t tee) Write(samples []complex64) error {
+	for _, s := range t {
+		if err := s.Write(samples); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Every sink is closed even if some fail.
+func (t tee) Close() (err error) {
+	for _, s := range t {
+		if cerr := s.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Returns a buffer of length n, reusing buf if it's large enough.
+func resize(buf []complex64, n int) []complex64 {
+	if cap(buf) < n {
+		return make([]complex64, n)
+	}
+	return buf[:n]
+}
@@ -0,0 +1,211 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package dsp
+
+import "bytes"
+import "carpcomm/pb"
+import "math"
+import "math/cmplx"
+import "testing"
+
+// Records everything written to it.
+type collector struct {
+	samples []complex64
+	writes int
+	closed bool
+}
+
+func (c *collector) Write(samples []complex64) error {
+	c.samples = append(c.samples, samples...)
+	c.writes++
+	return nil
+}
+
+func (c *collector) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestReadIQ(t *testing.T) {
+	cases := []struct {
+		t pb.IQParams_Type
+		data []byte
+		expected []complex64
+	}{
+		{pb.IQParams_UINT8,
+			[]byte{127, 252, 2, 127, 0},
+			[]complex64{complex(0, 1), complex(-1, 0)}},
+		{pb.IQParams_SINT16,
+			[]byte{0, 0x40, 0, 0xc0, 0xff, 0x7f, 1},
+			[]complex64{complex(0.5, -0.5)}},
+		{pb.IQParams_FLOAT32,
+			[]byte{0, 0, 0x80, 0x3f, 0, 0, 0, 0xc0, 0},
+			[]complex64{complex(1, -2)}},
+	}
+	for _, tc := range cases {
+		c := &collector{}
+		err := ReadIQ(bytes.NewBuffer(tc.data), tc.t, c)
+		if err != nil {
+			t.Fatalf("%s: ReadIQ: %s", tc.t, err.Error())
+		}
+		if !c.closed {
+			t.Errorf("%s: sink not closed", tc.t)
+		}
+		if len(c.samples) != len(tc.expected) {
+			t.Fatalf("%s: wrong samples: %v", tc.t, c.samples)
+		}
+		for i, s := range c.samples {
+			if cmplx.Abs(complex128(s-tc.expected[i])) > 1e-6 {
+				t.Errorf("%s: sample %d is %v, expected %v",
+					tc.t, i, s, tc.expected[i])
+			}
+		}
+	}
+
+	if ReadIQ(&bytes.Buffer{}, pb.IQParams_Type(-1), &collector{}) == nil {
+		t.Errorf("Expected an error for an invalid sample type")
+	}
+}
+
+func TestReadIQBlocks(t *testing.T) {
+	n := kBlockSize + 10
+	c := &collector{}
+	err := ReadIQ(bytes.NewBuffer(make([]byte, 2*n)), pb.IQParams_UINT8, c)
+	if err != nil {
+		t.Fatalf("ReadIQ: %s", err.Error())
+	}
+	if len(c.samples) != n || c.writes != 2 {
+		t.Errorf("Got %d samples in %d writes",
+			len(c.samples), c.writes)
+	}
+}
+
+func TestTee(t *testing.T) {
+	a, b := &collector{}, &collector{}
+	s := Tee(a, b)
+	s.Write([]complex64{1, 2})
+	s.Write([]complex64{3})
+	s.Close()
+	for _, c := range []*collector{a, b} {
+		if len(c.samples) != 3 || c.samples[2] != 3 || !c.closed {
+			t.Errorf("Wrong output: %v", c)
+		}
+	}
+}
+
+func tone(frac float64, n int) []complex64 {
+	s := make([]complex64, n)
+	for i := range s {
+		φ := 2 * math.Pi * frac * float64(i)
+		s[i] = complex64(cmplx.Exp(complex(0, φ)))
+	}
+	return s
+}
+
+func TestMixer(t *testing.T) {
+	c := &collector{}
+	m := NewMixer(0.1, c)
+	s := tone(0.1, 100)
+	// The phase continues across blocks.
+	m.Write(s[:37])
+	m.Write(s[37:])
+	if len(c.samples) != 100 {
+		t.Fatalf("Got %d samples", len(c.samples))
+	}
+	for i, x := range c.samples {
+		if cmplx.Abs(complex128(x)-1) > 1e-4 {
+			t.Fatalf("Sample %d is %v, expected 1", i, x)
+		}
+	}
+}
+
+// Returns the average power of the second half of the samples, after the
+// filter has settled.
+func settledPower(s []complex64) float64 {
+	p := 0.0
+	for _, x := range s[len(s)/2:] {
+		p += real(complex128(x) * cmplx.Conj(complex128(x)))
+	}
+	return p / float64(len(s)-len(s)/2)
+}
+
+func TestFIRDecimator(t *testing.T) {
+	taps := LowPassTaps(0.05, 81)
+
+	dc := &collector{}
+	d := NewFIRDecimator(taps, 4, dc)
+	d.Write(tone(0, 1000))
+	if len(dc.samples) != 250 {
+		t.Errorf("Got %d samples, expected 250", len(dc.samples))
+	}
+	if p := settledPower(dc.samples); math.Abs(p-1) > 1e-3 {
+		t.Errorf("DC power is %f, expected 1", p)
+	}
+
+	hf := &collector{}
+	d = NewFIRDecimator(taps, 4, hf)
+	d.Write(tone(0.2, 1000))
+	if p := settledPower(hf.samples); p > 1e-3 {
+		t.Errorf("High frequency power is %f", p)
+	}
+
+	// The output mustn't depend on how the input is split into blocks.
+	s := tone(0.03, 1000)
+	whole := &collector{}
+	NewFIRDecimator(taps, 3, whole).Write(s)
+	split := &collector{}
+	d = NewFIRDecimator(taps, 3, split)
+	for i := 0; i < len(s); i += 7 {
+		end := i + 7
+		if end > len(s) {
+			end = len(s)
+		}
+		d.Write(s[i:end])
+	}
+	if len(whole.samples) != len(split.samples) {
+		t.Fatalf("Got %d and %d samples",
+			len(whole.samples), len(split.samples))
+	}
+	for i := range whole.samples {
+		if whole.samples[i] != split.samples[i] {
+			t.Fatalf("Sample %d differs: %v != %v",
+				i, whole.samples[i], split.samples[i])
+		}
+	}
+}
+
+func TestLogPowerSpectrum(t *testing.T) {
+	const n = 64
+	frame := make([]complex128, n)
+	for i, s := range tone(10.0/n, n) {
+		frame[i] = complex128(s)
+	}
+	logpower := LogPowerSpectrum(frame, BlackmanWindow(n))
+	peak := 0
+	for i, v := range logpower {
+		if v > logpower[peak] {
+			peak = i
+		}
+	}
+	if peak != n/2+10 {
+		t.Errorf("Peak at %d, expected %d", peak, n/2+10)
+	}
+}
+
+func TestSpectrum(t *testing.T) {
+	frames := 0
+	s := NewSpectrum(16, func(logpower []float32) {
+		if len(logpower) != 16 {
+			t.Errorf("Wrong frame size: %d", len(logpower))
+		}
+		frames++
+	})
+	s.Write(make([]complex64, 20))
+	s.Write(make([]complex64, 20))
+	s.Close()
+	// The partial frame at the end is dropped.
+	if frames != 2 {
+		t.Errorf("Got %d frames, expected 2", frames)
+	}
+}
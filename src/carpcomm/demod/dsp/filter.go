@@ -0,0 +1,88 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package dsp
+
+import "math"
+
+// LowPassTaps returns the taps of a windowed sinc low pass filter with unity
+// gain. cutoff is a fraction of the sample rate and n should be odd.
+func LowPassTaps(cutoff float64, n int) []float32 {
+	taps := make([]float32, n)
+	sum := 0.0
+	for i := range taps {
+		x := float64(i) - float64(n-1)/2
+		h := 2 * cutoff
+		if x != 0 {
+			h = math.Sin(2*math.Pi*cutoff*x) / (math.Pi * x)
+		}
+		// Hamming window.
+		if n > 1 {
+			h *= 0.54 - 0.46*math.Cos(
+				2*math.Pi*float64(i)/float64(n-1))
+		}
+		taps[i] = float32(h)
+		sum += h
+	}
+	for i := range taps {
+		taps[i] /= float32(sum)
+	}
+	return taps
+}
+
+// A FIRDecimator filters the signal and keeps every decimation'th sample.
+// Only the kept samples are computed.
+type FIRDecimator struct {
+	taps []float32
+	decimation int
+
+	// The last len(taps)-1 samples of the previous block followed by the
+	// current block.
+	buf []complex64
+	// Index in buf of the sample at which the next output is computed.
+	pos int
+	out []complex64
+	next Sink
+}
+
+func NewFIRDecimator(taps []float32, decimation int,
+	next Sink) *FIRDecimator {
+	history := len(taps) - 1
+	return &FIRDecimator{
+		taps: taps,
+		decimation: decimation,
+		buf: make([]complex64, history),
+		pos: history,
+		next: next,
+	}
+}
+
+func (f *FIRDecimator) Write(samples []complex64) error {
+	history := len(f.taps) - 1
+	f.buf = append(f.buf[:history], samples...)
+
+	f.out = f.out[:0]
+	for ; f.pos < len(f.buf); f.pos += f.decimation {
+		var re, im float32
+		x := f.buf[f.pos-history : f.pos+1]
+		for j, t := range f.taps {
+			c := x[history-j]
+			re += t * real(c)
+			im += t * imag(c)
+		}
+		f.out = append(f.out, complex(re, im))
+	}
+
+	drop := len(f.buf) - history
+	copy(f.buf, f.buf[drop:])
+	f.pos -= drop
+
+	if len(f.out) == 0 {
+		return nil
+	}
+	return f.next.Write(f.out)
+}
+
+func (f *FIRDecimator) Close() error {
+	return f.next.Close()
+}
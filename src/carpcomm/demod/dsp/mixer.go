@@ -0,0 +1,39 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package dsp
+
+import "math"
+import "math/cmplx"
+
+// A Mixer shifts the signal down by Frequency, which is a fraction of the
+// sample rate. The frequency may be changed between blocks without a phase
+// discontinuity.
+type Mixer struct {
+	Frequency float64
+
+	// In cycles.
+	phase float64
+	out []complex64
+	next Sink
+}
+
+func NewMixer(frequency float64, next Sink) *Mixer {
+	return &Mixer{Frequency: frequency, next: next}
+}
+
+func (m *Mixer) Write(samples []complex64) error {
+	m.out = resize(m.out, len(samples))
+	for i, c := range samples {
+		// exp(-i 2πΔf t)
+		lo := cmplx.Exp(complex(0, -2*math.Pi*m.phase))
+		m.out[i] = c * complex64(lo)
+		m.phase += m.Frequency
+		m.phase -= math.Floor(m.phase)
+	}
+	return m.next.Write(m.out)
+}
+
+func (m *Mixer) Close() error {
+	return m.next.Close()
+}
@@ -0,0 +1,94 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package dsp
+
+import "carpcomm/pb"
+import "bufio"
+import "errors"
+import "io"
+import "math"
+import "os"
+
+// Samples are read in blocks of this many samples.
+const kBlockSize = 1 << 16
+
+// Returns the number of bytes per sample or 0 if the type is unknown.
+func sampleSize(t pb.IQParams_Type) int {
+	switch t {
+	case pb.IQParams_UINT8:
+		return 2
+	case pb.IQParams_SINT16:
+		return 4
+	case pb.IQParams_FLOAT32:
+		return 8
+	}
+	return 0
+}
+
+// The scaling is the same as in carpcomm/util/binary.
+func convertSamples(t pb.IQParams_Type, b []byte, samples []complex64) {
+	switch t {
+	case pb.IQParams_UINT8:
+		for i := range samples {
+			re := (float32(b[2*i]) - 127.0) * 0.008
+			im := (float32(b[2*i+1]) - 127.0) * 0.008
+			samples[i] = complex(re, im)
+		}
+	case pb.IQParams_SINT16:
+		for i := range samples {
+			s := b[4*i : 4*i+4]
+			re := float32(int16(s[0])|int16(s[1])<<8) / 32768.0
+			im := float32(int16(s[2])|int16(s[3])<<8) / 32768.0
+			samples[i] = complex(re, im)
+		}
+	case pb.IQParams_FLOAT32:
+		for i := range samples {
+			s := b[8*i : 8*i+8]
+			re := uint32(s[0]) | uint32(s[1])<<8 |
+				uint32(s[2])<<16 | uint32(s[3])<<24
+			im := uint32(s[4]) | uint32(s[5])<<8 |
+				uint32(s[6])<<16 | uint32(s[7])<<24
+			samples[i] = complex(math.Float32frombits(re),
+				math.Float32frombits(im))
+		}
+	}
+}
+
+// ReadIQ writes the IQ data of the given type to the sink and closes it at
+// the end. A trailing partial sample is ignored.
+func ReadIQ(r io.Reader, t pb.IQParams_Type, sink Sink) error {
+	size := sampleSize(t)
+	if size == 0 {
+		return errors.New("Invalid sample type")
+	}
+	buf := make([]byte, size*kBlockSize)
+	block := make([]complex64, kBlockSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		num_samples := n / size
+		if num_samples > 0 {
+			convertSamples(t, buf[:num_samples*size],
+				block[:num_samples])
+			if err := sink.Write(block[:num_samples]); err != nil {
+				return err
+			}
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return sink.Close()
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// ReadIQFile is like ReadIQ but reads the file at path.
+func ReadIQFile(path string, t pb.IQParams_Type, sink Sink) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return ReadIQ(bufio.NewReader(f), t, sink)
+}
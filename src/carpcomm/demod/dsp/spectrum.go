@@ -0,0 +1,101 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package dsp
+
+import "math"
+import "math/cmplx"
+
+// FFT computes the discrete Fourier transform in place. len(x) must be a
+// power of two.
+func FFT(x []complex128) {
+	n := len(x)
+
+	// Bit reversal permutation.
+	j := 0
+	for i := 1; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a := x[start+k]
+				b := w * x[start+k+size/2]
+				x[start+k] = a + b
+				x[start+k+size/2] = a - b
+				w *= step
+			}
+		}
+	}
+}
+
+// BlackmanWindow is the same as numpy.blackman.
+func BlackmanWindow(n int) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		x := 2 * math.Pi * float64(i) / float64(n-1)
+		w[i] = 0.42 - 0.5*math.Cos(x) + 0.08*math.Cos(2*x)
+	}
+	return w
+}
+
+// LogPowerSpectrum returns the log power spectrum of the windowed frame with
+// zero frequency in the middle. The frame is overwritten.
+func LogPowerSpectrum(frame []complex128, window []float64) []float32 {
+	for i := range frame {
+		frame[i] *= complex(window[i], 0)
+	}
+	FFT(frame)
+	r := make([]float32, len(frame))
+	half := len(frame) / 2
+	for i, c := range frame {
+		p := real(c)*real(c) + imag(c)*imag(c)
+		r[(i+half)%len(frame)] = float32(math.Log(p + 1e-30))
+	}
+	return r
+}
+
+// A Spectrum computes the log power spectrum of consecutive frames of
+// samples and passes each one to a function. A partial frame at the end is
+// ignored.
+type Spectrum struct {
+	frame []complex128
+	n int
+	window []float64
+	emit func(logpower []float32)
+}
+
+// fft_size must be a power of two.
+func NewSpectrum(fft_size int, emit func(logpower []float32)) *Spectrum {
+	return &Spectrum{
+		frame: make([]complex128, fft_size),
+		window: BlackmanWindow(fft_size),
+		emit: emit,
+	}
+}
+
+func (s *Spectrum) Write(samples []complex64) error {
+	for _, c := range samples {
+		s.frame[s.n] = complex128(c)
+		s.n++
+		if s.n == len(s.frame) {
+			s.emit(LogPowerSpectrum(s.frame, s.window))
+			s.n = 0
+		}
+	}
+	return nil
+}
+
+func (s *Spectrum) Close() error {
+	return nil
+}
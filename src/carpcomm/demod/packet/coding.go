@@ -0,0 +1,64 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package packet
+
+// Non-Return-to-Zero Inverted (NRZI) encoding:
+// 0 causes a state transition and 1 does not.
+func NRZIEncode(bits []bool) {
+	state := false
+	for i, b := range bits {
+		if !b {
+			state = !state
+		}
+		bits[i] = state
+	}
+}
+
+// Non-Return-to-Zero Inverted (NRZI) decoding.
+func NRZIDecode(bits []bool) {
+	for i := 0; i < len(bits)-1; i++ {
+		bits[i] = bits[i] == bits[i+1]
+	}
+}
+
+func G3RUHScramble(bits []bool) {
+	// See http://www.amsat.org/amsat/articles/g3ruh/109/fig03.gif
+	shift_reg := 0xffffff  // We use 17 bits.
+	for i, b := range bits {
+		var in int
+		if b {
+			in = 1
+		}
+		new_bit := ((shift_reg >> 16) ^ (shift_reg >> 11) ^ in) & 1
+		shift_reg = (shift_reg << 1) | new_bit
+		bits[i] = new_bit > 0
+	}
+}
+
+func G3RUHDescramble(bits []bool) {
+	d := newG3RUHDescrambler()
+	for i, b := range bits {
+		bits[i] = d.next(b)
+	}
+}
+
+// Descrambles a bit stream one bit at a time.
+type g3ruhDescrambler struct {
+	shift_reg int
+}
+
+func newG3RUHDescrambler() *g3ruhDescrambler {
+	return &g3ruhDescrambler{0xffffff}  // We use 17 bits.
+}
+
+func (d *g3ruhDescrambler) next(b bool) bool {
+	// See http://www.amsat.org/amsat/articles/g3ruh/109/fig03.gif
+	var in int
+	if b {
+		in = 1
+	}
+	new_bit := ((d.shift_reg >> 16) ^ (d.shift_reg >> 11) ^ in) & 1
+	d.shift_reg = ((d.shift_reg << 1) | in) & 0x1ffff
+	return new_bit > 0
+}
@@ -1,7 +1,7 @@
 // Author: Timothy Stranex <tstranex@carpcomm.com>
 // Copyright 2013 Timothy Stranex
 
-package main
+package packet
 
 import "testing"
 
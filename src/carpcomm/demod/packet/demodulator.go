@@ -0,0 +1,220 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package packet
+
+import "math"
+import "math/cmplx"
+
+// After Doppler correction the AFSK1200 mark tone is at the centre since the
+// Doppler analysis locks onto it. On LSB the space tone is 1000 Hz below it
+// but some receivers swap I and Q so both sides are checked.
+const kAFSK1200MarkHz = 0
+const kAFSK1200ShiftHz = 1000
+
+const kG3RUHDeviationHz = 2400
+
+// The same frame is usually decoded from several adjacent bit streams within
+// this many bits of each other.
+const kDuplicateFrameBits = 2
+
+// Correlates the last samples with a tone. The correlation is updated for
+// each sample instead of being recomputed for every window.
+type slidingTone struct {
+	rotation complex128
+	phasor complex128
+	terms []complex128
+	sum complex128
+}
+
+func newSlidingTone(hz, sample_rate float64, window int) *slidingTone {
+	return &slidingTone{
+		rotation: cmplx.Exp(complex(0, -2*math.Pi*hz/sample_rate)),
+		phasor: 1,
+		terms: make([]complex128, window),
+	}
+}
+
+// Replaces the sample at position i of the window.
+func (t *slidingTone) add(c complex64, i int) {
+	term := complex128(c) * t.phasor
+	t.sum += term - t.terms[i]
+	t.terms[i] = term
+	t.phasor *= t.rotation
+
+	if i == len(t.terms)-1 {
+		// Stop rounding errors from accumulating.
+		t.phasor /= complex(cmplx.Abs(t.phasor), 0)
+		t.sum = 0
+		for _, x := range t.terms {
+			t.sum += x
+		}
+	}
+}
+
+func (t *slidingTone) power() float64 {
+	return real(t.sum)*real(t.sum) + imag(t.sum)*imag(t.sum)
+}
+
+func maxPower(tones []*slidingTone) (p float64) {
+	for _, t := range tones {
+		p = math.Max(p, t.power())
+	}
+	return p
+}
+
+// Recovers frames from the bits sampled at one clock phase.
+type bitStream struct {
+	// nil if the bits aren't scrambled.
+	descrambler *g3ruhDescrambler
+	last bool
+	hdlc hdlcDecoder
+}
+
+// Returns the frame that ends with the bit or nil.
+func (s *bitStream) next(b bool) []byte {
+	if s.descrambler != nil {
+		b = s.descrambler.next(b)
+	}
+	// NRZI decoding.
+	bit := b == s.last
+	s.last = b
+	return s.hdlc.next(bit)
+}
+
+// A Demodulator decodes HDLC frames from binary FSK IQ data. The data can be
+// written in blocks of any size so that recordings don't need to fit into
+// memory.
+type Demodulator struct {
+	mark, space []*slidingTone
+	samples_per_bit float64
+	// Bits are decided from windows of this many samples.
+	window int
+	// Position in the window ring buffer.
+	ring int
+	num_samples int64
+
+	// For clock recovery / bit synchronization, we simply try many
+	// different bit streams. At least one of them will be synchronized.
+	streams []bitStream
+	phase int
+	period int
+	excess float64
+
+	// When each frame was last decoded.
+	recent map[string]int64
+	emit func(frame []byte)
+}
+
+func newDemodulator(sample_rate, baud float64,
+	mark_hz, space_hz []float64, scrambled bool,
+	emit func(frame []byte)) *Demodulator {
+	d := &Demodulator{
+		samples_per_bit: sample_rate / baud,
+		recent: make(map[string]int64),
+		emit: emit,
+	}
+	d.window = int(d.samples_per_bit)
+	if d.window < 1 {
+		d.window = 1
+	}
+	for _, hz := range mark_hz {
+		d.mark = append(d.mark,
+			newSlidingTone(hz, sample_rate, d.window))
+	}
+	for _, hz := range space_hz {
+		d.space = append(d.space,
+			newSlidingTone(hz, sample_rate, d.window))
+	}
+	d.streams = make([]bitStream, d.window)
+	if scrambled {
+		for i := range d.streams {
+			d.streams[i].descrambler = newG3RUHDescrambler()
+		}
+	}
+	d.nextPeriod()
+	return d
+}
+
+// NewAFSK1200Demodulator returns a demodulator for 1200 baud Bell 202 FSK
+// received on LSB. The IQ data must be Doppler corrected. emit is called with
+// each frame.
+func NewAFSK1200Demodulator(sample_rate float64,
+	emit func(frame []byte)) *Demodulator {
+	space := []float64{
+		kAFSK1200MarkHz - kAFSK1200ShiftHz,
+		kAFSK1200MarkHz + kAFSK1200ShiftHz,
+	}
+	return newDemodulator(sample_rate, 1200,
+		[]float64{kAFSK1200MarkHz}, space, false, emit)
+}
+
+// NewG3RUHDemodulator returns a demodulator for G3RUH 9600 baud FSK centred
+// at the carrier frequency. emit is called with each frame.
+func NewG3RUHDemodulator(sample_rate, carrier float64,
+	emit func(frame []byte)) *Demodulator {
+	return newDemodulator(sample_rate, 9600,
+		[]float64{carrier + kG3RUHDeviationHz},
+		[]float64{carrier - kG3RUHDeviationHz}, true, emit)
+}
+
+// Bit periods are a whole number of samples long. The fractional parts are
+// carried over so that the clock doesn't drift.
+func (d *Demodulator) nextPeriod() {
+	skip := d.samples_per_bit + d.excess
+	d.period = int(skip)
+	if d.period < 1 {
+		d.period = 1
+	}
+	d.excess = skip - float64(d.period)
+	d.phase = 0
+}
+
+func (d *Demodulator) Write(samples []complex64) error {
+	for _, c := range samples {
+		for _, t := range d.mark {
+			t.add(c, d.ring)
+		}
+		for _, t := range d.space {
+			t.add(c, d.ring)
+		}
+		d.ring = (d.ring + 1) % d.window
+		d.num_samples++
+		if d.num_samples < int64(d.window) {
+			continue
+		}
+
+		// A period may be one sample longer than the window, in
+		// which case the last sample doesn't start a bit.
+		if d.phase < d.window {
+			bit := maxPower(d.mark) > maxPower(d.space)
+			if frame := d.streams[d.phase].next(bit); frame != nil {
+				d.decoded(frame)
+			}
+		}
+		d.phase++
+		if d.phase == d.period {
+			d.nextPeriod()
+		}
+	}
+	return nil
+}
+
+func (d *Demodulator) Close() error {
+	return nil
+}
+
+func (d *Demodulator) decoded(frame []byte) {
+	max_age := int64(kDuplicateFrameBits * d.samples_per_bit)
+	for k, n := range d.recent {
+		if d.num_samples-n > max_age {
+			delete(d.recent, k)
+		}
+	}
+	key := string(frame)
+	_, duplicate := d.recent[key]
+	d.recent[key] = d.num_samples
+	if !duplicate {
+		d.emit(frame)
+	}
+}
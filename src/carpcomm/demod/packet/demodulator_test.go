@@ -0,0 +1,111 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package packet
+
+import "carpcomm/demod/doppler"
+import "carpcomm/demod/dsp"
+import "carpcomm/pb"
+import "carpcomm/util/binary"
+import "bytes"
+import "math"
+import "math/cmplx"
+import "testing"
+
+// Writes the samples to the demodulator in small blocks and returns the
+// frames.
+func demodulate(d *Demodulator, frames *[][]byte,
+	samples []complex64) [][]byte {
+	for len(samples) > 0 {
+		n := 1000
+		if n > len(samples) {
+			n = len(samples)
+		}
+		d.Write(samples[:n])
+		samples = samples[n:]
+	}
+	return *frames
+}
+
+func expectFrames(t *testing.T, name string, frames [][]byte,
+	expected ...string) {
+	if len(frames) != len(expected) {
+		t.Errorf("%s: %d frames decoded, expected %d",
+			name, len(frames), len(expected))
+		return
+	}
+	for i := range frames {
+		if string(frames[i]) != expected[i] {
+			t.Errorf("%s: frame %d is '%s', expected '%s'",
+				name, i, string(frames[i]), expected[i])
+		}
+	}
+}
+
+func TestAFSK1200Demodulator(t *testing.T) {
+	payload := "Hello world!"
+	rate := 48000.0
+	samples := ModulateAFSK1200IQ(EncodeHDLC([]byte(payload)), rate)
+
+	var frames [][]byte
+	emit := func(f []byte) { frames = append(frames, f) }
+	d := NewAFSK1200Demodulator(rate, emit)
+	expectFrames(t, "AFSK1200", demodulate(d, &frames, samples), payload)
+
+	// Some receivers swap I and Q.
+	for i, c := range samples {
+		samples[i] = complex(imag(c), real(c))
+	}
+	frames = nil
+	d = NewAFSK1200Demodulator(rate, emit)
+	expectFrames(t, "AFSK1200 swapped", demodulate(d, &frames, samples),
+		payload)
+}
+
+func TestG3RUHDemodulator(t *testing.T) {
+	rate := 96000.0
+	carrier := 20000.0
+	bits := append(EncodeHDLC([]byte("beacon")), make([]bool, 40)...)
+	bits = append(bits, EncodeHDLC([]byte("beacon"))...)
+	samples := ModulateG3RUHIQ(bits, rate, carrier)
+
+	var frames [][]byte
+	d := NewG3RUHDemodulator(rate, carrier, func(f []byte) {
+		frames = append(frames, f)
+	})
+	// Repeated frames must not be mistaken for duplicates.
+	expectFrames(t, "G3RUH", demodulate(d, &frames, samples),
+		"beacon", "beacon")
+}
+
+func TestDecoder(t *testing.T) {
+	rate := 250000.0
+	samples := ModulateAFSK1200IQ(EncodeHDLC([]byte("packet")), rate)
+	// The signal is offset by 2500 Hz.
+	var buf bytes.Buffer
+	for i, c := range samples {
+		shift := cmplx.Exp(complex(0, 2*math.Pi*0.01*float64(i)))
+		binary.WriteComplex64LE(&buf, c*complex64(shift))
+	}
+
+	c := pb.Channel{
+		Modulation: pb.Channel_LSB_BFSK.Enum(),
+		Baud: new(float64),
+		DopplerStrategy: pb.Channel_HRBE_LINEAR.Enum(),
+	}
+	*c.Baud = 1200
+	if !CanDecode(c) {
+		t.Fatalf("CanDecode is false")
+	}
+	corrections := []doppler.Correction{{SampleNum: 0, DeltaFrac: 0.01}}
+	d := NewDecoder(c, rate, corrections)
+	err := dsp.ReadIQ(&buf, pb.IQParams_FLOAT32, d)
+	if err != nil {
+		t.Fatalf("ReadIQ: %s", err.Error())
+	}
+	blobs := d.Blobs()
+	if len(blobs) != 1 || string(blobs[0].InlineData) != "packet" ||
+		blobs[0].GetFormat() != pb.Contact_Blob_FRAME {
+		t.Errorf("Wrong blobs: %v", blobs)
+	}
+}
@@ -1,7 +1,7 @@
 // Author: Timothy Stranex <tstranex@carpcomm.com>
 // Copyright 2013 Timothy Stranex
 
-package main
+package packet
 
 const HDLC_FLAG = 0x7e
 
@@ -134,35 +134,53 @@ func decodeHDLCBitFrame(bits []bool) []byte {
 	return payload
 }
 
+// Frames longer than this are discarded so that noise can't use up memory.
+// AX.25 frames have at most 256 bytes of information.
+const kMaxHDLCFrameBits = 400 * 8
+
+// Decodes HDLC frames from a bit stream one bit at a time.
+type hdlcDecoder struct {
+	stream int
+	unstuffed []bool
+	num_ones int
+}
+
+// Returns the frame that ends with the bit or nil.
+func (d *hdlcDecoder) next(b bool) []byte {
+	d.stream = (d.stream << 1) & 0xff
+	if b {
+		d.stream = d.stream | 1
+	}
+
+	if d.num_ones != 5 && len(d.unstuffed) < kMaxHDLCFrameBits {
+		d.unstuffed = append(d.unstuffed, b)
+	}
+	if b {
+		d.num_ones++
+	} else {
+		d.num_ones = 0
+	}
+
+	if d.stream != HDLC_FLAG {
+		return nil
+	}
+	var packet []byte
+	if len(d.unstuffed) < kMaxHDLCFrameBits {
+		packet = decodeHDLCBitFrame(d.unstuffed)
+	}
+	d.unstuffed = d.unstuffed[:0]
+	return packet
+}
+
 func DecodeHDLC(bits []bool) (r [][]byte) {
 	r = make([][]byte, 0)
 
-	stream := 0
-	unstuffed := make([]bool, 0)
-	num_ones := 0
+	var d hdlcDecoder
 	for _, b := range bits {
-		stream = (stream << 1) & 0xff
-		if b {
-			stream = stream | 1
-		}
-
-		if num_ones != 5 {
-			unstuffed = append(unstuffed, b)
-		}
-		if b {
-			num_ones++
-		} else {
-			num_ones = 0
-		}
-
-		if stream == HDLC_FLAG {
-			packet := decodeHDLCBitFrame(unstuffed)
-			if packet != nil {
-				r = append(r, packet)
-			}
-			unstuffed = make([]bool, 0)
+		if packet := d.next(b); packet != nil {
+			r = append(r, packet)
 		}
 	}
 
 	return r
-}
\ No newline at end of file
+}
@@ -0,0 +1,56 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package packet
+
+import "math"
+import "math/cmplx"
+
+func modulateFSKComplex(bits []bool, sample_rate float64,
+	zero_hz, one_hz, baud float64) []complex64 {
+	const π = math.Pi
+	Δt := 1.0 / sample_rate
+	Δφ_one :=  2 * π * one_hz * Δt
+	Δφ_zero :=  2 * π * zero_hz * Δt
+	samples_per_bit := sample_rate / baud
+
+	samples := make([]complex64, 0)
+	φ := 0.0
+	num_samples := 0.0
+	for _, b := range bits {
+		num_samples += samples_per_bit
+		for ; num_samples > 0.5; num_samples -= 1.0 {
+			if b {
+				φ += Δφ_one
+			} else {
+				φ += Δφ_zero
+			}
+			samples = append(samples,
+				complex64(cmplx.Exp(complex(0, φ))))
+		}
+	}
+	return samples
+}
+
+// Returns the IQ signal of a 1200 baud Bell 202 FSK signal transmitted on
+// LSB as seen after Doppler correction.
+func ModulateAFSK1200IQ(bits []bool, sample_rate float64) []complex64 {
+	// Start and end with a burst to aid tuning.
+	bits = append(append(make([]bool, 100), bits...), make([]bool, 100)...)
+	NRZIEncode(bits)
+	return modulateFSKComplex(bits, sample_rate,
+		kAFSK1200MarkHz, kAFSK1200MarkHz-kAFSK1200ShiftHz, 1200)
+}
+
+// G3RUH 9600-baud modulator.
+// This is based on the information here:
+// http://www.amsat.org/amsat/articles/g3ruh/109.html
+func ModulateG3RUHIQ(bits []bool, sample_rate, carrier float64) []complex64 {
+	const N = 100
+	bits = append(append(make([]bool, N), bits...), make([]bool, N)...)
+	NRZIEncode(bits)
+	G3RUHScramble(bits)
+	markHz := carrier + kG3RUHDeviationHz
+	spaceHz := carrier - kG3RUHDeviationHz
+	return modulateFSKComplex(bits, sample_rate, markHz, spaceHz, 9600)
+}
@@ -2,128 +2,87 @@ package packet
 
 import "carpcomm/pb"
 import "carpcomm/demod/doppler"
-import "bufio"
-import "strings"
+import "carpcomm/demod/dsp"
 import "encoding/hex"
-import "fmt"
 import "log"
-import "os/exec"
-import "os"
 
-const dopplerAnalysisPath = "src/carpcomm/demod/packet/doppler_analysis.py"
-const afsk1200LSBPath = "src/carpcomm/demod/packet/afsk1200_lsb.py"
-const nbfm9600Path = "src/carpcomm/demod/packet/nbfm9600.py"
-const multimonPath = "bin/multimon"
+// The demodulators work at about this many samples per bit. The IQ data is
+// filtered and decimated to this rate first since recordings are usually at
+// a much higher rate.
+const kSamplesPerBit = 8
 
-// FIXME: add format param
-func DecodePackets(path string,
-	sample_rate_hz float64,
-	sample_type pb.IQParams_Type,
-	c pb.Channel) (
-	blobs []pb.Contact_Blob, err error) {
+// Number of filter taps per decimated sample.
+const kTapsPerDecimation = 8
 
-	if c.DopplerStrategy == nil {
-		return
-	}
-	doppler_strategy := c.DopplerStrategy.String()
+type demodulatorFunc func(
+	sample_rate float64, emit func(frame []byte)) *Demodulator
 
+// Return the demodulator for the channel or nil if the channel can't be
+// decoded.
+func demodulatorFor(c pb.Channel) demodulatorFunc {
 	if c.Modulation == nil || c.Baud == nil {
-		return
+		return nil
 	}
-	var demod_script string
-	var multimon_type string
 	if *c.Modulation == pb.Channel_LSB_BFSK &&
 		*c.Baud == 1200 {
-		demod_script = afsk1200LSBPath
-		multimon_type = "AFSK1200"
+		return NewAFSK1200Demodulator
 	} else if *c.Modulation == pb.Channel_FM_GMSK &&
 		*c.Baud == 9600 {
-		demod_script = nbfm9600Path
-		multimon_type = "FSK9600"
-	} else {
-		return nil, nil
+		// The carrier is at the centre after Doppler correction.
+		return func(sample_rate float64,
+			emit func(frame []byte)) *Demodulator {
+			return NewG3RUHDemodulator(sample_rate, 0, emit)
+		}
 	}
+	return nil
+}
 
-	log.Printf("Running doppler analysis")
-	doppler_path := fmt.Sprintf("%s_doppler", path)
-	c_analysis := exec.Command("python", dopplerAnalysisPath,
-		path, sample_type.String(), doppler_path, doppler_strategy)
-	err = c_analysis.Run()
-	if err != nil {
-		log.Printf("Error running %s: %s",
-			dopplerAnalysisPath, err.Error())
-		return nil, err
-	}
+// CanDecode returns whether NewDecoder supports the channel.
+func CanDecode(c pb.Channel) bool {
+	return c.DopplerStrategy != nil && demodulatorFor(c) != nil
+}
 
-	log.Printf("Running doppler correction")
-	corrected_path := fmt.Sprintf("%s_corrected", path)
-	err = doppler.ApplyDopplerCorrections(
-		path, sample_type, doppler_path, corrected_path)
-	if err != nil {
-		log.Printf(
-			"Error applying doppler corrections: %s", err.Error())
-		return nil, err
-	}
+// A Decoder decodes the frames of a channel from the IQ data written to it.
+type Decoder struct {
+	dsp.Sink
+	blobs []pb.Contact_Blob
+}
 
-	log.Printf("Running demodulation")
-	wav_path := fmt.Sprintf("%s.wav", path)
-	c_demod := exec.Command("python", demod_script,
-		corrected_path, fmt.Sprintf("%f", sample_rate_hz), wav_path)
-	err = c_demod.Run()
-	if err != nil {
-		log.Printf("Error running %s: %s", demod_script, err.Error())
-		return nil, err
+// NewDecoder returns nil if the channel can't be decoded. The corrections
+// come from a doppler.Analyzer for the channel's Doppler strategy.
+func NewDecoder(c pb.Channel, sample_rate_hz float64,
+	corrections []doppler.Correction) *Decoder {
+	if !CanDecode(c) {
+		return nil
 	}
+	d := &Decoder{}
 
-	log.Printf("Running multimon")
-	c_decode := exec.Command(multimonPath,
-		"-a", multimon_type,
-		"-t", "wav",
-		wav_path)
-	out_pipe, err := c_decode.StdoutPipe()
-	if err != nil {
-		return nil, err
+	decimation := int(sample_rate_hz / (kSamplesPerBit * *c.Baud))
+	if decimation < 1 {
+		decimation = 1
 	}
-	if err := c_decode.Start(); err != nil {
-		return nil, err
-	}
-
-	r := bufio.NewReader(out_pipe)
-	for {
-		line, err := r.ReadString('\n')
-		if err != nil {
-			break
-		}
+	rate := sample_rate_hz / float64(decimation)
+	demodulator := demodulatorFor(c)(rate, d.addFrame)
 
-		const packetPrefix = "HEXPACKET: "
-		if !strings.HasPrefix(line, packetPrefix) {
-			continue
-		}
-		log.Printf("%s", line)
-
-		line = line[len(packetPrefix):len(line)-1]
-		frame, err := hex.DecodeString(line)
-		if err != nil {
-			log.Printf("Error decoding hex packet: %s", err.Error())
-		}
-		if len(frame) > 0 {
-			var blob pb.Contact_Blob
-			blob.Format = pb.Contact_Blob_FRAME.Enum()
-			blob.InlineData = frame
-			blobs = append(blobs, blob)
-		}
+	var sink dsp.Sink = demodulator
+	if decimation > 1 {
+		taps := dsp.LowPassTaps(0.4/float64(decimation),
+			kTapsPerDecimation*decimation+1)
+		sink = dsp.NewFIRDecimator(taps, decimation, sink)
 	}
-	out_pipe.Close()
-	c_decode.Wait()
-
-	log.Printf("Decoded %d packets.", len(blobs))
+	d.Sink = doppler.NewCorrector(corrections, sink)
+	return d
+}
 
-	// Delete temporary files.
-	err = os.Remove(corrected_path)
-	if err != nil {
-		log.Printf("Error deleting file: %s", err.Error())
-		return blobs, err
-	}
+func (d *Decoder) addFrame(frame []byte) {
+	log.Printf("Frame: %s", hex.EncodeToString(frame))
+	var blob pb.Contact_Blob
+	blob.Format = pb.Contact_Blob_FRAME.Enum()
+	blob.InlineData = frame
+	d.blobs = append(d.blobs, blob)
+}
 
-	return blobs, nil
-}
\ No newline at end of file
+// Blobs returns the frames decoded so far.
+func (d *Decoder) Blobs() []pb.Contact_Blob {
+	return d.blobs
+}
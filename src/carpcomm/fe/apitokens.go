@@ -0,0 +1,200 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package main
+
+import "carpcomm/db"
+import "carpcomm/pb"
+import "net/http"
+import "log"
+import "strconv"
+import "strings"
+import "time"
+
+// The scopes offered on the user page.
+var apiTokenScopes = []pb.APIToken_Scope{
+	pb.APIToken_POST_PACKETS,
+	pb.APIToken_READ_PACKETS,
+	pb.APIToken_READ_IQ,
+}
+
+type apiTokenView struct {
+	Id string
+	Name string
+	StationName string
+	Scopes string
+	Satellites string
+	Created string
+	Expires string
+	Expired bool
+}
+
+func formatTokenTime(t int64) string {
+	return time.Unix(t, 0).UTC().Format(ContactTimeFormat)
+}
+
+func fillAPITokenView(t *pb.APIToken, stations []*pb.Station,
+	now time.Time) (v apiTokenView) {
+	v.Id = t.GetId()
+	v.Name = t.GetName()
+	v.StationName = t.GetStationId()
+	for _, s := range stations {
+		if s.GetId() == t.GetStationId() {
+			v.StationName = s.GetName()
+		}
+	}
+	scopes := make([]string, len(t.Scope))
+	for i, s := range t.Scope {
+		scopes[i] = s.String()
+	}
+	v.Scopes = strings.Join(scopes, ", ")
+	v.Satellites = "all"
+	if len(t.SatelliteId) > 0 {
+		v.Satellites = strings.Join(t.SatelliteId, ", ")
+	}
+	v.Created = formatTokenTime(t.GetCreated())
+	v.Expires = "never"
+	if t.Expires != nil {
+		v.Expires = formatTokenTime(t.GetExpires())
+		v.Expired = now.Unix() >= t.GetExpires()
+	}
+	return v
+}
+
+// Parse the token creation form. Returns an error message for the user if
+// it's invalid.
+func parseAPITokenForm(r *http.Request, stations []*pb.Station) (
+	station_id string, scopes []pb.APIToken_Scope,
+	satellite_ids []string, expires time.Time, message string) {
+	station_id = r.Form.Get("station_id")
+	found := false
+	for _, s := range stations {
+		if s.GetId() == station_id {
+			found = true
+		}
+	}
+	if !found {
+		return "", nil, nil, expires, "Unknown station."
+	}
+
+	for _, name := range r.Form["scope"] {
+		v, ok := pb.APIToken_Scope_value[name]
+		if !ok {
+			return "", nil, nil, expires, "Unknown scope."
+		}
+		scopes = append(scopes, pb.APIToken_Scope(v))
+	}
+	if len(scopes) == 0 {
+		return "", nil, nil, expires, "Select at least one scope."
+	}
+
+	for _, id := range strings.Split(r.Form.Get("satellites"), ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if db.GlobalSatelliteDB().Map[id] == nil {
+			return "", nil, nil, expires,
+				"Unknown satellite: " + id
+		}
+		satellite_ids = append(satellite_ids, id)
+	}
+
+	if d := r.Form.Get("expires_days"); d != "" {
+		days, err := strconv.Atoi(d)
+		if err != nil || days < 0 {
+			return "", nil, nil, expires, "Invalid expiry."
+		}
+		if days > 0 {
+			expires = time.Now().Add(
+				time.Duration(days) * 24 * time.Hour)
+		}
+	}
+	return station_id, scopes, satellite_ids, expires, ""
+}
+
+func createAPITokenHandler(
+	cdb *db.ContactDB, userdb *db.UserDB, stationdb *db.StationDB,
+	tokendb *db.APITokenDB,
+	w http.ResponseWriter, r *http.Request, user userView) {
+	if r.Method != "POST" {
+		http.Error(w, "", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	u, err := userdb.Lookup(user.Id)
+	if err != nil || u == nil {
+		log.Printf("Error looking up user: %v", err)
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	stations, err := stationdb.UserStations(user.Id)
+	if err != nil {
+		log.Printf("Error getting user stations: %s", err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	station_id, scopes, satellite_ids, expires, message :=
+		parseAPITokenForm(r, stations)
+	if message != "" {
+		http.Error(w, message, http.StatusBadRequest)
+		return
+	}
+
+	t, token, err := db.NewAPIToken(user.Id, station_id,
+		r.Form.Get("name"), scopes, satellite_ids, time.Now(), expires)
+	if err != nil {
+		log.Printf("Error creating API token: %s", err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	if err := tokendb.Store(t); err != nil {
+		log.Printf("Error storing API token: %s", err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Created API token %s for station %s", t.GetId(),
+		station_id)
+
+	// Only the hash is stored so the token is only shown now.
+	renderUserProfile(cdb, stationdb, tokendb, w, r, user, u, token)
+}
+
+func revokeAPITokenHandler(tokendb *db.APITokenDB,
+	w http.ResponseWriter, r *http.Request, user userView) {
+	if r.Method != "POST" {
+		http.Error(w, "", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "'id' param missing", http.StatusBadRequest)
+		return
+	}
+
+	t, err := tokendb.Lookup(id)
+	if err != nil {
+		log.Printf("Error looking up API token: %s", err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	if t == nil || t.GetUserid() != user.Id {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := tokendb.Revoke(t, time.Now()); err != nil {
+		log.Printf("Error revoking API token: %s", err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Revoked API token %s", id)
+
+	http.Redirect(w, r, userURLPrefix+user.Id, http.StatusFound)
+}
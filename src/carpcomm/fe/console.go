@@ -9,12 +9,16 @@ import (
 	"carpcomm/pb"
 	"carpcomm/scheduler"
 	"carpcomm/streamer/contacts"
+	"encoding/json"
 	"html/template"
+	"io"
 	"log"
 	"net/http"
 	"net/rpc"
 	"net/url"
 	"sort"
+	"strings"
+	"time"
 )
 
 
@@ -111,9 +115,10 @@ func consolePageHandler(sdb *db.StationDB, contactdb *db.ContactDB,
 }
 
 func callStation(m *rpc.Client, w http.ResponseWriter,
-	station_id string, action string, params url.Values) {
+	station_id, lease_token string, action string, params url.Values) {
 	var args mux.StationCallArgs
 	args.StationId = station_id
+	args.LeaseToken = lease_token
 	u := url.URL{}
 	u.Path = "/" + action
 	if params != nil {
@@ -132,6 +137,74 @@ func callStation(m *rpc.Client, w http.ResponseWriter,
 	w.Write(result.Data)
 }
 
+// Like callStation but the response is passed on to the browser as it
+// arrives instead of being buffered by the mux and by us.
+func streamStation(w http.ResponseWriter,
+	station_id, lease_token string, action string, params url.Values) {
+	resp, err := mux.StreamStation(
+		*mux_address, station_id, lease_token, action, params)
+	if err != nil {
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, h := range []string{"Content-Type", "Content-Length"} {
+		if v := resp.Header.Get(h); v != "" {
+			w.Header().Set(h, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		log.Printf("Error streaming station response: %s", err.Error())
+	}
+}
+
+// How long the console keeps control of a station after the last action.
+const consoleLeaseDuration = 10 * time.Minute
+
+func consoleLeaseHolder(userid string) string {
+	return "console:" + userid
+}
+
+type leaseView struct {
+	Held bool
+	Holder string
+	Description string
+	Expires string
+	PreemptionRequested bool
+	IsYours bool
+}
+
+func writeLeaseJSON(w http.ResponseWriter,
+	lease mux.Lease, held bool, holder string, err error) {
+	if err != nil {
+		log.Printf("Lease error: %s", err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	var v leaseView
+	if held {
+		v.Held = true
+		v.Holder = lease.Holder
+		if strings.HasPrefix(lease.Holder, "console:") {
+			// Don't reveal user ids.
+			v.Holder = "console"
+		}
+		v.Description = lease.Description
+		v.Expires = lease.Expires.UTC().Format(TimeFormat)
+		v.PreemptionRequested = lease.PreemptionRequestedBy != ""
+		v.IsYours = lease.Holder == holder
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Error json marshalling lease: %s", err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	w.Write(data)
+}
+
 func canOperateStation(sdb *db.StationDB, station_id, userid string) (
 	bool, error) {
 	owner, err := sdb.GetStationUserId(station_id)
@@ -164,7 +237,51 @@ func consoleJSONHandler(sdb *db.StationDB, contactdb *db.ContactDB,
 		return
 	}
 
-	// FIXME: authentication and scheduling/locking
+	holder := consoleLeaseHolder(user.Id)
+	switch action {
+	case "LeaseStatus":
+		lease, held, err := mux.StationLeaseStatus(m, id)
+		writeLeaseJSON(w, lease, held, holder, err)
+		return
+	case "RequestPreemption":
+		lease, held, err := mux.StationRequestPreemption(m, id, holder)
+		writeLeaseJSON(w, lease, held, holder, err)
+		return
+	case "ReleaseLease":
+		// Acquiring a lease we already hold gives us its token.
+		lease, granted, err := mux.StationAcquireLease(
+			m, id, holder, "", consoleLeaseDuration)
+		if err == nil && granted {
+			err = mux.StationReleaseLease(m, id, lease.Token)
+		}
+		if err != nil {
+			log.Printf("ReleaseLease error: %s", err.Error())
+			http.Error(w, "", http.StatusInternalServerError)
+			return
+		}
+		lease, held, err := mux.StationLeaseStatus(m, id)
+		writeLeaseJSON(w, lease, held, holder, err)
+		return
+	}
+
+	// Control actions need the lease which we renew every time so that
+	// it only expires once the operator stops using the console.
+	var token string
+	if mux.IsControlAction(action) {
+		lease, granted, err := mux.StationAcquireLease(
+			m, id, holder, "Console", consoleLeaseDuration)
+		if err != nil {
+			log.Printf("StationAcquireLease error: %s", err.Error())
+			http.Error(w, "", http.StatusInternalServerError)
+			return
+		}
+		if !granted {
+			w.WriteHeader(http.StatusConflict)
+			writeLeaseJSON(w, lease, true, holder, nil)
+			return
+		}
+		token = lease.Token
+	}
 
 	satellite_id := query.Get("satellite_id")
 	if satellite_id != "" && 
@@ -177,7 +294,7 @@ func consoleJSONHandler(sdb *db.StationDB, contactdb *db.ContactDB,
 
 	switch action {
 	case "ReceiverGetState":
-		callStation(m, w, id, action, nil)
+		callStation(m, w, id, token, action, nil)
 	case "ReceiverStart":
 		log.Printf("ReceiverStart: satellite_id=%s", satellite_id)
 		contact_id, err := contacts.StartNewConsoleContact(
@@ -189,11 +306,11 @@ func consoleJSONHandler(sdb *db.StationDB, contactdb *db.ContactDB,
 		}
 		v := url.Values{}
 		v.Add("stream_url", scheduler.GetStreamURL(contact_id))
-		callStation(m, w, id, action, v)
+		callStation(m, w, id, token, action, v)
 	case "ReceiverStop":
-		callStation(m, w, id, action, nil)
+		callStation(m, w, id, token, action, nil)
 	case "ReceiverWaterfallPNG":
-		callStation(m, w, id, action, nil)
+		streamStation(w, id, token, action, nil)
 	case "ReceiverSetFrequency":
 		hz := query.Get("hz")
 		if hz == "" {
@@ -203,7 +320,17 @@ func consoleJSONHandler(sdb *db.StationDB, contactdb *db.ContactDB,
 		}
 		v := url.Values{}
 		v.Add("hz", hz)
-		callStation(m, w, id, action, v)
+		callStation(m, w, id, token, action, v)
+	case "ReceiverFrequencyProgram":
+		program := query.Get("program")
+		if program == "" {
+			http.Error(w, "'program' param missing",
+				http.StatusBadRequest)
+			return
+		}
+		v := url.Values{}
+		v.Add("program", program)
+		callStation(m, w, id, token, action, v)
 	case "TNCStart":
 		log.Printf("TNCStart: satellite_id=%s", satellite_id)
 
@@ -217,13 +344,13 @@ func consoleJSONHandler(sdb *db.StationDB, contactdb *db.ContactDB,
 		v.Add("api_host", host)
 		v.Add("api_port", port)
 		v.Add("satellite_id", satellite_id)
-		callStation(m, w, id, action, v)
+		callStation(m, w, id, token, action, v)
 	case "TNCStop":
-		callStation(m, w, id, action, nil)
+		callStation(m, w, id, token, action, nil)
 	case "TNCGetLatestFrames":
-		callStation(m, w, id, action, nil)
+		callStation(m, w, id, token, action, nil)
 	case "MotorGetState":
-		callStation(m, w, id, action, nil)
+		callStation(m, w, id, token, action, nil)
 	case "MotorStart":
 		program := query.Get("program")
 		if program == "" {
@@ -233,9 +360,9 @@ func consoleJSONHandler(sdb *db.StationDB, contactdb *db.ContactDB,
 		}
 		v := url.Values{}
 		v.Add("program", program)
-		callStation(m, w, id, action, v)
+		callStation(m, w, id, token, action, v)
 	case "MotorStop":
-		callStation(m, w, id, action, nil)
+		callStation(m, w, id, token, action, nil)
 	default:
 		http.NotFound(w, r)
 	}
@@ -6,7 +6,7 @@ package main
 import (
 	"carpcomm/db"
 	"carpcomm/pb"
-	"carpcomm/scheduler"
+	"carpcomm/streamer/downloads"
 	"log"
 	"net/http"
 	"html/template"
@@ -148,23 +148,75 @@ func stationContactsHandler(
 	}
 }
 
-func contactIQHandler(w http.ResponseWriter, r *http.Request, user userView) {
-	id := r.URL.Query().Get("id")
-	if id == "" {
-		http.Error(w, "'id' param missing", http.StatusBadRequest)
-		return
+// canDownloadContact returns whether the user may download the contact's IQ
+// data and spectrogram. That's the case if the user made the contact, owns
+// the station that received it or owns a station authorized for the
+// satellite.
+func canDownloadContact(sdb *db.StationDB, c *pb.Contact, userid string) (
+	bool, error) {
+	if c.GetUserId() == userid {
+		return true, nil
+	}
+
+	station_ids := []string{}
+	if c.StationId != nil {
+		station_ids = append(station_ids, c.GetStationId())
+	}
+	sat := db.GlobalSatelliteDB().Map[c.GetSatelliteId()]
+	if sat != nil {
+		station_ids = append(station_ids, sat.AuthorizedStationId...)
 	}
-	http.Redirect(w, r, scheduler.GetStreamURL(id), http.StatusFound)
+
+	for _, id := range station_ids {
+		owner, err := sdb.GetStationUserId(id)
+		if err != nil {
+			return false, err
+		}
+		if owner == userid {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
-func contactSpectrogramHandler(
-	w http.ResponseWriter, r *http.Request, user userView) {
+// Redirect to a signed streamer URL for one of the contact's artifacts.
+func contactDownloadHandler(cdb *db.ContactDB, sdb *db.StationDB,
+	artifact string, w http.ResponseWriter, r *http.Request, user userView) {
 	id := r.URL.Query().Get("id")
 	if id == "" {
 		http.Error(w, "'id' param missing", http.StatusBadRequest)
 		return
 	}
-	url := scheduler.GetStreamURL(id) + ".png"
+
+	c, err := cdb.Lookup(id)
+	if err != nil {
+		log.Printf("Contact DB lookup error: %s", err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	if c == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	ok, err := canDownloadContact(sdb, c, user.Id)
+	if err != nil {
+		log.Printf("Error checking download authorization: %s",
+			err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	url, err := downloads.URL(id, artifact)
+	if err != nil {
+		log.Printf("Error signing download URL: %s", err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
 	http.Redirect(w, r, url, http.StatusFound)
 }
 
@@ -175,8 +227,14 @@ func AddContactsHttpHandlers(
 		func(w http.ResponseWriter, r *http.Request, user userView) {
 		stationContactsHandler(contactdb, stationdb, w, r, user)
 	})
-	HandleFuncLoginOptional(httpmux, "/contact/iq", s,
-		contactIQHandler)
-	HandleFuncLoginOptional(httpmux, "/contact/spectrogram", s,
-		contactSpectrogramHandler)
+	HandleFuncLoginRequired(httpmux, "/contact/iq", s,
+		func(w http.ResponseWriter, r *http.Request, user userView) {
+		contactDownloadHandler(contactdb, stationdb, downloads.IQ,
+			w, r, user)
+	})
+	HandleFuncLoginRequired(httpmux, "/contact/spectrogram", s,
+		func(w http.ResponseWriter, r *http.Request, user userView) {
+		contactDownloadHandler(contactdb, stationdb,
+			downloads.Spectrogram, w, r, user)
+	})
 }
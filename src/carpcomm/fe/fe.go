@@ -44,6 +44,9 @@ func main() {
 	stationdb := domain.NewStationDB()
 	contactdb := domain.NewContactDB()
 	commentdb := domain.NewCommentDB()
+	tokendb := domain.NewAPITokenDB()
+
+	go db.RefreshTLEsForever()
 
 	s := NewSessions()
 
@@ -60,7 +63,7 @@ func main() {
 	AddCommentsHttpHandlers(http.DefaultServeMux, s, commentdb)
 	AddContactsHttpHandlers(http.DefaultServeMux, s, contactdb, stationdb)
 	AddUserHttpHandlers(http.DefaultServeMux, s,
-		stationdb, userdb, contactdb)
+		stationdb, userdb, contactdb, tokendb)
 
 	log.Printf("fe started.")
 
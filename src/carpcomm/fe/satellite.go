@@ -346,6 +346,7 @@ func satellitePostContactHandler(
 		frame,
 		user.Id,
 		"",
+		"",
 		station)
 
 	if poperr != nil {
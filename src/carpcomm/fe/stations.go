@@ -8,6 +8,7 @@ import (
 	"carpcomm/mux"
 	"carpcomm/pb"
 	"carpcomm/scheduler"
+	"carpcomm/scheduler/events"
 	"carpcomm/util/timestamp"
 	"fmt"
 	"html/template"
@@ -52,9 +53,37 @@ func stationKMLHandler(sdb *db.StationDB,
 	}
 }
 
+type stationSecretView struct {
+	Id, Name string
+	Secret string
+	// Whether the previous secrets still work for a while.
+	Rotated bool
+}
+
+var stationSecretTemplate = NewDebuggableTemplate(
+	nil,
+	"station_secret.html",
+	"src/carpcomm/fe/templates/station_secret.html",
+	"src/carpcomm/fe/templates/page.html")
+
+// Only the secret's hash is stored so this is the only time the owner gets to
+// see it.
+func renderStationSecret(w http.ResponseWriter, user userView,
+	s *pb.Station, secret string, rotated bool) {
+	v := stationSecretView{s.GetId(), s.GetName(), secret, rotated}
+	c := NewRenderContext(user, v)
+	err := stationSecretTemplate.Get().ExecuteTemplate(
+		w, "station_secret.html", c)
+	if err != nil {
+		log.Printf("Error rendering station secret: %s", err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+}
+
 func addStationHandler(sdb *db.StationDB,
 	w http.ResponseWriter, r *http.Request, user userView) {
-	station, err := db.NewStation(user.Id)
+	station, secret, err := db.NewStation(user.Id)
 	if err != nil {
 		log.Printf("Station DB NewStation error: %s", err.Error())
 		http.Error(w, "", http.StatusInternalServerError)
@@ -67,8 +96,54 @@ func addStationHandler(sdb *db.StationDB,
 		return
 	}
 
-	redirect := stationUrl("/station/edit", *station.Id)
-	http.Redirect(w, r, redirect, http.StatusFound)
+	renderStationSecret(w, user, station, secret, false)
+}
+
+func rotateStationSecretHandler(sdb *db.StationDB,
+	w http.ResponseWriter, r *http.Request, user userView) {
+	if r.Method != "POST" {
+		http.Error(w, "", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "'id' param missing", http.StatusBadRequest)
+		return
+	}
+
+	s, err := sdb.Lookup(id)
+	if err != nil {
+		log.Printf("Station DB lookup error: %s", err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	if s == nil || *s.Userid != user.Id {
+		http.NotFound(w, r)
+		return
+	}
+
+	secret, err := db.RotateStationSecret(s, time.Now())
+	if err != nil {
+		log.Printf("Error rotating station secret: %s", err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	if err = sdb.Store(s); err != nil {
+		log.Printf("Station DB store error: %s", err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("%s: Station secret rotated by %s", id, user.Id)
+
+	renderStationSecret(w, user, s, secret, true)
+}
+
+// Let the scheduler know so that it can reschedule the station immediately.
+func notifyStationEdited(id string) {
+	if err := events.Notify(id, events.StationEdited); err != nil {
+		log.Printf("%s: Error notifying scheduler: %s", id, err.Error())
+	}
 }
 
 func deleteStationHandler(sdb *db.StationDB,
@@ -98,6 +173,7 @@ func deleteStationHandler(sdb *db.StationDB,
 		http.Error(w, "", http.StatusInternalServerError)
 		return
 	}
+	go notifyStationEdited(id)
 
 	// Success
 	http.Redirect(w, r, "/home", http.StatusFound)
@@ -148,6 +224,14 @@ type predictionView struct {
 	StartAzimuth, EndAzimuth, MaxAltitude float64
 }
 
+type plannedPassView struct {
+	predictionView
+	Score float64
+	Scheduled bool
+	ConflictsWith predictionView
+	CoveredByStationId string
+}
+
 const TimeFormat = "02 Jan 15:04 MST"
 
 func FillPredictionView(p scheduler.Prediction, pv *predictionView) {
@@ -162,6 +246,129 @@ func FillPredictionView(p scheduler.Prediction, pv *predictionView) {
 	pv.MaxAltitude = p.MaxAltitudeDegrees
 }
 
+func fillScheduleView(s *scheduler.Schedule) []plannedPassView {
+	views := make([]plannedPassView, 0)
+	for _, p := range s.Passes {
+		var v plannedPassView
+		FillPredictionView(p.Prediction, &v.predictionView)
+		v.Score = p.Score
+		v.Scheduled = p.Scheduled
+		v.CoveredByStationId = p.CoveredByStationId
+		if p.ConflictsWith != nil {
+			FillPredictionView(*p.ConflictsWith, &v.ConflictsWith)
+		}
+		// Don't display unscheduled modes of the same satellite.
+		if !v.Scheduled && v.ConflictsWith.SatelliteId == v.SatelliteId {
+			continue
+		}
+		views = append(views, v)
+	}
+	return views
+}
+
+type captureView struct {
+	ContactId string
+	SatelliteId string
+	SatelliteName string
+	PlannedStart, ActualStart string
+	PlannedDuration, ActualDuration string
+	Status string
+	FailedRPCs []string
+	IQBytes int64
+	FramesDecoded int32
+}
+
+// Number of recent captures shown to station owners.
+const kStationCaptureLimit = 20
+
+func formatTimestamp(t int64) string {
+	return time.Unix(t, 0).UTC().Format(TimeFormat)
+}
+
+func fillCaptureView(c *pb.Contact) (v captureView) {
+	v.ContactId = *c.Id
+	v.SatelliteId = c.GetSatelliteId()
+	if sat := db.GlobalSatelliteDB().Map[v.SatelliteId]; sat != nil {
+		v.SatelliteName = RenderSatelliteName(sat.Name)
+	}
+	capture := c.Capture
+	v.PlannedStart = formatTimestamp(capture.GetPlannedStartTimestamp())
+	v.PlannedDuration = (time.Duration(
+		capture.GetPlannedEndTimestamp() -
+			capture.GetPlannedStartTimestamp()) * time.Second).String()
+	v.ActualStart = formatTimestamp(c.GetStartTimestamp())
+	if c.EndTimestamp != nil {
+		v.ActualDuration = (time.Duration(
+			*c.EndTimestamp - c.GetStartTimestamp()) *
+			time.Second).String()
+	}
+	v.Status = capture.GetStatus().String()
+	for _, f := range capture.RpcFailure {
+		v.FailedRPCs = append(v.FailedRPCs, f.GetRpc())
+	}
+	v.IQBytes = capture.GetIqBytes()
+	v.FramesDecoded = capture.GetFramesDecoded()
+	return v
+}
+
+type healthItem struct {
+	Name, Value string
+}
+
+type healthView struct {
+	ReceivedTime string
+	Fault string
+	Items []healthItem
+}
+
+func deviceState(started bool, fault string) string {
+	if fault != "" {
+		return "Fault: " + fault
+	}
+	if started {
+		return "Started"
+	}
+	return "Stopped"
+}
+
+func fillHealthView(h *pb.StationHealth) (v healthView) {
+	v.ReceivedTime = formatTimestamp(h.GetReceivedTimestamp())
+	v.Fault = mux.HealthFault(h, time.Now())
+	add := func(name, format string, a ...interface{}) {
+		v.Items = append(v.Items,
+			healthItem{name, fmt.Sprintf(format, a...)})
+	}
+
+	status := h.GetStatus()
+	if r := status.GetReceiver(); r != nil {
+		add("Receiver", "%s (%s)",
+			deviceState(r.GetStarted(), r.GetFault()), r.GetDriver())
+		add("Receiver frequency", "%.3f MHz",
+			float64(r.GetHardwareTunerHz())/1e6)
+	}
+	if m := status.GetMotor(); m != nil {
+		add("Motor", "%s (%s)",
+			deviceState(m.GetIsMoving(), m.GetFault()), m.GetDriver())
+		add("Motor position", "az %.1f°, el %.1f°",
+			m.GetAzimuthDegrees(), m.GetElevationDegrees())
+	}
+	if t := status.GetTnc(); t != nil {
+		add("TNC", "%s", deviceState(t.GetStarted(), t.GetFault()))
+	}
+	if h.LoadAverage != nil {
+		add("Load average", "%.2f", h.GetLoadAverage())
+	}
+	if h.DiskFreeBytes != nil {
+		add("Free disk space", "%.1f GB",
+			float64(h.GetDiskFreeBytes())/1e9)
+	}
+	if h.ClockOffsetS != nil {
+		add("Clock offset", "%.3f s (round trip %.3f s)",
+			h.GetClockOffsetS(), h.GetRoundTripS())
+	}
+	return v
+}
+
 func LookupUserView(userdb *db.UserDB, userid string) (v userView) {
 	v.Id = userid
 
@@ -190,6 +397,13 @@ type stationContext struct {
 	IsOnline      bool
 	CurrentTime   string
 	NextPasses    []predictionView
+	Schedule      []plannedPassView
+	// Whether Schedule is the station's part of the network plan.
+	NetworkPlanned bool
+	Captures      []captureView
+	CaptureStats  map[pb.Contact_Capture_Status]int
+	Health        *healthView
+	ClockWarning  string
 	Operator      userView
 	Contacts []*pb.Contact
 }
@@ -233,6 +447,60 @@ func GetStationContext(s *pb.Station, userid string,
 			// This is not a fatal error.
 		}
 		sc.Contacts = c
+
+		// Show the passes assigned to the station by the network
+		// plan. Stations that aren't part of it, e.g. because
+		// automatic control is disabled, are planned on their own.
+		schedule, err := scheduler.NetworkSchedule(*s.Id)
+		if err != nil {
+			log.Printf("NetworkSchedule error: %s", err.Error())
+			// This is not a fatal error.
+		}
+		sc.NetworkPlanned = schedule != nil
+		if schedule == nil {
+			schedule, err = scheduler.PlanStation(s, cdb,
+				scheduler.DefaultPlannerConfig(), time.Now())
+			if err != nil {
+				log.Printf("PlanStation error: %s", err.Error())
+				// This is not a fatal error.
+			}
+		}
+		if schedule != nil {
+			sc.Schedule = fillScheduleView(schedule)
+		}
+
+		captures, err := cdb.SearchCapturesByStationId(
+			*s.Id, kStationCaptureLimit)
+		if err != nil {
+			log.Printf("SearchCapturesByStationId error: %s",
+				err.Error())
+			// This is not a fatal error.
+		}
+		for _, c := range captures {
+			sc.Captures = append(sc.Captures, fillCaptureView(c))
+		}
+		sc.CaptureStats = db.CaptureStats(captures)
+
+		health, err := mux.StationHealth(m, *s.Id)
+		if err != nil {
+			log.Printf("StationHealth error: %s", err.Error())
+			// This is not a fatal error.
+		}
+		if health != nil {
+			v := fillHealthView(health)
+			sc.Health = &v
+		}
+
+		clock, known, err := mux.StationClock(m, *s.Id)
+		if err != nil {
+			log.Printf("StationClock error: %s", err.Error())
+			// This is not a fatal error.
+		}
+		if known && clock.Flagged {
+			sc.ClockWarning = fmt.Sprintf(
+				"The station's clock is off by %.1f seconds.",
+				clock.Best.OffsetS)
+		}
 	}
 
 	return sc
@@ -365,6 +633,7 @@ func editStationPOST(sdb *db.StationDB, s *pb.Station,
 		http.Error(w, "", http.StatusInternalServerError)
 		return
 	}
+	go notifyStationEdited(*s.Id)
 
 	redirect := stationUrl("/station", *s.Id)
 	http.Redirect(w, r, redirect, http.StatusFound)
@@ -425,6 +694,8 @@ func AddStationHttpHandlers(
 		stationHandler(stationdb, deleteStationHandler))
 	HandleFuncLoginRequired(httpmux, "/station/edit", s,
 		stationHandler(stationdb, editStationHandler))
+	HandleFuncLoginRequired(httpmux, "/station/rotate_secret", s,
+		stationHandler(stationdb, rotateStationSecretHandler))
 
 	HandleFuncLoginOptional(httpmux, "/station", s,
 		func(w http.ResponseWriter, r *http.Request, user userView) {
@@ -10,6 +10,7 @@ import "net/http"
 import "log"
 import "html/template"
 import "strings"
+import "time"
 
 const userURLPrefix = "/user/"
 
@@ -19,6 +20,13 @@ type profileView struct {
 	IsOwner bool
 	HeardSatellites []*pb.Satellite
 	Stations []*pb.Station
+
+	// Only filled in for the owner.
+	OwnStations []*pb.Station
+	APITokens []apiTokenView
+	APITokenScopes []pb.APIToken_Scope
+	// Set right after a token was created.
+	NewAPIToken string
 }
 
 var userViewTemplate = NewDebuggableTemplate(
@@ -31,8 +39,9 @@ var userViewTemplate = NewDebuggableTemplate(
 	"src/carpcomm/fe/templates/page.html")
 
 func renderUserProfile(
-	cdb *db.ContactDB, stationdb *db.StationDB,
-	w http.ResponseWriter, r *http.Request, user userView, u *pb.User) {
+	cdb *db.ContactDB, stationdb *db.StationDB, tokendb *db.APITokenDB,
+	w http.ResponseWriter, r *http.Request, user userView, u *pb.User,
+	new_api_token string) {
 	// TODO: It would be better if we could restrict to contacts which
 	// have telemetry.
 	contacts, err := cdb.SearchByUserId(*u.Id, 100)
@@ -75,6 +84,25 @@ func renderUserProfile(
 		}
 	}
 
+	if pv.IsOwner {
+		pv.OwnStations = stations
+		pv.APITokenScopes = apiTokenScopes
+		pv.NewAPIToken = new_api_token
+		tokens, err := tokendb.UserTokens(*u.Id)
+		if err != nil {
+			log.Printf("Error getting API tokens: %s", err.Error())
+			// Continue rendering since it's not a critial error.
+		}
+		now := time.Now()
+		for _, t := range tokens {
+			if t.Revoked != nil {
+				continue
+			}
+			pv.APITokens = append(pv.APITokens,
+				fillAPITokenView(t, stations, now))
+		}
+	}
+
 	c := NewRenderContext(user, pv)
 	err = userViewTemplate.Get().ExecuteTemplate(w, "user.html", c)
 	if err != nil {
@@ -121,7 +149,7 @@ func changeUserProfile(userdb *db.UserDB,
 
 func userViewHandler(
 	cdb *db.ContactDB, userdb *db.UserDB, stationdb *db.StationDB,
-	w http.ResponseWriter, r *http.Request, user userView) {
+	tokendb *db.APITokenDB, w http.ResponseWriter, r *http.Request, user userView) {
 
 	if len(r.URL.Path) < len(userURLPrefix) {
 		http.Error(w, "Invalid path", http.StatusBadRequest)
@@ -143,7 +171,7 @@ func userViewHandler(
 	if r.Method == "POST" && !changeUserProfile(userdb, w, r, user, u) {
 		return
 	}
-	renderUserProfile(cdb, stationdb, w, r, user, u)
+	renderUserProfile(cdb, stationdb, tokendb, w, r, user, u, "")
 }
 
 
@@ -151,9 +179,20 @@ func AddUserHttpHandlers(
 	httpmux *http.ServeMux, s *Sessions,
 	stationdb *db.StationDB,
 	userdb *db.UserDB,
-	contactdb *db.ContactDB) {
+	contactdb *db.ContactDB,
+	tokendb *db.APITokenDB) {
 	HandleFuncLoginOptional(httpmux, userURLPrefix, s,
 		func(w http.ResponseWriter, r *http.Request, user userView) {
-		userViewHandler(contactdb, userdb, stationdb, w, r, user)
+		userViewHandler(
+			contactdb, userdb, stationdb, tokendb, w, r, user)
+	})
+	HandleFuncLoginRequired(httpmux, "/api_token/create", s,
+		func(w http.ResponseWriter, r *http.Request, user userView) {
+		createAPITokenHandler(
+			contactdb, userdb, stationdb, tokendb, w, r, user)
+	})
+	HandleFuncLoginRequired(httpmux, "/api_token/revoke", s,
+		func(w http.ResponseWriter, r *http.Request, user userView) {
+		revokeAPITokenHandler(tokendb, w, r, user)
 	})
 }
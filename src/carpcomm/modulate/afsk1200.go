@@ -7,6 +7,9 @@ import "math"
 import "math/cmplx"
 import "os"
 import "log"
+import "carpcomm/demod/dsp"
+import "carpcomm/demod/packet"
+import "carpcomm/pb"
 import "carpcomm/util/binary"
 import "fmt"
 import "bufio"
@@ -17,25 +20,6 @@ const markHz = 1200
 const spaceHz = 2200
 const baud = 1200
 
-// Non-Return-to-Zero Inverted (NRZI) encoding:
-// 0 causes a state transition and 1 does not.
-func NRZIEncode(bits []bool) {
-	state := false
-	for i, b := range bits {
-		if !b {
-			state = !state
-		}
-		bits[i] = state
-	}
-}
-
-// Non-Return-to-Zero Inverted (NRZI) decoding.
-func NRZIDecode(bits []bool) {
-	for i := 0; i < len(bits)-1; i++ {
-		bits[i] = bits[i] == bits[i+1]
-	}
-}
-
 func modulateFSK(bits []bool, sample_rate float64,
 	zero_hz, one_hz, baud float64) []float64 {
 	const π = math.Pi
@@ -61,38 +45,12 @@ func modulateFSK(bits []bool, sample_rate float64,
 	return samples
 }
 
-func modulateFSKComplex(bits []bool, sample_rate float64,
-	zero_hz, one_hz, baud float64) []complex64 {
-	const π = math.Pi
-	Δt := 1.0 / sample_rate
-	Δφ_one :=  2 * π * one_hz * Δt
-	Δφ_zero :=  2 * π * zero_hz * Δt
-	samples_per_bit := sample_rate / baud
-
-	samples := make([]complex64, 0)
-	φ := 0.0
-	num_samples := 0.0
-	for _, b := range bits {
-		num_samples += samples_per_bit
-		for ; num_samples > 0.5; num_samples -= 1.0 {
-			if b {
-				φ += Δφ_one
-			} else {
-				φ += Δφ_zero
-			}
-			samples = append(samples,
-				complex64(cmplx.Exp(complex(0, φ))))
-		}
-	}
-	return samples
-}
-
 // Returns a 1200 baud Bell 202 FSK signal that encodes the bits.
 // This modulation is often used for APRS and AX.25 ametuer packet radio.
 func ModulateAFSK1200(bits []bool, sample_rate float64) []float64 {
 	// Start and end with a burst to aid tuning.
 	bits = append(append(make([]bool, 100), bits...), make([]bool, 100)...)
-	NRZIEncode(bits)
+	packet.NRZIEncode(bits)
 	return modulateFSK(bits, sample_rate, markHz, spaceHz, baud)
 }
 
@@ -153,27 +111,6 @@ func readFromFile(path string) (samples []float64) {
 	return samples
 }
 
-func readComplexFile(path string, read_sample binary.ReadSampleFunc) (
-	samples []complex64) {
-	f, err := os.Open(path)
-	if err != nil {
-		log.Fatalf("Error opening file: %s", err.Error())
-	}
-	r := bufio.NewReader(f)
-
-	samples = make([]complex64, 0)
-	for {
-		c, err := read_sample(r)
-		if err != nil {
-			break
-		}
-		samples = append(samples, c)
-	}
-	f.Close()
-
-	return samples
-}
-
 func computeTable(n int, centre_hz, Δt float64) (r []complex64) {
 	const π = math.Pi
 	ω1 := - 2 * π * centre_hz
@@ -224,28 +161,6 @@ func correlateBit(samples []float64, sample_rate,
 	return cmplx.Abs(complex128(F_mark)) > cmplx.Abs(complex128(F_space))
 }
 
-func correlateBitComplex(samples []complex64, sample_rate,
-	markHz, spaceHz float64) bool {
-	const π = math.Pi
-	Δt := 1.0 / sample_rate
-
-	// TODO(tstranex): This is not thread safe. We should create a
-	// correlator struct to store the tables.
-	if len(table_mark) != len(samples) || len(table_space) != len(samples) {
-		table_mark = computeTable(len(samples), markHz, Δt)
-		table_space = computeTable(len(samples), spaceHz, Δt)
-	}
-
-	var F_mark complex64
-	var F_space complex64
-	for i, c := range samples {
-		F_mark += c * table_mark[i]
-		F_space += c * table_space[i]
-	}
-
-	return cmplx.Abs(complex128(F_mark)) > cmplx.Abs(complex128(F_space))
-}
-
 func DemodulateAFSK1200(samples []float64, sample_rate float64) (
 	packets [][]byte) {
 	samples_per_bit := sample_rate / baud
@@ -272,8 +187,8 @@ func DemodulateAFSK1200(samples []float64, sample_rate float64) (
 	packet_set := make(map[string][]byte)
 
 	for i := 0; i < len(bits); i++ {
-		NRZIDecode(bits[i])
-		for _, p := range DecodeHDLC(bits[i]) {
+		packet.NRZIDecode(bits[i])
+		for _, p := range packet.DecodeHDLC(bits[i]) {
 			packet_set[string(p)] = p
 		}
 	}
@@ -284,90 +199,44 @@ func DemodulateAFSK1200(samples []float64, sample_rate float64) (
 	return packets
 }
 
-func DemodulateAFSK1200Complex(samples []complex64, sample_rate float64) (
-	packets [][]byte) {
-	const markHz = 0
-	const spaceHz = 1000
-	const baud = 1200
-	samples_per_bit := sample_rate / baud
-	clen := int(samples_per_bit)
-
-	// For clock recovery / bit synchronization, we simply try many
-	// different bit streams. At least one of them will be synchronized.
- 	bits := make([][]bool, clen)
-
-	excess := 0.0
-	for i := clen; i < len(samples); {
-		for j := i; j < i + clen; j++ {
-			bit := correlateBitComplex(
-				samples[j-clen:j], sample_rate, markHz, spaceHz)
-			bits[j-i] = append(bits[j-i], bit)
-		}
-		skip := samples_per_bit + excess
-		i += int(skip)
-		excess = skip - float64(int(skip))
-	}
-
-	// We may detect the same packet multiple times.
-	// Store them in a map to remove duplicates.
-	packet_set := make(map[string][]byte)
-
-	for i := 0; i < len(bits); i++ {
-		NRZIDecode(bits[i])
-		for _, p := range DecodeHDLC(bits[i]) {
-			packet_set[string(p)] = p
-		}
-	}
+func main3() {
+	rate := 266650.0
 
-	for _, v := range packet_set {
-		packets = append(packets, v)
+	d := packet.NewAFSK1200Demodulator(rate, printPacket)
+	err := dsp.ReadIQFile("/Users/tstranex/tmp/1362145622_corrected",
+		pb.IQParams_FLOAT32, d)
+	if err != nil {
+		log.Fatalf("Error reading file: %s", err.Error())
 	}
-	return packets
 }
 
-func main3() {
-	rate := 266650.0
-
-	samples := readComplexFile(
-		"/Users/tstranex/tmp/1362145622_corrected",
-		binary.ReadComplex64LE)
-	fmt.Printf("read samples\n")
-	
-	packets := DemodulateAFSK1200Complex(samples, rate)
-	for _, p := range packets {
-		//fmt.Printf("Packet %d:\n", i)
-		h := strings.ToUpper(hex.EncodeToString(p))
-		for i := 0; i < len(h); i += 2 {
-			fmt.Printf("%s ", h[i:i+2])
-		}
-		fmt.Printf("\n")
+func printPacket(p []byte) {
+	h := strings.ToUpper(hex.EncodeToString(p))
+	for i := 0; i < len(h); i += 2 {
+		fmt.Printf("%s ", h[i:i+2])
 	}
+	fmt.Printf("\n")
 }
 
 func main() {
 	rate := 266910.0
 	carrier := 49.0 / 256.0 * rate
 
-	samples := readComplexFile(
-		"/Users/tstranex/tmp/strand1_20130304.cut.bin", binary.ReadSampleUINT8)
-	fmt.Printf("read samples\n")
-	
-	packets := DemodulateG3RUHIQ(samples, rate, carrier)
-	for _, p := range packets {
-		//fmt.Printf("Packet %d:\n", i)
-		h := strings.ToUpper(hex.EncodeToString(p))
-		for i := 0; i < len(h); i += 2 {
-			fmt.Printf("%s ", h[i:i+2])
-		}
-		fmt.Printf("\n")
+	d := packet.NewG3RUHDemodulator(rate, carrier, printPacket)
+	err := dsp.ReadIQFile("/Users/tstranex/tmp/strand1_20130304.cut.bin",
+		pb.IQParams_UINT8, d)
+	if err != nil {
+		log.Fatalf("Error reading file: %s", err.Error())
 	}
 }
 
 func main2() {
 	rate := 22050.0
 
-	//samples := ModulateAFSK1200(EncodeHDLC([]byte("Hello world!")), rate)
-	samples := ModulateG3RUH(EncodeHDLC([]byte("Hello world!")), rate)
+	//samples := ModulateAFSK1200(
+	//	packet.EncodeHDLC([]byte("Hello world!")), rate)
+	samples := ModulateG3RUH(
+		packet.EncodeHDLC([]byte("Hello world!")), rate)
 	writeToFile("test.raw", samples)
 
 	/*
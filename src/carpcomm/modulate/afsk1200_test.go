@@ -3,12 +3,13 @@
 
 package main
 
+import "carpcomm/demod/packet"
 import "testing"
 
 func TestAFSK1200(t *testing.T) {
 	payload := "Hello world!"
 	rate := 22050.0
-	samples := ModulateAFSK1200(EncodeHDLC([]byte(payload)), rate)
+	samples := ModulateAFSK1200(packet.EncodeHDLC([]byte(payload)), rate)
 	packets := DemodulateAFSK1200(samples, rate)
 
 	if len(packets) != 1 {
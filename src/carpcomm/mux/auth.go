@@ -5,13 +5,11 @@ package mux
 
 import "carpcomm/db"
 
-func AuthenticateStation(sdb *db.StationDB, id, secret string) (bool, error) {
+func AuthenticateStation(
+	sdb *db.StationDB, id, secret, source string) (bool, error) {
 	s, err := sdb.Lookup(id)
 	if err != nil {
 		return false, err
 	}
-	if s == nil {
-		return false, nil
-	}
-	return *s.Id == id && *s.Secret == secret, nil
-}
\ No newline at end of file
+	return db.AuthenticateStation(s, id, secret, source), nil
+}
@@ -0,0 +1,110 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package mux
+
+import "log"
+import "math"
+import "net/rpc"
+import "time"
+
+// Only the latest samples are used so that the estimate follows clock
+// drift.
+const clockSampleCount = 8
+
+// Stations whose clocks are further off than this are flagged. Their motor
+// and frequency programs and packet timestamps are still corrected but it
+// usually means that NTP isn't running on the station.
+const maxClockOffsetS = 5.0
+
+// A measurement of a station's clock taken while handling a request.
+type ClockSample struct {
+	// When the response was received according to the mux's clock.
+	Timestamp time.Time
+	// Station clock minus mux clock.
+	OffsetS float64
+	RoundTripS float64
+}
+
+// Like NTP, the station is assumed to have handled the request half way
+// through the round trip.
+func newClockSample(station_time float64, sent, received time.Time) ClockSample {
+	rtt := received.Sub(sent)
+	midpoint := float64(sent.UnixNano())/1e9 + rtt.Seconds()/2
+	return ClockSample{received, station_time - midpoint, rtt.Seconds()}
+}
+
+type ClockEstimate struct {
+	// The sample with the shortest round trip is the least affected by
+	// queueing delays.
+	Best ClockSample
+	Samples int
+	Flagged bool
+}
+
+func estimateClock(samples []ClockSample) ClockEstimate {
+	var e ClockEstimate
+	for i, s := range samples {
+		if i == 0 || s.RoundTripS < e.Best.RoundTripS {
+			e.Best = s
+		}
+	}
+	e.Samples = len(samples)
+	e.Flagged = math.Abs(e.Best.OffsetS) > maxClockOffsetS
+	return e
+}
+
+func (c *Coordinator) addClockSample(station_id string, s ClockSample) {
+	c.stations_lock.Lock()
+	samples := append(c.clocks[station_id], s)
+	if len(samples) > clockSampleCount {
+		samples = samples[len(samples)-clockSampleCount:]
+	}
+	was_flagged := estimateClock(c.clocks[station_id]).Flagged
+	c.clocks[station_id] = samples
+	e := estimateClock(samples)
+	c.stations_lock.Unlock()
+
+	if e.Flagged && !was_flagged {
+		log.Printf("%s: Station clock is off by %.3fs",
+			station_id, e.Best.OffsetS)
+	}
+}
+
+// Returns false if the station hasn't sent any timestamps since the mux
+// started.
+func (c *Coordinator) stationClock(station_id string) (ClockEstimate, bool) {
+	c.stations_lock.RLock()
+	defer c.stations_lock.RUnlock()
+	samples := c.clocks[station_id]
+	if len(samples) == 0 {
+		return ClockEstimate{}, false
+	}
+	return estimateClock(samples), true
+}
+
+func (c *Coordinator) StationClock(
+	args *StationClockArgs, result *StationClockResult) error {
+	result.Estimate, result.Known = c.stationClock(args.StationId)
+	return nil
+}
+
+// StationClock returns the estimated clock offset of the station. The
+// second result is false if the offset isn't known.
+func StationClock(mux_client *rpc.Client, station_id string) (
+	ClockEstimate, bool, error) {
+	args := StationClockArgs{station_id}
+	var result StationClockResult
+	err := mux_client.Call("Coordinator.StationClock", args, &result)
+	if err != nil {
+		return ClockEstimate{}, false, err
+	}
+	return result.Estimate, result.Known, nil
+}
+
+// ToMuxTimestamp converts a Unix timestamp according to the station's clock
+// to the mux's clock, rounded to the nearest second.
+func (e ClockEstimate) ToMuxTimestamp(station_timestamp int64) int64 {
+	return int64(math.Floor(
+		float64(station_timestamp) - e.Best.OffsetS + 0.5))
+}
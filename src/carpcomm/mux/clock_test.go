@@ -0,0 +1,112 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package mux
+
+import "carpcomm/pb"
+import "code.google.com/p/goprotobuf/proto"
+import "math"
+import "testing"
+import "time"
+
+func TestNewClockSample(t *testing.T) {
+	sent := time.Unix(1000, 0)
+	received := sent.Add(2 * time.Second)
+	s := newClockSample(1011, sent, received)
+	if s.RoundTripS != 2 || !s.Timestamp.Equal(received) {
+		t.Errorf("Wrong sample: %v", s)
+	}
+	if math.Abs(s.OffsetS-10) > 1e-6 {
+		t.Errorf("Wrong clock offset: %f", s.OffsetS)
+	}
+}
+
+func TestStationClock(t *testing.T) {
+	c := NewCoordinator(nil)
+	var result StationClockResult
+	c.StationClock(&StationClockArgs{"station"}, &result)
+	if result.Known {
+		t.Errorf("Station hasn't sent its clock")
+	}
+
+	now := time.Unix(1000, 0)
+	c.addClockSample("station", ClockSample{now, 20, 0.1})
+	c.addClockSample("station", ClockSample{now, 6, 1.5})
+	c.StationClock(&StationClockArgs{"station"}, &result)
+	if !result.Known || result.Estimate.Best.OffsetS != 20 ||
+		!result.Estimate.Flagged || result.Estimate.Samples != 2 {
+		t.Errorf("Wrong estimate: %v", result)
+	}
+
+	// The fastest sample eventually falls out of the window.
+	for i := 0; i < clockSampleCount; i++ {
+		c.addClockSample("station", ClockSample{now, 0.5, 0.2})
+	}
+	e, _ := c.stationClock("station")
+	if e.Best.OffsetS != 0.5 || e.Flagged ||
+		e.Samples != clockSampleCount {
+		t.Errorf("Wrong estimate: %v", e)
+	}
+
+	if ts := e.ToMuxTimestamp(2000); ts != 2000 {
+		t.Errorf("Wrong mux timestamp: %d", ts)
+	}
+	e.Best.OffsetS = -30.7
+	if ts := e.ToMuxTimestamp(2000); ts != 2031 {
+		t.Errorf("Wrong mux timestamp: %d", ts)
+	}
+}
+
+func TestParsePong(t *testing.T) {
+	if ts, ok := parsePong([]byte("pong 1357000000.5")); !ok ||
+		ts != 1357000000.5 {
+		t.Errorf("Wrong time: %f, %v", ts, ok)
+	}
+	// Older stations.
+	if _, ok := parsePong([]byte("pong")); ok {
+		t.Errorf("Old pong shouldn't have a time")
+	}
+}
+
+func TestSetStationTimestamps(t *testing.T) {
+	req := newStationRequest(pb.StationRequest_MOTOR_START)
+	req.MotorProgram = []*pb.MotorCoordinate{
+		&pb.MotorCoordinate{OffsetS: proto.Float64(0)},
+		&pb.MotorCoordinate{OffsetS: proto.Float64(2.5)},
+	}
+	clock := ClockEstimate{Best: ClockSample{OffsetS: -10}}
+	setStationTimestamps(req, time.Unix(1000, 0), clock)
+	if req.MotorProgram[0].GetStationTimestamp() != 990 ||
+		req.MotorProgram[1].GetStationTimestamp() != 992.5 {
+		t.Errorf("Wrong timestamps: %v", req.MotorProgram)
+	}
+}
+
+func TestSetStationTimestampsAbsolute(t *testing.T) {
+	// The program is built at a fractional second and sent slightly
+	// later, which must not shift the station timestamps.
+	now := time.Unix(1000, 750000000)
+	program := []MotorCoordinate{
+		MotorCoordinate{Timestamp: 1010.5},
+		MotorCoordinate{Timestamp: 1020.25},
+	}
+	req := motorProgramRequest(program, now)
+	if req.MotorProgram[0].GetOffsetS() != 9.75 {
+		t.Errorf("Wrong offset: %v", req.MotorProgram[0])
+	}
+
+	clock := ClockEstimate{Best: ClockSample{OffsetS: 2.25}}
+	setStationTimestamps(req, now.Add(300*time.Millisecond), clock)
+	if req.MotorProgram[0].GetStationTimestamp() != 1012.75 ||
+		req.MotorProgram[1].GetStationTimestamp() != 1022.5 {
+		t.Errorf("Wrong timestamps: %v", req.MotorProgram)
+	}
+
+	freq := frequencyProgramRequest(
+		[]FrequencyCoordinate{FrequencyCoordinate{1010.5, 437000000}},
+		now)
+	setStationTimestamps(freq, now, clock)
+	if freq.FrequencyProgram[0].GetStationTimestamp() != 1012.75 {
+		t.Errorf("Wrong timestamps: %v", freq.FrequencyProgram)
+	}
+}
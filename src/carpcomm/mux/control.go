@@ -0,0 +1,284 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package mux
+
+import "bytes"
+import "carpcomm/pb"
+import "code.google.com/p/goprotobuf/proto"
+import "encoding/json"
+import "errors"
+import "fmt"
+import "net/http"
+import "net/url"
+import "time"
+
+// Version of the typed control protocol (pb.StationRequest) spoken by the
+// mux. Stations that report an older version or none at all are sent the
+// original URL actions instead.
+const StationProtocolVersion = 4
+
+// Requests are POSTed since version 4. Earlier versions took them in the
+// query string, which limits the length of programs, so those stations are
+// sent the URL actions instead. GET_HEALTH and program station timestamps
+// have no URL action equivalent so they're only available to stations that
+// support this version.
+const typedControlProtocolVersion = 4
+
+const stationControlPath = "/Control"
+
+// The station action corresponding to each request type. This determines the
+// lease and priority of the request. Except for GetStatus and GetHealth,
+// these are also the URL actions understood by older stations.
+var requestActions = map[pb.StationRequest_Type]string{
+	pb.StationRequest_PING: stationPing,
+	pb.StationRequest_GET_STATUS: stationGetStatus,
+	pb.StationRequest_RECEIVER_SET_FREQUENCY: stationReceiverSetFrequency,
+	pb.StationRequest_RECEIVER_START: stationReceiverStart,
+	pb.StationRequest_RECEIVER_STOP: stationReceiverStop,
+	pb.StationRequest_RECEIVER_FREQUENCY_PROGRAM:
+		stationReceiverFrequencyProgram,
+	pb.StationRequest_TNC_START: stationTNCStart,
+	pb.StationRequest_TNC_STOP: stationTNCStop,
+	pb.StationRequest_MOTOR_START: stationMotorStart,
+	pb.StationRequest_MOTOR_STOP: stationMotorStop,
+	pb.StationRequest_GET_HEALTH: stationGetHealth,
+}
+
+func supportsTypedControl(id *pb.StationIdentity) bool {
+	return id != nil &&
+		id.GetProtocolVersion() >= typedControlProtocolVersion
+}
+
+// Translate a request into the URL action understood by older stations.
+// Returns an empty string if there is no equivalent.
+func legacyStationURL(req *pb.StationRequest) (string, error) {
+	params := url.Values{}
+	switch req.GetType() {
+	case pb.StationRequest_RECEIVER_SET_FREQUENCY:
+		params.Add("hz", fmt.Sprintf("%d", req.GetFrequencyHz()))
+	case pb.StationRequest_RECEIVER_START:
+		params.Add("stream_url", req.GetStreamUrl())
+	case pb.StationRequest_RECEIVER_FREQUENCY_PROGRAM:
+		coords := make([][2]float64, len(req.FrequencyProgram))
+		for i, c := range req.FrequencyProgram {
+			coords[i][0] = c.GetOffsetS()
+			coords[i][1] = float64(c.GetFrequencyHz())
+		}
+		p, err := json.Marshal(coords)
+		if err != nil {
+			return "", err
+		}
+		params.Add("program", (string)(p))
+	case pb.StationRequest_TNC_START:
+		params.Add("api_host", req.GetApiHost())
+		params.Add("api_port", fmt.Sprintf("%d", req.GetApiPort()))
+		params.Add("satellite_id", req.GetSatelliteId())
+	case pb.StationRequest_MOTOR_START:
+		coords := make([][3]float64, len(req.MotorProgram))
+		for i, c := range req.MotorProgram {
+			coords[i][0] = c.GetOffsetS()
+			coords[i][1] = c.GetAzimuthDegrees()
+			coords[i][2] = c.GetAltitudeDegrees()
+		}
+		p, err := json.Marshal(coords)
+		if err != nil {
+			return "", err
+		}
+		params.Add("program", (string)(p))
+	case pb.StationRequest_GET_STATUS, pb.StationRequest_GET_HEALTH:
+		return "", nil
+	}
+
+	u := url.URL{}
+	u.Path = "/" + requestActions[req.GetType()]
+	if len(params) > 0 {
+		u.RawQuery = params.Encode()
+	}
+	return u.String(), nil
+}
+
+// Fill in the station timestamps of the motor and frequency programs.
+// Coordinates without an absolute timestamp come from older callers. Their
+// offsets are relative to the time the request was made, which is close
+// enough to now since the caller runs next to the mux.
+func setStationTimestamps(
+	req *pb.StationRequest, now time.Time, clock ClockEstimate) {
+	base := unixSeconds(now) + clock.Best.OffsetS
+	convert := func(timestamp *float64, offset_s float64) *float64 {
+		if timestamp == nil {
+			return proto.Float64(base + offset_s)
+		}
+		return proto.Float64(*timestamp + clock.Best.OffsetS)
+	}
+	for _, c := range req.FrequencyProgram {
+		c.StationTimestamp = convert(c.Timestamp, c.GetOffsetS())
+	}
+	for _, c := range req.MotorProgram {
+		c.StationTimestamp = convert(c.Timestamp, c.GetOffsetS())
+	}
+}
+
+// The request is POSTed JSON-encoded so that stations don't need a protobuf
+// library. The body is returned as well since it's needed to send the
+// request again.
+func typedStationRequest(req *pb.StationRequest) (
+	*http.Request, []byte, error) {
+	req.ProtocolVersion = proto.Int32(StationProtocolVersion)
+	j, err := json.Marshal(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := http.NewRequest(
+		"POST", stationControlPath, bytes.NewReader(j))
+	if err != nil {
+		return nil, nil, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	return r, j, nil
+}
+
+func unsupportedResponse(message string) *pb.StationResponse {
+	return &pb.StationResponse{
+		Status: pb.StationResponse_UNSUPPORTED.Enum(),
+		Error: proto.String(message),
+	}
+}
+
+func errorResponse(code int) *pb.StationResponse {
+	return &pb.StationResponse{
+		Status: pb.StationResponse_ERROR.Enum(),
+		Error: proto.String(fmt.Sprintf(
+			"Station returned error code: %d", code)),
+	}
+}
+
+// Older stations only return a status code.
+func legacyStationResponse(resp *Response) *pb.StationResponse {
+	switch resp.code {
+	case stationStatusCodeOk:
+		return &pb.StationResponse{
+			Status: pb.StationResponse_OK.Enum(),
+		}
+	case http.StatusNotFound:
+		return unsupportedResponse("Action not supported by station")
+	}
+	return errorResponse(resp.code)
+}
+
+func typedStationResponse(resp *Response) (*pb.StationResponse, error) {
+	if resp.code == http.StatusNotFound {
+		return unsupportedResponse("Control not supported by station"), nil
+	}
+	if resp.code != stationStatusCodeOk {
+		return errorResponse(resp.code), nil
+	}
+	sr := &pb.StationResponse{}
+	if err := json.Unmarshal(resp.data, sr); err != nil {
+		return nil, errors.New(fmt.Sprintf(
+			"Bad control response from station: %s", err.Error()))
+	}
+	if sr.Status == nil {
+		return nil, errors.New("Control response without status")
+	}
+	return sr, nil
+}
+
+func (c *Coordinator) setStationIdentity(
+	s *stationSession, id *pb.StationIdentity) {
+	c.stations_lock.Lock()
+	s.identity = id
+	c.stations_lock.Unlock()
+}
+
+// Returns nil if the station isn't connected.
+func (c *Coordinator) stationIdentity(station_id string) *pb.StationIdentity {
+	c.stations_lock.RLock()
+	defer c.stations_lock.RUnlock()
+	s := c.stations[station_id]
+	if s == nil {
+		return nil
+	}
+	return s.identity
+}
+
+// StationControl sends a typed request to the station. Requests are
+// translated to the URL actions for stations that don't support
+// StationProtocolVersion.
+func (c *Coordinator) StationControl(
+	args *StationControlArgs, result *StationControlResult) error {
+	var req pb.StationRequest
+	if err := proto.Unmarshal(args.Request, &req); err != nil {
+		return err
+	}
+	sr, err := c.stationControl(args, &req)
+	if err != nil {
+		return err
+	}
+	result.Response, err = proto.Marshal(sr)
+	return err
+}
+
+func (c *Coordinator) stationControl(args *StationControlArgs,
+	req *pb.StationRequest) (*pb.StationResponse, error) {
+	action, ok := requestActions[req.GetType()]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf(
+			"Unknown station request type: %d", req.GetType()))
+	}
+
+	id := c.stationIdentity(args.StationId)
+	if supportsTypedControl(id) {
+		if clock, ok := c.stationClock(args.StationId); ok {
+			setStationTimestamps(req, time.Now(), clock)
+		}
+		r, body, err := typedStationRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		return c.callControl(args, action, r, body)
+	}
+
+	u, err := legacyStationURL(req)
+	if err != nil {
+		return nil, err
+	}
+	if u == "" {
+		return unsupportedResponse("Station protocol too old"), nil
+	}
+	r, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.callControl(args, action, r, nil)
+}
+
+func (c *Coordinator) callControl(args *StationControlArgs,
+	action string, r *http.Request, body []byte) (
+	*pb.StationResponse, error) {
+	call_args := StationCallArgs{
+		StationId: args.StationId,
+		URL: r.URL.String(),
+		LeaseToken: args.LeaseToken,
+		Priority: args.Priority,
+		Timeout: args.Timeout,
+	}
+
+	cancel := make(chan bool)
+	req, err := c.newActionRequest(&call_args, r, action, cancel)
+	if err != nil {
+		return nil, err
+	}
+	req.body = body
+	timer := time.AfterFunc(req.timeout, func() { close(cancel) })
+	defer timer.Stop()
+
+	resp := c.call(args.StationId, req)
+	if resp == nil {
+		return nil, errors.New("Station RPC error")
+	}
+	if r.URL.Path == stationControlPath {
+		return typedStationResponse(resp)
+	}
+	return legacyStationResponse(resp), nil
+}
@@ -0,0 +1,123 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package mux
+
+import "bufio"
+import "bytes"
+import "carpcomm/pb"
+import "code.google.com/p/goprotobuf/proto"
+import "fmt"
+import "io/ioutil"
+import "net/http"
+import "testing"
+
+func TestLegacyStationURL(t *testing.T) {
+	req := newStationRequest(pb.StationRequest_MOTOR_START)
+	req.MotorProgram = []*pb.MotorCoordinate{
+		&pb.MotorCoordinate{
+			OffsetS: proto.Float64(1.5),
+			AzimuthDegrees: proto.Float64(90),
+			AltitudeDegrees: proto.Float64(45),
+		},
+	}
+	u, err := legacyStationURL(req)
+	if err != nil {
+		t.Fatalf("legacyStationURL: %s", err.Error())
+	}
+	if u != "/MotorStart?program=%5B%5B1.5%2C90%2C45%5D%5D" {
+		t.Errorf("Wrong url: %s", u)
+	}
+
+	u, err = legacyStationURL(
+		newStationRequest(pb.StationRequest_RECEIVER_STOP))
+	if err != nil || u != "/ReceiverStop" {
+		t.Errorf("Wrong url: %s, %v", u, err)
+	}
+
+	u, err = legacyStationURL(
+		newStationRequest(pb.StationRequest_GET_STATUS))
+	if err != nil || u != "" {
+		t.Errorf("GET_STATUS has no URL action: %s, %v", u, err)
+	}
+}
+
+func TestTypedStationRequest(t *testing.T) {
+	req := newStationRequest(pb.StationRequest_RECEIVER_START)
+	req.StreamUrl = proto.String("http://example.com/stream")
+	r, body, err := typedStationRequest(req)
+	if err != nil {
+		t.Fatalf("typedStationRequest: %s", err.Error())
+	}
+	if r.Method != "POST" || r.URL.String() != stationControlPath {
+		t.Fatalf("Wrong request: %s %s", r.Method, r.URL)
+	}
+	expected := fmt.Sprintf(
+		`{"protocol_version":%d,"type":"RECEIVER_START",`+
+			`"stream_url":"http://example.com/stream"}`,
+		StationProtocolVersion)
+
+	// The body is sent in full every time the request is written.
+	sr := newRequest(r, PriorityNormal, stationRPCTimeout, nil)
+	sr.body = body
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		if err := writeStationRequest(&buf, sr); err != nil {
+			t.Fatalf("writeStationRequest: %s", err.Error())
+		}
+		hr, err := http.ReadRequest(bufio.NewReader(&buf))
+		if err != nil {
+			t.Fatalf("ReadRequest: %s", err.Error())
+		}
+		b, _ := ioutil.ReadAll(hr.Body)
+		if string(b) != expected {
+			t.Errorf("Wrong request %d: %s", i, b)
+		}
+	}
+}
+
+func TestTypedStationResponse(t *testing.T) {
+	resp := &Response{200, []byte(`{"protocol_version": 1,
+		"status": "OK",
+		"station_status": {"receiver": {"started": true}}}`)}
+	sr, err := typedStationResponse(resp)
+	if err != nil {
+		t.Fatalf("typedStationResponse: %s", err.Error())
+	}
+	if sr.GetStatus() != pb.StationResponse_OK ||
+		!sr.GetStationStatus().GetReceiver().GetStarted() {
+		t.Errorf("Wrong response: %v", sr)
+	}
+
+	resp = &Response{200, []byte(`{}`)}
+	if _, err := typedStationResponse(resp); err == nil {
+		t.Errorf("Expected error for response without status")
+	}
+
+	resp = &Response{500, nil}
+	sr, err = typedStationResponse(resp)
+	if err != nil || sr.GetStatus() != pb.StationResponse_ERROR {
+		t.Errorf("Wrong response: %v, %v", sr, err)
+	}
+}
+
+func TestStationControlOldStation(t *testing.T) {
+	c := NewCoordinator(nil)
+	if _, _, err := c.stationConnected("station", ""); err != nil {
+		t.Fatalf("stationConnected: %s", err.Error())
+	}
+
+	// Old stations don't have a status request so it isn't even sent.
+	b, _ := proto.Marshal(newStationRequest(pb.StationRequest_GET_STATUS))
+	var result StationControlResult
+	err := c.StationControl(
+		&StationControlArgs{StationId: "station", Request: b}, &result)
+	if err != nil {
+		t.Fatalf("StationControl: %s", err.Error())
+	}
+	var resp pb.StationResponse
+	proto.Unmarshal(result.Response, &resp)
+	if resp.GetStatus() != pb.StationResponse_UNSUPPORTED {
+		t.Errorf("Wrong status: %s", resp.GetStatus())
+	}
+}
@@ -5,9 +5,13 @@ package mux
 
 import "net/http"
 import "carpcomm/db"
+import "carpcomm/pb"
 import "sync"
 import "log"
 import "errors"
+import "fmt"
+import "strings"
+import "time"
 
 type Response struct {
 	code int
@@ -16,22 +20,91 @@ type Response struct {
 
 type Request struct {
 	request *http.Request  // nil means disconnect immediately
-	response chan *Response
+	response chan *Response  // buffered so that it never blocks
+	retries int
+
+	priority int
+	timeout time.Duration
+	cancel <-chan bool  // may be nil
+	seq uint64  // set by requestQueue
+
+	// Maximum size of the response body.
+	limit int64
+	// If set, the response body is written here instead of to
+	// Response.data.
+	stream *responseStream
+
+	// The body of the HTTP request, if any. It's kept since request's
+	// body can only be read once but the request may be sent again.
+	body []byte
+}
+
+func newRequest(r *http.Request, priority int, timeout time.Duration,
+	cancel <-chan bool) Request {
+	req := Request{
+		request: r,
+		response: make(chan *Response, 1),
+		priority: priority,
+		timeout: timeout,
+		cancel: cancel,
+	}
+	if r != nil {
+		req.limit = responseLimit(strings.TrimPrefix(r.URL.Path, "/"))
+	}
+	return req
+}
+
+// Whether the caller is no longer waiting for the response.
+func (r *Request) cancelled() bool {
+	select {
+	case <-r.cancel:
+		return true
+	default:
+		return false
+	}
 }
 
 type Coordinator struct {
-	stations map[string] chan Request
+	// Includes suspended sessions that can still be resumed.
+	stations map[string]*stationSession
+	history map[string][]ConnectionEvent
+	// Latest health report of each station. Kept after disconnection.
+	health map[string]*pb.StationHealth
+	// Recent clock samples of each station. Kept after disconnection.
+	clocks map[string][]ClockSample
 	stations_lock sync.RWMutex
 	sdb *db.StationDB
+	leases *leaseTable
+
+	// Called in a new goroutine when a station connects or disconnects.
+	// Brief disconnections after which the session is resumed aren't
+	// reported.
+	station_event_handler func(station_id string, connected bool)
 }
 
 func NewCoordinator(sdb *db.StationDB) *Coordinator {
 	var c Coordinator
-	c.stations = make(map[string] chan Request)
+	c.stations = make(map[string]*stationSession)
+	c.history = make(map[string][]ConnectionEvent)
+	c.health = make(map[string]*pb.StationHealth)
+	c.clocks = make(map[string][]ClockSample)
 	c.sdb = sdb
+	c.leases = newLeaseTable()
 	return &c
 }
 
+// SetStationEventHandler must be called before any stations connect.
+func (c *Coordinator) SetStationEventHandler(
+	f func(station_id string, connected bool)) {
+	c.station_event_handler = f
+}
+
+func (c *Coordinator) notifyStationEvent(station_id string, connected bool) {
+	if c.station_event_handler != nil {
+		go c.station_event_handler(station_id, connected)
+	}
+}
+
 func (c *Coordinator) StationCount(
 	args *StationCountArgs, reply *StationCountResult) error {
 	c.stations_lock.RLock()
@@ -40,6 +113,8 @@ func (c *Coordinator) StationCount(
 	return nil
 }
 
+// Suspended stations are included since they're expected to reconnect soon
+// and calls to them are queued in the meantime.
 func (c *Coordinator) StationList(
 	args *StationListArgs, result *StationListResult) error {
 	result.StationIds = []string{}
@@ -54,20 +129,78 @@ func (c *Coordinator) StationList(
 func (c *Coordinator) StationStatus(
 	args *StationStatusArgs, result *StationStatusResult) error {
 	c.stations_lock.RLock()
-	result.IsConnected = (c.stations[args.StationId] != nil)
+	session := c.stations[args.StationId]
+	result.IsConnected = session != nil && session.connected
+	result.IsSuspended = session != nil && !session.connected
+	if session != nil && session.identity != nil {
+		result.SoftwareVersion = session.identity.GetVersion()
+		result.ProtocolVersion = session.identity.GetProtocolVersion()
+	}
 	c.stations_lock.RUnlock()
 	return nil
 }
 
-func (c *Coordinator) StationCall(
-	args *StationCallArgs, result *StationCallResult) error {
+func (c *Coordinator) StationConnectionHistory(
+	args *StationConnectionHistoryArgs,
+	result *StationConnectionHistoryResult) error {
+	c.stations_lock.RLock()
+	h := c.history[args.StationId]
+	result.Events = make([]ConnectionEvent, len(h))
+	copy(result.Events, h)
+	c.stations_lock.RUnlock()
+	return nil
+}
 
+// Checks the lease and applies the defaults for the call.
+func (c *Coordinator) newCallRequest(args *StationCallArgs,
+	cancel <-chan bool) (req Request, err error) {
 	r, err := http.NewRequest("GET", args.URL, nil)
+	if err != nil {
+		return req, err
+	}
+	return c.newActionRequest(
+		args, r, strings.TrimPrefix(r.URL.Path, "/"), cancel)
+}
+
+// Like newCallRequest but the lease and priority are determined by action
+// instead of the request path.
+func (c *Coordinator) newActionRequest(args *StationCallArgs,
+	r *http.Request, action string, cancel <-chan bool) (
+	req Request, err error) {
+	if !c.leases.allowed(args.StationId, action, args.LeaseToken) {
+		return req, errors.New(fmt.Sprintf(
+			"Station is leased by someone else: %s", action))
+	}
+
+	priority := args.Priority
+	if priority == PriorityDefault {
+		priority = defaultPriority(action)
+	}
+	timeout := args.Timeout
+	if timeout <= 0 {
+		timeout = stationRPCTimeout
+	}
+
+	req = newRequest(r, priority, timeout, cancel)
+	if args.MaxResponseBytes > 0 && args.MaxResponseBytes < req.limit {
+		req.limit = args.MaxResponseBytes
+	}
+	return req, nil
+}
+
+func (c *Coordinator) StationCall(
+	args *StationCallArgs, result *StationCallResult) error {
+	cancel := make(chan bool)
+	req, err := c.newCallRequest(args, cancel)
 	if err != nil {
 		return err
 	}
 
-	resp := c.call(args.StationId, r)
+	// The timeout includes the time spent waiting in the queue.
+	timer := time.AfterFunc(req.timeout, func() { close(cancel) })
+	defer timer.Stop()
+
+	resp := c.call(args.StationId, req)
 	if resp == nil {
 		return errors.New("Station RPC error")
 	}
@@ -77,44 +210,64 @@ func (c *Coordinator) StationCall(
 	return nil
 }
 
+func (c *Coordinator) StationAcquireLease(
+	args *StationAcquireLeaseArgs, result *StationAcquireLeaseResult) error {
+	if args.Holder == "" || args.Duration <= 0 {
+		return errors.New("Invalid lease holder or duration.")
+	}
+	lease, granted, err := c.leases.acquire(
+		args.StationId, args.Holder, args.Description, args.Duration)
+	if err != nil {
+		return err
+	}
+	result.Granted = granted
+	result.Lease = lease
+	return nil
+}
+
+func (c *Coordinator) StationReleaseLease(
+	args *StationReleaseLeaseArgs, result *StationReleaseLeaseResult) error {
+	if !c.leases.release(args.StationId, args.Token) {
+		return errors.New("Lease not held.")
+	}
+	return nil
+}
+
+func (c *Coordinator) StationLeaseStatus(
+	args *StationLeaseStatusArgs, result *StationLeaseStatusResult) error {
+	result.Lease, result.Held = c.leases.status(args.StationId)
+	return nil
+}
+
+func (c *Coordinator) StationRequestPreemption(
+	args *StationRequestPreemptionArgs,
+	result *StationRequestPreemptionResult) error {
+	result.Lease, result.Held = c.leases.requestPreemption(
+		args.StationId, args.RequestedBy)
+	return nil
+}
+
 // returns nil for rpc errors
-func (c *Coordinator) call(station_id string, r *http.Request) *Response {
+// The call is abandoned if req.cancel is closed.
+func (c *Coordinator) call(station_id string, req Request) *Response {
 	c.stations_lock.RLock()
-	station, ok := c.stations[station_id]
+	session, ok := c.stations[station_id]
 	c.stations_lock.RUnlock()
 	if !ok {
 		log.Printf("No such station connected.")
 		return nil
 	}
 
-	response := make(chan *Response)
-	station <- Request{r, response}
-	return <-response;
-}
-
-func (c *Coordinator) stationConnected(station_id string, input chan Request) {
-	// If the station is already connected, disconnect it first.
-	c.stations_lock.RLock()
-	existing_input, already_connected := c.stations[station_id]
-	c.stations_lock.RUnlock()
-	if already_connected {
-		log.Printf("Disconnecting duplicate existing station.")
-		r := Request{nil, make(chan *Response)}
-		existing_input <- r
-		<- r.response
+	// If the station is briefly disconnected, this waits until it
+	// reconnects or the session expires.
+	if !session.send(req) {
+		log.Printf("Station session ended.")
+		return nil
 	}
-
-	c.stations_lock.Lock()
-	_, ok := c.stations[station_id]
-	if ok {
-		log.Printf("Station already connected. This shouldn't happen!")
+	select {
+	case resp := <-req.response:
+		return resp
+	case <-req.cancel:
+		return nil
 	}
-	c.stations[station_id] = input
-	c.stations_lock.Unlock()
-}
-
-func (c *Coordinator) stationDisconnected(station_id string) {
-	c.stations_lock.Lock()
-	delete(c.stations, station_id)
-	c.stations_lock.Unlock()
 }
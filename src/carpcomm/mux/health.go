@@ -0,0 +1,133 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package mux
+
+import "carpcomm/pb"
+import "code.google.com/p/goprotobuf/proto"
+import "log"
+import "net/rpc"
+import "time"
+
+const healthPollInterval = 1 * time.Minute
+const healthPollTimeout = 30 * time.Second
+
+// Reports older than this aren't used to decide whether a station is
+// faulted.
+const healthMaxAge = 10 * time.Minute
+
+// Poll the station's health until its session ends. The mux polls rather
+// than having stations push reports so that stations don't need a way to
+// send unsolicited requests and the poll doubles as a clock sample. Polls
+// are sent at low priority so that they don't delay control requests from
+// the scheduler and the console.
+func (c *Coordinator) pollHealth(station_id string, s *stationSession) {
+	ticker := time.NewTicker(healthPollInterval)
+	defer ticker.Stop()
+	for {
+		c.updateHealth(station_id)
+		select {
+		case <-ticker.C:
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+func (c *Coordinator) updateHealth(station_id string) {
+	id := c.stationIdentity(station_id)
+	if !supportsTypedControl(id) {
+		return
+	}
+
+	args := StationControlArgs{
+		StationId: station_id,
+		Priority: PriorityLow,
+		Timeout: healthPollTimeout,
+	}
+	req := newStationRequest(pb.StationRequest_GET_HEALTH)
+	sent := time.Now()
+	resp, err := c.stationControl(&args, req)
+	received := time.Now()
+	if err != nil {
+		log.Printf("%s: Error polling health: %s",
+			station_id, err.Error())
+		return
+	}
+	if resp.GetStatus() != pb.StationResponse_OK || resp.Health == nil {
+		log.Printf("%s: Bad health response: %s %s", station_id,
+			resp.GetStatus().String(), resp.GetError())
+		return
+	}
+
+	h := resp.Health
+	h.ReceivedTimestamp = proto.Int64(received.Unix())
+	h.RoundTripS = proto.Float64(received.Sub(sent).Seconds())
+	if h.StationTime != nil {
+		c.addClockSample(station_id,
+			newClockSample(h.GetStationTime(), sent, received))
+	}
+	// Report the filtered estimate rather than this sample's offset.
+	if e, ok := c.stationClock(station_id); ok {
+		h.ClockOffsetS = proto.Float64(e.Best.OffsetS)
+	}
+
+	c.stations_lock.Lock()
+	c.health[station_id] = h
+	c.stations_lock.Unlock()
+}
+
+func (c *Coordinator) StationHealth(
+	args *StationHealthArgs, result *StationHealthResult) error {
+	c.stations_lock.RLock()
+	h := c.health[args.StationId]
+	c.stations_lock.RUnlock()
+	if h == nil {
+		return nil
+	}
+	var err error
+	result.Reported = true
+	result.Health, err = proto.Marshal(h)
+	return err
+}
+
+// StationHealth returns the latest health report of the station or nil if it
+// hasn't reported one.
+func StationHealth(mux_client *rpc.Client, station_id string) (
+	*pb.StationHealth, error) {
+	args := StationHealthArgs{station_id}
+	var result StationHealthResult
+	err := mux_client.Call("Coordinator.StationHealth", args, &result)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Reported {
+		return nil, nil
+	}
+	h := &pb.StationHealth{}
+	if err := proto.Unmarshal(result.Health, h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// HealthFault describes why the station's receiver or motor isn't working
+// according to its latest health report. It returns an empty string if
+// there's no recent report or nothing is faulted.
+func HealthFault(h *pb.StationHealth, now time.Time) string {
+	if h == nil {
+		return ""
+	}
+	age := now.Sub(time.Unix(h.GetReceivedTimestamp(), 0))
+	if age > healthMaxAge {
+		return ""
+	}
+	status := h.GetStatus()
+	if f := status.GetReceiver().GetFault(); f != "" {
+		return "receiver: " + f
+	}
+	if f := status.GetMotor().GetFault(); f != "" {
+		return "motor: " + f
+	}
+	return ""
+}
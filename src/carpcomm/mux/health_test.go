@@ -0,0 +1,56 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package mux
+
+import "carpcomm/pb"
+import "code.google.com/p/goprotobuf/proto"
+import "testing"
+import "time"
+
+func TestHealthFault(t *testing.T) {
+	now := time.Unix(10000, 0)
+	h := &pb.StationHealth{
+		ReceivedTimestamp: proto.Int64(now.Unix() - 60),
+		Status: &pb.StationStatus{
+			Receiver: &pb.StationStatus_Receiver{},
+			Motor: &pb.StationStatus_Motor{
+				Fault: proto.String("stuck"),
+			},
+		},
+	}
+	if f := HealthFault(h, now); f != "motor: stuck" {
+		t.Errorf("Wrong fault: %s", f)
+	}
+
+	// Old reports are ignored.
+	if f := HealthFault(h, now.Add(time.Hour)); f != "" {
+		t.Errorf("Stale report shouldn't be faulted: %s", f)
+	}
+
+	h.Status.Motor.Fault = nil
+	if f := HealthFault(h, now); f != "" {
+		t.Errorf("Unexpected fault: %s", f)
+	}
+	if f := HealthFault(nil, now); f != "" {
+		t.Errorf("Unexpected fault: %s", f)
+	}
+}
+
+func TestStationHealthRPC(t *testing.T) {
+	c := NewCoordinator(nil)
+	var result StationHealthResult
+	c.StationHealth(&StationHealthArgs{"station"}, &result)
+	if result.Reported {
+		t.Errorf("Station hasn't reported its health")
+	}
+
+	c.health["station"] = &pb.StationHealth{
+		LoadAverage: proto.Float64(0.5)}
+	c.StationHealth(&StationHealthArgs{"station"}, &result)
+	var h pb.StationHealth
+	proto.Unmarshal(result.Health, &h)
+	if !result.Reported || h.GetLoadAverage() != 0.5 {
+		t.Errorf("Wrong health: %v, %v", result.Reported, h)
+	}
+}
@@ -0,0 +1,146 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package mux
+
+import "carpcomm/db"
+import "sync"
+import "time"
+
+// A lease grants exclusive control of a station's receiver, TNC and motor
+// until it expires or is released.
+type Lease struct {
+	// Identifies who holds the lease, e.g. "scheduler" or
+	// "console:<userid>".
+	Holder string
+	Description string
+	Expires time.Time
+
+	// Needed to release the lease and to control the station. It's only
+	// returned to the holder.
+	Token string
+
+	// Set if someone asked the holder to give up the lease. The holder
+	// is expected to release it as soon as possible.
+	PreemptionRequestedBy string
+}
+
+// Station actions that require the lease if there is one.
+var controlActions = map[string]bool{
+	stationReceiverSetFrequency: true,
+	stationReceiverStart: true,
+	stationReceiverStop: true,
+	stationReceiverFrequencyProgram: true,
+	stationTNCStart: true,
+	stationTNCStop: true,
+	stationMotorStart: true,
+	stationMotorStop: true,
+}
+
+// IsControlAction returns whether the station action requires the lease.
+func IsControlAction(action string) bool {
+	return controlActions[action]
+}
+
+type leaseTable struct {
+	mu sync.Mutex
+	leases map[string]*Lease
+	now func() time.Time
+}
+
+func newLeaseTable() *leaseTable {
+	return &leaseTable{leases: make(map[string]*Lease), now: time.Now}
+}
+
+// Returns the active lease for the station or nil. Must be called with
+// t.mu held.
+func (t *leaseTable) active(station_id string) *Lease {
+	l := t.leases[station_id]
+	if l == nil {
+		return nil
+	}
+	if !t.now().Before(l.Expires) {
+		delete(t.leases, station_id)
+		return nil
+	}
+	return l
+}
+
+// Grant the lease if the station isn't leased by anyone else. If the holder
+// already has the lease, it's extended and the same token is returned.
+// Otherwise the current lease is returned without its token.
+func (t *leaseTable) acquire(station_id, holder, description string,
+	d time.Duration) (lease Lease, granted bool, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	l := t.active(station_id)
+	if l != nil && l.Holder != holder {
+		lease = *l
+		lease.Token = ""
+		return lease, false, nil
+	}
+	if l == nil {
+		token, err := db.CryptoRandId()
+		if err != nil {
+			return lease, false, err
+		}
+		l = &Lease{Holder: holder, Token: token}
+		t.leases[station_id] = l
+	}
+	l.Description = description
+	l.Expires = t.now().Add(d)
+	return *l, true, nil
+}
+
+// Returns false if the token doesn't match the active lease.
+func (t *leaseTable) release(station_id, token string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l := t.active(station_id)
+	if l == nil || l.Token != token {
+		return false
+	}
+	delete(t.leases, station_id)
+	return true
+}
+
+// Returns the active lease without its token.
+func (t *leaseTable) status(station_id string) (lease Lease, held bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l := t.active(station_id)
+	if l == nil {
+		return lease, false
+	}
+	lease = *l
+	lease.Token = ""
+	return lease, true
+}
+
+func (t *leaseTable) requestPreemption(station_id, requested_by string) (
+	lease Lease, held bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l := t.active(station_id)
+	if l == nil {
+		return lease, false
+	}
+	if l.Holder != requested_by {
+		l.PreemptionRequestedBy = requested_by
+	}
+	lease = *l
+	lease.Token = ""
+	return lease, true
+}
+
+// Whether the action may be performed with the token.
+func (t *leaseTable) allowed(station_id, action, token string) bool {
+	if !IsControlAction(action) {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l := t.active(station_id)
+	return l == nil || l.Token == token
+}
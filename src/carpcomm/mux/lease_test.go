@@ -0,0 +1,73 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package mux
+
+import "testing"
+import "time"
+
+func TestLeaseTable(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := newLeaseTable()
+	l.now = func() time.Time { return now }
+
+	if !l.allowed("s", stationReceiverStart, "") {
+		t.Errorf("Control denied without a lease")
+	}
+
+	a, granted, err := l.acquire("s", "scheduler", "capture", time.Minute)
+	if err != nil || !granted || a.Token == "" {
+		t.Fatalf("acquire failed: %v, %v, %v", a, granted, err)
+	}
+
+	b, granted, err := l.acquire("s", "console:u", "", time.Minute)
+	if err != nil || granted {
+		t.Errorf("Lease granted twice: %v, %v", granted, err)
+	}
+	if b.Holder != "scheduler" || b.Token != "" {
+		t.Errorf("Wrong current lease: %+v", b)
+	}
+
+	// The same holder gets the same lease back.
+	now = now.Add(30 * time.Second)
+	a2, granted, _ := l.acquire("s", "scheduler", "capture", time.Minute)
+	if !granted || a2.Token != a.Token || !a2.Expires.After(a.Expires) {
+		t.Errorf("Lease not extended: %+v", a2)
+	}
+
+	if l.allowed("s", stationReceiverStart, "") {
+		t.Errorf("Control allowed without the token")
+	}
+	if !l.allowed("s", stationReceiverStart, a.Token) {
+		t.Errorf("Control denied with the token")
+	}
+	if !l.allowed("s", "ReceiverGetState", "") {
+		t.Errorf("Status denied without the token")
+	}
+
+	p, held := l.requestPreemption("s", "console:u")
+	if !held || p.PreemptionRequestedBy != "console:u" || p.Token != "" {
+		t.Errorf("Wrong preemption: %+v, %v", p, held)
+	}
+
+	if l.release("s", "wrong") {
+		t.Errorf("Released with the wrong token")
+	}
+	if !l.release("s", a.Token) {
+		t.Errorf("Release failed")
+	}
+	if _, held := l.status("s"); held {
+		t.Errorf("Lease still held after release")
+	}
+
+	// Leases expire.
+	l.acquire("s", "console:u", "", time.Minute)
+	now = now.Add(time.Minute)
+	if _, held := l.status("s"); held {
+		t.Errorf("Lease didn't expire")
+	}
+	if _, granted, _ := l.acquire("s", "scheduler", "", time.Minute);
+		!granted {
+		t.Errorf("Expired lease not replaced")
+	}
+}
@@ -0,0 +1,246 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package mux
+
+import "bufio"
+import "bytes"
+import "encoding/binary"
+import "errors"
+import "io"
+import "io/ioutil"
+import "log"
+import "net"
+import "sync"
+import "time"
+
+// Frames larger than this are treated as a protocol error.
+const maxFrameLength = 64 * 1024 * 1024
+
+// How many requests may be outstanding on a framed link at the same time.
+const maxFramedInFlight = 8
+
+// These errors only affect a single request. Any other error means the link
+// is broken.
+var errRequestTimeout = errors.New("Station RPC timed out")
+var errRequestCancelled = errors.New("Station RPC cancelled")
+
+// Returned by do if the link was already broken so that none of the request
+// was written. Such requests are safe to send again.
+type notSentError struct {
+	err error
+}
+
+func (e notSentError) Error() string {
+	return e.err.Error()
+}
+
+// A connection to a station over which RPCs are performed.
+type stationLink interface {
+	// Perform the RPC. It's safe to call concurrently up to maxInFlight
+	// times.
+	do(r Request) (*Response, error)
+
+	maxInFlight() int
+
+	close()
+}
+
+// The original protocol: plain HTTP requests and responses, one at a time.
+// There's no way to abandon a request so it can't be cancelled once started
+// and a timeout breaks the link.
+type legacyLink struct {
+	conn net.Conn
+}
+
+func (l *legacyLink) do(r Request) (*Response, error) {
+	if r.cancelled() {
+		return nil, errRequestCancelled
+	}
+	return doStationRequest(l.conn, r)
+}
+
+func (l *legacyLink) maxInFlight() int {
+	return 1
+}
+
+func (l *legacyLink) close() {
+	l.conn.Close()
+}
+
+// Each frame is a big endian uint32 length of the rest of the frame, a
+// uint32 request id and then the HTTP request or response. Responses carry
+// the id of their request and may arrive in any order.
+//
+// Streamed response bodies are forwarded while the frame is being read so a
+// slow reader holds up the responses behind it.
+type framedLink struct {
+	conn net.Conn
+
+	write_lock sync.Mutex
+
+	mu sync.Mutex
+	next_id uint32
+	pending map[uint32]*pendingRequest
+	closed chan bool
+	err error
+}
+
+type linkResult struct {
+	resp *Response
+	err error
+}
+
+type pendingRequest struct {
+	r Request
+	result chan linkResult  // buffered
+}
+
+func newFramedLink(conn net.Conn) *framedLink {
+	l := &framedLink{
+		conn: conn,
+		pending: make(map[uint32]*pendingRequest),
+		closed: make(chan bool),
+	}
+	// Dead links are detected by pings instead of read deadlines.
+	conn.SetDeadline(time.Time{})
+	go l.readLoop()
+	return l
+}
+
+func writeFrame(w io.Writer, id uint32, payload []byte) error {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(payload)+4))
+	binary.BigEndian.PutUint32(header[4:8], id)
+	if _, err := w.Write(append(header[:], payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Returns the length of the payload that follows.
+func readFrameHeader(r io.Reader) (id uint32, length int64, err error) {
+	var header [8]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return 0, 0, err
+	}
+	n := binary.BigEndian.Uint32(header[0:4])
+	if n < 4 || n > maxFrameLength {
+		return 0, 0, errors.New("Invalid frame length")
+	}
+	return binary.BigEndian.Uint32(header[4:8]), int64(n - 4), nil
+}
+
+func readFrame(r io.Reader) (id uint32, payload []byte, err error) {
+	id, length, err := readFrameHeader(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return id, payload, nil
+}
+
+func (l *framedLink) fail(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return
+	}
+	l.err = err
+	close(l.closed)
+	l.conn.Close()
+}
+
+func (l *framedLink) readLoop() {
+	br := bufio.NewReader(l.conn)
+	for {
+		id, length, err := readFrameHeader(br)
+		if err != nil {
+			log.Printf("Error reading frame: %s", err.Error())
+			l.fail(err)
+			return
+		}
+		payload := &io.LimitedReader{R: br, N: length}
+
+		l.mu.Lock()
+		p := l.pending[id]
+		delete(l.pending, id)
+		l.mu.Unlock()
+		// p is nil if the request was abandoned.
+		if p != nil {
+			resp, err := readStationResponse(
+				bufio.NewReader(payload), p.r)
+			if err != nil && err != errResponseTooLarge {
+				l.fail(err)
+				return
+			}
+			p.result <- linkResult{resp, err}
+		}
+
+		// Skip whatever is left of the frame.
+		if _, err := io.Copy(ioutil.Discard, payload); err != nil {
+			l.fail(err)
+			return
+		}
+	}
+}
+
+func (l *framedLink) do(r Request) (*Response, error) {
+	var buf bytes.Buffer
+	if err := writeStationRequest(&buf, r); err != nil {
+		return nil, err
+	}
+
+	p := &pendingRequest{r, make(chan linkResult, 1)}
+	l.mu.Lock()
+	if l.err != nil {
+		l.mu.Unlock()
+		return nil, notSentError{l.err}
+	}
+	l.next_id++
+	id := l.next_id
+	l.pending[id] = p
+	l.mu.Unlock()
+	abandon := func() {
+		l.mu.Lock()
+		delete(l.pending, id)
+		l.mu.Unlock()
+	}
+
+	l.write_lock.Lock()
+	l.conn.SetWriteDeadline(time.Now().Add(r.timeout))
+	err := writeFrame(l.conn, id, buf.Bytes())
+	l.write_lock.Unlock()
+	if err != nil {
+		abandon()
+		l.fail(err)
+		return nil, err
+	}
+
+	timer := time.NewTimer(r.timeout)
+	defer timer.Stop()
+	select {
+	case res := <-p.result:
+		return res.resp, res.err
+	case <-timer.C:
+		abandon()
+		return nil, errRequestTimeout
+	case <-r.cancel:
+		abandon()
+		return nil, errRequestCancelled
+	case <-l.closed:
+		abandon()
+		return nil, l.err
+	}
+}
+
+func (l *framedLink) maxInFlight() int {
+	return maxFramedInFlight
+}
+
+func (l *framedLink) close() {
+	l.fail(errors.New("Link closed"))
+}
@@ -0,0 +1,139 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package mux
+
+import "bufio"
+import "bytes"
+import "fmt"
+import "net"
+import "net/http"
+import "testing"
+import "time"
+
+func TestRequestQueue(t *testing.T) {
+	var q requestQueue
+	q.push(Request{priority: PriorityLow, timeout: 1})
+	q.push(Request{priority: PriorityHigh, timeout: 2})
+	q.push(Request{priority: PriorityNormal, timeout: 3})
+	q.push(Request{priority: PriorityHigh, timeout: 4})
+
+	expected := []time.Duration{2, 4, 3, 1}
+	for _, e := range expected {
+		if r := q.pop(); r.timeout != e {
+			t.Errorf("Wrong order: got %d, expected %d", r.timeout, e)
+		}
+	}
+}
+
+// Answers requests in reverse order of arrival, once n have arrived.
+func fakeFramedStation(t *testing.T, conn net.Conn, n int) {
+	br := bufio.NewReader(conn)
+	var ids []uint32
+	var paths []string
+	for i := 0; i < n; i++ {
+		id, payload, err := readFrame(br)
+		if err != nil {
+			t.Errorf("readFrame: %s", err.Error())
+			return
+		}
+		r, err := http.ReadRequest(
+			bufio.NewReader(bytes.NewReader(payload)))
+		if err != nil {
+			t.Errorf("ReadRequest: %s", err.Error())
+			return
+		}
+		ids = append(ids, id)
+		paths = append(paths, r.URL.Path)
+	}
+	for i := n - 1; i >= 0; i-- {
+		body := paths[i]
+		resp := fmt.Sprintf("HTTP/1.1 200 OK\r\n"+
+			"Content-Length: %d\r\n\r\n%s", len(body), body)
+		if err := writeFrame(conn, ids[i], []byte(resp)); err != nil {
+			t.Errorf("writeFrame: %s", err.Error())
+			return
+		}
+	}
+}
+
+func TestFramedLink(t *testing.T) {
+	mux_conn, station_conn := net.Pipe()
+	defer station_conn.Close()
+	go fakeFramedStation(t, station_conn, 2)
+
+	l := newFramedLink(mux_conn)
+	defer l.close()
+
+	done := make(chan string, 2)
+	for _, path := range []string{"/A", "/B"} {
+		go func(path string) {
+			r, _ := http.NewRequest("GET", path, nil)
+			resp, err := l.do(
+				newRequest(r, PriorityNormal, time.Second, nil))
+			if err != nil {
+				t.Errorf("%s: %s", path, err.Error())
+				done <- ""
+				return
+			}
+			if string(resp.data) != path {
+				t.Errorf("Response %s for request %s",
+					resp.data, path)
+			}
+			done <- path
+		}(path)
+	}
+	<-done
+	<-done
+}
+
+func TestFramedLinkCancel(t *testing.T) {
+	mux_conn, station_conn := net.Pipe()
+	defer station_conn.Close()
+	// Never answers.
+	go readFrame(station_conn)
+
+	l := newFramedLink(mux_conn)
+	defer l.close()
+
+	cancel := make(chan bool)
+	close(cancel)
+	r, _ := http.NewRequest("GET", "/Slow", nil)
+	_, err := l.do(newRequest(r, PriorityNormal, time.Minute, cancel))
+	if err != errRequestCancelled {
+		t.Errorf("Expected cancellation, got %v", err)
+	}
+	if len(l.pending) != 0 {
+		t.Errorf("Cancelled request still pending")
+	}
+}
+
+func TestFramedLinkBroken(t *testing.T) {
+	mux_conn, station_conn := net.Pipe()
+	// Reads the request and then disconnects.
+	go func() {
+		readFrame(station_conn)
+		station_conn.Close()
+	}()
+
+	l := newFramedLink(mux_conn)
+	defer l.close()
+
+	// The request is written but the link breaks before the response.
+	r, _ := http.NewRequest("GET", "/ReceiverStart", nil)
+	done := make(chan error)
+	go func() {
+		_, err := l.do(newRequest(r, PriorityNormal, time.Minute, nil))
+		done <- err
+	}()
+	err := <-done
+	if _, ok := err.(notSentError); err == nil || ok {
+		t.Errorf("Expected a link error, got %v", err)
+	}
+
+	// Later requests are never written.
+	_, err = l.do(newRequest(r, PriorityNormal, time.Minute, nil))
+	if _, ok := err.(notSentError); !ok {
+		t.Errorf("Expected notSentError, got %v", err)
+	}
+}
@@ -3,15 +3,19 @@
 
 package mux
 
+import "carpcomm/pb"
 import "net"
 import "net/http"
 import "net/url"
 import "crypto/tls"
 import "bufio"
 import "io"
+import "io/ioutil"
+import "bytes"
 import "encoding/json"
 import "log"
 import "errors"
+import "fmt"
 import "time"
 import "strings"
 
@@ -19,53 +23,107 @@ const keepAlivePingInterval = 20 * time.Minute
 const stationRPCTimeout = 1 * time.Minute
 
 func doStationRPC(conn net.Conn, r *http.Request) (*Response, error) {
-	conn.SetDeadline(time.Now().Add(stationRPCTimeout))
+	return doStationRequest(
+		conn, newRequest(r, PriorityNormal, stationRPCTimeout, nil))
+}
+
+// The body is reset first since the request may be written more than once.
+func writeStationRequest(w io.Writer, r Request) error {
+	if r.body != nil {
+		r.request.Body = ioutil.NopCloser(bytes.NewReader(r.body))
+		r.request.ContentLength = int64(len(r.body))
+	}
+	return r.request.Write(w)
+}
+
+func doStationRequest(conn net.Conn, r Request) (*Response, error) {
+	conn.SetDeadline(time.Now().Add(r.timeout))
 
-	err := r.Write(conn)
+	err := writeStationRequest(conn, r)
 	if err != nil {
 		log.Printf("Error while writing: %s", err.Error())
 		return nil, err
 	}
 
-	http_resp, err := http.ReadResponse(bufio.NewReader(conn), r)
+	return readStationResponse(bufio.NewReader(conn), r)
+}
+
+// The whole body is always read so that the next response can be read from
+// br, even if the body is too large or the stream's reader went away.
+func readStationResponse(br *bufio.Reader, r Request) (*Response, error) {
+	http_resp, err := http.ReadResponse(br, r.request)
 	if err != nil {
 		log.Printf("Error reading response: %s", err.Error())
 		return nil, err
 	}
+	defer http_resp.Body.Close()
 
 	var resp Response
 	resp.code = http_resp.StatusCode
 	resp.data = []byte{}
 
-	buf := make([]byte, 256)
+	too_large := http_resp.ContentLength > r.limit
+	if r.stream != nil && !too_large {
+		r.stream.start(streamHeader{
+			resp.code,
+			http_resp.Header.Get("Content-Type"),
+			http_resp.ContentLength})
+	}
+
+	var n int64
+	var write_err error
+	buf := make([]byte, 32 * 1024)
 	for {
-		n, err := http_resp.Body.Read(buf)
-		if n > 0 {
-			// FIXME: We need a max length limit.
-			resp.data = append(resp.data, buf[:n]...)
+		m, err := http_resp.Body.Read(buf)
+		n += int64(m)
+		if n > r.limit {
+			too_large = true
+		}
+		if m > 0 && !too_large {
+			if r.stream == nil {
+				resp.data = append(resp.data, buf[:m]...)
+			} else if write_err == nil {
+				_, write_err = r.stream.w.Write(buf[:m])
+			}
 		}
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
 			log.Printf("Error while reading body: %s", err.Error())
-			break
+			if r.stream != nil {
+				r.stream.w.CloseWithError(err)
+			}
+			return nil, err
 		}
 	}
 
-	http_resp.Body.Close()
-
+	if too_large {
+		log.Printf("Station response larger than %d bytes: %s",
+			r.limit, r.request.URL.Path)
+		if r.stream != nil {
+			r.stream.w.CloseWithError(errResponseTooLarge)
+		}
+		return nil, errResponseTooLarge
+	}
+	if r.stream != nil {
+		r.stream.w.Close()
+	}
 	return &resp, nil
 }
 
-type identity struct {
-	Version string
-	Client string
-	Station_id string
-	Secret string
+const protocolFramed = "framed"
+
+func supportsProtocol(id *pb.StationIdentity, protocol string) bool {
+	for _, p := range id.Protocols {
+		if p == protocol {
+			return true
+		}
+	}
+	return false
 }
 
-func callIdentify(conn net.Conn) (*identity, error) {
+func callIdentify(conn net.Conn) (*pb.StationIdentity, error) {
 	r, err := http.NewRequest("GET", "/Identify", nil)
 	if err != nil {
 		log.Printf("Error calling Identify: %s", err.Error())
@@ -82,7 +140,7 @@ func callIdentify(conn net.Conn) (*identity, error) {
 		return nil, errors.New("Bad Identify status code")
 	}
 
-	var id identity
+	var id pb.StationIdentity
 	if err = json.Unmarshal(resp.data, &id); err != nil {
 		log.Printf("Bad Identify json response from station: %s",
 			err.Error())
@@ -90,13 +148,14 @@ func callIdentify(conn net.Conn) (*identity, error) {
 	}
 
 	// Avoid logging the secret.
-	d := strings.Replace((string)(resp.data), id.Secret, "SECRET", -1)
+	d := strings.Replace(
+		(string)(resp.data), id.GetSecret(), "SECRET", -1)
 	log.Printf("Identify info: %s", d)
 
 	return &id, nil
 }
 
-func callDisconnect(conn net.Conn, reason string) {
+func callDisconnect(link stationLink, reason string) {
 	log.Printf("Disconnecting station because: %s", reason)
 	u := url.URL{}
 	u.Path = "/Disconnect"
@@ -107,89 +166,251 @@ func callDisconnect(conn net.Conn, reason string) {
 			err.Error())
 		return
 	}
-	doStationRPC(conn, r)
+	link.do(newRequest(r, PriorityHigh, stationRPCTimeout, nil))
 }
 
-func callPing(conn net.Conn) bool {
+// Give the station its session token so that it can resume the session if
+// it reconnects. Older clients don't support this, in which case they just
+// get a new session every time.
+func callSession(conn net.Conn, token string) {
 	u := url.URL{}
-	u.Path = "/Ping"
+	u.Path = "/Session"
+	u.RawQuery = url.Values{"token": {token}}.Encode()
 	r, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		log.Printf("Error ping url: %s", err.Error())
-		return false
+		log.Printf("Error constructing session url: %s", err.Error())
+		return
 	}
 	resp, err := doStationRPC(conn, r)
 	if err != nil {
 		log.Printf("RPC error: %s", err.Error())
-		return false
+		return
 	}
 	if resp.code != 200 {
-		log.Printf("Ping error code: %d", resp.code)
-		return false
+		log.Printf("Session not supported by station: %d", resp.code)
 	}
-	return true
 }
 
-func handleStation(c *Coordinator, conn net.Conn) {
-	defer conn.Close()
-	log.Printf("Station connected.")
-
-	id, err := callIdentify(conn)
-	if err != nil {
-		log.Printf("Error calling Identify: %s", err.Error())
-		return
+// Switch to the framed protocol if the station supports it. Otherwise
+// requests are sent one at a time.
+func startLink(conn net.Conn, id *pb.StationIdentity) stationLink {
+	if !supportsProtocol(id, protocolFramed) {
+		return &legacyLink{conn}
 	}
-	log.Printf("Station identified: %s, %s, %s\n",
-		id.Station_id, id.Version, id.Client)
-
-	ok, err := AuthenticateStation(c.sdb, id.Station_id, id.Secret)
+	r, err := http.NewRequest("GET", "/StartFraming", nil)
 	if err != nil {
-		log.Printf("Authentication error: %s", err.Error())
-		callDisconnect(conn, "internal server error")
-		return
+		log.Printf("Error constructing framing url: %s", err.Error())
+		return &legacyLink{conn}
 	}
-	if !ok {
-		log.Printf("Authentication denied for station.")
-		callDisconnect(conn, "authentication denied")
-		return
+	resp, err := doStationRPC(conn, r)
+	if err != nil || resp.code != 200 {
+		log.Printf("Station failed to start framing.")
+		return &legacyLink{conn}
 	}
+	return newFramedLink(conn)
+}
+
+type requestResult struct {
+	r Request
+	resp *Response
+	err error
+	// Used to measure the station's clock.
+	sent, received time.Time
+}
+
+// Newer stations reply to pings with their clock, e.g. "pong 1357000000.5".
+func parsePong(data []byte) (station_time float64, ok bool) {
+	var t float64
+	n, _ := fmt.Sscanf((string)(data), "pong %f", &t)
+	return t, n == 1
+}
 
-	input := make(chan Request)
-	c.stationConnected(id.Station_id, input)
+// Send requests to the station until the link breaks or the station is
+// disconnected. Requests are sent in order of priority and up to the link's
+// limit at the same time.
+func serveSession(c *Coordinator, station_id string,
+	session *stationSession, link stationLink) {
+	var queue requestQueue
+	in_flight := 0
+	results := make(chan requestResult)
+
+	// Set while a ping is queued or in flight.
+	var ping_response chan *Response
 
 	keepalive := time.NewTicker(keepAlivePingInterval)
 	defer keepalive.Stop()
 
+	// Requests that haven't been sent yet are sent if the station resumes
+	// the session.
+	suspend := func(reason string) {
+		link.close()
+		c.stationSuspended(station_id, session, reason)
+		for queue.Len() > 0 {
+			r := queue.pop()
+			if r.response != ping_response {
+				session.requeue(r)
+			}
+		}
+		for ; in_flight > 0; in_flight-- {
+			res := <-results
+			if res.r.response == ping_response {
+				continue
+			}
+			if res.err == nil {
+				res.r.response <- res.resp
+			} else {
+				session.retry(res.r, res.err)
+			}
+		}
+	}
+
+	// Tell the station why it's being disconnected. This is skipped if
+	// the link can't take another request at the moment since a
+	// legacyLink would write it to the connection in the middle of the
+	// request in flight.
+	disconnect := func(reason string) {
+		if in_flight < link.maxInFlight() {
+			callDisconnect(link, reason)
+		}
+	}
+
 	for {
+		for in_flight < link.maxInFlight() && queue.Len() > 0 {
+			r := queue.pop()
+			if r.cancelled() {
+				r.response <- nil
+				continue
+			}
+			in_flight++
+			go func(r Request) {
+				sent := time.Now()
+				resp, err := link.do(r)
+				results <- requestResult{
+					r, resp, err, sent, time.Now()}
+			}(r)
+		}
+
 		select {
-		case r := <-input:
+		case r := <-session.input:
 			if r.request == nil {
 				// nil request means we should disconnect
-				callDisconnect(conn, "disconnected by server")
-				c.stationDisconnected(id.Station_id)
+				disconnect("disconnected by server")
+				suspend("replaced by a new connection")
 				r.response <- nil
 				return
 			}
+			queue.push(r)
 
-			resp, err := doStationRPC(conn, r.request)
-			r.response <- resp
-			if err != nil {
+		case res := <-results:
+			in_flight--
+			if res.r.response == ping_response {
+				ping_response = nil
+				if res.err != nil || res.resp.code != 200 {
+					disconnect("ping time out")
+					suspend("ping time out")
+					log.Printf("Station connection timed out.")
+					return
+				}
+				if t, ok := parsePong(res.resp.data); ok {
+					c.addClockSample(station_id, newClockSample(
+						t, res.sent, res.received))
+				}
+				continue
+			}
+
+			switch res.err {
+			case nil:
+				res.r.response <- res.resp
+			case errRequestTimeout, errRequestCancelled,
+				errResponseTooLarge:
+				log.Printf("%s: %s", station_id, res.err.Error())
+				res.r.response <- nil
+			default:
 				log.Printf(
 					"Error during station RPC: %s",
-					err.Error())
-				c.stationDisconnected(id.Station_id)
+					res.err.Error())
+				// Try again once the station reconnects if it's
+				// safe.
+				session.retry(res.r, res.err)
+				suspend("RPC error: " + res.err.Error())
 				return
 			}
 
 		case <- keepalive.C:
-			if !callPing(conn) {
-				callDisconnect(conn, "ping time out")
-				c.stationDisconnected(id.Station_id)
-				log.Printf("Station connection timed out.")
-				return
+			if ping_response != nil {
+				continue
+			}
+			r, err := http.NewRequest("GET", "/Ping", nil)
+			if err != nil {
+				log.Printf("Error ping url: %s", err.Error())
+				continue
 			}
+			ping := newRequest(r, PriorityHigh, stationRPCTimeout, nil)
+			ping_response = ping.response
+			queue.push(ping)
+		}
+	}
+}
+
+func handleStation(c *Coordinator, conn net.Conn) {
+	defer conn.Close()
+	log.Printf("Station connected.")
+
+	// Finish the handshake first so that it doesn't count towards
+	// Identify's round trip.
+	if tls_conn, ok := conn.(*tls.Conn); ok {
+		tls_conn.SetDeadline(time.Now().Add(stationRPCTimeout))
+		if err := tls_conn.Handshake(); err != nil {
+			log.Printf("TLS handshake error: %s", err.Error())
+			return
 		}
 	}
+
+	sent := time.Now()
+	id, err := callIdentify(conn)
+	if err != nil {
+		log.Printf("Error calling Identify: %s", err.Error())
+		return
+	}
+	received := time.Now()
+	station_id := id.GetStationId()
+	log.Printf("Station identified: %s, %s, %s, protocol version %d\n",
+		station_id, id.GetVersion(), id.GetClient(),
+		id.GetProtocolVersion())
+
+	ok, err := AuthenticateStation(c.sdb, station_id, id.GetSecret(),
+		"mux "+conn.RemoteAddr().String())
+	if err != nil {
+		log.Printf("Authentication error: %s", err.Error())
+		callDisconnect(&legacyLink{conn}, "internal server error")
+		return
+	}
+	if !ok {
+		log.Printf("Authentication denied for station.")
+		callDisconnect(&legacyLink{conn}, "authentication denied")
+		return
+	}
+
+	session, resumed, err := c.stationConnected(
+		station_id, id.GetSession())
+	if err != nil {
+		log.Printf("Error starting session: %s", err.Error())
+		callDisconnect(&legacyLink{conn}, "internal server error")
+		return
+	}
+	c.setStationIdentity(session, id)
+	if id.StationTime != nil {
+		c.addClockSample(station_id,
+			newClockSample(id.GetStationTime(), sent, received))
+	}
+	if resumed {
+		log.Printf("Station resumed session: %s", station_id)
+	} else {
+		go c.pollHealth(station_id, session)
+	}
+	callSession(conn, session.token)
+
+	serveSession(c, station_id, session, startLink(conn, id))
 }
 
 func ListenAndServe(c *Coordinator, cert_file, private_key, port string) {
@@ -2,6 +2,7 @@ package main
 
 import "carpcomm/db"
 import "carpcomm/mux"
+import "carpcomm/scheduler/events"
 import "net/http"
 import "net/rpc"
 import "log"
@@ -22,6 +23,18 @@ var external_port = flag.String(
 	"external_port", ":1234", "External mux port")
 var db_prefix = flag.String("db_prefix", "r1-", "Database table prefix")
 
+// Let the scheduler know immediately instead of waiting for it to poll.
+func notifyScheduler(station_id string, connected bool) {
+	t := events.StationDisconnected
+	if connected {
+		t = events.StationConnected
+	}
+	if err := events.Notify(station_id, t); err != nil {
+		log.Printf("%s: Error notifying scheduler: %s",
+			station_id, err.Error())
+	}
+}
+
 func main() {
 	flag.Parse()
 	log.Printf("Starting multiplexer.")
@@ -32,10 +45,12 @@ func main() {
 	}
 	stationdb := domain.NewStationDB()
 	c := mux.NewCoordinator(stationdb)
+	c.SetStationEventHandler(notifyScheduler)
 
 	go mux.ListenAndServe(c, *cert_file, *private_key_file, *external_port)
 
 	rpc.Register(c)
 	rpc.HandleHTTP()
+	mux.HandleStreamHTTP(c)
 	http.ListenAndServe(*rpc_port, nil)
 }
\ No newline at end of file
@@ -3,6 +3,8 @@
 
 package mux
 
+import "time"
+
 
 type StationCountArgs struct {
 }
@@ -26,15 +28,137 @@ type StationStatusArgs struct {
 
 type StationStatusResult struct {
 	IsConnected bool
+
+	// The station lost its connection recently but may still resume its
+	// session.
+	IsSuspended bool
+
+	// carpsd release and control protocol version reported by the
+	// station. ProtocolVersion is 0 for stations that only support URL
+	// actions.
+	SoftwareVersion string
+	ProtocolVersion int32
+}
+
+
+type StationConnectionHistoryArgs struct {
+	StationId string
 }
 
+type StationConnectionHistoryResult struct {
+	Events []ConnectionEvent  // oldest first
+}
+
+
+// Requests to a station are sent in order of priority.
+const (
+	// Depends on the action.
+	PriorityDefault = iota
+	PriorityLow
+	PriorityNormal
+	PriorityHigh
+)
 
 type StationCallArgs struct {
 	StationId string
 	URL string
+
+	// Required for control actions if the station is leased.
+	LeaseToken string
+
+	Priority int
+	// Includes the time spent waiting for other requests. Zero means the
+	// default.
+	Timeout time.Duration
+
+	// Lowers the limit on the size of the response body. Zero means the
+	// default for the action.
+	MaxResponseBytes int64
 }
 
 type StationCallResult struct {
 	StatusCode int
 	Data []byte
-}
\ No newline at end of file
+}
+
+
+type StationControlArgs struct {
+	StationId string
+	LeaseToken string
+
+	Request []byte  // serialized pb.StationRequest
+
+	Priority int
+	Timeout time.Duration
+}
+
+type StationControlResult struct {
+	Response []byte  // serialized pb.StationResponse
+}
+
+
+type StationHealthArgs struct {
+	StationId string
+}
+
+type StationHealthResult struct {
+	// False if the station hasn't reported its health since the mux
+	// started.
+	Reported bool
+	Health []byte  // serialized pb.StationHealth
+}
+
+
+type StationClockArgs struct {
+	StationId string
+}
+
+type StationClockResult struct {
+	// False if the station hasn't sent a timestamp since the mux started.
+	Known bool
+	Estimate ClockEstimate
+}
+
+
+type StationAcquireLeaseArgs struct {
+	StationId string
+	Holder string
+	Description string
+	Duration time.Duration
+}
+
+type StationAcquireLeaseResult struct {
+	Granted bool
+	// If not granted, the current lease without its token.
+	Lease Lease
+}
+
+
+type StationReleaseLeaseArgs struct {
+	StationId string
+	Token string
+}
+
+type StationReleaseLeaseResult struct {
+}
+
+
+type StationLeaseStatusArgs struct {
+	StationId string
+}
+
+type StationLeaseStatusResult struct {
+	Held bool
+	Lease Lease  // without the token
+}
+
+
+type StationRequestPreemptionArgs struct {
+	StationId string
+	RequestedBy string
+}
+
+type StationRequestPreemptionResult struct {
+	Held bool
+	Lease Lease  // without the token
+}
@@ -0,0 +1,58 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package mux
+
+import "container/heap"
+
+// Controlling the station shouldn't have to wait for slow requests like
+// waterfall images.
+func defaultPriority(action string) int {
+	if IsControlAction(action) {
+		return PriorityHigh
+	}
+	if action == "ReceiverWaterfallPNG" {
+		return PriorityLow
+	}
+	return PriorityNormal
+}
+
+// Requests waiting to be sent to a station, highest priority first and in
+// arrival order for equal priorities.
+type requestQueue struct {
+	requests []Request
+	next_seq uint64
+}
+
+func (q *requestQueue) Len() int {
+	return len(q.requests)
+}
+func (q *requestQueue) Less(i, j int) bool {
+	a, b := q.requests[i], q.requests[j]
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.seq < b.seq
+}
+func (q *requestQueue) Swap(i, j int) {
+	q.requests[i], q.requests[j] = q.requests[j], q.requests[i]
+}
+func (q *requestQueue) Push(x interface{}) {
+	q.requests = append(q.requests, x.(Request))
+}
+func (q *requestQueue) Pop() interface{} {
+	n := len(q.requests)
+	r := q.requests[n-1]
+	q.requests = q.requests[:n-1]
+	return r
+}
+
+func (q *requestQueue) push(r Request) {
+	r.seq = q.next_seq
+	q.next_seq++
+	heap.Push(q, r)
+}
+
+func (q *requestQueue) pop() Request {
+	return heap.Pop(q).(Request)
+}
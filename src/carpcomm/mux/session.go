@@ -0,0 +1,189 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package mux
+
+import "carpcomm/db"
+import "carpcomm/pb"
+import "log"
+import "time"
+
+// How long a station that lost its connection has to resume its session.
+const sessionResumeTimeout = 2 * time.Minute
+
+// Number of connection events kept for each station.
+const connectionHistoryLength = 50
+
+// How many times a request is retried if the connection fails before it's
+// sent.
+const maxRequestRetries = 1
+
+const (
+	connectionEventConnected = "connected"
+	connectionEventResumed = "resumed"
+	connectionEventSuspended = "suspended"
+	connectionEventDisconnected = "disconnected"
+)
+
+type ConnectionEvent struct {
+	Timestamp time.Time
+	Type string
+	Reason string
+}
+
+// A station session survives brief disconnections. Requests are queued on
+// input until the station reconnects with the session token or the session
+// expires.
+type stationSession struct {
+	token string
+	input chan Request
+	closed chan bool  // closed when the session ends
+
+	// The following are protected by Coordinator.stations_lock.
+	connected bool
+	expiry *time.Timer  // set while suspended
+	// From the station's most recent connection.
+	identity *pb.StationIdentity
+}
+
+func newStationSession() (*stationSession, error) {
+	token, err := db.CryptoRandId()
+	if err != nil {
+		return nil, err
+	}
+	return &stationSession{
+		token: token,
+		input: make(chan Request),
+		closed: make(chan bool),
+		connected: true,
+	}, nil
+}
+
+// Queue a request. Returns false if the session ends or the request is
+// cancelled first.
+func (s *stationSession) send(r Request) bool {
+	select {
+	case s.input <- r:
+		return true
+	case <-s.closed:
+		return false
+	case <-r.cancel:
+		return false
+	}
+}
+
+// Called when the connection failed during the RPC. The station may already
+// have run the request, e.g. started the motor, so it's only sent again
+// once the station resumes if none of it was written. Otherwise the caller
+// gets no response.
+func (s *stationSession) retry(r Request, err error) {
+	if _, ok := err.(notSentError); !ok {
+		r.response <- nil
+		return
+	}
+	r.retries++
+	if r.retries > maxRequestRetries {
+		r.response <- nil
+		return
+	}
+	s.requeue(r)
+}
+
+// Queue a request again that hasn't been sent yet. Doesn't block.
+func (s *stationSession) requeue(r Request) {
+	go func() {
+		if !s.send(r) {
+			r.response <- nil
+		}
+	}()
+}
+
+// Must be called with stations_lock held.
+func (c *Coordinator) addConnectionEvent(station_id, t, reason string) {
+	e := ConnectionEvent{time.Now(), t, reason}
+	h := append(c.history[station_id], e)
+	if len(h) > connectionHistoryLength {
+		h = h[len(h)-connectionHistoryLength:]
+	}
+	c.history[station_id] = h
+}
+
+// Must be called with stations_lock held.
+func (c *Coordinator) endSession(
+	station_id string, s *stationSession, reason string) {
+	if s.expiry != nil {
+		s.expiry.Stop()
+	}
+	delete(c.stations, station_id)
+	close(s.closed)
+	c.addConnectionEvent(station_id, connectionEventDisconnected, reason)
+	c.notifyStationEvent(station_id, false)
+}
+
+// Called once the station is authenticated. If the token matches the
+// station's current session, the session is resumed. Otherwise a new session
+// is started.
+func (c *Coordinator) stationConnected(station_id, token string) (
+	session *stationSession, resumed bool, err error) {
+	// If the station is already connected, disconnect it first.
+	c.stations_lock.RLock()
+	existing := c.stations[station_id]
+	already_connected := existing != nil && existing.connected
+	c.stations_lock.RUnlock()
+	if already_connected {
+		log.Printf("Disconnecting duplicate existing station.")
+		r := newRequest(nil, PriorityHigh, stationRPCTimeout, nil)
+		if existing.send(r) {
+			<- r.response
+		}
+	}
+
+	c.stations_lock.Lock()
+	defer c.stations_lock.Unlock()
+
+	existing = c.stations[station_id]
+	if existing != nil && existing.connected {
+		log.Printf("Station already connected. This shouldn't happen!")
+	}
+	if existing != nil && token != "" && existing.token == token {
+		if existing.expiry != nil {
+			existing.expiry.Stop()
+			existing.expiry = nil
+		}
+		existing.connected = true
+		c.addConnectionEvent(station_id, connectionEventResumed, "")
+		return existing, true, nil
+	}
+	if existing != nil {
+		c.endSession(station_id, existing, "replaced by a new session")
+	}
+
+	session, err = newStationSession()
+	if err != nil {
+		return nil, false, err
+	}
+	c.stations[station_id] = session
+	c.addConnectionEvent(station_id, connectionEventConnected, "")
+	c.notifyStationEvent(station_id, true)
+	return session, false, nil
+}
+
+// Called when the station's connection is lost. The session ends unless the
+// station reconnects within sessionResumeTimeout.
+func (c *Coordinator) stationSuspended(
+	station_id string, s *stationSession, reason string) {
+	c.stations_lock.Lock()
+	defer c.stations_lock.Unlock()
+	if c.stations[station_id] != s {
+		return
+	}
+	s.connected = false
+	c.addConnectionEvent(station_id, connectionEventSuspended, reason)
+	s.expiry = time.AfterFunc(sessionResumeTimeout, func() {
+		c.stations_lock.Lock()
+		defer c.stations_lock.Unlock()
+		if c.stations[station_id] == s && !s.connected {
+			c.endSession(station_id, s, "session expired")
+		}
+	})
+}
@@ -0,0 +1,108 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package mux
+
+import "errors"
+import "net/http"
+import "testing"
+
+func historyTypes(c *Coordinator, station_id string) (types []string) {
+	var result StationConnectionHistoryResult
+	c.StationConnectionHistory(
+		&StationConnectionHistoryArgs{station_id}, &result)
+	for _, e := range result.Events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestSessionResume(t *testing.T) {
+	c := NewCoordinator(nil)
+
+	s, resumed, err := c.stationConnected("station", "")
+	if err != nil || resumed {
+		t.Fatalf("stationConnected: %v, %v", resumed, err)
+	}
+	c.stationSuspended("station", s, "test")
+
+	var status StationStatusResult
+	c.StationStatus(&StationStatusArgs{"station"}, &status)
+	if status.IsConnected || !status.IsSuspended {
+		t.Errorf("Wrong status: %+v", status)
+	}
+
+	// Calls are queued while the station is suspended.
+	r, _ := http.NewRequest("GET", "/Ping", nil)
+	done := make(chan *Response)
+	go func() {
+		done <- c.call("station", newRequest(
+			r, PriorityNormal, stationRPCTimeout, nil))
+	}()
+
+	s2, resumed, err := c.stationConnected("station", s.token)
+	if err != nil || !resumed || s2 != s {
+		t.Fatalf("Session not resumed: %v, %v", resumed, err)
+	}
+	req := <-s.input
+	req.response <- &Response{200, nil}
+	if resp := <-done; resp == nil || resp.code != 200 {
+		t.Errorf("Wrong response: %v", resp)
+	}
+
+	// A wrong token starts a new session.
+	c.stationSuspended("station", s, "test")
+	s3, resumed, err := c.stationConnected("station", "wrong")
+	if err != nil || resumed || s3 == s {
+		t.Fatalf("Session resumed with the wrong token")
+	}
+	select {
+	case <-s.closed:
+	default:
+		t.Errorf("Old session not closed")
+	}
+
+	expected := []string{"connected", "suspended", "resumed", "suspended",
+		"disconnected", "connected"}
+	types := historyTypes(c, "station")
+	if len(types) != len(expected) {
+		t.Fatalf("Wrong history: %v", types)
+	}
+	for i := range expected {
+		if types[i] != expected[i] {
+			t.Errorf("Wrong history: %v", types)
+			break
+		}
+	}
+}
+
+func TestSessionRetry(t *testing.T) {
+	s, _ := newStationSession()
+	r := newRequest(nil, PriorityNormal, stationRPCTimeout, nil)
+	not_sent := notSentError{errors.New("Link closed")}
+
+	s.retry(r, not_sent)
+	r2 := <-s.input
+	if r2.retries != 1 {
+		t.Errorf("Wrong retries: %d", r2.retries)
+	}
+
+	// Give up after too many retries.
+	go s.retry(r2, not_sent)
+	if resp := <-r.response; resp != nil {
+		t.Errorf("Expected nil response")
+	}
+
+	// The station may already have run a request that was written so it
+	// fails instead of running again.
+	r = newRequest(nil, PriorityNormal, stationRPCTimeout, nil)
+	s.retry(r, errors.New("Connection reset"))
+	select {
+	case resp := <-r.response:
+		if resp != nil {
+			t.Errorf("Expected nil response")
+		}
+	case r2 = <-s.input:
+		t.Errorf("Sent request was retried")
+	}
+}
@@ -0,0 +1,225 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package mux
+
+import "errors"
+import "flag"
+import "fmt"
+import "io"
+import "log"
+import "net/http"
+import "net/url"
+import "strconv"
+import "strings"
+import "sync"
+import "time"
+
+var station_response_limit = flag.Int64(
+	"station_response_limit", 1024 * 1024,
+	"Default maximum size in bytes of station RPC response bodies")
+var station_response_limits = flag.String(
+	"station_response_limits", "ReceiverWaterfallPNG=16777216",
+	"Comma-separated list of action=bytes overriding "+
+		"station_response_limit for particular actions")
+
+// The request fails if the response body is larger than its limit.
+var errResponseTooLarge = errors.New("Station response too large")
+
+var response_limits map[string]int64
+var response_limits_once sync.Once
+
+func parseResponseLimits(s string) (map[string]int64, error) {
+	limits := make(map[string]int64)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.New(fmt.Sprintf(
+				"Invalid response limit: %s", entry))
+		}
+		n, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || n < 0 {
+			return nil, errors.New(fmt.Sprintf(
+				"Invalid response limit: %s", entry))
+		}
+		limits[parts[0]] = n
+	}
+	return limits, nil
+}
+
+// Maximum size of the response body for a station action.
+func responseLimit(action string) int64 {
+	response_limits_once.Do(func() {
+		limits, err := parseResponseLimits(*station_response_limits)
+		if err != nil {
+			log.Printf("Ignoring station_response_limits: %s",
+				err.Error())
+		}
+		response_limits = limits
+	})
+	if n, ok := response_limits[action]; ok {
+		return n
+	}
+	return *station_response_limit
+}
+
+type streamHeader struct {
+	code int
+	content_type string
+	content_length int64  // -1 if unknown
+}
+
+// Receives the body of a response as it arrives from the station instead of
+// buffering it in Response.data.
+type responseStream struct {
+	// Sent once, before any of the body is written.
+	header chan streamHeader
+	r *io.PipeReader
+	w *io.PipeWriter
+}
+
+func newResponseStream() *responseStream {
+	r, w := io.Pipe()
+	return &responseStream{make(chan streamHeader, 1), r, w}
+}
+
+func (s *responseStream) start(h streamHeader) {
+	select {
+	case s.header <- h:
+	default:
+	}
+}
+
+// Path of the streaming variant of Coordinator.StationCall on the mux's RPC
+// port.
+const StationStreamPath = "/StationStream"
+
+func encodeStationCallArgs(args StationCallArgs) string {
+	v := url.Values{}
+	v.Set("station_id", args.StationId)
+	v.Set("url", args.URL)
+	v.Set("lease_token", args.LeaseToken)
+	v.Set("priority", strconv.Itoa(args.Priority))
+	v.Set("timeout", args.Timeout.String())
+	v.Set("max_response_bytes",
+		strconv.FormatInt(args.MaxResponseBytes, 10))
+	return v.Encode()
+}
+
+func decodeStationCallArgs(v url.Values) (args StationCallArgs, err error) {
+	args.StationId = v.Get("station_id")
+	args.URL = v.Get("url")
+	args.LeaseToken = v.Get("lease_token")
+	if s := v.Get("priority"); s != "" {
+		if args.Priority, err = strconv.Atoi(s); err != nil {
+			return args, err
+		}
+	}
+	if s := v.Get("timeout"); s != "" {
+		if args.Timeout, err = time.ParseDuration(s); err != nil {
+			return args, err
+		}
+	}
+	if s := v.Get("max_response_bytes"); s != "" {
+		args.MaxResponseBytes, err = strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return args, err
+		}
+	}
+	return args, nil
+}
+
+// Like StationCall except that the response body is forwarded as it arrives
+// from the station. This is meant for large responses like waterfall images
+// that we don't want to hold in memory.
+func (c *Coordinator) serveStationStream(
+	w http.ResponseWriter, r *http.Request) {
+	args, err := decodeStationCallArgs(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cancel := make(chan bool)
+	req, err := c.newCallRequest(&args, cancel)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	timer := time.AfterFunc(req.timeout, func() { close(cancel) })
+	defer timer.Stop()
+
+	stream := newResponseStream()
+	req.stream = stream
+	// Part of the body may already have been forwarded if the connection
+	// breaks so the request can't be retried.
+	req.retries = maxRequestRetries
+	// Unblocks the station link if we stop reading early.
+	defer stream.r.Close()
+
+	done := make(chan *Response, 1)
+	go func() {
+		done <- c.call(args.StationId, req)
+	}()
+
+	var h streamHeader
+	select {
+	case h = <-stream.header:
+	case <-done:
+		select {
+		case h = <-stream.header:
+		default:
+			http.Error(w, "Station RPC error", http.StatusBadGateway)
+			return
+		}
+	}
+
+	if h.content_type != "" {
+		w.Header().Set("Content-Type", h.content_type)
+	}
+	if h.content_length >= 0 {
+		w.Header().Set("Content-Length",
+			strconv.FormatInt(h.content_length, 10))
+	}
+	w.WriteHeader(h.code)
+	if _, err := io.Copy(w, stream.r); err != nil {
+		log.Printf("%s: Error streaming station response: %s",
+			args.StationId, err.Error())
+	}
+}
+
+// HandleStreamHTTP registers the streaming variant of StationCall on
+// http.DefaultServeMux.
+func HandleStreamHTTP(c *Coordinator) {
+	http.HandleFunc(StationStreamPath, c.serveStationStream)
+}
+
+// StreamStation is like CallStation except that the response body is read
+// from the station as the caller consumes it. mux_address is the mux's RPC
+// address. The caller must close the response body.
+func StreamStation(mux_address string,
+	station_id, lease_token string, action string, params url.Values) (
+	*http.Response, error) {
+
+	var args StationCallArgs
+	args.StationId = station_id
+	args.LeaseToken = lease_token
+	u := url.URL{}
+	u.Path = "/" + action
+	if params != nil {
+		u.RawQuery = params.Encode()
+	}
+	args.URL = u.String()
+
+	resp, err := http.Get(fmt.Sprintf("http://%s%s?%s",
+		mux_address, StationStreamPath, encodeStationCallArgs(args)))
+	if err != nil {
+		log.Printf("StationStream error: %s", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,97 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package mux
+
+import "bufio"
+import "io/ioutil"
+import "net"
+import "net/http"
+import "net/http/httptest"
+import "strings"
+import "testing"
+
+func TestParseResponseLimits(t *testing.T) {
+	limits, err := parseResponseLimits("A=10, B=0,")
+	if err != nil {
+		t.Fatalf("parseResponseLimits: %s", err.Error())
+	}
+	if len(limits) != 2 || limits["A"] != 10 || limits["B"] != 0 {
+		t.Errorf("Wrong limits: %v", limits)
+	}
+
+	for _, s := range []string{"A", "A=x", "A=-1"} {
+		if _, err := parseResponseLimits(s); err == nil {
+			t.Errorf("Expected error for %s", s)
+		}
+	}
+}
+
+func TestReadStationResponseLimit(t *testing.T) {
+	br := bufio.NewReader(strings.NewReader(
+		"HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello" +
+			"HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"))
+	r, _ := http.NewRequest("GET", "/Test", nil)
+	req := newRequest(r, PriorityNormal, stationRPCTimeout, nil)
+
+	req.limit = 4
+	if _, err := readStationResponse(br, req); err != errResponseTooLarge {
+		t.Errorf("Expected errResponseTooLarge, got %v", err)
+	}
+
+	// The rest of the large response was skipped.
+	resp, err := readStationResponse(br, req)
+	if err != nil {
+		t.Fatalf("readStationResponse: %s", err.Error())
+	}
+	if string(resp.data) != "ok" {
+		t.Errorf("Wrong data: %s", resp.data)
+	}
+}
+
+func TestServeStationStream(t *testing.T) {
+	c := NewCoordinator(nil)
+	s, _, err := c.stationConnected("station", "")
+	if err != nil {
+		t.Fatalf("stationConnected: %s", err.Error())
+	}
+
+	mux_conn, station_conn := net.Pipe()
+	defer station_conn.Close()
+	go fakeFramedStation(t, station_conn, 1)
+	go serveSession(c, "station", s, newFramedLink(mux_conn))
+
+	r, _ := http.NewRequest("GET", StationStreamPath+"?"+
+		encodeStationCallArgs(StationCallArgs{
+			StationId: "station",
+			URL: "/ReceiverWaterfallPNG",
+		}), nil)
+	w := httptest.NewRecorder()
+	c.serveStationStream(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Wrong status code: %d", w.Code)
+	}
+	body, _ := ioutil.ReadAll(w.Body)
+	if string(body) != "/ReceiverWaterfallPNG" {
+		t.Errorf("Wrong body: %s", body)
+	}
+	if w.HeaderMap.Get("Content-Length") != "21" {
+		t.Errorf("Wrong Content-Length: %s",
+			w.HeaderMap.Get("Content-Length"))
+	}
+}
+
+func TestServeStationStreamNotConnected(t *testing.T) {
+	c := NewCoordinator(nil)
+	r, _ := http.NewRequest("GET", StationStreamPath+"?"+
+		encodeStationCallArgs(StationCallArgs{
+			StationId: "station",
+			URL: "/Ping",
+		}), nil)
+	w := httptest.NewRecorder()
+	c.serveStationStream(w, r)
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("Wrong status code: %d", w.Code)
+	}
+}
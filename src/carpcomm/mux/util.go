@@ -8,15 +8,23 @@ import "net/url"
 import "log"
 import "errors"
 import "fmt"
-import "encoding/json"
+import "strconv"
 import "time"
+import "carpcomm/pb"
 import "carpcomm/util"
+import "code.google.com/p/goprotobuf/proto"
 
 const stationCallRPC = "Coordinator.StationCall"
+const stationControlRPC = "Coordinator.StationControl"
+
+const stationPing = "Ping"
+const stationGetStatus = "GetStatus"
+const stationGetHealth = "GetHealth"
 
 const stationReceiverSetFrequency = "ReceiverSetFrequency"
 const stationReceiverStart = "ReceiverStart"
 const stationReceiverStop = "ReceiverStop"
+const stationReceiverFrequencyProgram = "ReceiverFrequencyProgram"
 
 const stationTNCStart = "TNCStart"
 const stationTNCStop = "TNCStop"
@@ -27,12 +35,14 @@ const stationMotorStop = "MotorStop"
 const stationStatusCodeOk = 200
 
 
+// lease_token is required for control actions if the station is leased.
 func CallStation(mux_client *rpc.Client,
-	station_id string, action string, params url.Values) (
+	station_id, lease_token string, action string, params url.Values) (
 	StationCallResult, error) {
 
 	var args StationCallArgs
 	args.StationId = station_id
+	args.LeaseToken = lease_token
 	u := url.URL{}
 	u.Path = "/" + action
 	if params != nil {
@@ -51,8 +61,9 @@ func CallStation(mux_client *rpc.Client,
 }
 
 func CallStationAndCheckStatus(mux_client *rpc.Client,
-	station_id string, action string, params url.Values) error {
-	result, err := CallStation(mux_client, station_id, action, params)
+	station_id, lease_token string, action string, params url.Values) error {
+	result, err := CallStation(
+		mux_client, station_id, lease_token, action, params)
 	if err != nil {
 		return err
 	}
@@ -64,32 +75,135 @@ func CallStationAndCheckStatus(mux_client *rpc.Client,
 }
 
 
+// StationControl sends a typed request to the station.
+// lease_token is required for control actions if the station is leased.
+func StationControl(mux_client *rpc.Client,
+	station_id, lease_token string, req *pb.StationRequest) (
+	*pb.StationResponse, error) {
+
+	var args StationControlArgs
+	args.StationId = station_id
+	args.LeaseToken = lease_token
+	var err error
+	args.Request, err = proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result StationControlResult
+	err = mux_client.Call(stationControlRPC, args, &result)
+	if err != nil {
+		log.Printf("StationControl error: %s", err.Error())
+		return nil, err
+	}
+
+	resp := &pb.StationResponse{}
+	if err = proto.Unmarshal(result.Response, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func StationControlAndCheckStatus(mux_client *rpc.Client,
+	station_id, lease_token string, req *pb.StationRequest) error {
+	resp, err := StationControl(mux_client, station_id, lease_token, req)
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != pb.StationResponse_OK {
+		return errors.New(fmt.Sprintf(
+			"Station returned %s: %s",
+			resp.GetStatus().String(), resp.GetError()))
+	}
+	return nil
+}
+
+func newStationRequest(t pb.StationRequest_Type) *pb.StationRequest {
+	return &pb.StationRequest{Type: t.Enum()}
+}
+
+// StationGetStatus returns the state of the station's devices. Stations
+// using an older protocol version don't support this.
+func StationGetStatus(mux_client *rpc.Client, station_id string) (
+	*pb.StationStatus, error) {
+	resp, err := StationControl(mux_client, station_id, "",
+		newStationRequest(pb.StationRequest_GET_STATUS))
+	if err != nil {
+		return nil, err
+	}
+	if resp.GetStatus() != pb.StationResponse_OK {
+		return nil, errors.New(fmt.Sprintf(
+			"Station returned %s: %s",
+			resp.GetStatus().String(), resp.GetError()))
+	}
+	return resp.StationStatus, nil
+}
+
 func StationReceiverSetFrequency(mux_client *rpc.Client,
-	station_id string,
+	station_id, lease_token string,
 	freq_hz int64) error {
 
-	params := url.Values{}
-	params.Add("hz", fmt.Sprintf("%d", freq_hz))
-	return CallStationAndCheckStatus(
-		mux_client, station_id, stationReceiverSetFrequency, params)
+	req := newStationRequest(pb.StationRequest_RECEIVER_SET_FREQUENCY)
+	req.FrequencyHz = proto.Int64(freq_hz)
+	return StationControlAndCheckStatus(
+		mux_client, station_id, lease_token, req)
 }
 
-func StationReceiverStart(
-	mux_client *rpc.Client, station_id, stream_url string) error {
+type FrequencyCoordinate struct {
+	Timestamp float64
+	FrequencyHz int64
+}
+
+// StationReceiverFrequencyProgram makes the station retune its receiver at
+// the given times, e.g. to follow the Doppler shift during a pass. The
+// program is stopped by StationReceiverStop.
+func StationReceiverFrequencyProgram(mux_client *rpc.Client,
+	station_id, lease_token string,
+	program []FrequencyCoordinate) error {
+
+	if len(program) == 0 {
+		return errors.New("Empty frequency program.")
+	}
+	req := frequencyProgramRequest(program, time.Now())
+	return StationControlAndCheckStatus(
+		mux_client, station_id, lease_token, req)
+}
+
+// The absolute timestamps are sent so that the mux can convert them to the
+// station's clock. The offsets are for stations without a clock estimate.
+func frequencyProgramRequest(
+	program []FrequencyCoordinate, now time.Time) *pb.StationRequest {
+	req := newStationRequest(pb.StationRequest_RECEIVER_FREQUENCY_PROGRAM)
+	start_t := unixSeconds(now)
+	for _, c := range program {
+		req.FrequencyProgram = append(req.FrequencyProgram,
+			&pb.FrequencyCoordinate{
+				Timestamp: proto.Float64(c.Timestamp),
+				OffsetS: proto.Float64(c.Timestamp - start_t),
+				FrequencyHz: proto.Int64(c.FrequencyHz),
+			})
+	}
+	return req
+}
 
-	params := url.Values{}
-	params.Add("stream_url", stream_url)
-	return CallStationAndCheckStatus(
-		mux_client, station_id, stationReceiverStart, params)
+func StationReceiverStart(mux_client *rpc.Client,
+	station_id, lease_token, stream_url string) error {
+
+	req := newStationRequest(pb.StationRequest_RECEIVER_START)
+	req.StreamUrl = proto.String(stream_url)
+	return StationControlAndCheckStatus(
+		mux_client, station_id, lease_token, req)
 }
 
-func StationReceiverStop(mux_client *rpc.Client, station_id string) error {
-	return CallStationAndCheckStatus(
-		mux_client, station_id, stationReceiverStop, nil)
+func StationReceiverStop(mux_client *rpc.Client,
+	station_id, lease_token string) error {
+	return StationControlAndCheckStatus(
+		mux_client, station_id, lease_token,
+		newStationRequest(pb.StationRequest_RECEIVER_STOP))
 }
 
 func StationTNCStart(
-	mux_client *rpc.Client, station_id string,
+	mux_client *rpc.Client, station_id, lease_token string,
 	api_server string,
 	satellite_id string) error {
 
@@ -97,19 +211,24 @@ func StationTNCStart(
 	if err != nil {
 		return err
 	}
+	port_num, err := strconv.Atoi(port)
+	if err != nil {
+		return err
+	}
 
-	params := url.Values{}
-	params.Add("api_host", host)
-	params.Add("api_port", port)
-	params.Add("satellite_id", satellite_id)
-
-	return CallStationAndCheckStatus(
-		mux_client, station_id, stationTNCStart, params)
+	req := newStationRequest(pb.StationRequest_TNC_START)
+	req.ApiHost = proto.String(host)
+	req.ApiPort = proto.Int32(int32(port_num))
+	req.SatelliteId = proto.String(satellite_id)
+	return StationControlAndCheckStatus(
+		mux_client, station_id, lease_token, req)
 }
 
-func StationTNCStop(mux_client *rpc.Client, station_id string) error {
-	return CallStationAndCheckStatus(
-		mux_client, station_id, stationTNCStop, nil)
+func StationTNCStop(mux_client *rpc.Client,
+	station_id, lease_token string) error {
+	return StationControlAndCheckStatus(
+		mux_client, station_id, lease_token,
+		newStationRequest(pb.StationRequest_TNC_STOP))
 }
 
 type MotorCoordinate struct {
@@ -118,35 +237,79 @@ type MotorCoordinate struct {
 	AltitudeDegrees float64
 }
 
-func StationMotorStart(mux_client *rpc.Client, station_id string,
+func StationMotorStart(mux_client *rpc.Client,
+	station_id, lease_token string,
 	program []MotorCoordinate) error {
 
 	if len(program) == 0 {
 		return errors.New("Empty motor program.")
 	}
-	coords := make([][3]float64, len(program))
-	start_t := float64(time.Now().Unix())
-	for i, c := range program {
-		coords[i][0] = c.Timestamp - start_t
-		coords[i][1] = c.AzimuthDegrees
-		coords[i][2] = c.AltitudeDegrees
-	}
+	req := motorProgramRequest(program, time.Now())
+	return StationControlAndCheckStatus(
+		mux_client, station_id, lease_token, req)
+}
 
-	p, err := json.Marshal(coords)
-	if err != nil {
-		log.Printf("Error json marshalling motor program: %s",
-			err.Error())
-		return err
+// See frequencyProgramRequest.
+func motorProgramRequest(
+	program []MotorCoordinate, now time.Time) *pb.StationRequest {
+	req := newStationRequest(pb.StationRequest_MOTOR_START)
+	start_t := unixSeconds(now)
+	for _, c := range program {
+		req.MotorProgram = append(req.MotorProgram,
+			&pb.MotorCoordinate{
+				Timestamp: proto.Float64(c.Timestamp),
+				OffsetS: proto.Float64(c.Timestamp - start_t),
+				AzimuthDegrees: proto.Float64(c.AzimuthDegrees),
+				AltitudeDegrees: proto.Float64(
+					c.AltitudeDegrees),
+			})
 	}
+	return req
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
 
-	params := url.Values{}
-	params.Add("program", (string)(p))
+func StationMotorStop(mux_client *rpc.Client,
+	station_id, lease_token string) error {
+	return StationControlAndCheckStatus(
+		mux_client, station_id, lease_token,
+		newStationRequest(pb.StationRequest_MOTOR_STOP))
+}
+
+
+// Returns the lease if it was granted. Otherwise the current lease is
+// returned without its token.
+func StationAcquireLease(mux_client *rpc.Client,
+	station_id, holder, description string,
+	d time.Duration) (lease Lease, granted bool, err error) {
+	args := StationAcquireLeaseArgs{station_id, holder, description, d}
+	var result StationAcquireLeaseResult
+	err = mux_client.Call("Coordinator.StationAcquireLease", args, &result)
+	return result.Lease, result.Granted, err
+}
+
+func StationReleaseLease(mux_client *rpc.Client,
+	station_id, token string) error {
+	args := StationReleaseLeaseArgs{station_id, token}
+	var result StationReleaseLeaseResult
+	return mux_client.Call("Coordinator.StationReleaseLease", args, &result)
+}
 
-	return CallStationAndCheckStatus(
-		mux_client, station_id, stationMotorStart, params)
+func StationLeaseStatus(mux_client *rpc.Client, station_id string) (
+	lease Lease, held bool, err error) {
+	args := StationLeaseStatusArgs{station_id}
+	var result StationLeaseStatusResult
+	err = mux_client.Call("Coordinator.StationLeaseStatus", args, &result)
+	return result.Lease, result.Held, err
 }
 
-func StationMotorStop(mux_client *rpc.Client, station_id string) error {
-	return CallStationAndCheckStatus(
-		mux_client, station_id, stationMotorStop, nil)
+func StationRequestPreemption(mux_client *rpc.Client,
+	station_id, requested_by string) (lease Lease, held bool, err error) {
+	args := StationRequestPreemptionArgs{station_id, requested_by}
+	var result StationRequestPreemptionResult
+	err = mux_client.Call(
+		"Coordinator.StationRequestPreemption", args, &result)
+	return result.Lease, result.Held, err
 }
@@ -0,0 +1,139 @@
+// Code generated by protoc-gen-go.
+// source: carpcomm/pb/api_token.proto
+// DO NOT EDIT!
+
+package pb
+
+import proto "code.google.com/p/goprotobuf/proto"
+import json "encoding/json"
+import math "math"
+
+// Reference proto, json, and math imports to suppress error if they are not otherwise used.
+var _ = proto.Marshal
+var _ = &json.SyntaxError{}
+var _ = math.Inf
+
+type APIToken_Scope int32
+
+const (
+	APIToken_POST_PACKETS APIToken_Scope = 1
+	APIToken_READ_PACKETS APIToken_Scope = 2
+	APIToken_READ_IQ      APIToken_Scope = 3
+)
+
+var APIToken_Scope_name = map[int32]string{
+	1: "POST_PACKETS",
+	2: "READ_PACKETS",
+	3: "READ_IQ",
+}
+var APIToken_Scope_value = map[string]int32{
+	"POST_PACKETS": 1,
+	"READ_PACKETS": 2,
+	"READ_IQ":      3,
+}
+
+func (x APIToken_Scope) Enum() *APIToken_Scope {
+	p := new(APIToken_Scope)
+	*p = x
+	return p
+}
+func (x APIToken_Scope) String() string {
+	return proto.EnumName(APIToken_Scope_name, int32(x))
+}
+func (x APIToken_Scope) MarshalJSON() ([]byte, error) {
+	return json.Marshal(x.String())
+}
+func (x *APIToken_Scope) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(APIToken_Scope_value, data, "APIToken_Scope")
+	if err != nil {
+		return err
+	}
+	*x = APIToken_Scope(value)
+	return nil
+}
+
+type APIToken struct {
+	Id               *string          `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Userid           *string          `protobuf:"bytes,2,opt,name=userid" json:"userid,omitempty"`
+	StationId        *string          `protobuf:"bytes,3,opt,name=station_id" json:"station_id,omitempty"`
+	Name             *string          `protobuf:"bytes,4,opt,name=name" json:"name,omitempty"`
+	Scope            []APIToken_Scope `protobuf:"varint,5,rep,name=scope,enum=pb.APIToken_Scope" json:"scope,omitempty"`
+	SatelliteId      []string         `protobuf:"bytes,6,rep,name=satellite_id" json:"satellite_id,omitempty"`
+	Salt             []byte           `protobuf:"bytes,7,opt,name=salt" json:"salt,omitempty"`
+	Hash             []byte           `protobuf:"bytes,8,opt,name=hash" json:"hash,omitempty"`
+	Created          *int64           `protobuf:"varint,9,opt,name=created" json:"created,omitempty"`
+	Expires          *int64           `protobuf:"varint,10,opt,name=expires" json:"expires,omitempty"`
+	Revoked          *int64           `protobuf:"varint,11,opt,name=revoked" json:"revoked,omitempty"`
+	XXX_unrecognized []byte           `json:"-"`
+}
+
+func (this *APIToken) Reset()         { *this = APIToken{} }
+func (this *APIToken) String() string { return proto.CompactTextString(this) }
+func (*APIToken) ProtoMessage()       {}
+
+func (this *APIToken) GetId() string {
+	if this != nil && this.Id != nil {
+		return *this.Id
+	}
+	return ""
+}
+
+func (this *APIToken) GetUserid() string {
+	if this != nil && this.Userid != nil {
+		return *this.Userid
+	}
+	return ""
+}
+
+func (this *APIToken) GetStationId() string {
+	if this != nil && this.StationId != nil {
+		return *this.StationId
+	}
+	return ""
+}
+
+func (this *APIToken) GetName() string {
+	if this != nil && this.Name != nil {
+		return *this.Name
+	}
+	return ""
+}
+
+func (this *APIToken) GetSalt() []byte {
+	if this != nil {
+		return this.Salt
+	}
+	return nil
+}
+
+func (this *APIToken) GetHash() []byte {
+	if this != nil {
+		return this.Hash
+	}
+	return nil
+}
+
+func (this *APIToken) GetCreated() int64 {
+	if this != nil && this.Created != nil {
+		return *this.Created
+	}
+	return 0
+}
+
+func (this *APIToken) GetExpires() int64 {
+	if this != nil && this.Expires != nil {
+		return *this.Expires
+	}
+	return 0
+}
+
+func (this *APIToken) GetRevoked() int64 {
+	if this != nil && this.Revoked != nil {
+		return *this.Revoked
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterEnum("pb.APIToken_Scope", APIToken_Scope_name, APIToken_Scope_value)
+}
@@ -0,0 +1,197 @@
+// Code generated by protoc-gen-go.
+// source: carpcomm/pb/iq_job.proto
+// DO NOT EDIT!
+
+package pb
+
+import proto "code.google.com/p/goprotobuf/proto"
+import json "encoding/json"
+import math "math"
+
+// Reference proto, json, and math imports to suppress error if they are not otherwise used.
+var _ = proto.Marshal
+var _ = &json.SyntaxError{}
+var _ = math.Inf
+
+type IQJob_Status int32
+
+const (
+	IQJob_QUEUED  IQJob_Status = 1
+	IQJob_RUNNING IQJob_Status = 2
+	IQJob_FAILED  IQJob_Status = 3
+	IQJob_DONE    IQJob_Status = 4
+)
+
+var IQJob_Status_name = map[int32]string{
+	1: "QUEUED",
+	2: "RUNNING",
+	3: "FAILED",
+	4: "DONE",
+}
+var IQJob_Status_value = map[string]int32{
+	"QUEUED":  1,
+	"RUNNING": 2,
+	"FAILED":  3,
+	"DONE":    4,
+}
+
+func (x IQJob_Status) Enum() *IQJob_Status {
+	p := new(IQJob_Status)
+	*p = x
+	return p
+}
+func (x IQJob_Status) String() string {
+	return proto.EnumName(IQJob_Status_name, int32(x))
+}
+func (x IQJob_Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(x.String())
+}
+func (x *IQJob_Status) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(IQJob_Status_value, data, "IQJob_Status")
+	if err != nil {
+		return err
+	}
+	*x = IQJob_Status(value)
+	return nil
+}
+
+type IQJob struct {
+	Id               *string        `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Status           *IQJob_Status  `protobuf:"varint,2,opt,name=status,enum=pb.IQJob_Status" json:"status,omitempty"`
+	Created          *int64         `protobuf:"varint,3,opt,name=created" json:"created,omitempty"`
+	Updated          *int64         `protobuf:"varint,4,opt,name=updated" json:"updated,omitempty"`
+	NotBefore        *int64         `protobuf:"varint,5,opt,name=not_before" json:"not_before,omitempty"`
+	Attempts         *int32         `protobuf:"varint,6,opt,name=attempts" json:"attempts,omitempty"`
+	Worker           *string        `protobuf:"bytes,7,opt,name=worker" json:"worker,omitempty"`
+	LeaseExpires     *int64         `protobuf:"varint,11,opt,name=lease_expires" json:"lease_expires,omitempty"`
+	Error            *string        `protobuf:"bytes,8,opt,name=error" json:"error,omitempty"`
+	Stage            []*IQJob_Stage `protobuf:"bytes,9,rep,name=stage" json:"stage,omitempty"`
+	Version          *int64         `protobuf:"varint,10,opt,name=version" json:"version,omitempty"`
+	XXX_unrecognized []byte         `json:"-"`
+}
+
+func (this *IQJob) Reset()         { *this = IQJob{} }
+func (this *IQJob) String() string { return proto.CompactTextString(this) }
+func (*IQJob) ProtoMessage()       {}
+
+func (this *IQJob) GetId() string {
+	if this != nil && this.Id != nil {
+		return *this.Id
+	}
+	return ""
+}
+
+func (this *IQJob) GetStatus() IQJob_Status {
+	if this != nil && this.Status != nil {
+		return *this.Status
+	}
+	return 0
+}
+
+func (this *IQJob) GetCreated() int64 {
+	if this != nil && this.Created != nil {
+		return *this.Created
+	}
+	return 0
+}
+
+func (this *IQJob) GetUpdated() int64 {
+	if this != nil && this.Updated != nil {
+		return *this.Updated
+	}
+	return 0
+}
+
+func (this *IQJob) GetNotBefore() int64 {
+	if this != nil && this.NotBefore != nil {
+		return *this.NotBefore
+	}
+	return 0
+}
+
+func (this *IQJob) GetAttempts() int32 {
+	if this != nil && this.Attempts != nil {
+		return *this.Attempts
+	}
+	return 0
+}
+
+func (this *IQJob) GetWorker() string {
+	if this != nil && this.Worker != nil {
+		return *this.Worker
+	}
+	return ""
+}
+
+func (this *IQJob) GetLeaseExpires() int64 {
+	if this != nil && this.LeaseExpires != nil {
+		return *this.LeaseExpires
+	}
+	return 0
+}
+
+func (this *IQJob) GetError() string {
+	if this != nil && this.Error != nil {
+		return *this.Error
+	}
+	return ""
+}
+
+func (this *IQJob) GetVersion() int64 {
+	if this != nil && this.Version != nil {
+		return *this.Version
+	}
+	return 0
+}
+
+type IQJob_Stage struct {
+	Name             *string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Started          *int64  `protobuf:"varint,2,opt,name=started" json:"started,omitempty"`
+	Finished         *int64  `protobuf:"varint,3,opt,name=finished" json:"finished,omitempty"`
+	Error            *string `protobuf:"bytes,4,opt,name=error" json:"error,omitempty"`
+	Blobs            *int32  `protobuf:"varint,5,opt,name=blobs" json:"blobs,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (this *IQJob_Stage) Reset()         { *this = IQJob_Stage{} }
+func (this *IQJob_Stage) String() string { return proto.CompactTextString(this) }
+func (*IQJob_Stage) ProtoMessage()       {}
+
+func (this *IQJob_Stage) GetName() string {
+	if this != nil && this.Name != nil {
+		return *this.Name
+	}
+	return ""
+}
+
+func (this *IQJob_Stage) GetStarted() int64 {
+	if this != nil && this.Started != nil {
+		return *this.Started
+	}
+	return 0
+}
+
+func (this *IQJob_Stage) GetFinished() int64 {
+	if this != nil && this.Finished != nil {
+		return *this.Finished
+	}
+	return 0
+}
+
+func (this *IQJob_Stage) GetError() string {
+	if this != nil && this.Error != nil {
+		return *this.Error
+	}
+	return ""
+}
+
+func (this *IQJob_Stage) GetBlobs() int32 {
+	if this != nil && this.Blobs != nil {
+		return *this.Blobs
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterEnum("pb.IQJob_Status", IQJob_Status_name, IQJob_Status_value)
+}
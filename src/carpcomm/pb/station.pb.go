@@ -149,21 +149,62 @@ func (this *Capabilities) GetMaxElevationDegrees() float64 {
 	return 0
 }
 
+type StationSecret struct {
+	Salt             []byte `protobuf:"bytes,1,opt,name=salt" json:"salt,omitempty"`
+	Hash             []byte `protobuf:"bytes,2,opt,name=hash" json:"hash,omitempty"`
+	Created          *int64 `protobuf:"varint,3,opt,name=created" json:"created,omitempty"`
+	Expires          *int64 `protobuf:"varint,4,opt,name=expires" json:"expires,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (this *StationSecret) Reset()         { *this = StationSecret{} }
+func (this *StationSecret) String() string { return proto.CompactTextString(this) }
+func (*StationSecret) ProtoMessage()       {}
+
+func (this *StationSecret) GetSalt() []byte {
+	if this != nil {
+		return this.Salt
+	}
+	return nil
+}
+
+func (this *StationSecret) GetHash() []byte {
+	if this != nil {
+		return this.Hash
+	}
+	return nil
+}
+
+func (this *StationSecret) GetCreated() int64 {
+	if this != nil && this.Created != nil {
+		return *this.Created
+	}
+	return 0
+}
+
+func (this *StationSecret) GetExpires() int64 {
+	if this != nil && this.Expires != nil {
+		return *this.Expires
+	}
+	return 0
+}
+
 type Station struct {
-	Id               *string       `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
-	Secret           *string       `protobuf:"bytes,2,opt,name=secret" json:"secret,omitempty"`
-	Userid           *string       `protobuf:"bytes,3,opt,name=userid" json:"userid,omitempty"`
-	Name             *string       `protobuf:"bytes,4,opt,name=name" json:"name,omitempty"`
-	Lat              *float64      `protobuf:"fixed64,5,opt,name=lat" json:"lat,omitempty"`
-	Lng              *float64      `protobuf:"fixed64,6,opt,name=lng" json:"lng,omitempty"`
-	Elevation        *float64      `protobuf:"fixed64,7,opt,name=elevation" json:"elevation,omitempty"`
-	Created          *int64        `protobuf:"varint,8,opt,name=created" json:"created,omitempty"`
-	LastConnect      *int64        `protobuf:"varint,9,opt,name=last_connect" json:"last_connect,omitempty"`
-	SchedulerEnabled *bool         `protobuf:"varint,13,opt,name=scheduler_enabled" json:"scheduler_enabled,omitempty"`
-	Locality         *string       `protobuf:"bytes,10,opt,name=locality" json:"locality,omitempty"`
-	Notes            *string       `protobuf:"bytes,11,opt,name=notes" json:"notes,omitempty"`
-	Capabilities     *Capabilities `protobuf:"bytes,12,opt,name=capabilities" json:"capabilities,omitempty"`
-	XXX_unrecognized []byte        `json:"-"`
+	Id               *string          `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Secret           *string          `protobuf:"bytes,2,opt,name=secret" json:"secret,omitempty"`
+	SecretHashes     []*StationSecret `protobuf:"bytes,14,rep,name=secret_hashes" json:"secret_hashes,omitempty"`
+	Userid           *string          `protobuf:"bytes,3,opt,name=userid" json:"userid,omitempty"`
+	Name             *string          `protobuf:"bytes,4,opt,name=name" json:"name,omitempty"`
+	Lat              *float64         `protobuf:"fixed64,5,opt,name=lat" json:"lat,omitempty"`
+	Lng              *float64         `protobuf:"fixed64,6,opt,name=lng" json:"lng,omitempty"`
+	Elevation        *float64         `protobuf:"fixed64,7,opt,name=elevation" json:"elevation,omitempty"`
+	Created          *int64           `protobuf:"varint,8,opt,name=created" json:"created,omitempty"`
+	LastConnect      *int64           `protobuf:"varint,9,opt,name=last_connect" json:"last_connect,omitempty"`
+	SchedulerEnabled *bool            `protobuf:"varint,13,opt,name=scheduler_enabled" json:"scheduler_enabled,omitempty"`
+	Locality         *string          `protobuf:"bytes,10,opt,name=locality" json:"locality,omitempty"`
+	Notes            *string          `protobuf:"bytes,11,opt,name=notes" json:"notes,omitempty"`
+	Capabilities     *Capabilities    `protobuf:"bytes,12,opt,name=capabilities" json:"capabilities,omitempty"`
+	XXX_unrecognized []byte           `json:"-"`
 }
 
 func (this *Station) Reset()         { *this = Station{} }
@@ -0,0 +1,636 @@
+// Code generated by protoc-gen-go.
+// source: carpcomm/pb/station_control.proto
+// DO NOT EDIT!
+
+package pb
+
+import proto "code.google.com/p/goprotobuf/proto"
+import json "encoding/json"
+import math "math"
+
+// Reference proto, json, and math imports to suppress error if they are not otherwise used.
+var _ = proto.Marshal
+var _ = &json.SyntaxError{}
+var _ = math.Inf
+
+type StationRequest_Type int32
+
+const (
+	StationRequest_PING                       StationRequest_Type = 1
+	StationRequest_GET_STATUS                 StationRequest_Type = 2
+	StationRequest_RECEIVER_SET_FREQUENCY     StationRequest_Type = 3
+	StationRequest_RECEIVER_START             StationRequest_Type = 4
+	StationRequest_RECEIVER_STOP              StationRequest_Type = 5
+	StationRequest_RECEIVER_FREQUENCY_PROGRAM StationRequest_Type = 6
+	StationRequest_TNC_START                  StationRequest_Type = 7
+	StationRequest_TNC_STOP                   StationRequest_Type = 8
+	StationRequest_MOTOR_START                StationRequest_Type = 9
+	StationRequest_MOTOR_STOP                 StationRequest_Type = 10
+	StationRequest_GET_HEALTH                 StationRequest_Type = 11
+)
+
+var StationRequest_Type_name = map[int32]string{
+	1:  "PING",
+	2:  "GET_STATUS",
+	3:  "RECEIVER_SET_FREQUENCY",
+	4:  "RECEIVER_START",
+	5:  "RECEIVER_STOP",
+	6:  "RECEIVER_FREQUENCY_PROGRAM",
+	7:  "TNC_START",
+	8:  "TNC_STOP",
+	9:  "MOTOR_START",
+	10: "MOTOR_STOP",
+	11: "GET_HEALTH",
+}
+var StationRequest_Type_value = map[string]int32{
+	"PING":                       1,
+	"GET_STATUS":                 2,
+	"RECEIVER_SET_FREQUENCY":     3,
+	"RECEIVER_START":             4,
+	"RECEIVER_STOP":              5,
+	"RECEIVER_FREQUENCY_PROGRAM": 6,
+	"TNC_START":                  7,
+	"TNC_STOP":                   8,
+	"MOTOR_START":                9,
+	"MOTOR_STOP":                 10,
+	"GET_HEALTH":                 11,
+}
+
+func (x StationRequest_Type) Enum() *StationRequest_Type {
+	p := new(StationRequest_Type)
+	*p = x
+	return p
+}
+func (x StationRequest_Type) String() string {
+	return proto.EnumName(StationRequest_Type_name, int32(x))
+}
+func (x StationRequest_Type) MarshalJSON() ([]byte, error) {
+	return json.Marshal(x.String())
+}
+func (x *StationRequest_Type) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(StationRequest_Type_value, data, "StationRequest_Type")
+	if err != nil {
+		return err
+	}
+	*x = StationRequest_Type(value)
+	return nil
+}
+
+type StationResponse_Status int32
+
+const (
+	StationResponse_OK          StationResponse_Status = 1
+	StationResponse_ERROR       StationResponse_Status = 2
+	StationResponse_UNSUPPORTED StationResponse_Status = 3
+)
+
+var StationResponse_Status_name = map[int32]string{
+	1: "OK",
+	2: "ERROR",
+	3: "UNSUPPORTED",
+}
+var StationResponse_Status_value = map[string]int32{
+	"OK":          1,
+	"ERROR":       2,
+	"UNSUPPORTED": 3,
+}
+
+func (x StationResponse_Status) Enum() *StationResponse_Status {
+	p := new(StationResponse_Status)
+	*p = x
+	return p
+}
+func (x StationResponse_Status) String() string {
+	return proto.EnumName(StationResponse_Status_name, int32(x))
+}
+func (x StationResponse_Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(x.String())
+}
+func (x *StationResponse_Status) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(StationResponse_Status_value, data, "StationResponse_Status")
+	if err != nil {
+		return err
+	}
+	*x = StationResponse_Status(value)
+	return nil
+}
+
+type StationIdentity struct {
+	Version          *string          `protobuf:"bytes,1,opt,name=version" json:"version,omitempty"`
+	Client           *string          `protobuf:"bytes,2,opt,name=client" json:"client,omitempty"`
+	StationId        *string          `protobuf:"bytes,3,opt,name=station_id" json:"station_id,omitempty"`
+	Secret           *string          `protobuf:"bytes,4,opt,name=secret" json:"secret,omitempty"`
+	Session          *string          `protobuf:"bytes,5,opt,name=session" json:"session,omitempty"`
+	Protocols        []string         `protobuf:"bytes,6,rep,name=protocols" json:"protocols,omitempty"`
+	ProtocolVersion  *int32           `protobuf:"varint,7,opt,name=protocol_version" json:"protocol_version,omitempty"`
+	Features         *StationFeatures `protobuf:"bytes,8,opt,name=features" json:"features,omitempty"`
+	StationTime      *float64         `protobuf:"fixed64,9,opt,name=station_time" json:"station_time,omitempty"`
+	XXX_unrecognized []byte           `json:"-"`
+}
+
+func (this *StationIdentity) Reset()         { *this = StationIdentity{} }
+func (this *StationIdentity) String() string { return proto.CompactTextString(this) }
+func (*StationIdentity) ProtoMessage()       {}
+
+func (this *StationIdentity) GetVersion() string {
+	if this != nil && this.Version != nil {
+		return *this.Version
+	}
+	return ""
+}
+
+func (this *StationIdentity) GetClient() string {
+	if this != nil && this.Client != nil {
+		return *this.Client
+	}
+	return ""
+}
+
+func (this *StationIdentity) GetStationId() string {
+	if this != nil && this.StationId != nil {
+		return *this.StationId
+	}
+	return ""
+}
+
+func (this *StationIdentity) GetSecret() string {
+	if this != nil && this.Secret != nil {
+		return *this.Secret
+	}
+	return ""
+}
+
+func (this *StationIdentity) GetSession() string {
+	if this != nil && this.Session != nil {
+		return *this.Session
+	}
+	return ""
+}
+
+func (this *StationIdentity) GetProtocolVersion() int32 {
+	if this != nil && this.ProtocolVersion != nil {
+		return *this.ProtocolVersion
+	}
+	return 0
+}
+
+func (this *StationIdentity) GetFeatures() *StationFeatures {
+	if this != nil {
+		return this.Features
+	}
+	return nil
+}
+
+func (this *StationIdentity) GetStationTime() float64 {
+	if this != nil && this.StationTime != nil {
+		return *this.StationTime
+	}
+	return 0
+}
+
+type StationFeatures struct {
+	Receiver         *bool  `protobuf:"varint,1,opt,name=receiver" json:"receiver,omitempty"`
+	Tnc              *bool  `protobuf:"varint,2,opt,name=tnc" json:"tnc,omitempty"`
+	Motor            *bool  `protobuf:"varint,3,opt,name=motor" json:"motor,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (this *StationFeatures) Reset()         { *this = StationFeatures{} }
+func (this *StationFeatures) String() string { return proto.CompactTextString(this) }
+func (*StationFeatures) ProtoMessage()       {}
+
+func (this *StationFeatures) GetReceiver() bool {
+	if this != nil && this.Receiver != nil {
+		return *this.Receiver
+	}
+	return false
+}
+
+func (this *StationFeatures) GetTnc() bool {
+	if this != nil && this.Tnc != nil {
+		return *this.Tnc
+	}
+	return false
+}
+
+func (this *StationFeatures) GetMotor() bool {
+	if this != nil && this.Motor != nil {
+		return *this.Motor
+	}
+	return false
+}
+
+type FrequencyCoordinate struct {
+	OffsetS          *float64 `protobuf:"fixed64,1,opt,name=offset_s" json:"offset_s,omitempty"`
+	FrequencyHz      *int64   `protobuf:"varint,2,opt,name=frequency_hz" json:"frequency_hz,omitempty"`
+	StationTimestamp *float64 `protobuf:"fixed64,3,opt,name=station_timestamp" json:"station_timestamp,omitempty"`
+	Timestamp        *float64 `protobuf:"fixed64,4,opt,name=timestamp" json:"timestamp,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (this *FrequencyCoordinate) Reset()         { *this = FrequencyCoordinate{} }
+func (this *FrequencyCoordinate) String() string { return proto.CompactTextString(this) }
+func (*FrequencyCoordinate) ProtoMessage()       {}
+
+func (this *FrequencyCoordinate) GetOffsetS() float64 {
+	if this != nil && this.OffsetS != nil {
+		return *this.OffsetS
+	}
+	return 0
+}
+
+func (this *FrequencyCoordinate) GetFrequencyHz() int64 {
+	if this != nil && this.FrequencyHz != nil {
+		return *this.FrequencyHz
+	}
+	return 0
+}
+
+func (this *FrequencyCoordinate) GetStationTimestamp() float64 {
+	if this != nil && this.StationTimestamp != nil {
+		return *this.StationTimestamp
+	}
+	return 0
+}
+
+func (this *FrequencyCoordinate) GetTimestamp() float64 {
+	if this != nil && this.Timestamp != nil {
+		return *this.Timestamp
+	}
+	return 0
+}
+
+type MotorCoordinate struct {
+	OffsetS          *float64 `protobuf:"fixed64,1,opt,name=offset_s" json:"offset_s,omitempty"`
+	AzimuthDegrees   *float64 `protobuf:"fixed64,2,opt,name=azimuth_degrees" json:"azimuth_degrees,omitempty"`
+	AltitudeDegrees  *float64 `protobuf:"fixed64,3,opt,name=altitude_degrees" json:"altitude_degrees,omitempty"`
+	StationTimestamp *float64 `protobuf:"fixed64,4,opt,name=station_timestamp" json:"station_timestamp,omitempty"`
+	Timestamp        *float64 `protobuf:"fixed64,5,opt,name=timestamp" json:"timestamp,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (this *MotorCoordinate) Reset()         { *this = MotorCoordinate{} }
+func (this *MotorCoordinate) String() string { return proto.CompactTextString(this) }
+func (*MotorCoordinate) ProtoMessage()       {}
+
+func (this *MotorCoordinate) GetOffsetS() float64 {
+	if this != nil && this.OffsetS != nil {
+		return *this.OffsetS
+	}
+	return 0
+}
+
+func (this *MotorCoordinate) GetAzimuthDegrees() float64 {
+	if this != nil && this.AzimuthDegrees != nil {
+		return *this.AzimuthDegrees
+	}
+	return 0
+}
+
+func (this *MotorCoordinate) GetAltitudeDegrees() float64 {
+	if this != nil && this.AltitudeDegrees != nil {
+		return *this.AltitudeDegrees
+	}
+	return 0
+}
+
+func (this *MotorCoordinate) GetStationTimestamp() float64 {
+	if this != nil && this.StationTimestamp != nil {
+		return *this.StationTimestamp
+	}
+	return 0
+}
+
+func (this *MotorCoordinate) GetTimestamp() float64 {
+	if this != nil && this.Timestamp != nil {
+		return *this.Timestamp
+	}
+	return 0
+}
+
+type StationRequest struct {
+	ProtocolVersion  *int32                 `protobuf:"varint,1,opt,name=protocol_version" json:"protocol_version,omitempty"`
+	Type             *StationRequest_Type   `protobuf:"varint,2,opt,name=type,enum=pb.StationRequest_Type" json:"type,omitempty"`
+	FrequencyHz      *int64                 `protobuf:"varint,3,opt,name=frequency_hz" json:"frequency_hz,omitempty"`
+	StreamUrl        *string                `protobuf:"bytes,4,opt,name=stream_url" json:"stream_url,omitempty"`
+	FrequencyProgram []*FrequencyCoordinate `protobuf:"bytes,5,rep,name=frequency_program" json:"frequency_program,omitempty"`
+	ApiHost          *string                `protobuf:"bytes,6,opt,name=api_host" json:"api_host,omitempty"`
+	ApiPort          *int32                 `protobuf:"varint,7,opt,name=api_port" json:"api_port,omitempty"`
+	SatelliteId      *string                `protobuf:"bytes,8,opt,name=satellite_id" json:"satellite_id,omitempty"`
+	MotorProgram     []*MotorCoordinate     `protobuf:"bytes,9,rep,name=motor_program" json:"motor_program,omitempty"`
+	XXX_unrecognized []byte                 `json:"-"`
+}
+
+func (this *StationRequest) Reset()         { *this = StationRequest{} }
+func (this *StationRequest) String() string { return proto.CompactTextString(this) }
+func (*StationRequest) ProtoMessage()       {}
+
+func (this *StationRequest) GetProtocolVersion() int32 {
+	if this != nil && this.ProtocolVersion != nil {
+		return *this.ProtocolVersion
+	}
+	return 0
+}
+
+func (this *StationRequest) GetType() StationRequest_Type {
+	if this != nil && this.Type != nil {
+		return *this.Type
+	}
+	return 0
+}
+
+func (this *StationRequest) GetFrequencyHz() int64 {
+	if this != nil && this.FrequencyHz != nil {
+		return *this.FrequencyHz
+	}
+	return 0
+}
+
+func (this *StationRequest) GetStreamUrl() string {
+	if this != nil && this.StreamUrl != nil {
+		return *this.StreamUrl
+	}
+	return ""
+}
+
+func (this *StationRequest) GetApiHost() string {
+	if this != nil && this.ApiHost != nil {
+		return *this.ApiHost
+	}
+	return ""
+}
+
+func (this *StationRequest) GetApiPort() int32 {
+	if this != nil && this.ApiPort != nil {
+		return *this.ApiPort
+	}
+	return 0
+}
+
+func (this *StationRequest) GetSatelliteId() string {
+	if this != nil && this.SatelliteId != nil {
+		return *this.SatelliteId
+	}
+	return ""
+}
+
+type StationStatus struct {
+	Receiver         *StationStatus_Receiver `protobuf:"bytes,1,opt,name=receiver" json:"receiver,omitempty"`
+	Tnc              *StationStatus_TNC      `protobuf:"bytes,2,opt,name=tnc" json:"tnc,omitempty"`
+	Motor            *StationStatus_Motor    `protobuf:"bytes,3,opt,name=motor" json:"motor,omitempty"`
+	XXX_unrecognized []byte                  `json:"-"`
+}
+
+func (this *StationStatus) Reset()         { *this = StationStatus{} }
+func (this *StationStatus) String() string { return proto.CompactTextString(this) }
+func (*StationStatus) ProtoMessage()       {}
+
+func (this *StationStatus) GetReceiver() *StationStatus_Receiver {
+	if this != nil {
+		return this.Receiver
+	}
+	return nil
+}
+
+func (this *StationStatus) GetTnc() *StationStatus_TNC {
+	if this != nil {
+		return this.Tnc
+	}
+	return nil
+}
+
+func (this *StationStatus) GetMotor() *StationStatus_Motor {
+	if this != nil {
+		return this.Motor
+	}
+	return nil
+}
+
+type StationStatus_Receiver struct {
+	Driver           *string `protobuf:"bytes,1,opt,name=driver" json:"driver,omitempty"`
+	Started          *bool   `protobuf:"varint,2,opt,name=started" json:"started,omitempty"`
+	HardwareTunerHz  *int64  `protobuf:"varint,3,opt,name=hardware_tuner_hz" json:"hardware_tuner_hz,omitempty"`
+	Fault            *string `protobuf:"bytes,4,opt,name=fault" json:"fault,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (this *StationStatus_Receiver) Reset()         { *this = StationStatus_Receiver{} }
+func (this *StationStatus_Receiver) String() string { return proto.CompactTextString(this) }
+func (*StationStatus_Receiver) ProtoMessage()       {}
+
+func (this *StationStatus_Receiver) GetDriver() string {
+	if this != nil && this.Driver != nil {
+		return *this.Driver
+	}
+	return ""
+}
+
+func (this *StationStatus_Receiver) GetStarted() bool {
+	if this != nil && this.Started != nil {
+		return *this.Started
+	}
+	return false
+}
+
+func (this *StationStatus_Receiver) GetHardwareTunerHz() int64 {
+	if this != nil && this.HardwareTunerHz != nil {
+		return *this.HardwareTunerHz
+	}
+	return 0
+}
+
+func (this *StationStatus_Receiver) GetFault() string {
+	if this != nil && this.Fault != nil {
+		return *this.Fault
+	}
+	return ""
+}
+
+type StationStatus_TNC struct {
+	Started          *bool   `protobuf:"varint,1,opt,name=started" json:"started,omitempty"`
+	Fault            *string `protobuf:"bytes,2,opt,name=fault" json:"fault,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (this *StationStatus_TNC) Reset()         { *this = StationStatus_TNC{} }
+func (this *StationStatus_TNC) String() string { return proto.CompactTextString(this) }
+func (*StationStatus_TNC) ProtoMessage()       {}
+
+func (this *StationStatus_TNC) GetStarted() bool {
+	if this != nil && this.Started != nil {
+		return *this.Started
+	}
+	return false
+}
+
+func (this *StationStatus_TNC) GetFault() string {
+	if this != nil && this.Fault != nil {
+		return *this.Fault
+	}
+	return ""
+}
+
+type StationStatus_Motor struct {
+	Driver           *string  `protobuf:"bytes,1,opt,name=driver" json:"driver,omitempty"`
+	IsMoving         *bool    `protobuf:"varint,2,opt,name=is_moving" json:"is_moving,omitempty"`
+	AzimuthDegrees   *float64 `protobuf:"fixed64,3,opt,name=azimuth_degrees" json:"azimuth_degrees,omitempty"`
+	ElevationDegrees *float64 `protobuf:"fixed64,4,opt,name=elevation_degrees" json:"elevation_degrees,omitempty"`
+	Fault            *string  `protobuf:"bytes,5,opt,name=fault" json:"fault,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (this *StationStatus_Motor) Reset()         { *this = StationStatus_Motor{} }
+func (this *StationStatus_Motor) String() string { return proto.CompactTextString(this) }
+func (*StationStatus_Motor) ProtoMessage()       {}
+
+func (this *StationStatus_Motor) GetDriver() string {
+	if this != nil && this.Driver != nil {
+		return *this.Driver
+	}
+	return ""
+}
+
+func (this *StationStatus_Motor) GetIsMoving() bool {
+	if this != nil && this.IsMoving != nil {
+		return *this.IsMoving
+	}
+	return false
+}
+
+func (this *StationStatus_Motor) GetAzimuthDegrees() float64 {
+	if this != nil && this.AzimuthDegrees != nil {
+		return *this.AzimuthDegrees
+	}
+	return 0
+}
+
+func (this *StationStatus_Motor) GetElevationDegrees() float64 {
+	if this != nil && this.ElevationDegrees != nil {
+		return *this.ElevationDegrees
+	}
+	return 0
+}
+
+func (this *StationStatus_Motor) GetFault() string {
+	if this != nil && this.Fault != nil {
+		return *this.Fault
+	}
+	return ""
+}
+
+type StationResponse struct {
+	ProtocolVersion  *int32                  `protobuf:"varint,1,opt,name=protocol_version" json:"protocol_version,omitempty"`
+	Status           *StationResponse_Status `protobuf:"varint,2,opt,name=status,enum=pb.StationResponse_Status" json:"status,omitempty"`
+	Error            *string                 `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+	StationStatus    *StationStatus          `protobuf:"bytes,4,opt,name=station_status" json:"station_status,omitempty"`
+	Health           *StationHealth          `protobuf:"bytes,5,opt,name=health" json:"health,omitempty"`
+	XXX_unrecognized []byte                  `json:"-"`
+}
+
+func (this *StationResponse) Reset()         { *this = StationResponse{} }
+func (this *StationResponse) String() string { return proto.CompactTextString(this) }
+func (*StationResponse) ProtoMessage()       {}
+
+func (this *StationResponse) GetProtocolVersion() int32 {
+	if this != nil && this.ProtocolVersion != nil {
+		return *this.ProtocolVersion
+	}
+	return 0
+}
+
+func (this *StationResponse) GetStatus() StationResponse_Status {
+	if this != nil && this.Status != nil {
+		return *this.Status
+	}
+	return 0
+}
+
+func (this *StationResponse) GetError() string {
+	if this != nil && this.Error != nil {
+		return *this.Error
+	}
+	return ""
+}
+
+func (this *StationResponse) GetStationStatus() *StationStatus {
+	if this != nil {
+		return this.StationStatus
+	}
+	return nil
+}
+
+func (this *StationResponse) GetHealth() *StationHealth {
+	if this != nil {
+		return this.Health
+	}
+	return nil
+}
+
+type StationHealth struct {
+	Status            *StationStatus `protobuf:"bytes,1,opt,name=status" json:"status,omitempty"`
+	LoadAverage       *float64       `protobuf:"fixed64,2,opt,name=load_average" json:"load_average,omitempty"`
+	DiskFreeBytes     *int64         `protobuf:"varint,3,opt,name=disk_free_bytes" json:"disk_free_bytes,omitempty"`
+	StationTime       *float64       `protobuf:"fixed64,4,opt,name=station_time" json:"station_time,omitempty"`
+	ReceivedTimestamp *int64         `protobuf:"varint,5,opt,name=received_timestamp" json:"received_timestamp,omitempty"`
+	ClockOffsetS      *float64       `protobuf:"fixed64,6,opt,name=clock_offset_s" json:"clock_offset_s,omitempty"`
+	RoundTripS        *float64       `protobuf:"fixed64,7,opt,name=round_trip_s" json:"round_trip_s,omitempty"`
+	XXX_unrecognized  []byte         `json:"-"`
+}
+
+func (this *StationHealth) Reset()         { *this = StationHealth{} }
+func (this *StationHealth) String() string { return proto.CompactTextString(this) }
+func (*StationHealth) ProtoMessage()       {}
+
+func (this *StationHealth) GetStatus() *StationStatus {
+	if this != nil {
+		return this.Status
+	}
+	return nil
+}
+
+func (this *StationHealth) GetLoadAverage() float64 {
+	if this != nil && this.LoadAverage != nil {
+		return *this.LoadAverage
+	}
+	return 0
+}
+
+func (this *StationHealth) GetDiskFreeBytes() int64 {
+	if this != nil && this.DiskFreeBytes != nil {
+		return *this.DiskFreeBytes
+	}
+	return 0
+}
+
+func (this *StationHealth) GetStationTime() float64 {
+	if this != nil && this.StationTime != nil {
+		return *this.StationTime
+	}
+	return 0
+}
+
+func (this *StationHealth) GetReceivedTimestamp() int64 {
+	if this != nil && this.ReceivedTimestamp != nil {
+		return *this.ReceivedTimestamp
+	}
+	return 0
+}
+
+func (this *StationHealth) GetClockOffsetS() float64 {
+	if this != nil && this.ClockOffsetS != nil {
+		return *this.ClockOffsetS
+	}
+	return 0
+}
+
+func (this *StationHealth) GetRoundTripS() float64 {
+	if this != nil && this.RoundTripS != nil {
+		return *this.RoundTripS
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterEnum("pb.StationRequest_Type", StationRequest_Type_name, StationRequest_Type_value)
+	proto.RegisterEnum("pb.StationResponse_Status", StationResponse_Status_name, StationResponse_Status_value)
+}
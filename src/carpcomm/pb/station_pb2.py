@@ -11,7 +11,7 @@ from google.protobuf import descriptor_pb2
 DESCRIPTOR = descriptor.FileDescriptor(
   name='carpcomm/pb/station.proto',
   package='pb',
-  serialized_pb='\n\x19\x63\x61rpcomm/pb/station.proto\x12\x02pb\"\x84\x01\n\nAzElLimits\x12\x1b\n\x13min_azimuth_degrees\x18\x01 \x01(\x01\x12\x1b\n\x13max_azimuth_degrees\x18\x02 \x01(\x01\x12\x1d\n\x15min_elevation_degrees\x18\x03 \x01(\x01\x12\x1d\n\x15max_elevation_degrees\x18\x04 \x01(\x01\"\xdc\x02\n\x0c\x43\x61pabilities\x12\"\n\nvhf_limits\x18\n \x01(\x0b\x32\x0e.pb.AzElLimits\x12\"\n\nuhf_limits\x18\x0b \x01(\x0b\x32\x0e.pb.AzElLimits\x12\x14\n\x0chas_receiver\x18\x01 \x01(\x08\x12!\n\x19min_receiver_frequency_hz\x18\x02 \x01(\x01\x12!\n\x19max_receiver_frequency_hz\x18\x03 \x01(\x01\x12\x1d\n\x15receiver_bandwidth_hz\x18\x04 \x01(\x01\x12\x11\n\thas_motor\x18\x05 \x01(\x08\x12\x1b\n\x13min_azimuth_degrees\x18\x06 \x01(\x01\x12\x1b\n\x13max_azimuth_degrees\x18\x07 \x01(\x01\x12\x1d\n\x15min_elevation_degrees\x18\x08 \x01(\x01\x12\x1d\n\x15max_elevation_degrees\x18\t \x01(\x01\"\xfb\x01\n\x07Station\x12\n\n\x02id\x18\x01 \x01(\t\x12\x0e\n\x06secret\x18\x02 \x01(\t\x12\x0e\n\x06userid\x18\x03 \x01(\t\x12\x0c\n\x04name\x18\x04 \x01(\t\x12\x0b\n\x03lat\x18\x05 \x01(\x01\x12\x0b\n\x03lng\x18\x06 \x01(\x01\x12\x11\n\televation\x18\x07 \x01(\x01\x12\x0f\n\x07\x63reated\x18\x08 \x01(\x03\x12\x14\n\x0clast_connect\x18\t \x01(\x03\x12\x19\n\x11scheduler_enabled\x18\r \x01(\x08\x12\x10\n\x08locality\x18\n \x01(\t\x12\r\n\x05notes\x18\x0b \x01(\t\x12&\n\x0c\x63\x61pabilities\x18\x0c \x01(\x0b\x32\x10.pb.Capabilities')
+  serialized_pb='\n\x19\x63\x61rpcomm/pb/station.proto\x12\x02pb\"\x84\x01\n\nAzElLimits\x12\x1b\n\x13min_azimuth_degrees\x18\x01 \x01(\x01\x12\x1b\n\x13max_azimuth_degrees\x18\x02 \x01(\x01\x12\x1d\n\x15min_elevation_degrees\x18\x03 \x01(\x01\x12\x1d\n\x15max_elevation_degrees\x18\x04 \x01(\x01\"\xdc\x02\n\x0c\x43\x61pabilities\x12\"\n\nvhf_limits\x18\n \x01(\x0b\x32\x0e.pb.AzElLimits\x12\"\n\nuhf_limits\x18\x0b \x01(\x0b\x32\x0e.pb.AzElLimits\x12\x14\n\x0chas_receiver\x18\x01 \x01(\x08\x12!\n\x19min_receiver_frequency_hz\x18\x02 \x01(\x01\x12!\n\x19max_receiver_frequency_hz\x18\x03 \x01(\x01\x12\x1d\n\x15receiver_bandwidth_hz\x18\x04 \x01(\x01\x12\x11\n\thas_motor\x18\x05 \x01(\x08\x12\x1b\n\x13min_azimuth_degrees\x18\x06 \x01(\x01\x12\x1b\n\x13max_azimuth_degrees\x18\x07 \x01(\x01\x12\x1d\n\x15min_elevation_degrees\x18\x08 \x01(\x01\x12\x1d\n\x15max_elevation_degrees\x18\t \x01(\x01\"M\n\rStationSecret\x12\x0c\n\x04salt\x18\x01 \x01(\x0c\x12\x0c\n\x04hash\x18\x02 \x01(\x0c\x12\x0f\n\x07\x63reated\x18\x03 \x01(\x03\x12\x0f\n\x07\x65xpires\x18\x04 \x01(\x03\"\xa5\x02\n\x07Station\x12\n\n\x02id\x18\x01 \x01(\t\x12\x0e\n\x06secret\x18\x02 \x01(\t\x12(\n\rsecret_hashes\x18\x0e \x03(\x0b\x32\x11.pb.StationSecret\x12\x0e\n\x06userid\x18\x03 \x01(\t\x12\x0c\n\x04name\x18\x04 \x01(\t\x12\x0b\n\x03lat\x18\x05 \x01(\x01\x12\x0b\n\x03lng\x18\x06 \x01(\x01\x12\x11\n\televation\x18\x07 \x01(\x01\x12\x0f\n\x07\x63reated\x18\x08 \x01(\x03\x12\x14\n\x0clast_connect\x18\t \x01(\x03\x12\x19\n\x11scheduler_enabled\x18\r \x01(\x08\x12\x10\n\x08locality\x18\n \x01(\t\x12\r\n\x05notes\x18\x0b \x01(\t\x12&\n\x0c\x63\x61pabilities\x18\x0c \x01(\x0b\x32\x10.pb.Capabilities')
 
 
 
@@ -163,6 +163,55 @@ _CAPABILITIES = descriptor.Descriptor(
 )
 
 
+_STATIONSECRET = descriptor.Descriptor(
+  name='StationSecret',
+  full_name='pb.StationSecret',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    descriptor.FieldDescriptor(
+      name='salt', full_name='pb.StationSecret.salt', index=0,
+      number=1, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value="",
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    descriptor.FieldDescriptor(
+      name='hash', full_name='pb.StationSecret.hash', index=1,
+      number=2, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value="",
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    descriptor.FieldDescriptor(
+      name='created', full_name='pb.StationSecret.created', index=2,
+      number=3, type=3, cpp_type=2, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    descriptor.FieldDescriptor(
+      name='expires', full_name='pb.StationSecret.expires', index=3,
+      number=4, type=3, cpp_type=2, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  extension_ranges=[],
+  serialized_start=519,
+  serialized_end=596,
+)
+
+
 _STATION = descriptor.Descriptor(
   name='Station',
   full_name='pb.Station',
@@ -185,77 +234,84 @@ _STATION = descriptor.Descriptor(
       is_extension=False, extension_scope=None,
       options=None),
     descriptor.FieldDescriptor(
-      name='userid', full_name='pb.Station.userid', index=2,
+      name='secret_hashes', full_name='pb.Station.secret_hashes', index=2,
+      number=14, type=11, cpp_type=10, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    descriptor.FieldDescriptor(
+      name='userid', full_name='pb.Station.userid', index=3,
       number=3, type=9, cpp_type=9, label=1,
       has_default_value=False, default_value=unicode("", "utf-8"),
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     descriptor.FieldDescriptor(
-      name='name', full_name='pb.Station.name', index=3,
+      name='name', full_name='pb.Station.name', index=4,
       number=4, type=9, cpp_type=9, label=1,
       has_default_value=False, default_value=unicode("", "utf-8"),
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     descriptor.FieldDescriptor(
-      name='lat', full_name='pb.Station.lat', index=4,
+      name='lat', full_name='pb.Station.lat', index=5,
       number=5, type=1, cpp_type=5, label=1,
       has_default_value=False, default_value=0,
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     descriptor.FieldDescriptor(
-      name='lng', full_name='pb.Station.lng', index=5,
+      name='lng', full_name='pb.Station.lng', index=6,
       number=6, type=1, cpp_type=5, label=1,
       has_default_value=False, default_value=0,
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     descriptor.FieldDescriptor(
-      name='elevation', full_name='pb.Station.elevation', index=6,
+      name='elevation', full_name='pb.Station.elevation', index=7,
       number=7, type=1, cpp_type=5, label=1,
       has_default_value=False, default_value=0,
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     descriptor.FieldDescriptor(
-      name='created', full_name='pb.Station.created', index=7,
+      name='created', full_name='pb.Station.created', index=8,
       number=8, type=3, cpp_type=2, label=1,
       has_default_value=False, default_value=0,
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     descriptor.FieldDescriptor(
-      name='last_connect', full_name='pb.Station.last_connect', index=8,
+      name='last_connect', full_name='pb.Station.last_connect', index=9,
       number=9, type=3, cpp_type=2, label=1,
       has_default_value=False, default_value=0,
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     descriptor.FieldDescriptor(
-      name='scheduler_enabled', full_name='pb.Station.scheduler_enabled', index=9,
+      name='scheduler_enabled', full_name='pb.Station.scheduler_enabled', index=10,
       number=13, type=8, cpp_type=7, label=1,
       has_default_value=False, default_value=False,
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     descriptor.FieldDescriptor(
-      name='locality', full_name='pb.Station.locality', index=10,
+      name='locality', full_name='pb.Station.locality', index=11,
       number=10, type=9, cpp_type=9, label=1,
       has_default_value=False, default_value=unicode("", "utf-8"),
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     descriptor.FieldDescriptor(
-      name='notes', full_name='pb.Station.notes', index=11,
+      name='notes', full_name='pb.Station.notes', index=12,
       number=11, type=9, cpp_type=9, label=1,
       has_default_value=False, default_value=unicode("", "utf-8"),
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     descriptor.FieldDescriptor(
-      name='capabilities', full_name='pb.Station.capabilities', index=12,
+      name='capabilities', full_name='pb.Station.capabilities', index=13,
       number=12, type=11, cpp_type=10, label=1,
       has_default_value=False, default_value=None,
       message_type=None, enum_type=None, containing_type=None,
@@ -270,15 +326,17 @@ _STATION = descriptor.Descriptor(
   options=None,
   is_extendable=False,
   extension_ranges=[],
-  serialized_start=520,
-  serialized_end=771,
+  serialized_start=599,
+  serialized_end=892,
 )
 
 _CAPABILITIES.fields_by_name['vhf_limits'].message_type = _AZELLIMITS
 _CAPABILITIES.fields_by_name['uhf_limits'].message_type = _AZELLIMITS
+_STATION.fields_by_name['secret_hashes'].message_type = _STATIONSECRET
 _STATION.fields_by_name['capabilities'].message_type = _CAPABILITIES
 DESCRIPTOR.message_types_by_name['AzElLimits'] = _AZELLIMITS
 DESCRIPTOR.message_types_by_name['Capabilities'] = _CAPABILITIES
+DESCRIPTOR.message_types_by_name['StationSecret'] = _STATIONSECRET
 DESCRIPTOR.message_types_by_name['Station'] = _STATION
 
 class AzElLimits(message.Message):
@@ -293,6 +351,12 @@ class Capabilities(message.Message):
   
   # @@protoc_insertion_point(class_scope:pb.Capabilities)
 
+class StationSecret(message.Message):
+  __metaclass__ = reflection.GeneratedProtocolMessageType
+  DESCRIPTOR = _STATIONSECRET
+  
+  # @@protoc_insertion_point(class_scope:pb.StationSecret)
+
 class Station(message.Message):
   __metaclass__ = reflection.GeneratedProtocolMessageType
   DESCRIPTOR = _STATION
@@ -97,6 +97,51 @@ func (x *Contact_Blob_Format) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+type Contact_Capture_Status int32
+
+const (
+	Contact_Capture_PENDING         Contact_Capture_Status = 1
+	Contact_Capture_SUCCESS         Contact_Capture_Status = 2
+	Contact_Capture_PARTIAL         Contact_Capture_Status = 3
+	Contact_Capture_NO_SIGNAL       Contact_Capture_Status = 4
+	Contact_Capture_STATION_OFFLINE Contact_Capture_Status = 5
+)
+
+var Contact_Capture_Status_name = map[int32]string{
+	1: "PENDING",
+	2: "SUCCESS",
+	3: "PARTIAL",
+	4: "NO_SIGNAL",
+	5: "STATION_OFFLINE",
+}
+var Contact_Capture_Status_value = map[string]int32{
+	"PENDING":         1,
+	"SUCCESS":         2,
+	"PARTIAL":         3,
+	"NO_SIGNAL":       4,
+	"STATION_OFFLINE": 5,
+}
+
+func (x Contact_Capture_Status) Enum() *Contact_Capture_Status {
+	p := new(Contact_Capture_Status)
+	*p = x
+	return p
+}
+func (x Contact_Capture_Status) String() string {
+	return proto.EnumName(Contact_Capture_Status_name, int32(x))
+}
+func (x Contact_Capture_Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(x.String())
+}
+func (x *Contact_Capture_Status) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(Contact_Capture_Status_value, data, "Contact_Capture_Status")
+	if err != nil {
+		return err
+	}
+	*x = Contact_Capture_Status(value)
+	return nil
+}
+
 type IQParams struct {
 	SampleRate       *int32         `protobuf:"varint,1,opt,name=sample_rate" json:"sample_rate,omitempty"`
 	Type             *IQParams_Type `protobuf:"varint,2,opt,name=type,enum=pb.IQParams_Type" json:"type,omitempty"`
@@ -122,17 +167,19 @@ func (this *IQParams) GetType() IQParams_Type {
 }
 
 type Contact struct {
-	Id               *string         `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
-	SatelliteId      *string         `protobuf:"bytes,9,opt,name=satellite_id" json:"satellite_id,omitempty"`
-	StartTimestamp   *int64          `protobuf:"varint,6,opt,name=start_timestamp" json:"start_timestamp,omitempty"`
-	EndTimestamp     *int64          `protobuf:"varint,8,opt,name=end_timestamp" json:"end_timestamp,omitempty"`
-	Blob             []*Contact_Blob `protobuf:"bytes,10,rep,name=blob" json:"blob,omitempty"`
-	StationId        *string         `protobuf:"bytes,2,opt,name=station_id" json:"station_id,omitempty"`
-	UserId           *string         `protobuf:"bytes,7,opt,name=user_id" json:"user_id,omitempty"`
-	Lat              *float64        `protobuf:"fixed64,3,opt,name=lat" json:"lat,omitempty"`
-	Lng              *float64        `protobuf:"fixed64,4,opt,name=lng" json:"lng,omitempty"`
-	Elevation        *float64        `protobuf:"fixed64,5,opt,name=elevation" json:"elevation,omitempty"`
-	XXX_unrecognized []byte          `json:"-"`
+	Id               *string          `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	SatelliteId      *string          `protobuf:"bytes,9,opt,name=satellite_id" json:"satellite_id,omitempty"`
+	StartTimestamp   *int64           `protobuf:"varint,6,opt,name=start_timestamp" json:"start_timestamp,omitempty"`
+	EndTimestamp     *int64           `protobuf:"varint,8,opt,name=end_timestamp" json:"end_timestamp,omitempty"`
+	Blob             []*Contact_Blob  `protobuf:"bytes,10,rep,name=blob" json:"blob,omitempty"`
+	Capture          *Contact_Capture `protobuf:"bytes,11,opt,name=capture" json:"capture,omitempty"`
+	Version          *int64           `protobuf:"varint,12,opt,name=version" json:"version,omitempty"`
+	StationId        *string          `protobuf:"bytes,2,opt,name=station_id" json:"station_id,omitempty"`
+	UserId           *string          `protobuf:"bytes,7,opt,name=user_id" json:"user_id,omitempty"`
+	Lat              *float64         `protobuf:"fixed64,3,opt,name=lat" json:"lat,omitempty"`
+	Lng              *float64         `protobuf:"fixed64,4,opt,name=lng" json:"lng,omitempty"`
+	Elevation        *float64         `protobuf:"fixed64,5,opt,name=elevation" json:"elevation,omitempty"`
+	XXX_unrecognized []byte           `json:"-"`
 }
 
 func (this *Contact) Reset()         { *this = Contact{} }
@@ -167,6 +214,20 @@ func (this *Contact) GetEndTimestamp() int64 {
 	return 0
 }
 
+func (this *Contact) GetCapture() *Contact_Capture {
+	if this != nil {
+		return this.Capture
+	}
+	return nil
+}
+
+func (this *Contact) GetVersion() int64 {
+	if this != nil && this.Version != nil {
+		return *this.Version
+	}
+	return 0
+}
+
 func (this *Contact) GetStationId() string {
 	if this != nil && this.StationId != nil {
 		return *this.StationId
@@ -250,7 +311,81 @@ func (this *Contact_Blob) GetIqParams() *IQParams {
 	return nil
 }
 
+type Contact_Capture struct {
+	PlannedStartTimestamp *int64                        `protobuf:"varint,1,opt,name=planned_start_timestamp" json:"planned_start_timestamp,omitempty"`
+	PlannedEndTimestamp   *int64                        `protobuf:"varint,2,opt,name=planned_end_timestamp" json:"planned_end_timestamp,omitempty"`
+	RpcFailure            []*Contact_Capture_RPCFailure `protobuf:"bytes,3,rep,name=rpc_failure" json:"rpc_failure,omitempty"`
+	IqBytes               *int64                        `protobuf:"varint,4,opt,name=iq_bytes" json:"iq_bytes,omitempty"`
+	FramesDecoded         *int32                        `protobuf:"varint,5,opt,name=frames_decoded" json:"frames_decoded,omitempty"`
+	Status                *Contact_Capture_Status       `protobuf:"varint,6,opt,name=status,enum=pb.Contact_Capture_Status" json:"status,omitempty"`
+	XXX_unrecognized      []byte                        `json:"-"`
+}
+
+func (this *Contact_Capture) Reset()         { *this = Contact_Capture{} }
+func (this *Contact_Capture) String() string { return proto.CompactTextString(this) }
+func (*Contact_Capture) ProtoMessage()       {}
+
+func (this *Contact_Capture) GetPlannedStartTimestamp() int64 {
+	if this != nil && this.PlannedStartTimestamp != nil {
+		return *this.PlannedStartTimestamp
+	}
+	return 0
+}
+
+func (this *Contact_Capture) GetPlannedEndTimestamp() int64 {
+	if this != nil && this.PlannedEndTimestamp != nil {
+		return *this.PlannedEndTimestamp
+	}
+	return 0
+}
+
+func (this *Contact_Capture) GetIqBytes() int64 {
+	if this != nil && this.IqBytes != nil {
+		return *this.IqBytes
+	}
+	return 0
+}
+
+func (this *Contact_Capture) GetFramesDecoded() int32 {
+	if this != nil && this.FramesDecoded != nil {
+		return *this.FramesDecoded
+	}
+	return 0
+}
+
+func (this *Contact_Capture) GetStatus() Contact_Capture_Status {
+	if this != nil && this.Status != nil {
+		return *this.Status
+	}
+	return 0
+}
+
+type Contact_Capture_RPCFailure struct {
+	Rpc              *string `protobuf:"bytes,1,opt,name=rpc" json:"rpc,omitempty"`
+	Error            *string `protobuf:"bytes,2,opt,name=error" json:"error,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (this *Contact_Capture_RPCFailure) Reset()         { *this = Contact_Capture_RPCFailure{} }
+func (this *Contact_Capture_RPCFailure) String() string { return proto.CompactTextString(this) }
+func (*Contact_Capture_RPCFailure) ProtoMessage()       {}
+
+func (this *Contact_Capture_RPCFailure) GetRpc() string {
+	if this != nil && this.Rpc != nil {
+		return *this.Rpc
+	}
+	return ""
+}
+
+func (this *Contact_Capture_RPCFailure) GetError() string {
+	if this != nil && this.Error != nil {
+		return *this.Error
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterEnum("pb.IQParams_Type", IQParams_Type_name, IQParams_Type_value)
 	proto.RegisterEnum("pb.Contact_Blob_Format", Contact_Blob_Format_name, Contact_Blob_Format_value)
+	proto.RegisterEnum("pb.Contact_Capture_Status", Contact_Capture_Status_name, Contact_Capture_Status_value)
 }
@@ -12,7 +12,7 @@ import carpcomm.pb.telemetry_pb2
 DESCRIPTOR = descriptor.FileDescriptor(
   name='carpcomm/pb/stream.proto',
   package='pb',
-  serialized_pb='\n\x18\x63\x61rpcomm/pb/stream.proto\x12\x02pb\x1a\x1b\x63\x61rpcomm/pb/telemetry.proto\"l\n\x08IQParams\x12\x13\n\x0bsample_rate\x18\x01 \x01(\x05\x12\x1f\n\x04type\x18\x02 \x01(\x0e\x32\x11.pb.IQParams.Type\"*\n\x04Type\x12\t\n\x05UINT8\x10\x01\x12\n\n\x06SINT16\x10\x02\x12\x0b\n\x07\x46LOAT32\x10\x03\"\xa7\x03\n\x07\x43ontact\x12\n\n\x02id\x18\x01 \x01(\t\x12\x14\n\x0csatellite_id\x18\t \x01(\t\x12\x17\n\x0fstart_timestamp\x18\x06 \x01(\x03\x12\x15\n\rend_timestamp\x18\x08 \x01(\x03\x12\x1e\n\x04\x62lob\x18\n \x03(\x0b\x32\x10.pb.Contact.Blob\x12\x12\n\nstation_id\x18\x02 \x01(\t\x12\x0f\n\x07user_id\x18\x07 \x01(\t\x12\x0b\n\x03lat\x18\x03 \x01(\x01\x12\x0b\n\x03lng\x18\x04 \x01(\x01\x12\x11\n\televation\x18\x05 \x01(\x01\x1a\xd7\x01\n\x04\x42lob\x12\'\n\x06\x66ormat\x18\x02 \x01(\x0e\x32\x17.pb.Contact.Blob.Format\x12\x0c\n\x04path\x18\x01 \x01(\t\x12\x13\n\x0binline_data\x18\x03 \x01(\x0c\x12!\n\x05\x64\x61tum\x18\x04 \x01(\x0b\x32\x12.pb.TelemetryDatum\x12\x1f\n\tiq_params\x18\x05 \x01(\x0b\x32\x0c.pb.IQParams\"?\n\x06\x46ormat\x12\x06\n\x02IQ\x10\x01\x12\t\n\x05MORSE\x10\x02\x12\t\n\x05\x46RAME\x10\x03\x12\t\n\x05\x44\x41TUM\x10\x04\x12\x0c\n\x08\x46REEFORM\x10\x05')
+  serialized_pb='\n\x18\x63\x61rpcomm/pb/stream.proto\x12\x02pb\x1a\x1b\x63\x61rpcomm/pb/telemetry.proto\"l\n\x08IQParams\x12\x13\n\x0bsample_rate\x18\x01 \x01(\x05\x12\x1f\n\x04type\x18\x02 \x01(\x0e\x32\x11.pb.IQParams.Type\"*\n\x04Type\x12\t\n\x05UINT8\x10\x01\x12\n\n\x06SINT16\x10\x02\x12\x0b\n\x07\x46LOAT32\x10\x03\"\xb4\x06\n\x07\x43ontact\x12\n\n\x02id\x18\x01 \x01(\t\x12\x14\n\x0csatellite_id\x18\t \x01(\t\x12\x17\n\x0fstart_timestamp\x18\x06 \x01(\x03\x12\x15\n\rend_timestamp\x18\x08 \x01(\x03\x12\x1e\n\x04\x62lob\x18\n \x03(\x0b\x32\x10.pb.Contact.Blob\x12$\n\x07\x63\x61pture\x18\x0b \x01(\x0b\x32\x13.pb.Contact.Capture\x12\x0f\n\x07version\x18\x0c \x01(\x03\x12\x12\n\nstation_id\x18\x02 \x01(\t\x12\x0f\n\x07user_id\x18\x07 \x01(\t\x12\x0b\n\x03lat\x18\x03 \x01(\x01\x12\x0b\n\x03lng\x18\x04 \x01(\x01\x12\x11\n\televation\x18\x05 \x01(\x01\x1a\xd7\x01\n\x04\x42lob\x12\'\n\x06\x66ormat\x18\x02 \x01(\x0e\x32\x17.pb.Contact.Blob.Format\x12\x0c\n\x04path\x18\x01 \x01(\t\x12\x13\n\x0binline_data\x18\x03 \x01(\x0c\x12!\n\x05\x64\x61tum\x18\x04 \x01(\x0b\x32\x12.pb.TelemetryDatum\x12\x1f\n\tiq_params\x18\x05 \x01(\x0b\x32\x0c.pb.IQParams\"?\n\x06\x46ormat\x12\x06\n\x02IQ\x10\x01\x12\t\n\x05MORSE\x10\x02\x12\t\n\x05\x46RAME\x10\x03\x12\t\n\x05\x44\x41TUM\x10\x04\x12\x0c\n\x08\x46REEFORM\x10\x05\x1a\xd3\x02\n\x07\x43\x61pture\x12\x1f\n\x17planned_start_timestamp\x18\x01 \x01(\x03\x12\x1d\n\x15planned_end_timestamp\x18\x02 \x01(\x03\x12\x33\n\x0brpc_failure\x18\x03 \x03(\x0b\x32\x1e.pb.Contact.Capture.RPCFailure\x12\x10\n\x08iq_bytes\x18\x04 \x01(\x03\x12\x16\n\x0e\x66rames_decoded\x18\x05 \x01(\x05\x12*\n\x06status\x18\x06 \x01(\x0e\x32\x1a.pb.Contact.Capture.Status\x1a(\n\nRPCFailure\x12\x0b\n\x03rpc\x18\x01 \x01(\t\x12\r\n\x05\x65rror\x18\x02 \x01(\t\"S\n\x06Status\x12\x0b\n\x07PENDING\x10\x01\x12\x0b\n\x07SUCCESS\x10\x02\x12\x0b\n\x07PARTIAL\x10\x03\x12\r\n\tNO_SIGNAL\x10\x04\x12\x13\n\x0fSTATION_OFFLINE\x10\x05')
 
 
 
@@ -70,8 +70,41 @@ _CONTACT_BLOB_FORMAT = descriptor.EnumDescriptor(
   ],
   containing_type=None,
   options=None,
-  serialized_start=532,
-  serialized_end=595,
+  serialized_start=587,
+  serialized_end=650,
+)
+
+_CONTACT_CAPTURE_STATUS = descriptor.EnumDescriptor(
+  name='Status',
+  full_name='pb.Contact.Capture.Status',
+  filename=None,
+  file=DESCRIPTOR,
+  values=[
+    descriptor.EnumValueDescriptor(
+      name='PENDING', index=0, number=1,
+      options=None,
+      type=None),
+    descriptor.EnumValueDescriptor(
+      name='SUCCESS', index=1, number=2,
+      options=None,
+      type=None),
+    descriptor.EnumValueDescriptor(
+      name='PARTIAL', index=2, number=3,
+      options=None,
+      type=None),
+    descriptor.EnumValueDescriptor(
+      name='NO_SIGNAL', index=3, number=4,
+      options=None,
+      type=None),
+    descriptor.EnumValueDescriptor(
+      name='STATION_OFFLINE', index=4, number=5,
+      options=None,
+      type=None),
+  ],
+  containing_type=None,
+  options=None,
+  serialized_start=909,
+  serialized_end=992,
 )
 
 
@@ -163,8 +196,105 @@ _CONTACT_BLOB = descriptor.Descriptor(
   options=None,
   is_extendable=False,
   extension_ranges=[],
-  serialized_start=380,
-  serialized_end=595,
+  serialized_start=435,
+  serialized_end=650,
+)
+
+_CONTACT_CAPTURE_RPCFAILURE = descriptor.Descriptor(
+  name='RPCFailure',
+  full_name='pb.Contact.Capture.RPCFailure',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    descriptor.FieldDescriptor(
+      name='rpc', full_name='pb.Contact.Capture.RPCFailure.rpc', index=0,
+      number=1, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=unicode("", "utf-8"),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    descriptor.FieldDescriptor(
+      name='error', full_name='pb.Contact.Capture.RPCFailure.error', index=1,
+      number=2, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=unicode("", "utf-8"),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  extension_ranges=[],
+  serialized_start=867,
+  serialized_end=907,
+)
+
+_CONTACT_CAPTURE = descriptor.Descriptor(
+  name='Capture',
+  full_name='pb.Contact.Capture',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    descriptor.FieldDescriptor(
+      name='planned_start_timestamp', full_name='pb.Contact.Capture.planned_start_timestamp', index=0,
+      number=1, type=3, cpp_type=2, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    descriptor.FieldDescriptor(
+      name='planned_end_timestamp', full_name='pb.Contact.Capture.planned_end_timestamp', index=1,
+      number=2, type=3, cpp_type=2, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    descriptor.FieldDescriptor(
+      name='rpc_failure', full_name='pb.Contact.Capture.rpc_failure', index=2,
+      number=3, type=11, cpp_type=10, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    descriptor.FieldDescriptor(
+      name='iq_bytes', full_name='pb.Contact.Capture.iq_bytes', index=3,
+      number=4, type=3, cpp_type=2, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    descriptor.FieldDescriptor(
+      name='frames_decoded', full_name='pb.Contact.Capture.frames_decoded', index=4,
+      number=5, type=5, cpp_type=1, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    descriptor.FieldDescriptor(
+      name='status', full_name='pb.Contact.Capture.status', index=5,
+      number=6, type=14, cpp_type=8, label=1,
+      has_default_value=False, default_value=1,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[_CONTACT_CAPTURE_RPCFAILURE, ],
+  enum_types=[
+    _CONTACT_CAPTURE_STATUS,
+  ],
+  options=None,
+  is_extendable=False,
+  extension_ranges=[],
+  serialized_start=653,
+  serialized_end=992,
 )
 
 _CONTACT = descriptor.Descriptor(
@@ -210,35 +340,49 @@ _CONTACT = descriptor.Descriptor(
       is_extension=False, extension_scope=None,
       options=None),
     descriptor.FieldDescriptor(
-      name='station_id', full_name='pb.Contact.station_id', index=5,
+      name='capture', full_name='pb.Contact.capture', index=5,
+      number=11, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    descriptor.FieldDescriptor(
+      name='version', full_name='pb.Contact.version', index=6,
+      number=12, type=3, cpp_type=2, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    descriptor.FieldDescriptor(
+      name='station_id', full_name='pb.Contact.station_id', index=7,
       number=2, type=9, cpp_type=9, label=1,
       has_default_value=False, default_value=unicode("", "utf-8"),
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     descriptor.FieldDescriptor(
-      name='user_id', full_name='pb.Contact.user_id', index=6,
+      name='user_id', full_name='pb.Contact.user_id', index=8,
       number=7, type=9, cpp_type=9, label=1,
       has_default_value=False, default_value=unicode("", "utf-8"),
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     descriptor.FieldDescriptor(
-      name='lat', full_name='pb.Contact.lat', index=7,
+      name='lat', full_name='pb.Contact.lat', index=9,
       number=3, type=1, cpp_type=5, label=1,
       has_default_value=False, default_value=0,
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     descriptor.FieldDescriptor(
-      name='lng', full_name='pb.Contact.lng', index=8,
+      name='lng', full_name='pb.Contact.lng', index=10,
       number=4, type=1, cpp_type=5, label=1,
       has_default_value=False, default_value=0,
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     descriptor.FieldDescriptor(
-      name='elevation', full_name='pb.Contact.elevation', index=9,
+      name='elevation', full_name='pb.Contact.elevation', index=11,
       number=5, type=1, cpp_type=5, label=1,
       has_default_value=False, default_value=0,
       message_type=None, enum_type=None, containing_type=None,
@@ -247,14 +391,14 @@ _CONTACT = descriptor.Descriptor(
   ],
   extensions=[
   ],
-  nested_types=[_CONTACT_BLOB, ],
+  nested_types=[_CONTACT_BLOB, _CONTACT_CAPTURE, ],
   enum_types=[
   ],
   options=None,
   is_extendable=False,
   extension_ranges=[],
   serialized_start=172,
-  serialized_end=595,
+  serialized_end=992,
 )
 
 _IQPARAMS.fields_by_name['type'].enum_type = _IQPARAMS_TYPE
@@ -264,7 +408,13 @@ _CONTACT_BLOB.fields_by_name['datum'].message_type = carpcomm.pb.telemetry_pb2._
 _CONTACT_BLOB.fields_by_name['iq_params'].message_type = _IQPARAMS
 _CONTACT_BLOB.containing_type = _CONTACT;
 _CONTACT_BLOB_FORMAT.containing_type = _CONTACT_BLOB;
+_CONTACT_CAPTURE_RPCFAILURE.containing_type = _CONTACT_CAPTURE;
+_CONTACT_CAPTURE.fields_by_name['rpc_failure'].message_type = _CONTACT_CAPTURE_RPCFAILURE
+_CONTACT_CAPTURE.fields_by_name['status'].enum_type = _CONTACT_CAPTURE_STATUS
+_CONTACT_CAPTURE.containing_type = _CONTACT;
+_CONTACT_CAPTURE_STATUS.containing_type = _CONTACT_CAPTURE;
 _CONTACT.fields_by_name['blob'].message_type = _CONTACT_BLOB
+_CONTACT.fields_by_name['capture'].message_type = _CONTACT_CAPTURE
 DESCRIPTOR.message_types_by_name['IQParams'] = _IQPARAMS
 DESCRIPTOR.message_types_by_name['Contact'] = _CONTACT
 
@@ -282,6 +432,18 @@ class Contact(message.Message):
     DESCRIPTOR = _CONTACT_BLOB
     
     # @@protoc_insertion_point(class_scope:pb.Contact.Blob)
+  
+  class Capture(message.Message):
+    __metaclass__ = reflection.GeneratedProtocolMessageType
+    
+    class RPCFailure(message.Message):
+      __metaclass__ = reflection.GeneratedProtocolMessageType
+      DESCRIPTOR = _CONTACT_CAPTURE_RPCFAILURE
+      
+      # @@protoc_insertion_point(class_scope:pb.Contact.Capture.RPCFailure)
+    DESCRIPTOR = _CONTACT_CAPTURE
+    
+    # @@protoc_insertion_point(class_scope:pb.Contact.Capture)
   DESCRIPTOR = _CONTACT
   
   # @@protoc_insertion_point(class_scope:pb.Contact)
@@ -0,0 +1,45 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package scheduler
+
+import "carpcomm/mux"
+import "flag"
+import "math"
+
+var doppler_program_step_hz = flag.Float64(
+	"doppler_program_step_hz",
+	200.0,
+	"Minimum change in the Doppler shifted frequency before the station "+
+		"receiver is retuned")
+
+const kSpeedOfLight = 299792458.0  // [m/s]
+
+// The time resolution of the frequency program [s].
+const kFrequencyProgramResolution = 1.0
+
+// Frequency received from a transmitter at freq_hz moving away from the
+// receiver at range_velocity [m/s].
+func dopplerShiftedFrequency(freq_hz, range_velocity float64) float64 {
+	return freq_hz * (1.0 - range_velocity/kSpeedOfLight)
+}
+
+// Compute the receiver frequency program for the pass. To avoid needlessly
+// retuning the receiver, a new frequency is only added once it differs from
+// the last one by at least step_hz. The first point is always included.
+func dopplerFrequencyProgram(points []SatPoint,
+	freq_hz, step_hz float64) []mux.FrequencyCoordinate {
+	var program []mux.FrequencyCoordinate
+	for _, p := range points {
+		f := math.Floor(
+			dopplerShiftedFrequency(freq_hz, p.RangeVelocity) + 0.5)
+		if n := len(program); n > 0 &&
+			math.Abs(f-float64(program[n-1].FrequencyHz)) < step_hz {
+			continue
+		}
+		program = append(program, mux.FrequencyCoordinate{
+			Timestamp: p.Timestamp,
+			FrequencyHz: int64(f)})
+	}
+	return program
+}
@@ -0,0 +1,73 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package scheduler
+
+import "testing"
+
+func TestDopplerShiftedFrequency(t *testing.T) {
+	// Approaching at 7.5 km/s shifts 437 MHz up by about 10.9 kHz.
+	f := dopplerShiftedFrequency(437e6, -7500.0)
+	if f < 437e6+10.9e3 || f > 437e6+11.0e3 {
+		t.Errorf("Wrong frequency: %f", f)
+	}
+	if dopplerShiftedFrequency(437e6, 0.0) != 437e6 {
+		t.Errorf("Unexpected shift without motion")
+	}
+}
+
+func TestDopplerFrequencyProgram(t *testing.T) {
+	points := []SatPoint{
+		SatPoint{Timestamp: 0, RangeVelocity: -7000.0},
+		SatPoint{Timestamp: 1, RangeVelocity: -6990.0},
+		SatPoint{Timestamp: 2, RangeVelocity: -6000.0},
+		SatPoint{Timestamp: 3, RangeVelocity: 6000.0},
+	}
+	program := dopplerFrequencyProgram(points, 145.825e6, 200.0)
+	if len(program) != 3 {
+		t.Fatalf("Wrong program length: %v", program)
+	}
+	if program[0].Timestamp != 0 || program[1].Timestamp != 2 ||
+		program[2].Timestamp != 3 {
+		t.Errorf("Wrong timestamps: %v", program)
+	}
+	for i := 1; i < len(program); i++ {
+		if program[i].FrequencyHz >= program[i-1].FrequencyHz {
+			t.Errorf("Frequency should decrease: %v", program)
+		}
+	}
+}
+
+// The frequency should decrease monotonically over a real pass.
+func TestDopplerFrequencyProgramPass(t *testing.T) {
+	p, err := testPrediction(timeWithOneHighAltitudePass)
+	if err != nil || len(p) != 1 {
+		t.Fatalf("Unexpected predictions: %v, %v", p, err)
+	}
+	begin := timestampToTime(p[0].StartTimestamp)
+	duration := Duration(p[0].EndTimestamp - p[0].StartTimestamp)
+	points, err := PassDetails(begin, duration, 47.4, 8.5, 400.0,
+		swisscubeTLE, kFrequencyProgramResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const freq_hz = 145e6
+	program := dopplerFrequencyProgram(points, freq_hz, 200.0)
+	if len(program) < 10 || len(program) > len(points) {
+		t.Errorf("Wrong program length: %d", len(program))
+	}
+	for i := 1; i < len(program); i++ {
+		d := program[i].FrequencyHz - program[i-1].FrequencyHz
+		if d > -200 {
+			t.Errorf("Wrong frequency step at %d: %d", i, d)
+		}
+	}
+	if shift := program[0].FrequencyHz - freq_hz; shift < 2000 ||
+		shift > 3500 {
+		t.Errorf("Wrong initial Doppler shift: %d", shift)
+	}
+	if program[len(program)-1].Timestamp-program[0].Timestamp >
+		duration.Seconds() {
+		t.Errorf("Program is longer than the pass")
+	}
+}
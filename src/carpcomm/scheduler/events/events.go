@@ -0,0 +1,83 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package events
+
+// Notifications about changes to stations so that the scheduler can
+// reschedule them immediately instead of polling.
+
+import "flag"
+import "net/rpc"
+
+var schedd_address = flag.String(
+	"schedd_address", ":1236", "Scheduler RPC address")
+
+type StationEventType int
+
+const (
+	// The station was edited or deleted by its owner.
+	StationEdited StationEventType = iota
+	StationConnected
+	StationDisconnected
+)
+
+func (t StationEventType) String() string {
+	switch t {
+	case StationEdited:
+		return "edited"
+	case StationConnected:
+		return "connected"
+	case StationDisconnected:
+		return "disconnected"
+	}
+	return "unknown"
+}
+
+type StationEvent struct {
+	StationId string
+	Type StationEventType
+}
+
+
+type NotifyArgs struct {
+	Event StationEvent
+}
+
+type NotifyResult struct {
+}
+
+// Events is the RPC service that receives notifications in schedd.
+type Events struct {
+	c chan StationEvent
+}
+
+func NewEvents() *Events {
+	return &Events{make(chan StationEvent, 100)}
+}
+
+func (e *Events) Notify(args *NotifyArgs, result *NotifyResult) error {
+	e.c <- args.Event
+	return nil
+}
+
+func (e *Events) Chan() <-chan StationEvent {
+	return e.c
+}
+
+// Call makes an RPC to schedd. Calls are rare so we don't keep a connection
+// open. This way schedd doesn't have to be running when the caller starts.
+func Call(method string, args interface{}, result interface{}) error {
+	client, err := rpc.DialHTTP("tcp", *schedd_address)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return client.Call(method, args, result)
+}
+
+// Notify sends an event to schedd.
+func Notify(station_id string, t StationEventType) error {
+	args := NotifyArgs{StationEvent{station_id, t}}
+	var result NotifyResult
+	return Call("Events.Notify", args, &result)
+}
@@ -0,0 +1,434 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package scheduler
+
+import "carpcomm/db"
+import "carpcomm/pb"
+import "carpcomm/scheduler/events"
+import "flag"
+import "log"
+import "math"
+import "sort"
+import "strings"
+import "sync"
+import "time"
+
+var network_replan_interval = flag.Duration(
+	"network_replan_interval",
+	10*time.Minute,
+	"How often to recompute the assignment of passes to stations")
+var diversity_satellites = flag.String(
+	"diversity_satellites",
+	"",
+	"Comma separated list of satellite ids that several stations may "+
+		"capture at the same time for diversity reception")
+var repeat_coverage_discount = flag.Float64(
+	"repeat_coverage_discount",
+	0.5,
+	"Factor by which the value of a pass is reduced for each pass of the "+
+		"same satellite already assigned to a station in the network")
+
+type NetworkConfig struct {
+	PlannerConfig
+
+	// Satellites that may be captured by several stations at once.
+	DiversitySatellites map[string]bool
+
+	RepeatCoverageDiscount float64
+}
+
+func parseSatelliteSet(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, id := range strings.Split(s, ",") {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			set[id] = true
+		}
+	}
+	return set
+}
+
+// DefaultNetworkConfig returns the configuration given by the flags.
+func DefaultNetworkConfig() NetworkConfig {
+	return NetworkConfig{
+		PlannerConfig: DefaultPlannerConfig(),
+		DiversitySatellites: parseSatelliteSet(*diversity_satellites),
+		RepeatCoverageDiscount: *repeat_coverage_discount,
+	}
+}
+
+// Whether the passes are visible at the same time.
+func passesOverlap(a, b Prediction) bool {
+	return a.StartTimestamp < b.EndTimestamp &&
+		b.StartTimestamp < a.EndTimestamp
+}
+
+// Refers to the i'th candidate pass of a station.
+type passRef struct {
+	station_id string
+	i int
+}
+
+// Assign the candidate passes of each station to stations so that as many
+// different satellites as possible are covered.
+//
+// Passes are assigned greedily in order of their marginal value: the pass
+// score discounted for every pass of the same satellite that has already
+// been assigned anywhere in the network. A pass can't be assigned if it
+// conflicts with a pass already assigned to the same station, or if
+// another station already captures the satellite at the same time, unless
+// the satellite needs diversity reception.
+func (c *NetworkConfig) assignPasses(candidates map[string][]PlannedPass) {
+	// Sort the stations so that ties are broken consistently.
+	var station_ids []string
+	for station_id, _ := range candidates {
+		station_ids = append(station_ids, station_id)
+	}
+	sort.Strings(station_ids)
+	var remaining []passRef
+	for _, station_id := range station_ids {
+		for i := range candidates[station_id] {
+			remaining = append(remaining, passRef{station_id, i})
+		}
+	}
+	pass := func(r passRef) *PlannedPass {
+		return &candidates[r.station_id][r.i]
+	}
+
+	by_station := make(map[string][]passRef)
+	by_satellite := make(map[string][]passRef)
+	for len(remaining) > 0 {
+		best := -1
+		best_value := 0.0
+		for k := 0; k < len(remaining); k++ {
+			r := remaining[k]
+			p := pass(r)
+			if c.blocked(p, r.station_id,
+				by_station[r.station_id],
+				by_satellite[*p.Satellite.Id], pass) {
+				// Assignments are never undone so it can't
+				// become unblocked.
+				remaining[k] = remaining[len(remaining)-1]
+				remaining = remaining[:len(remaining)-1]
+				k--
+				continue
+			}
+			value := p.Score * math.Pow(c.RepeatCoverageDiscount,
+				float64(len(by_satellite[*p.Satellite.Id])))
+			if best < 0 || value > best_value {
+				best = k
+				best_value = value
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		r := remaining[best]
+		p := pass(r)
+		p.Scheduled = true
+		by_station[r.station_id] = append(by_station[r.station_id], r)
+		id := *p.Satellite.Id
+		by_satellite[id] = append(by_satellite[id], r)
+		remaining[best] = remaining[len(remaining)-1]
+		remaining = remaining[:len(remaining)-1]
+	}
+}
+
+// Whether p can't be assigned to the station given the passes already
+// assigned to the station and to the satellite. The reason is recorded in
+// p.
+func (c *NetworkConfig) blocked(p *PlannedPass, station_id string,
+	station_passes, satellite_passes []passRef,
+	pass func(passRef) *PlannedPass) bool {
+	for _, r := range station_passes {
+		other := pass(r).Prediction
+		if !c.canFollow(p.Prediction, other) &&
+			!c.canFollow(other, p.Prediction) {
+			p.ConflictsWith = &pass(r).Prediction
+			return true
+		}
+	}
+	if c.DiversitySatellites[*p.Satellite.Id] {
+		return false
+	}
+	for _, r := range satellite_passes {
+		if r.station_id != station_id &&
+			passesOverlap(p.Prediction, pass(r).Prediction) {
+			p.CoveredByStationId = r.station_id
+			return true
+		}
+	}
+	return false
+}
+
+// NetworkPlanner keeps track of the passes assigned to each online station.
+// It's safe to use from multiple goroutines.
+type NetworkPlanner struct {
+	stationdb *db.StationDB
+	contactdb *db.ContactDB
+
+	mu sync.Mutex
+	station_ids []string  // sorted
+	schedules map[string]*Schedule
+	planned time.Time
+}
+
+func NewNetworkPlanner(stationdb *db.StationDB,
+	contactdb *db.ContactDB) *NetworkPlanner {
+	return &NetworkPlanner{
+		stationdb: stationdb,
+		contactdb: contactdb,
+		schedules: make(map[string]*Schedule),
+	}
+}
+
+func sameStations(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Invalidate forces the next Update to replan the network, for example
+// because a station was edited.
+func (n *NetworkPlanner) Invalidate() {
+	n.mu.Lock()
+	n.planned = time.Time{}
+	n.mu.Unlock()
+}
+
+// Update replans the network if the set of online stations changed or the
+// current plan is too old. It returns whether the network was replanned.
+func (n *NetworkPlanner) Update(station_ids []string, now time.Time) bool {
+	ids := make([]string, len(station_ids))
+	copy(ids, station_ids)
+	sort.Strings(ids)
+
+	n.mu.Lock()
+	fresh := sameStations(ids, n.station_ids) &&
+		now.Sub(n.planned) < *network_replan_interval
+	n.mu.Unlock()
+	if fresh {
+		return false
+	}
+
+	schedules := planNetwork(n.stationdb, n.contactdb,
+		DefaultNetworkConfig(), ids, now)
+
+	n.mu.Lock()
+	n.station_ids = ids
+	n.schedules = schedules
+	n.planned = now
+	n.mu.Unlock()
+	return true
+}
+
+// NextPass returns the next pass assigned to the station that starts after
+// now. ok is false if there isn't one.
+func (n *NetworkPlanner) NextPass(station_id string, now time.Time) (
+	pass Prediction, ok bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	s := n.schedules[station_id]
+	if s == nil {
+		return pass, false
+	}
+	t := 1e-9 * float64(now.UnixNano())
+	for _, p := range s.Passes {
+		if p.Scheduled && p.StartTimestamp >= t {
+			return p.Prediction, true
+		}
+	}
+	return pass, false
+}
+
+// StationSchedule returns the station's part of the network plan or nil if
+// the station isn't being planned, e.g. because it's offline or automatic
+// control is disabled.
+func (n *NetworkPlanner) StationSchedule(station_id string) *Schedule {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	// Schedules are replaced rather than modified so it's safe to share.
+	return n.schedules[station_id]
+}
+
+// A pass in StationScheduleResult. Predictions can't be sent over RPC since
+// their compatible mode isn't exported.
+type NetworkPass struct {
+	SatelliteId string
+	StartTimestamp, EndTimestamp float64
+	StartAzimuthDegrees, EndAzimuthDegrees float64
+	MaxAltitudeDegrees float64
+	Score float64
+	Scheduled bool
+	ConflictsWith *NetworkPass
+	CoveredByStationId string
+}
+
+func toNetworkPass(p Prediction) NetworkPass {
+	return NetworkPass{
+		SatelliteId: *p.Satellite.Id,
+		StartTimestamp: p.StartTimestamp,
+		EndTimestamp: p.EndTimestamp,
+		StartAzimuthDegrees: p.StartAzimuthDegrees,
+		EndAzimuthDegrees: p.EndAzimuthDegrees,
+		MaxAltitudeDegrees: p.MaxAltitudeDegrees,
+	}
+}
+
+// Returns false if the satellite no longer exists.
+func (np NetworkPass) prediction(satellites map[string]*pb.Satellite) (
+	p Prediction, ok bool) {
+	sat := satellites[np.SatelliteId]
+	if sat == nil {
+		return p, false
+	}
+	p.Satellite = sat
+	p.StartTimestamp = np.StartTimestamp
+	p.EndTimestamp = np.EndTimestamp
+	p.StartAzimuthDegrees = np.StartAzimuthDegrees
+	p.EndAzimuthDegrees = np.EndAzimuthDegrees
+	p.MaxAltitudeDegrees = np.MaxAltitudeDegrees
+	return p, true
+}
+
+type StationScheduleArgs struct {
+	StationId string
+}
+
+type StationScheduleResult struct {
+	// False if the station isn't part of the network plan.
+	Planned bool
+	Begin, End time.Time
+	Passes []NetworkPass
+}
+
+// NetworkService is the RPC service in schedd that lets the frontend show
+// the passes assigned to each station.
+type NetworkService struct {
+	network *NetworkPlanner
+}
+
+func NewNetworkService(network *NetworkPlanner) *NetworkService {
+	return &NetworkService{network}
+}
+
+func (s *NetworkService) StationSchedule(args *StationScheduleArgs,
+	result *StationScheduleResult) error {
+	schedule := s.network.StationSchedule(args.StationId)
+	if schedule == nil {
+		return nil
+	}
+	result.Planned = true
+	result.Begin = schedule.Begin
+	result.End = schedule.End
+	for _, p := range schedule.Passes {
+		np := toNetworkPass(p.Prediction)
+		np.Score = p.Score
+		np.Scheduled = p.Scheduled
+		np.CoveredByStationId = p.CoveredByStationId
+		if p.ConflictsWith != nil {
+			c := toNetworkPass(*p.ConflictsWith)
+			np.ConflictsWith = &c
+		}
+		result.Passes = append(result.Passes, np)
+	}
+	return nil
+}
+
+// NetworkSchedule asks schedd for the passes assigned to the station. It
+// returns nil, nil if the station isn't part of the network plan.
+func NetworkSchedule(station_id string) (*Schedule, error) {
+	args := StationScheduleArgs{station_id}
+	var result StationScheduleResult
+	err := events.Call("NetworkService.StationSchedule", args, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result.schedule(station_id, db.GlobalSatelliteDB().Map), nil
+}
+
+// Returns nil if the station isn't part of the network plan. Passes of
+// satellites that no longer exist are dropped.
+func (r *StationScheduleResult) schedule(station_id string,
+	satellites map[string]*pb.Satellite) *Schedule {
+	if !r.Planned {
+		return nil
+	}
+	s := &Schedule{
+		StationId: station_id,
+		Begin: r.Begin,
+		End: r.End,
+	}
+	for _, np := range r.Passes {
+		var p PlannedPass
+		var ok bool
+		if p.Prediction, ok = np.prediction(satellites); !ok {
+			continue
+		}
+		p.Score = np.Score
+		p.Scheduled = np.Scheduled
+		p.CoveredByStationId = np.CoveredByStationId
+		if np.ConflictsWith != nil {
+			c, ok := np.ConflictsWith.prediction(satellites)
+			if ok {
+				p.ConflictsWith = &c
+			}
+		}
+		s.Passes = append(s.Passes, p)
+	}
+	return s
+}
+
+// Plan all stations with automatic control enabled together.
+func planNetwork(stationdb *db.StationDB,
+	contactdb *db.ContactDB,
+	config NetworkConfig,
+	station_ids []string,
+	now time.Time) map[string]*Schedule {
+	infos := make(map[string]satelliteInfo)
+	candidates := make(map[string][]PlannedPass)
+	for _, id := range station_ids {
+		station, err := stationdb.Lookup(id)
+		if err != nil {
+			log.Printf("%s: Station lookup error: %s", id, err.Error())
+			continue
+		}
+		if station == nil || station.SchedulerEnabled == nil ||
+			*station.SchedulerEnabled == false {
+			continue
+		}
+		passes, err := candidatePasses(
+			station, contactdb, config.PlannerConfig, now, infos)
+		if err != nil {
+			log.Printf("%s: Error getting pass predictions: %s",
+				id, err.Error())
+		}
+		candidates[id] = passes
+	}
+
+	config.assignPasses(candidates)
+
+	schedules := make(map[string]*Schedule)
+	num_scheduled := 0
+	for id, passes := range candidates {
+		schedules[id] = &Schedule{
+			StationId: id,
+			Begin: now,
+			End: now.Add(config.Horizon),
+			Passes: passes,
+		}
+		num_scheduled += len(schedules[id].Scheduled())
+	}
+	log.Printf("Network plan: %d stations, %d satellites, "+
+		"%d passes scheduled", len(schedules), len(infos), num_scheduled)
+	return schedules
+}
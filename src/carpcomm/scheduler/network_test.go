@@ -0,0 +1,162 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package scheduler
+
+import "bytes"
+import "carpcomm/pb"
+import "encoding/gob"
+import "testing"
+
+func testNetworkConfig() NetworkConfig {
+	return NetworkConfig{
+		PlannerConfig: testPlannerConfig(),
+		DiversitySatellites: map[string]bool{"diversity": true},
+		RepeatCoverageDiscount: 0.5,
+	}
+}
+
+func TestAssignPassesUniqueCoverage(t *testing.T) {
+	c := testNetworkConfig()
+
+	// Both stations see a at the same time but only station1 sees b.
+	candidates := map[string][]PlannedPass{
+		"station1": []PlannedPass{
+			testPass("a", 0, 300, 0, 0, 2.0),
+			testPass("b", 100, 400, 0, 0, 1.5),
+		},
+		"station2": []PlannedPass{
+			testPass("a", 10, 310, 0, 0, 1.0),
+		},
+	}
+	c.assignPasses(candidates)
+
+	ids1 := scheduledIds(candidates["station1"])
+	ids2 := scheduledIds(candidates["station2"])
+	if len(ids1) != 1 || ids1[0] != "a" {
+		t.Errorf("Wrong station1 passes: %v", ids1)
+	}
+	if len(ids2) != 0 {
+		t.Errorf("Wrong station2 passes: %v", ids2)
+	}
+	if candidates["station2"][0].CoveredByStationId != "station1" {
+		t.Errorf("Wrong coverage: %+v", candidates["station2"][0])
+	}
+	if candidates["station1"][1].ConflictsWith == nil {
+		t.Errorf("Missing conflict: %+v", candidates["station1"][1])
+	}
+
+	// With a higher score for b, both satellites are covered.
+	candidates["station1"][1].Score = 2.5
+	for _, passes := range candidates {
+		for i := range passes {
+			passes[i].Scheduled = false
+			passes[i].ConflictsWith = nil
+			passes[i].CoveredByStationId = ""
+		}
+	}
+	c.assignPasses(candidates)
+	ids1 = scheduledIds(candidates["station1"])
+	ids2 = scheduledIds(candidates["station2"])
+	if len(ids1) != 1 || ids1[0] != "b" {
+		t.Errorf("Wrong station1 passes: %v", ids1)
+	}
+	if len(ids2) != 1 || ids2[0] != "a" {
+		t.Errorf("Wrong station2 passes: %v", ids2)
+	}
+}
+
+func TestAssignPassesDiversity(t *testing.T) {
+	c := testNetworkConfig()
+	candidates := map[string][]PlannedPass{
+		"station1": []PlannedPass{
+			testPass("diversity", 0, 300, 0, 0, 1.0),
+		},
+		"station2": []PlannedPass{
+			testPass("diversity", 10, 310, 0, 0, 1.0),
+		},
+	}
+	c.assignPasses(candidates)
+	if len(scheduledIds(candidates["station1"])) != 1 ||
+		len(scheduledIds(candidates["station2"])) != 1 {
+		t.Errorf("Diversity satellite wasn't scheduled at both stations")
+	}
+}
+
+func TestAssignPassesRepeatCoverage(t *testing.T) {
+	c := testNetworkConfig()
+
+	// Station2 can capture either the second pass of a or a pass of c.
+	// a has already been covered so c is preferred even though its
+	// score is lower.
+	candidates := map[string][]PlannedPass{
+		"station1": []PlannedPass{
+			testPass("a", 0, 300, 0, 0, 2.0),
+		},
+		"station2": []PlannedPass{
+			testPass("a", 5000, 5300, 0, 0, 1.8),
+			testPass("c", 5100, 5400, 0, 0, 1.2),
+		},
+	}
+	c.assignPasses(candidates)
+	ids2 := scheduledIds(candidates["station2"])
+	if len(ids2) != 1 || ids2[0] != "c" {
+		t.Errorf("Wrong station2 passes: %v", ids2)
+	}
+}
+
+func TestNetworkServiceStationSchedule(t *testing.T) {
+	a := testPass("a", 0, 300, 10, 20, 2.0)
+	a.Scheduled = true
+	b := testPass("b", 100, 400, 30, 40, 1.0)
+	b.ConflictsWith = &a.Prediction
+	c := testPass("deleted", 500, 600, 0, 0, 1.0)
+	c.CoveredByStationId = "station2"
+
+	n := NewNetworkPlanner(nil, nil)
+	n.schedules["station1"] = &Schedule{
+		StationId: "station1",
+		Passes: []PlannedPass{a, b, c},
+	}
+	s := NewNetworkService(n)
+
+	var result StationScheduleResult
+	err := s.StationSchedule(&StationScheduleArgs{"station2"}, &result)
+	if err != nil || result.schedule("station2", nil) != nil {
+		t.Errorf("Unplanned station: %+v, %v", result, err)
+	}
+
+	// The result is sent over RPC.
+	var buf bytes.Buffer
+	result = StationScheduleResult{}
+	err = s.StationSchedule(&StationScheduleArgs{"station1"}, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gob.NewEncoder(&buf).Encode(result); err != nil {
+		t.Fatal(err)
+	}
+	var decoded StationScheduleResult
+	if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	// The passes are rebuilt from the satellite database.
+	satellites := map[string]*pb.Satellite{
+		"a": a.Satellite,
+		"b": b.Satellite,
+	}
+	schedule := decoded.schedule("station1", satellites)
+	if schedule == nil || len(schedule.Passes) != 2 {
+		t.Fatalf("Wrong schedule: %+v", schedule)
+	}
+	p := schedule.Passes[0]
+	if !p.Prediction.Equals(a.Prediction) || !p.Scheduled || p.Score != 2 {
+		t.Errorf("Wrong first pass: %+v", p)
+	}
+	p = schedule.Passes[1]
+	if p.Scheduled || p.ConflictsWith == nil ||
+		!p.ConflictsWith.Equals(a.Prediction) {
+		t.Errorf("Wrong second pass: %+v", p)
+	}
+}
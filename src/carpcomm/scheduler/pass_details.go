@@ -3,8 +3,7 @@
 
 package scheduler
 
-import "os/exec"
-import "fmt"
+import "carpcomm/sgp4"
 import "time"
 
 type SatPoint struct {
@@ -20,11 +19,7 @@ type SatPoint struct {
 }
 
 
-const passDetailsBinaryPath = "src/carpcomm/scheduler/pass_details.py"
-
-func passDetails(
-	binary_path string,
-	begin_time time.Time,
+func PassDetails(begin_time time.Time,
 	duration time.Duration,
 	latitude_degrees,
 	longitude_degrees,
@@ -32,71 +27,35 @@ func passDetails(
 	tle string,
 	resolution_seconds float64) ([]SatPoint, error) {
 
-	begin_timestamp := 1e-9 * float64(begin_time.UnixNano())
-
-	c := exec.Command("python", binary_path)
-	in_pipe, err := c.StdinPipe()
-	if err != nil {
-		return nil, err
-	}
-	defer in_pipe.Close()
-	out_pipe, err := c.StdoutPipe()
+	sat, err := sgp4.NewSatelliteFromTLE(tle)
 	if err != nil {
 		return nil, err
 	}
-	defer out_pipe.Close()
-	if err := c.Start(); err != nil {
-		return nil, err
-	}
-	defer c.Wait()
+	obs := sgp4.Observer{
+		LatitudeDegrees: latitude_degrees,
+		LongitudeDegrees: longitude_degrees,
+		Elevation: elevation_metres}
 
-	fmt.Fprintf(in_pipe, "%f\n%f\n%f\n%f\n%f\n%s\n%f\n",
-		begin_timestamp,
-		duration.Seconds(),
-		latitude_degrees,
-		longitude_degrees,
-		elevation_metres,
-		tle,
-		resolution_seconds)
-	in_pipe.Close()
-
-	var n int
-	_, err = fmt.Fscanln(out_pipe, &n)
-	if err != nil {
-		return nil, err
-	}
+	begin_timestamp := 1e-9 * float64(begin_time.UnixNano())
 
+	n := int(duration.Seconds() / resolution_seconds)
 	r := make([]SatPoint, n)
 	for i := 0; i < n; i++ {
-		p := &r[i]
-		fmt.Fscanln(out_pipe,
-			&p.Timestamp,
-			&p.AzimuthDegrees,
-			&p.AltitudeDegrees,
-			&p.Range,
-			&p.RangeVelocity,
-			&p.LatitudeDegrees,
-			&p.LongitudeDegrees,
-			&p.Elevation,
-			&p.IsEclipsed)
+		timestamp := begin_timestamp + float64(i)*resolution_seconds
+		o, err := sat.Observe(obs, timestampToTime(timestamp))
+		if err != nil {
+			return nil, err
+		}
+		r[i] = SatPoint{
+			timestamp,
+			o.AzimuthDegrees,
+			o.AltitudeDegrees,
+			o.Range,
+			o.RangeVelocity,
+			o.LatitudeDegrees,
+			o.LongitudeDegrees,
+			o.Elevation,
+			o.IsEclipsed}
 	}
 	return r, nil
 }
-
-func PassDetails(begin_time time.Time,
-	duration time.Duration,
-	latitude_degrees,
-	longitude_degrees,
-	elevation_metres float64,
-	tle string,
-	resolution_seconds float64) ([]SatPoint, error) {
-
-	return passDetails(passDetailsBinaryPath,
-		begin_time,
-		duration,
-		latitude_degrees,
-		longitude_degrees,
-		elevation_metres,
-		tle,
-		resolution_seconds)
-}
\ No newline at end of file
@@ -3,15 +3,32 @@
 
 package scheduler
 
+import "math"
 import "testing"
 import "time"
 
+// PyEphem reports the geocentric latitude and the height above a spherical
+// Earth for the sub-satellite point while we use geodetic coordinates, so
+// those fields differ by up to 0.2 degrees and 12 km at mid latitudes.
+// PyEphem's range velocity is inaccurate and isn't compared at all (see
+// TestPassDetailsRangeVelocity).
 func pointsEqual(a, b []SatPoint) bool {
 	if len(a) != len(b) {
 		return false
 	}
 	for i := 0; i < len(a); i++ {
-		if a[i] != b[i] {
+		if a[i].Timestamp != b[i].Timestamp ||
+			math.Abs(a[i].AzimuthDegrees-b[i].AzimuthDegrees) >
+			kAngleTolerance ||
+			math.Abs(a[i].AltitudeDegrees-b[i].AltitudeDegrees) >
+			kAngleTolerance ||
+			math.Abs(a[i].Range-b[i].Range) > 1000.0 ||
+			math.Abs(a[i].LatitudeDegrees-b[i].LatitudeDegrees) >
+			kAngleTolerance ||
+			math.Abs(a[i].LongitudeDegrees-b[i].LongitudeDegrees) >
+			kAngleTolerance ||
+			math.Abs(a[i].Elevation-b[i].Elevation) > 15000.0 ||
+			a[i].IsEclipsed != b[i].IsEclipsed {
 			return false
 		}
 	}
@@ -19,8 +36,7 @@ func pointsEqual(a, b []SatPoint) bool {
 }
 
 func TestPassDetails(t *testing.T) {
-	points, err := passDetails(
-		"pass_details.py",
+	points, err := PassDetails(
 		time.Date(2012, 10, 9, 20, 36, 19, 0.0, time.UTC),
 		time.Minute,
 		47.4,
@@ -41,4 +57,28 @@ func TestPassDetails(t *testing.T) {
 		t.Errorf("Unexpected result: %v\nexpected: %v",
 			points, expected_result)
 	}
-}
\ No newline at end of file
+}
+
+// The range velocity should agree with the rate of change of the range.
+func TestPassDetailsRangeVelocity(t *testing.T) {
+	points, err := PassDetails(
+		time.Date(2012, 10, 9, 20, 36, 19, 0.0, time.UTC),
+		time.Minute,
+		47.4,
+		8.5,
+		400.0,
+		"1998-067CQ\n1 38854U 98067CP  12283.07336473  .00054398  00000-0  89879-3 0    14\n2 38854  51.6473 275.4897 0014651 145.1372 215.0744 15.51582271   671",
+		1.0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	for i := 1; i+1 < len(points); i++ {
+		dr := (points[i+1].Range - points[i-1].Range) /
+			(points[i+1].Timestamp - points[i-1].Timestamp)
+		if math.Abs(dr-points[i].RangeVelocity) > 5.0 {
+			t.Errorf("Range velocity at %d: %f, expected %f",
+				i, points[i].RangeVelocity, dr)
+		}
+	}
+}
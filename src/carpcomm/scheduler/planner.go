@@ -0,0 +1,371 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package scheduler
+
+import "carpcomm/db"
+import "carpcomm/demod"
+import "carpcomm/pb"
+import "carpcomm/sgp4"
+import "flag"
+import "log"
+import "math"
+import "sort"
+import "strconv"
+import "strings"
+import "time"
+
+var planning_horizon = flag.Duration(
+	"planning_horizon",
+	12*time.Hour,
+	"How far ahead to plan the passes of each station")
+var antenna_slew_rate = flag.Float64(
+	"antenna_slew_rate",
+	2.0,
+	"Assumed antenna rotator speed [degrees/s] used to leave enough "+
+		"time between consecutive passes")
+var antenna_settle_time = flag.Duration(
+	"antenna_settle_time",
+	10*time.Second,
+	"Extra time to leave between consecutive passes")
+var satellite_priorities = flag.String(
+	"satellite_priorities",
+	"",
+	"Comma separated list of satellite_id:priority pairs. "+
+		"Satellites that aren't listed have priority 1.")
+var satellite_priority_weight = flag.Float64(
+	"satellite_priority_weight", 1.0,
+	"Planning weight of the satellite priority")
+var elevation_weight = flag.Float64(
+	"elevation_weight", 1.0,
+	"Planning weight of the maximum altitude of a pass")
+var decoder_weight = flag.Float64(
+	"decoder_weight", 1.0,
+	"Planning weight of whether we can decode the satellite")
+var recently_heard_weight = flag.Float64(
+	"recently_heard_weight", 1.0,
+	"Planning weight of how recently the satellite was heard")
+var recently_heard_half_life = flag.Duration(
+	"recently_heard_half_life",
+	7*24*time.Hour,
+	"Time after which a satellite's recently heard score halves")
+
+// Number of recent contacts searched for decoded data.
+const kRecentlyHeardSearchLimit = 20
+
+type PlannerConfig struct {
+	Horizon time.Duration
+	MaxPassLength time.Duration
+
+	SlewRateDegrees float64  // [degrees/s]
+	SettleTime time.Duration
+
+	// Satellites that aren't listed have priority 1.
+	SatellitePriorities map[string]float64
+
+	SatellitePriorityWeight float64
+	ElevationWeight float64
+	DecoderWeight float64
+	RecentlyHeardWeight float64
+	RecentlyHeardHalfLife time.Duration
+}
+
+func parseSatellitePriorities(s string) map[string]float64 {
+	priorities := make(map[string]float64)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) != 2 {
+			log.Printf("Invalid satellite priority: %s", entry)
+			continue
+		}
+		p, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			log.Printf("Invalid satellite priority: %s", entry)
+			continue
+		}
+		priorities[parts[0]] = p
+	}
+	return priorities
+}
+
+// DefaultPlannerConfig returns the configuration given by the flags.
+func DefaultPlannerConfig() PlannerConfig {
+	return PlannerConfig{
+		Horizon: *planning_horizon,
+		MaxPassLength: maxPassLength,
+		SlewRateDegrees: *antenna_slew_rate,
+		SettleTime: *antenna_settle_time,
+		SatellitePriorities: parseSatellitePriorities(
+			*satellite_priorities),
+		SatellitePriorityWeight: *satellite_priority_weight,
+		ElevationWeight: *elevation_weight,
+		DecoderWeight: *decoder_weight,
+		RecentlyHeardWeight: *recently_heard_weight,
+		RecentlyHeardHalfLife: *recently_heard_half_life,
+	}
+}
+
+// The planning inputs that depend on the satellite rather than the pass.
+type satelliteInfo struct {
+	HasDecoder bool
+	LastHeard time.Time  // Zero if never heard.
+}
+
+type PlannedPass struct {
+	Prediction
+	Score float64
+	Scheduled bool
+
+	// For passes that aren't scheduled, a scheduled pass that prevents
+	// it from being captured.
+	ConflictsWith *Prediction
+
+	// For passes that aren't scheduled because another station captures
+	// the satellite at the same time.
+	CoveredByStationId string
+}
+
+type Schedule struct {
+	StationId string
+	Begin, End time.Time
+
+	// All predicted passes sorted by start time, including those that
+	// weren't scheduled.
+	Passes []PlannedPass
+}
+
+// Scheduled returns the passes that will be captured.
+func (s *Schedule) Scheduled() (scheduled []PlannedPass) {
+	for _, p := range s.Passes {
+		if p.Scheduled {
+			scheduled = append(scheduled, p)
+		}
+	}
+	return scheduled
+}
+
+func (c *PlannerConfig) score(p Prediction, info satelliteInfo,
+	now time.Time) float64 {
+	priority := 1.0
+	if v, ok := c.SatellitePriorities[*p.Satellite.Id]; ok {
+		priority = v
+	}
+	s := c.SatellitePriorityWeight * priority
+	s += c.ElevationWeight * p.MaxAltitudeDegrees / 90.0
+	if info.HasDecoder {
+		s += c.DecoderWeight
+	}
+	if !info.LastHeard.IsZero() && c.RecentlyHeardHalfLife > 0 {
+		age := now.Sub(info.LastHeard)
+		if age < 0 {
+			age = 0
+		}
+		s += c.RecentlyHeardWeight * math.Pow(
+			0.5, age.Seconds()/c.RecentlyHeardHalfLife.Seconds())
+	}
+	return s
+}
+
+// Time [s] needed to move the antenna from the end of a to the start of b.
+// Passes start and end near the minimum elevation so only the azimuth
+// matters.
+func (c *PlannerConfig) slewTime(a, b Prediction) float64 {
+	d := math.Abs(b.StartAzimuthDegrees - a.EndAzimuthDegrees)
+	if d > 180.0 {
+		d = 360.0 - d
+	}
+	t := c.SettleTime.Seconds()
+	if c.SlewRateDegrees > 0.0 {
+		t += d / c.SlewRateDegrees
+	}
+	return t
+}
+
+// Whether b can be captured after a.
+func (c *PlannerConfig) canFollow(a, b Prediction) bool {
+	return a.EndTimestamp+c.slewTime(a, b) <= b.StartTimestamp
+}
+
+// Choose the set of mutually compatible passes with the highest total score.
+// Passes must be sorted by start time. Since each pass only has to be
+// compatible with the one before it, this is a longest path problem over
+// the passes in order.
+func (c *PlannerConfig) planPasses(passes []PlannedPass) {
+	n := len(passes)
+	best := make([]float64, n)
+	prev := make([]int, n)
+	last := -1
+	for i := range passes {
+		best[i] = passes[i].Score
+		prev[i] = -1
+		for j := 0; j < i; j++ {
+			if !c.canFollow(passes[j].Prediction,
+				passes[i].Prediction) {
+				continue
+			}
+			if s := best[j] + passes[i].Score; s > best[i] {
+				best[i] = s
+				prev[i] = j
+			}
+		}
+		if last < 0 || best[i] > best[last] {
+			last = i
+		}
+	}
+
+	for i := last; i >= 0; i = prev[i] {
+		passes[i].Scheduled = true
+	}
+
+	for i := range passes {
+		if passes[i].Scheduled {
+			continue
+		}
+		for j := range passes {
+			if !passes[j].Scheduled {
+				continue
+			}
+			a, b := passes[i].Prediction, passes[j].Prediction
+			if !c.canFollow(a, b) && !c.canFollow(b, a) {
+				passes[i].ConflictsWith = &passes[j].Prediction
+				break
+			}
+		}
+	}
+}
+
+// Shorten a pass to the given length around its midpoint.
+func trimPass(p Prediction, max_length time.Duration,
+	obs sgp4.Observer) Prediction {
+	d := p.EndTimestamp - p.StartTimestamp
+	if d <= max_length.Seconds() {
+		return p
+	}
+	Δ := d - max_length.Seconds()
+	p.StartTimestamp += Δ/2
+	p.EndTimestamp -= Δ/2
+
+	// Update the azimuths so that slew times are estimated correctly.
+	sat, err := sgp4.NewSatelliteFromTLE(*p.Satellite.Tle)
+	if err != nil {
+		return p
+	}
+	if o, err := sat.Observe(obs, timestampToTime(p.StartTimestamp));
+		err == nil {
+		p.StartAzimuthDegrees = o.AzimuthDegrees
+	}
+	if o, err := sat.Observe(obs, timestampToTime(p.EndTimestamp));
+		err == nil {
+		p.EndAzimuthDegrees = o.AzimuthDegrees
+	}
+	return p
+}
+
+// Return the start time of the latest contact with decoded data, or the zero
+// time if there isn't one.
+func lastHeard(contactdb *db.ContactDB, satellite_id string) time.Time {
+	contacts, err := contactdb.SearchBySatelliteId(
+		satellite_id, kRecentlyHeardSearchLimit)
+	if err != nil {
+		log.Printf("SearchBySatelliteId error: %s", err.Error())
+		return time.Time{}
+	}
+	for _, c := range contacts {
+		if c.StartTimestamp == nil {
+			continue
+		}
+		for _, b := range c.Blob {
+			if b.Format != nil && *b.Format != pb.Contact_Blob_IQ {
+				return time.Unix(*c.StartTimestamp, 0)
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// Predict and score all passes over the station during the planning horizon
+// sorted by start time. infos caches the satellite information between
+// calls. contactdb may be nil in which case recently heard satellites
+// aren't preferred.
+func candidatePasses(station *pb.Station,
+	contactdb *db.ContactDB,
+	config PlannerConfig,
+	begin_time time.Time,
+	infos map[string]satelliteInfo) ([]PlannedPass, error) {
+	predictions, err := predictPasses(station, begin_time, config.Horizon)
+	// Prediction errors may only affect some satellites so we plan with
+	// whatever we have.
+	if len(predictions) == 0 {
+		return nil, err
+	}
+
+	obs := sgp4.Observer{
+		LatitudeDegrees: *station.Lat,
+		LongitudeDegrees: *station.Lng}
+	if station.Elevation != nil {
+		obs.Elevation = *station.Elevation
+	}
+
+	var passes []PlannedPass
+	for _, p := range predictions {
+		if p.Satellite.DisableTracking != nil &&
+			*p.Satellite.DisableTracking == true {
+			continue
+		}
+
+		id := *p.Satellite.Id
+		info, ok := infos[id]
+		if !ok {
+			info.HasDecoder = demod.HasDecoder(p.Satellite)
+			if contactdb != nil {
+				info.LastHeard = lastHeard(contactdb, id)
+			}
+			infos[id] = info
+		}
+
+		p = trimPass(p, config.MaxPassLength, obs)
+		passes = append(passes, PlannedPass{
+			Prediction: p,
+			Score: config.score(p, info, begin_time),
+		})
+	}
+
+	// Sort again since trimming may affect the order.
+	sort.Sort(plannedPassList(passes))
+	return passes, err
+}
+
+// PlanStation predicts all passes over the station during the planning
+// horizon and chooses which ones to capture, ignoring other stations.
+// contactdb may be nil in which case recently heard satellites aren't
+// preferred.
+func PlanStation(station *pb.Station,
+	contactdb *db.ContactDB,
+	config PlannerConfig,
+	begin_time time.Time) (*Schedule, error) {
+	passes, err := candidatePasses(station, contactdb, config, begin_time,
+		make(map[string]satelliteInfo))
+	config.planPasses(passes)
+	return &Schedule{
+		StationId: *station.Id,
+		Begin: begin_time,
+		End: begin_time.Add(config.Horizon),
+		Passes: passes,
+	}, err
+}
+
+type plannedPassList []PlannedPass
+
+func (p plannedPassList) Len() int {
+	return len(p)
+}
+func (p plannedPassList) Less(i, j int) bool {
+	return p[i].StartTimestamp < p[j].StartTimestamp
+}
+func (p plannedPassList) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+}
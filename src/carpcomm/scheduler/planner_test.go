@@ -0,0 +1,155 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package scheduler
+
+import "carpcomm/pb"
+import "carpcomm/sgp4"
+import "code.google.com/p/goprotobuf/proto"
+import "math"
+import "testing"
+import "time"
+
+func testPlannerConfig() PlannerConfig {
+	return PlannerConfig{
+		Horizon: 12 * time.Hour,
+		MaxPassLength: 5 * time.Minute,
+		SlewRateDegrees: 2.0,
+		SettleTime: 10 * time.Second,
+		SatellitePriorities: map[string]float64{"important": 5.0},
+		SatellitePriorityWeight: 1.0,
+		ElevationWeight: 1.0,
+		DecoderWeight: 1.0,
+		RecentlyHeardWeight: 1.0,
+		RecentlyHeardHalfLife: 24 * time.Hour,
+	}
+}
+
+func testPass(id string, start, end, start_az, end_az, score float64) PlannedPass {
+	var p PlannedPass
+	p.Satellite = &pb.Satellite{Id: proto.String(id)}
+	p.StartTimestamp = start
+	p.EndTimestamp = end
+	p.StartAzimuthDegrees = start_az
+	p.EndAzimuthDegrees = end_az
+	p.Score = score
+	return p
+}
+
+func scheduledIds(passes []PlannedPass) (ids []string) {
+	for _, p := range passes {
+		if p.Scheduled {
+			ids = append(ids, *p.Satellite.Id)
+		}
+	}
+	return ids
+}
+
+func TestPlanPassesOverlap(t *testing.T) {
+	c := testPlannerConfig()
+	passes := []PlannedPass{
+		testPass("a", 0, 300, 0, 0, 1.0),
+		testPass("b", 200, 500, 0, 0, 1.5),
+		testPass("c", 400, 700, 0, 0, 1.0),
+		testPass("d", 2000, 2300, 0, 0, 0.1),
+	}
+	c.planPasses(passes)
+
+	// a and c together are worth more than b alone.
+	ids := scheduledIds(passes)
+	if len(ids) != 3 || ids[0] != "a" || ids[1] != "c" || ids[2] != "d" {
+		t.Errorf("Wrong passes scheduled: %v", ids)
+	}
+	if passes[1].ConflictsWith == nil ||
+		*passes[1].ConflictsWith.Satellite.Id != "a" {
+		t.Errorf("Wrong conflict: %v", passes[1].ConflictsWith)
+	}
+}
+
+func TestPlanPassesSlewTime(t *testing.T) {
+	c := testPlannerConfig()
+
+	// 180 degrees at 2 degrees/s takes 90 s plus the settle time.
+	passes := []PlannedPass{
+		testPass("a", 0, 300, 90, 0, 1.0),
+		testPass("b", 360, 600, 180, 90, 2.0),
+	}
+	c.planPasses(passes)
+	ids := scheduledIds(passes)
+	if len(ids) != 1 || ids[0] != "b" {
+		t.Errorf("Wrong passes scheduled: %v", ids)
+	}
+
+	// The shorter way around is only 20 degrees.
+	passes = []PlannedPass{
+		testPass("a", 0, 300, 90, 350, 1.0),
+		testPass("b", 360, 600, 10, 90, 2.0),
+	}
+	c.planPasses(passes)
+	ids = scheduledIds(passes)
+	if len(ids) != 2 {
+		t.Errorf("Wrong passes scheduled: %v", ids)
+	}
+}
+
+func TestScore(t *testing.T) {
+	c := testPlannerConfig()
+	now := time.Unix(1370000000, 0)
+
+	var p Prediction
+	p.Satellite = &pb.Satellite{Id: proto.String("other")}
+	p.MaxAltitudeDegrees = 45.0
+
+	base := c.score(p, satelliteInfo{}, now)
+	if math.Abs(base-1.5) > 1e-9 {
+		t.Errorf("Wrong base score: %f", base)
+	}
+
+	s := c.score(p, satelliteInfo{HasDecoder: true}, now)
+	if math.Abs(s-base-1.0) > 1e-9 {
+		t.Errorf("Wrong decoder score: %f", s)
+	}
+
+	heard := satelliteInfo{LastHeard: now.Add(-24 * time.Hour)}
+	s = c.score(p, heard, now)
+	if math.Abs(s-base-0.5) > 1e-9 {
+		t.Errorf("Wrong recently heard score: %f", s)
+	}
+
+	p.Satellite = &pb.Satellite{Id: proto.String("important")}
+	s = c.score(p, satelliteInfo{}, now)
+	if math.Abs(s-base-4.0) > 1e-9 {
+		t.Errorf("Wrong priority score: %f", s)
+	}
+}
+
+func TestTrimPass(t *testing.T) {
+	p, err := testPrediction(timeWithOneHighAltitudePass)
+	if err != nil || len(p) != 1 {
+		t.Fatalf("Unexpected predictions: %v, %v", p, err)
+	}
+	p[0].Satellite = &pb.Satellite{Tle: proto.String(swisscubeTLE)}
+
+	obs := sgp4.Observer{
+		LatitudeDegrees: 47.4,
+		LongitudeDegrees: 8.5,
+		Elevation: 400.0}
+	trimmed := trimPass(p[0], 2*time.Minute, obs)
+	d := trimmed.EndTimestamp - trimmed.StartTimestamp
+	if math.Abs(d-120.0) > 1e-6 {
+		t.Errorf("Wrong trimmed duration: %f", d)
+	}
+	mid := 0.5 * (p[0].StartTimestamp + p[0].EndTimestamp)
+	if math.Abs(0.5*(trimmed.StartTimestamp+trimmed.EndTimestamp)-mid) >
+		1e-6 {
+		t.Errorf("Trimmed pass isn't centered")
+	}
+	if math.Abs(trimmed.StartAzimuthDegrees-p[0].StartAzimuthDegrees) <
+		kAzimuthTolerance {
+		t.Errorf("Start azimuth wasn't updated")
+	}
+
+	if untrimmed := trimPass(p[0], time.Hour, obs); !untrimmed.Equals(p[0]) {
+		t.Errorf("Short pass was modified")
+	}
+}
@@ -5,8 +5,8 @@ package scheduler
 
 import "carpcomm/db"
 import "carpcomm/pb"
-import "os/exec"
-import "fmt"
+import "carpcomm/sgp4"
+import "math"
 import "sort"
 import "time"
 import "log"
@@ -52,10 +52,181 @@ func Duration(seconds float64) time.Duration {
 }
 
 
-const predictBinaryPath = "src/carpcomm/scheduler/predict.py"
+func timestampToTime(timestamp float64) time.Time {
+	sec := math.Floor(timestamp)
+	return time.Unix(int64(sec), int64(1e9*(timestamp-sec)))
+}
+
+func isVisible(o sgp4.Observation,
+	min_altitude_degrees,
+	min_azimuth_degrees,
+	max_azimuth_degrees float64) bool {
+	if o.AltitudeDegrees < min_altitude_degrees {
+		return false
+	}
+	if min_azimuth_degrees <= max_azimuth_degrees {
+		return min_azimuth_degrees <= o.AzimuthDegrees &&
+			o.AzimuthDegrees < max_azimuth_degrees
+	}
+	return o.AzimuthDegrees < max_azimuth_degrees ||
+		o.AzimuthDegrees >= min_azimuth_degrees
+}
+
+type passFinder struct {
+	sat *sgp4.Satellite
+	obs sgp4.Observer
+	min_altitude_degrees float64
+	min_azimuth_degrees float64
+	max_azimuth_degrees float64
+}
+
+func (f *passFinder) observe(timestamp float64) (sgp4.Observation, error) {
+	return f.sat.Observe(f.obs, timestampToTime(timestamp))
+}
+
+func (f *passFinder) visibleAt(timestamp float64) (
+	bool, sgp4.Observation, error) {
+	o, err := f.observe(timestamp)
+	if err != nil {
+		return false, o, err
+	}
+	return isVisible(o,
+		f.min_altitude_degrees,
+		f.min_azimuth_degrees,
+		f.max_azimuth_degrees), o, nil
+}
+
+// The time resolution of pass start and end times [s].
+const kPredictionResolution = 1.0
+
+// Run a binary search to find the time when the visibility changes.
+func (f *passFinder) findVisibleTransition(lower, upper float64) (
+	timestamp, azimuth_degrees float64, err error) {
+	v1, o, err := f.visibleAt(lower)
+	if err != nil {
+		return 0, 0, err
+	}
+	v2, _, err := f.visibleAt(upper)
+	if err != nil {
+		return 0, 0, err
+	}
+	if v1 == v2 {
+		return lower, o.AzimuthDegrees, nil
+	}
+
+	for upper - lower > kPredictionResolution {
+		t := 0.5 * (upper + lower)
+		v, _, err := f.visibleAt(t)
+		if err != nil {
+			return 0, 0, err
+		}
+		if v == v1 {
+			lower = t
+		} else {
+			upper = t
+		}
+	}
+
+	o, err = f.observe(upper)
+	if err != nil {
+		return 0, 0, err
+	}
+	return upper, o.AzimuthDegrees, nil
+}
+
+// Altitude is a unimodal function over the duration of a pass so we can
+// find the maximum with a ternary search.
+func (f *passFinder) findMaximumAltitude(lower, upper float64) (
+	float64, error) {
+	for upper - lower > kPredictionResolution {
+		dt := upper - lower
+		o1, err := f.observe(lower + 0.3*dt)
+		if err != nil {
+			return 0, err
+		}
+		o2, err := f.observe(lower + 0.7*dt)
+		if err != nil {
+			return 0, err
+		}
+		if o1.AltitudeDegrees > o2.AltitudeDegrees {
+			upper = lower + 0.7*dt
+		} else {
+			lower = lower + 0.3*dt
+		}
+	}
+	o, err := f.observe(lower)
+	if err != nil {
+		return 0, err
+	}
+	return o.AltitudeDegrees, nil
+}
+
+// Find passes by stepping through time and refining each visibility
+// transition.
+func (f *passFinder) nextPasses(begin_timestamp, duration_seconds float64) (
+	[]Prediction, error) {
+	const step = 60.0  // [s]
+
+	var passes []Prediction
+	var current *Prediction
+	t := begin_timestamp
+	for i := 1; float64(i) <= math.Ceil(duration_seconds/step); i++ {
+		tlast := t
+		t = begin_timestamp + float64(i)*step
+
+		visible, o, err := f.visibleAt(t)
+		if err != nil {
+			return nil, err
+		}
+
+		if current == nil {
+			if !visible {
+				continue
+			}
+			start, az, err := f.findVisibleTransition(tlast, t)
+			if err != nil {
+				return nil, err
+			}
+			current = &Prediction{
+				StartTimestamp: start,
+				StartAzimuthDegrees: az}
+		} else if !visible {
+			end, az, err := f.findVisibleTransition(tlast, t)
+			if err != nil {
+				return nil, err
+			}
+			current.EndTimestamp = end
+			current.EndAzimuthDegrees = az
+			current.MaxAltitudeDegrees, err = f.findMaximumAltitude(
+				current.StartTimestamp, end)
+			if err != nil {
+				return nil, err
+			}
+			passes = append(passes, *current)
+			current = nil
+		} else {
+			// Keep track of the latest position in case the
+			// pass is still in progress at the end.
+			current.EndAzimuthDegrees = o.AzimuthDegrees
+		}
+	}
+
+	// The final pass is cut off at the end of the interval.
+	if current != nil {
+		var err error
+		current.EndTimestamp = t
+		current.MaxAltitudeDegrees, err = f.findMaximumAltitude(
+			current.StartTimestamp, t)
+		if err != nil {
+			return nil, err
+		}
+		passes = append(passes, *current)
+	}
+
+	return passes, nil
+}
 
 func predict(
-	predict_binary_path string,
 	begin_time time.Time,
 	duration time.Duration,
 	latitude_degrees,
@@ -66,53 +237,22 @@ func predict(
 	max_azimuth_degrees float64,
 	tle string) ([]Prediction, error) {
 
-	begin_timestamp := 1e-9 * float64(begin_time.UnixNano())
-
-	c := exec.Command("python", predict_binary_path)
-	in_pipe, err := c.StdinPipe()
-	if err != nil {
-		return nil, err
-	}
-	defer in_pipe.Close()
-	out_pipe, err := c.StdoutPipe()
+	sat, err := sgp4.NewSatelliteFromTLE(tle)
 	if err != nil {
 		return nil, err
 	}
-	defer out_pipe.Close()
-	if err := c.Start(); err != nil {
-		return nil, err
-	}
-	defer c.Wait()
 
-	fmt.Fprintf(in_pipe, "%f\n%f\n%f\n%f\n%f\n%f\n%f\n%f\n%s\n",
-		begin_timestamp,
-		duration.Seconds(),
-		latitude_degrees,
-		longitude_degrees,
-		elevation_metres,
+	f := passFinder{
+		sat,
+		sgp4.Observer{
+			LatitudeDegrees: latitude_degrees,
+			LongitudeDegrees: longitude_degrees,
+			Elevation: elevation_metres},
 		min_altitude_degrees,
 		min_azimuth_degrees,
-		max_azimuth_degrees,
-		tle)
-	in_pipe.Close()
-
-	var n int
-	_, err = fmt.Fscanln(out_pipe, &n)
-	if err != nil {
-		return nil, err
-	}
-
-	r := make([]Prediction, n)
-	for i := 0; i < n; i++ {
-		p := &r[i]
-		fmt.Fscanln(out_pipe,
-			&p.StartTimestamp,
-			&p.EndTimestamp,
-			&p.StartAzimuthDegrees,
-			&p.EndAzimuthDegrees,
-			&p.MaxAltitudeDegrees)
-	}
-	return r, nil
+		max_azimuth_degrees}
+	begin_timestamp := 1e-9 * float64(begin_time.UnixNano())
+	return f.nextPasses(begin_timestamp, duration.Seconds())
 }
 
 func Is2mBand(hz float64) bool {
@@ -160,6 +300,14 @@ func CompatibleModes(station *pb.Station, sat *pb.Satellite) []CompatibleMode {
 }
 
 func PassPredictions(station *pb.Station) (PredictionList, error) {
+	return predictPasses(station, time.Now(), 18*time.Hour)
+}
+
+// Predict the passes of all satellites compatible with the station during
+// the given interval.
+func predictPasses(station *pb.Station,
+	begin_time time.Time,
+	duration time.Duration) (PredictionList, error) {
 	db := db.GlobalSatelliteDB()
 
 	if station.Lat == nil || station.Lng == nil {
@@ -173,8 +321,6 @@ func PassPredictions(station *pb.Station) (PredictionList, error) {
 		elevation = *station.Elevation
 	}
 
-	begin_time := time.Now()
-
 	var predictErr error = nil
 
 	var all_passes PredictionList
@@ -187,9 +333,8 @@ func PassPredictions(station *pb.Station) (PredictionList, error) {
 		for _, mode := range modes {
 
 			passes, err := predict(
-				predictBinaryPath,
 				begin_time,
-				18*time.Hour,
+				duration,
 				lat, lng, elevation,
 				*mode.limits.MinElevationDegrees,
 				*mode.limits.MinAzimuthDegrees,
@@ -3,15 +3,32 @@
 
 package scheduler
 
+import "math"
 import "testing"
 import "time"
 
+// The expected results were generated with PyEphem. Allow for small
+// differences in the models and the search resolution. The azimuth changes
+// quickly at the end of high passes so it gets a larger tolerance.
+const kTimestampTolerance = 3.0  // [s]
+const kAngleTolerance = 0.5  // [degrees]
+const kAzimuthTolerance = 1.0  // [degrees]
+
 func predictionsEqual(a, b []Prediction) bool {
 	if len(a) != len(b) {
 		return false
 	}
 	for i := 0; i < len(a); i++ {
-		if !a[i].Equals(b[i]) {
+		if math.Abs(a[i].StartTimestamp-b[i].StartTimestamp) >
+			kTimestampTolerance ||
+			math.Abs(a[i].EndTimestamp-b[i].EndTimestamp) >
+			kTimestampTolerance ||
+			math.Abs(a[i].StartAzimuthDegrees-
+				b[i].StartAzimuthDegrees) > kAzimuthTolerance ||
+			math.Abs(a[i].EndAzimuthDegrees-
+				b[i].EndAzimuthDegrees) > kAzimuthTolerance ||
+			math.Abs(a[i].MaxAltitudeDegrees-
+				b[i].MaxAltitudeDegrees) > kAngleTolerance {
 			return false
 		}
 	}
@@ -22,9 +39,10 @@ var timeWithTwoPasses = time.Date(2012, 6, 4, 8, 53, 18, 263081100, time.UTC)
 var timeWithOneHighAltitudePass = time.Date(
 	2012, 6, 11, 7, 33, 18, 263081100, time.UTC)
 
+const swisscubeTLE = "SWISSCUBE               \n1 35932U 09051B   12110.66765508  .00000638  00000-0  15500-3 0  5172\n2 35932  98.3348 213.8703 0006768 284.4795  75.6141 14.52927878136365"
+
 func testPrediction(begin_time time.Time) ([]Prediction, error) {
 	return predict(
-		"predict.py",
 		begin_time,
 		12*time.Hour,
 		47.4,
@@ -33,7 +51,7 @@ func testPrediction(begin_time time.Time) ([]Prediction, error) {
 		20.0,
 		270.0,
 		90.0,
-		"SWISSCUBE               \n1 35932U 09051B   12110.66765508  .00000638  00000-0  15500-3 0  5172\n2 35932  98.3348 213.8703 0006768 284.4795  75.6141 14.52927878136365")
+		swisscubeTLE)
 }
 
 func TestPredictTwoPasses(t *testing.T) {
@@ -75,7 +93,6 @@ func TestPredictOneHighAltitudePass(t *testing.T) {
 
 func TestPredictRegression1(t *testing.T) {
 	p, err := predict(
-		"predict.py",
 		time.Date(2012, 6, 19, 20, 7, 52, 0, time.UTC),
 		12*time.Hour,
 		40.134000,
@@ -103,9 +120,18 @@ func TestPredictRegression1(t *testing.T) {
 	}
 }
 
-// 2012-06-02 02:16: 45120960 ns/op
+// 2012-06-02 02:16: 45120960 ns/op (predict.py)
 func BenchmarkPredict(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		testPrediction(timeWithTwoPasses)
 	}
-}
\ No newline at end of file
+}
+
+func TestPredictInvalidTLE(t *testing.T) {
+	_, err := predict(
+		timeWithTwoPasses, time.Hour, 47.4, 8.5, 400.0, 0.0, 0.0, 360.0,
+		"SWISSCUBE\n1 35932U\n2 35932")
+	if err == nil {
+		t.Errorf("Expected an error")
+	}
+}
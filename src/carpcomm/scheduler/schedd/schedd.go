@@ -3,13 +3,18 @@ package main
 import (
 	"carpcomm/db"
 	"carpcomm/scheduler"
+	"carpcomm/scheduler/events"
 	"flag"
 	"log"
+	"net/http"
 	"net/rpc"
 )
 
 var mux_address = flag.String("mux_address", ":1235", "Mux address")
 var db_prefix = flag.String("db_prefix", "r1-", "Database table prefix")
+var rpc_port = flag.String(
+	"rpc_port", ":1236",
+	"Internal RPC port for station notifications and schedules")
 
 func main() {
 	flag.Parse()
@@ -26,5 +31,20 @@ func main() {
 	stationdb := domain.NewStationDB()
 	contactdb := domain.NewContactDB()
 
-	scheduler.ScheduleForever(stationdb, contactdb, mux)
-}
\ No newline at end of file
+	go db.RefreshTLEsForever()
+
+	network := scheduler.NewNetworkPlanner(stationdb, contactdb)
+
+	e := events.NewEvents()
+	rpc.Register(e)
+	rpc.Register(scheduler.NewNetworkService(network))
+	rpc.HandleHTTP()
+	go func() {
+		err := http.ListenAndServe(*rpc_port, nil)
+		if err != nil {
+			log.Fatalf("Error starting RPC server: %s", err.Error())
+		}
+	}()
+
+	scheduler.ScheduleForever(stationdb, contactdb, mux, network, e.Chan())
+}
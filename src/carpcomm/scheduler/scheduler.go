@@ -8,10 +8,10 @@ import "net/rpc"
 import "time"
 import "flag"
 import "fmt"
-import "sort"
 import "carpcomm/db"
 import "carpcomm/mux"
 import "carpcomm/pb"
+import "carpcomm/scheduler/events"
 import "carpcomm/util"
 import "carpcomm/util/timestamp"
 import "carpcomm/streamer/contacts"
@@ -26,9 +26,14 @@ var api_server_address = flag.String(
 	"192.168.1.48:5051",
 	"API server address")
 
-const maxPredictionDuration = 10 * time.Hour
-const controlDisabledDuration = 10 * time.Minute
 const errorWaitDuration = 10  * time.Minute
+const maxPassLength = 5 * time.Minute
+
+// The scheduler's lease on a station lasts until a little after the pass
+// in case stopping the capture takes a while.
+const kLeaseHolder = "scheduler"
+const kLeaseMargin = time.Minute
+const kLeaseCheckInterval = 10 * time.Second
 
 func GetStreamURL(contact_id string) string {
 	return fmt.Sprintf(
@@ -41,53 +46,14 @@ func GetAPIServer() (string, string) {
 }
 
 
-func getNextPass(station *pb.Station) (pass Prediction, err error) {
-	passes, err := PassPredictions(station)
-	if err != nil {
-		return pass, err
-	}
-
-	// Twiddle the predictions:
-
-	twiddled := make(PredictionList, 0)
-
-	// 1. Remove satellites with tracking disabled.
-	for _, p := range passes {
-		if p.Satellite.DisableTracking != nil &&
-			*p.Satellite.DisableTracking == true {
-			continue
-		}
-		twiddled = append(twiddled, p)
-	}
-
-	// 2. Impose maximum pass length.
-	const maxPassLength = 5 * 60
-	for _, p := range twiddled {
-		d := p.EndTimestamp - p.StartTimestamp
-		if d <= maxPassLength {
-			continue
-		}
-		Δ := d - maxPassLength
-		p.StartTimestamp += Δ/2
-		p.EndTimestamp -= Δ/2
-	}
-
-	// Sort again since twiddling may affect the order.
-	sort.Sort(twiddled)
-
-	if len(twiddled) > 0 {
-		return twiddled[0], nil
-	}
-	return pass, nil
-}
-
-
 // blocking
+// The capture is stopped early if cancel is closed.
 func capturePass(
 	contactdb *db.ContactDB,
 	mux_client *rpc.Client,
 	station pb.Station,
-	pass Prediction) error {
+	pass Prediction,
+	cancel <-chan bool) error {
 
 	log_label := *station.Id
 	satellite_id := *pass.Satellite.Id
@@ -116,12 +82,65 @@ func capturePass(
 		motor_program[i].AltitudeDegrees = p.AltitudeDegrees
 	}
 
+	var frequency_program []mux.FrequencyCoordinate
+	channel := pass.CompatibleMode.channel
+	if channel.GetDopplerStrategy() != pb.Channel_DISABLED {
+		doppler_points, err := PassDetails(
+			time.Now(),
+			duration,
+			*station.Lat,
+			*station.Lng,
+			*station.Elevation,
+			*pass.Satellite.Tle,
+			kFrequencyProgramResolution)
+		if err != nil {
+			log.Printf("%s: Error getting pass details: %s",
+				log_label, err.Error())
+			return err
+		}
+		frequency_program = dopplerFrequencyProgram(doppler_points,
+			*channel.FrequencyHz, *doppler_program_step_hz)
+	}
+
 	log.Printf("%s: Starting capture: satellite %s, "+
 		"duration: %s, freq_hz: %d, lateness: %f [s]",
 		log_label, satellite_id, duration, freq_hz, lateness)
 	log.Printf("%s: motor_program: %v", log_label, motor_program)
 
-	// FIXME: future: acquire lock
+	health, err := mux.StationHealth(mux_client, *station.Id)
+	if err != nil {
+		// Not fatal: we just don't know whether it's faulted.
+		log.Printf("%s: StationHealth failed: %s",
+			log_label, err.Error())
+	}
+	if fault := mux.HealthFault(health, time.Now()); fault != "" {
+		log.Printf("%s: Skipping pass, station is faulted: %s",
+			log_label, fault)
+		return nil
+	}
+
+	lease, granted, err := mux.StationAcquireLease(
+		mux_client, *station.Id, kLeaseHolder,
+		"Capturing "+satellite_id, duration+kLeaseMargin)
+	if err != nil {
+		log.Printf("%s: StationAcquireLease failed: %s",
+			log_label, err.Error())
+		return err
+	}
+	if !granted {
+		// Someone is using the console. Skip the pass.
+		log.Printf("%s: Station is leased by %s until %s",
+			log_label, lease.Holder, lease.Expires)
+		return nil
+	}
+	token := lease.Token
+	defer func() {
+		err := mux.StationReleaseLease(mux_client, *station.Id, token)
+		if err != nil {
+			log.Printf("%s: StationReleaseLease failed: %s",
+				log_label, err.Error())
+		}
+	}()
 
 	log.Printf("%s: 1", log_label)
 	contact, err := contacts.NewContact(
@@ -131,15 +150,30 @@ func capturePass(
 			log_label, err.Error())
 		return err
 	}
+	capture := contacts.NewCapture(
+		int64(pass.StartTimestamp), int64(pass.EndTimestamp))
+	contact.Capture = capture
 	log_label = *station.Id + "/" + *contact.Id
 
 	log.Printf("%s: Contact id: %s", log_label, *contact.Id)
 
+	failed := func(rpc string, err error) {
+		log.Printf("%s: %s failed: %s", log_label, rpc, err.Error())
+		contacts.AddRPCFailure(capture, rpc, err)
+	}
+
 	log.Printf("%s: 2", log_label)
-	err = mux.StationReceiverSetFrequency(mux_client, *station.Id, freq_hz)
+	err = mux.StationReceiverSetFrequency(
+		mux_client, *station.Id, token, freq_hz)
 	if err != nil {
-		log.Printf("%s: StationReceiverSetFrequency failed: %s",
-			log_label, err.Error())
+		failed("StationReceiverSetFrequency", err)
+		capture.Status = pb.Contact_Capture_STATION_OFFLINE.Enum()
+		now := time.Now().Unix()
+		contact.EndTimestamp = &now
+		if err := contactdb.Store(contact); err != nil {
+			log.Printf("%s: Error storing contact: %s",
+				log_label, err.Error())
+		}
 		return err
 	}
 
@@ -153,78 +187,222 @@ func capturePass(
 
 	log.Printf("%s: 4", log_label)
 	stream_url := GetStreamURL(*contact.Id)
-	err = mux.StationReceiverStart(mux_client, *station.Id, stream_url)
+	err = mux.StationReceiverStart(
+		mux_client, *station.Id, token, stream_url)
+	receiver_started := err == nil
 	if err != nil {
-		log.Printf("%s: StationReceiverStart failed: %s",
-			log_label, err.Error())
-		//return err
+		failed("StationReceiverStart", err)
+	}
+
+	if len(frequency_program) > 0 {
+		log.Printf("%s: StationReceiverFrequencyProgram len=%d",
+			log_label, len(frequency_program))
+		err = mux.StationReceiverFrequencyProgram(
+			mux_client, *station.Id, token, frequency_program)
+		if err != nil {
+			// Older stations don't support frequency programs.
+			// The Doppler shift is still corrected afterwards so
+			// this doesn't count as a failure.
+			log.Printf("%s: StationReceiverFrequencyProgram "+
+				"failed: %s", log_label, err.Error())
+		}
 	}
 
 	// Stop the TNC in case it's already started.
-	err = mux.StationTNCStop(mux_client, *station.Id)
+	err = mux.StationTNCStop(mux_client, *station.Id, token)
 	if err != nil {
 		log.Printf("%s: StationTNCStop failed: %s",
 			log_label, err.Error())
 	}
 
-	err = mux.StationTNCStart(
-		mux_client, *station.Id, *api_server_address, satellite_id)
+	err = mux.StationTNCStart(mux_client, *station.Id, token,
+		*api_server_address, satellite_id)
 	if err != nil {
-		log.Printf("%s: StationTNCStart failed: %s",
-			log_label, err.Error())
+		failed("StationTNCStart", err)
 	}
 
 	log.Printf("%s: 5 StationMotorStart len=%d",
 		log_label, len(motor_program))
-	err = mux.StationMotorStart(mux_client, *station.Id, motor_program)
+	err = mux.StationMotorStart(
+		mux_client, *station.Id, token, motor_program)
 	if err != nil {
-		log.Printf("%s: StationMotorStart failed: %s",
-			log_label, err.Error())
+		failed("StationMotorStart", err)
 	}
 
 	log.Printf("%s: 6", log_label)
-	time.Sleep(duration)
+	waitForCapture(mux_client, *station.Id, log_label, duration, cancel)
 
 	log.Printf("%s: 7", log_label)
 
-	err = mux.StationTNCStop(mux_client, *station.Id)
+	err = mux.StationTNCStop(mux_client, *station.Id, token)
 	if err != nil {
-		log.Printf("%s: StationTNCStop failed: %s",
-			log_label, err.Error())
+		failed("StationTNCStop", err)
 	}
 
-	err = mux.StationReceiverStop(mux_client, *station.Id)
+	err = mux.StationReceiverStop(mux_client, *station.Id, token)
 	if err != nil {
-		log.Printf("%s: StationReceiverStop failed: %s",
-			log_label, err.Error())
+		failed("StationReceiverStop", err)
 	}
 
-	err = mux.StationMotorStop(mux_client, *station.Id)
+	err = mux.StationMotorStop(mux_client, *station.Id, token)
 	if err != nil {
-		log.Printf("%s: StationMotorStop failed: %s",
-			log_label, err.Error())
+		failed("StationMotorStop", err)
 	}
 
-	// FIXME: send end timestamp and update contact
+	finishCapture(contactdb, contact, receiver_started)
+
+	return nil
+}
+
+// Wait until the capture is finished, cancelled or the console operator
+// requests control of the station.
+func waitForCapture(mux_client *rpc.Client, station_id, log_label string,
+	duration time.Duration, cancel <-chan bool) {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	check := time.NewTicker(kLeaseCheckInterval)
+	defer check.Stop()
+	for {
+		select {
+		case <-timer.C:
+			return
+		case <-cancel:
+			log.Printf("%s: Capture cancelled", log_label)
+			return
+		case <-check.C:
+			lease, held, err := mux.StationLeaseStatus(
+				mux_client, station_id)
+			if err != nil {
+				log.Printf("%s: StationLeaseStatus failed: %s",
+					log_label, err.Error())
+				continue
+			}
+			if held && lease.PreemptionRequestedBy != "" {
+				log.Printf("%s: Capture preempted by %s",
+					log_label, lease.PreemptionRequestedBy)
+				return
+			}
+		}
+	}
+}
+
+// Store the end of the capture. The streamer may have updated the contact
+// with the IQ data in the meantime so we merge our changes into the stored
+// version.
+func finishCapture(contactdb *db.ContactDB, contact *pb.Contact,
+	receiver_started bool) {
+	log_label := *contact.StationId + "/" + *contact.Id
+
 	now := time.Now().Unix()
-	contact.EndTimestamp = &now
-	
+	finish := func(stored *pb.Contact) error {
+		if stored.Capture == nil {
+			stored.Capture = contact.Capture
+		}
+		stored.EndTimestamp = &now
+		stored.Capture.RpcFailure = contact.Capture.RpcFailure
+
+		// Without a receiver no IQ data will arrive. If the IQ data
+		// has already been processed, the status must account for
+		// the failures that happened since.
+		pending := stored.Capture.GetStatus() ==
+			pb.Contact_Capture_PENDING
+		if !receiver_started || !pending {
+			contacts.UpdateCaptureStatus(stored)
+		}
+		return nil
+	}
+
+	stored, err := contactdb.Update(*contact.Id, finish)
+	if err == nil && stored == nil {
+		// Storing the contact at the start of the capture failed.
+		finish(contact)
+		err = contactdb.Store(contact)
+	}
+	if err != nil {
+		log.Printf("%s: Error storing contact: %s",
+			log_label, err.Error())
+	}
+}
 
-	// FIXME: future: release lock
+// Controls the goroutine that schedules a single station.
+type stationWorker struct {
+	station_id string
 
-	return nil
+	// Signalled when the station or the network plan changed.
+	wake chan bool
+
+	// Closed when the station should no longer be scheduled.
+	stop chan bool
+}
+
+func newStationWorker(station_id string) *stationWorker {
+	return &stationWorker{station_id, make(chan bool, 1), make(chan bool)}
+}
+
+// Doesn't block. Multiple wakeups are merged.
+func (w *stationWorker) wakeUp() {
+	select {
+	case w.wake <- true:
+	default:
+	}
+}
+
+// Sleep for the duration or until woken up. Returns false if the worker was
+// stopped.
+func (w *stationWorker) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-w.wake:
+	case <-w.stop:
+		return false
+	}
+	return true
+}
+
+// Returns a channel that is closed if the capture needs to be cancelled
+// because the station was disconnected, deleted or its scheduler disabled.
+// Closing done stops watching.
+func (w *stationWorker) watchCapture(stationdb *db.StationDB,
+	done chan bool) <-chan bool {
+	cancel := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-w.stop:
+				close(cancel)
+				return
+			case <-w.wake:
+				station, err := stationdb.Lookup(w.station_id)
+				if err != nil {
+					continue
+				}
+				if station == nil ||
+					station.SchedulerEnabled == nil ||
+					*station.SchedulerEnabled == false {
+					close(cancel)
+					return
+				}
+			}
+		}
+	}()
+	return cancel
 }
 
 // blocking
-// FIXME: handle disconnections
 func scheduleStation(stationdb *db.StationDB,
 	contactdb *db.ContactDB,
 	mux_client *rpc.Client,
-	station_id string,
-	shutdown_chan chan string) {
+	network *NetworkPlanner,
+	w *stationWorker,
+	shutdown_chan chan *stationWorker) {
 
-	defer func() { shutdown_chan <- station_id }()
+	defer func() { shutdown_chan <- w }()
 
+	station_id := w.station_id
 	log_label := station_id
 	log.Printf("%s: Scheduling starting.", log_label)
 
@@ -235,6 +413,9 @@ func scheduleStation(stationdb *db.StationDB,
 		if err != nil {
 			log.Printf("%s: Station lookup error: %s",
 				log_label, err.Error())
+			if !w.sleep(errorWaitDuration) {
+				return
+			}
 			continue
 		}
 		if station == nil {
@@ -242,25 +423,19 @@ func scheduleStation(stationdb *db.StationDB,
 			return
 		}
 
-		next_pass, err := getNextPass(station)
-		if err != nil {
-			log.Printf("%s: Error getting pass predictions: %s",
-				log_label, err.Error())
-		}
+		next_pass, _ := network.NextPass(station_id, time.Now())
 
 		var delay time.Duration
-		if next_pass.Satellite == nil {
-			// No passes coming up soon. Just wait a bit and try
-			// again.
-			log.Printf("%s: no upcoming passes", log_label)
-			delay = maxPredictionDuration
-		} else if  station.SchedulerEnabled == nil ||
+		if station.SchedulerEnabled == nil ||
 			*station.SchedulerEnabled == false {
-			//log.Printf("%s: scheduler disabled", station_id)
-			// TODO: We are effectively polling the SchedulerEnabled
-			// bit. We really should instead have the frontend
-			// send a notification to reschedule the station.
-			delay = controlDisabledDuration
+			// We are woken up when the owner enables it.
+			delay = *network_replan_interval
+			next_pass = Prediction{}
+		} else if next_pass.Satellite == nil {
+			// No passes assigned to this station. Wait for the
+			// network to be replanned and try again.
+			log.Printf("%s: no upcoming passes", log_label)
+			delay = *network_replan_interval
 		} else {
 			log.Printf("%s: next pass: %s",
 				log_label, *next_pass.Satellite.Id)
@@ -270,9 +445,18 @@ func scheduleStation(stationdb *db.StationDB,
 
 		log.Printf("%s: waiting for %s", log_label, delay)
 
-		// wait {timer, disconnect}
-		// FIXME: handle disconnections during the sleep
-		time.Sleep(delay)
+		// We're woken up if the station or the plan changes, in which
+		// case we start again since the next pass may be different.
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-w.wake:
+			timer.Stop()
+			continue
+		case <-w.stop:
+			timer.Stop()
+			return
+		}
 
 		if next_pass.Satellite == nil {
 			continue
@@ -296,57 +480,84 @@ func scheduleStation(stationdb *db.StationDB,
 			continue
 		}
 
-		err = capturePass(contactdb, mux_client, *station, next_pass)
+		done := make(chan bool)
+		cancel := w.watchCapture(stationdb, done)
+		err = capturePass(
+			contactdb, mux_client, *station, next_pass, cancel)
+		close(done)
 		if err != nil {
 			// There was an error of some sort.
 			// Wait a bit before trying again.
-			time.Sleep(errorWaitDuration)
+			if !w.sleep(errorWaitDuration) {
+				return
+			}
 		}
 	}
 }
 
+// How often to check the online stations in case a notification was lost.
+const stationListPollInterval = time.Minute
+
 func ScheduleForever(stationdb *db.StationDB,
 	contactdb *db.ContactDB,
-	mux_client *rpc.Client) {
+	mux_client *rpc.Client,
+	network *NetworkPlanner,
+	station_events <-chan events.StationEvent) {
 	log.Printf("Scheduler started")
 
-	active_stations := make(map[string]bool)
-	shutdown_chan := make(chan string)
+	workers := make(map[string]*stationWorker)
+	shutdown_chan := make(chan *stationWorker)
+	poll := time.NewTicker(stationListPollInterval)
+	defer poll.Stop()
 
 	for {
-		for {
-			all_handled := false
-			select {
-			case id := <- shutdown_chan:
-				log.Printf("Station no longer active: %s", id)
-				active_stations[id] = false
-				continue;
-			default:
-				all_handled = true
-			}
-			if all_handled {
-				break
-			}
-		}
-
 		var args mux.StationListArgs
 		var online_stations mux.StationListResult
 		err := mux_client.Call(
 			"Coordinator.StationList", args, &online_stations)
 		if err != nil {
 			log.Printf("Error calling mux: %s", err.Error())
-			continue
+		} else {
+			replanned := network.Update(
+				online_stations.StationIds, time.Now())
+
+			online := make(map[string]bool)
+			for _, id := range online_stations.StationIds {
+				online[id] = true
+				if workers[id] == nil {
+					w := newStationWorker(id)
+					workers[id] = w
+					go scheduleStation(
+						stationdb, contactdb,
+						mux_client, network, w,
+						shutdown_chan)
+				}
+			}
+			for id, w := range workers {
+				if !online[id] {
+					log.Printf("Station no longer "+
+						"online: %s", id)
+					close(w.stop)
+					delete(workers, id)
+				} else if replanned {
+					w.wakeUp()
+				}
+			}
 		}
 
-		for _, id := range online_stations.StationIds {
-			if !active_stations[id] {
-				go scheduleStation(
-					stationdb, contactdb, mux_client,
-					id, shutdown_chan)
-				active_stations[id] = true
+		select {
+		case e := <-station_events:
+			log.Printf("%s: Station %s", e.StationId, e.Type)
+			if e.Type == events.StationEdited {
+				network.Invalidate()
+			}
+		case w := <-shutdown_chan:
+			log.Printf("Station no longer active: %s",
+				w.station_id)
+			if workers[w.station_id] == w {
+				delete(workers, w.station_id)
 			}
+		case <-poll.C:
 		}
-
-		time.Sleep(time.Minute)
 	}
 }
@@ -0,0 +1,54 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package scheduler
+
+import "testing"
+import "time"
+
+func TestStationWorkerWakeUp(t *testing.T) {
+	w := newStationWorker("station")
+
+	// Multiple wakeups are merged and don't block.
+	w.wakeUp()
+	w.wakeUp()
+	begin := time.Now()
+	if !w.sleep(time.Hour) {
+		t.Errorf("sleep returned false")
+	}
+	if time.Now().Sub(begin) > time.Second {
+		t.Errorf("Wakeup didn't interrupt sleep")
+	}
+	if !w.sleep(time.Millisecond) {
+		t.Errorf("sleep returned false")
+	}
+
+	close(w.stop)
+	if w.sleep(time.Hour) {
+		t.Errorf("sleep returned true after stop")
+	}
+}
+
+func TestStationWorkerCancelCapture(t *testing.T) {
+	w := newStationWorker("station")
+
+	done := make(chan bool)
+	cancel := w.watchCapture(nil, done)
+	close(w.stop)
+	select {
+	case <-cancel:
+	case <-time.After(time.Second):
+		t.Errorf("Capture wasn't cancelled")
+	}
+
+	// Finishing the capture normally doesn't cancel it.
+	w = newStationWorker("station")
+	done = make(chan bool)
+	cancel = w.watchCapture(nil, done)
+	close(done)
+	select {
+	case <-cancel:
+		t.Errorf("Capture was cancelled")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
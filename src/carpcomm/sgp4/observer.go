@@ -0,0 +1,152 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package sgp4
+
+import "math"
+import "time"
+
+// WGS-84 ellipsoid used for geodetic coordinates.
+const kEllipsoidRadiusKm = 6378.137
+const kEllipsoidFlattening = 1.0 / 298.257223563
+const kEccentricitySquared = kEllipsoidFlattening * (2.0 - kEllipsoidFlattening)
+
+const kEarthRotationRate = 7.292115e-5  // [rad/s]
+const kAstronomicalUnitKm = 149597870.7
+
+// A ground station.
+type Observer struct {
+	LatitudeDegrees float64
+	LongitudeDegrees float64
+	Elevation float64  // [m]
+}
+
+// Observation describes the position of a satellite as seen by an
+// observer. Units match scheduler.SatPoint.
+type Observation struct {
+	Time time.Time
+	AzimuthDegrees float64  // [0, 360)
+	AltitudeDegrees float64
+	Range float64  // [m]
+	RangeVelocity float64  // [m/s], positive when receding.
+
+	// Sub-satellite point.
+	LatitudeDegrees float64
+	LongitudeDegrees float64  // [-180, 180]
+	Elevation float64  // [m] above the ellipsoid
+
+	IsEclipsed bool
+}
+
+// Rotate a TEME vector into the Earth fixed frame.
+func temeToECEF(v Vector, gmst float64) Vector {
+	c, s := math.Cos(gmst), math.Sin(gmst)
+	return Vector{c*v[0] + s*v[1], -s*v[0] + c*v[1], v[2]}
+}
+
+// Earth fixed position [km] of a geodetic point.
+func geodeticToECEF(lat, lng, height_km float64) Vector {
+	sinlat := math.Sin(lat)
+	n := kEllipsoidRadiusKm / math.Sqrt(1.0-kEccentricitySquared*sinlat*sinlat)
+	return Vector{
+		(n + height_km) * math.Cos(lat) * math.Cos(lng),
+		(n + height_km) * math.Cos(lat) * math.Sin(lng),
+		(n*(1.0-kEccentricitySquared) + height_km) * sinlat}
+}
+
+// Geodetic latitude, longitude [rad] and height [km] of an Earth fixed
+// position.
+func ecefToGeodetic(r Vector) (lat, lng, height_km float64) {
+	lng = math.Atan2(r[1], r[0])
+	p := math.Sqrt(r[0]*r[0] + r[1]*r[1])
+	lat = math.Atan2(r[2], p*(1.0-kEccentricitySquared))
+	for i := 0; i < 10; i++ {
+		sinlat := math.Sin(lat)
+		n := kEllipsoidRadiusKm /
+			math.Sqrt(1.0-kEccentricitySquared*sinlat*sinlat)
+		height_km = p/math.Cos(lat) - n
+		next := math.Atan2(r[2], p*(1.0-kEccentricitySquared*n/(n+height_km)))
+		if math.Abs(next-lat) < 1e-12 {
+			lat = next
+			break
+		}
+		lat = next
+	}
+	return lat, lng, height_km
+}
+
+// SunPosition returns the approximate position of the sun [km] in the
+// TEME frame. The error is about 0.01 degrees, which is plenty for eclipse
+// calculations.
+func SunPosition(t time.Time) Vector {
+	tut1 := (TimeToJulianDate(t) - 2451545.0) / 36525.0
+	meanlong := math.Mod(280.460+36000.771*tut1, 360.0)
+	meananomaly := math.Mod(357.5291092+35999.05034*tut1, 360.0) * deg2rad
+	eclplong := (meanlong + 1.914666471*math.Sin(meananomaly) +
+		0.019994643*math.Sin(2.0*meananomaly)) * deg2rad
+	obliquity := (23.439291 - 0.0130042*tut1) * deg2rad
+	magr := 1.000140612 - 0.016708617*math.Cos(meananomaly) -
+		0.000139589*math.Cos(2.0*meananomaly)
+	return Vector{
+		magr * math.Cos(eclplong),
+		magr * math.Cos(obliquity) * math.Sin(eclplong),
+		magr * math.Sin(obliquity) * math.Sin(eclplong)}.Scale(
+		kAstronomicalUnitKm)
+}
+
+// Whether a satellite at TEME position r [km] is in the Earth's shadow.
+// The shadow is modelled as a cylinder.
+func isEclipsed(r, sun Vector) bool {
+	sun_dir := sun.Scale(1.0 / sun.Norm())
+	d := r.Dot(sun_dir)
+	if d > 0.0 {
+		return false
+	}
+	perp := r.Sub(sun_dir.Scale(d))
+	return perp.Norm() < kRadiusEarthKm
+}
+
+// Observe computes the look angles and sub-satellite point at time t.
+func (s *Satellite) Observe(obs Observer, t time.Time) (Observation, error) {
+	r, v, err := s.PropagateTo(t)
+	if err != nil {
+		return Observation{}, err
+	}
+
+	gmst := GMST(t)
+	r_ecef := temeToECEF(r, gmst)
+	// Account for the rotation of the Earth fixed frame.
+	v_ecef := temeToECEF(v, gmst)
+	v_ecef[0] += kEarthRotationRate * r_ecef[1]
+	v_ecef[1] -= kEarthRotationRate * r_ecef[0]
+
+	lat := obs.LatitudeDegrees * deg2rad
+	lng := obs.LongitudeDegrees * deg2rad
+	rho := r_ecef.Sub(geodeticToECEF(lat, lng, obs.Elevation/1000.0))
+	rng := rho.Norm()
+
+	// Rotate into the topocentric south-east-zenith frame.
+	sinlat, coslat := math.Sin(lat), math.Cos(lat)
+	sinlng, coslng := math.Sin(lng), math.Cos(lng)
+	south := sinlat*coslng*rho[0] + sinlat*sinlng*rho[1] - coslat*rho[2]
+	east := -sinlng*rho[0] + coslng*rho[1]
+	zenith := coslat*coslng*rho[0] + coslat*sinlng*rho[1] + sinlat*rho[2]
+
+	var o Observation
+	o.Time = t
+	o.AzimuthDegrees = math.Atan2(east, -south) / deg2rad
+	if o.AzimuthDegrees < 0.0 {
+		o.AzimuthDegrees += 360.0
+	}
+	o.AltitudeDegrees = math.Asin(zenith/rng) / deg2rad
+	o.Range = rng * 1000.0
+	o.RangeVelocity = rho.Dot(v_ecef) / rng * 1000.0
+
+	sat_lat, sat_lng, height := ecefToGeodetic(r_ecef)
+	o.LatitudeDegrees = sat_lat / deg2rad
+	o.LongitudeDegrees = sat_lng / deg2rad
+	o.Elevation = height * 1000.0
+
+	o.IsEclipsed = isEclipsed(r, SunPosition(t))
+	return o, nil
+}
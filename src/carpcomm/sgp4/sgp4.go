@@ -0,0 +1,435 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package sgp4
+
+// This is a port of the SGP4/SDP4 propagator described in "Revisiting
+// Spacetrack Report #3" (Vallado, Crawford, Hujsak and Kelso, AIAA
+// 2006-6753). It uses the WGS-72 gravity constants and the "improved"
+// operation mode, like the reference implementation.
+
+import "errors"
+import "fmt"
+import "math"
+import "time"
+
+const twopi = 2.0 * math.Pi
+const deg2rad = math.Pi / 180.0
+const x2o3 = 2.0 / 3.0
+
+// WGS-72 constants.
+const (
+	kMu = 398600.8  // [km^3/s^2]
+	kRadiusEarthKm = 6378.135
+	kJ2 = 0.001082616
+	kJ3 = -0.00000253881
+	kJ4 = -0.00000165597
+	kJ3OverJ2 = kJ3 / kJ2
+)
+
+// sqrt(mu / radius^3) in [1/min].
+var xke = 60.0 / math.Sqrt(
+	kRadiusEarthKm*kRadiusEarthKm*kRadiusEarthKm/kMu)
+
+// Minutes per day divided by 2 pi.
+const xpdotp = 1440.0 / twopi
+
+type Vector [3]float64
+
+func (v Vector) Dot(w Vector) float64 {
+	return v[0]*w[0] + v[1]*w[1] + v[2]*w[2]
+}
+
+func (v Vector) Norm() float64 {
+	return math.Sqrt(v.Dot(v))
+}
+
+func (v Vector) Sub(w Vector) Vector {
+	return Vector{v[0] - w[0], v[1] - w[1], v[2] - w[2]}
+}
+
+func (v Vector) Scale(s float64) Vector {
+	return Vector{s * v[0], s * v[1], s * v[2]}
+}
+
+// Satellite holds the initialised propagator state for one element set.
+// It is safe for concurrent use since Propagate doesn't modify it.
+type Satellite struct {
+	TLE TLE
+
+	epoch float64  // Julian date
+	deep_space bool
+	isimp bool
+
+	// Mean elements at epoch [rad, rad/min].
+	ecco, inclo, nodeo, argpo, mo, no, bstar float64
+
+	aycof, con41, cc1, cc4, cc5, d2, d3, d4, delmo, eta, argpdot float64
+	omgcof, sinmao, t2cof, t3cof, t4cof, t5cof, x1mth2, x7thm1 float64
+	mdot, nodedot, xlcof, xmcof, nodecf float64
+
+	ds deepSpace
+}
+
+// NewSatellite initialises the propagator for the given element set.
+func NewSatellite(tle *TLE) (*Satellite, error) {
+	s := &Satellite{TLE: *tle}
+	s.epoch = tle.EpochJulianDate()
+	s.bstar = tle.BStar
+	s.ecco = tle.Eccentricity
+	s.argpo = tle.ArgumentOfPerigeeDegrees * deg2rad
+	s.inclo = tle.InclinationDegrees * deg2rad
+	s.mo = tle.MeanAnomalyDegrees * deg2rad
+	s.no = tle.MeanMotion / xpdotp
+	s.nodeo = tle.RightAscensionDegrees * deg2rad
+
+	if err := s.init(); err != nil {
+		return nil, err
+	}
+	// Check that the elements can be propagated at all.
+	if _, _, err := s.Propagate(0.0); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Parse and initialise an element set.
+func NewSatelliteFromTLE(tle string) (*Satellite, error) {
+	t, err := ParseTLE(tle)
+	if err != nil {
+		return nil, err
+	}
+	return NewSatellite(t)
+}
+
+func (s *Satellite) init() error {
+	const temp4 = 1.5e-12
+	ss := 78.0/kRadiusEarthKm + 1.0
+	qzms2t := math.Pow((120.0-78.0)/kRadiusEarthKm, 4)
+
+	// Recover the original mean motion and semi-major axis from the
+	// Kozai mean motion in the element set.
+	eccsq := s.ecco * s.ecco
+	omeosq := 1.0 - eccsq
+	rteosq := math.Sqrt(omeosq)
+	cosio := math.Cos(s.inclo)
+	cosio2 := cosio * cosio
+	ak := math.Pow(xke/s.no, x2o3)
+	d1 := 0.75 * kJ2 * (3.0*cosio2 - 1.0) / (rteosq * omeosq)
+	del := d1 / (ak * ak)
+	adel := ak * (1.0 - del*del - del*(1.0/3.0+134.0*del*del/81.0))
+	del = d1 / (adel * adel)
+	s.no = s.no / (1.0 + del)
+
+	ao := math.Pow(xke/s.no, x2o3)
+	sinio := math.Sin(s.inclo)
+	po := ao * omeosq
+	con42 := 1.0 - 5.0*cosio2
+	s.con41 = -con42 - cosio2 - cosio2
+	posq := po * po
+	rp := ao * (1.0 - s.ecco)
+	gsto := gstime(s.epoch)
+
+	if omeosq < 0.0 && s.no < 0.0 {
+		return errors.New("Invalid elements")
+	}
+
+	s.isimp = rp < 220.0/kRadiusEarthKm+1.0
+
+	// For perigees below 156 km, the values of s and qoms2t are
+	// altered.
+	sfour := ss
+	qzms24 := qzms2t
+	perige := (rp - 1.0) * kRadiusEarthKm
+	if perige < 156.0 {
+		sfour = perige - 78.0
+		if perige < 98.0 {
+			sfour = 20.0
+		}
+		qzms24 = math.Pow((120.0-sfour)/kRadiusEarthKm, 4)
+		sfour = sfour/kRadiusEarthKm + 1.0
+	}
+	pinvsq := 1.0 / posq
+
+	tsi := 1.0 / (ao - sfour)
+	s.eta = ao * s.ecco * tsi
+	etasq := s.eta * s.eta
+	eeta := s.ecco * s.eta
+	psisq := math.Abs(1.0 - etasq)
+	coef := qzms24 * math.Pow(tsi, 4)
+	coef1 := coef / math.Pow(psisq, 3.5)
+	cc2 := coef1 * s.no * (ao*(1.0+1.5*etasq+eeta*(4.0+etasq)) +
+		0.375*kJ2*tsi/psisq*s.con41*(8.0+3.0*etasq*(8.0+etasq)))
+	s.cc1 = s.bstar * cc2
+	cc3 := 0.0
+	if s.ecco > 1.0e-4 {
+		cc3 = -2.0 * coef * tsi * kJ3OverJ2 * s.no * sinio / s.ecco
+	}
+	s.x1mth2 = 1.0 - cosio2
+	s.cc4 = 2.0 * s.no * coef1 * ao * omeosq *
+		(s.eta*(2.0+0.5*etasq) + s.ecco*(0.5+2.0*etasq) -
+			kJ2*tsi/(ao*psisq)*
+				(-3.0*s.con41*(1.0-2.0*eeta+etasq*(1.5-0.5*eeta)) +
+					0.75*s.x1mth2*(2.0*etasq-eeta*(1.0+etasq))*
+						math.Cos(2.0*s.argpo)))
+	s.cc5 = 2.0 * coef1 * ao * omeosq *
+		(1.0 + 2.75*(etasq+eeta) + eeta*etasq)
+	cosio4 := cosio2 * cosio2
+	temp1 := 1.5 * kJ2 * pinvsq * s.no
+	temp2 := 0.5 * temp1 * kJ2 * pinvsq
+	temp3 := -0.46875 * kJ4 * pinvsq * pinvsq * s.no
+	s.mdot = s.no + 0.5*temp1*rteosq*s.con41 +
+		0.0625*temp2*rteosq*(13.0-78.0*cosio2+137.0*cosio4)
+	s.argpdot = -0.5*temp1*con42 +
+		0.0625*temp2*(7.0-114.0*cosio2+395.0*cosio4) +
+		temp3*(3.0-36.0*cosio2+49.0*cosio4)
+	xhdot1 := -temp1 * cosio
+	s.nodedot = xhdot1 + (0.5*temp2*(4.0-19.0*cosio2)+
+		2.0*temp3*(3.0-7.0*cosio2))*cosio
+	xpidot := s.argpdot + s.nodedot
+	s.omgcof = s.bstar * cc3 * math.Cos(s.argpo)
+	s.xmcof = 0.0
+	if s.ecco > 1.0e-4 {
+		s.xmcof = -x2o3 * coef * s.bstar / eeta
+	}
+	s.nodecf = 3.5 * omeosq * xhdot1 * s.cc1
+	s.t2cof = 1.5 * s.cc1
+	if math.Abs(cosio+1.0) > 1.5e-12 {
+		s.xlcof = -0.25 * kJ3OverJ2 * sinio * (3.0 + 5.0*cosio) /
+			(1.0 + cosio)
+	} else {
+		s.xlcof = -0.25 * kJ3OverJ2 * sinio * (3.0 + 5.0*cosio) / temp4
+	}
+	s.aycof = -0.5 * kJ3OverJ2 * sinio
+	s.delmo = math.Pow(1.0+s.eta*math.Cos(s.mo), 3)
+	s.sinmao = math.Sin(s.mo)
+	s.x7thm1 = 7.0*cosio2 - 1.0
+
+	// Deep space initialisation for periods of 225 minutes or more.
+	if twopi/s.no >= 225.0 {
+		s.deep_space = true
+		s.isimp = true
+		s.ds.init(s, gsto, eccsq, xpidot)
+	}
+
+	if !s.isimp {
+		cc1sq := s.cc1 * s.cc1
+		s.d2 = 4.0 * ao * tsi * cc1sq
+		temp := s.d2 * tsi * s.cc1 / 3.0
+		s.d3 = (17.0*ao + sfour) * temp
+		s.d4 = 0.5 * temp * ao * tsi * (221.0*ao + 31.0*sfour) * s.cc1
+		s.t3cof = s.d2 + 2.0*cc1sq
+		s.t4cof = 0.25 * (3.0*s.d3 + s.cc1*(12.0*s.d2+10.0*cc1sq))
+		s.t5cof = 0.2 * (3.0*s.d4 + 12.0*s.cc1*s.d3 +
+			6.0*s.d2*s.d2 + 15.0*cc1sq*(2.0*s.d2+cc1sq))
+	}
+	return nil
+}
+
+// Propagate returns the position [km] and velocity [km/s] in the TEME
+// frame at tsince minutes after the epoch.
+func (s *Satellite) Propagate(tsince float64) (r, v Vector, err error) {
+	const temp4 = 1.5e-12
+	vkmpersec := kRadiusEarthKm * xke / 60.0
+	t := tsince
+
+	// Update for secular gravity and atmospheric drag.
+	xmdf := s.mo + s.mdot*t
+	argpdf := s.argpo + s.argpdot*t
+	nodedf := s.nodeo + s.nodedot*t
+	argpm := argpdf
+	mm := xmdf
+	t2 := t * t
+	nodem := nodedf + s.nodecf*t2
+	tempa := 1.0 - s.cc1*t
+	tempe := s.bstar * s.cc4 * t
+	templ := s.t2cof * t2
+
+	if !s.isimp {
+		delomg := s.omgcof * t
+		delm := s.xmcof * (math.Pow(1.0+s.eta*math.Cos(xmdf), 3) -
+			s.delmo)
+		temp := delomg + delm
+		mm = xmdf + temp
+		argpm = argpdf - temp
+		t3 := t2 * t
+		t4 := t3 * t
+		tempa = tempa - s.d2*t2 - s.d3*t3 - s.d4*t4
+		tempe = tempe + s.bstar*s.cc5*(math.Sin(mm)-s.sinmao)
+		templ = templ + s.t3cof*t3 + t4*(s.t4cof+t*s.t5cof)
+	}
+
+	nm := s.no
+	em := s.ecco
+	inclm := s.inclo
+	if s.deep_space {
+		em, argpm, inclm, mm, nodem, nm = s.ds.space(
+			s, t, em, argpm, inclm, mm, nodem)
+	}
+
+	if nm <= 0.0 {
+		return r, v, errors.New(fmt.Sprintf(
+			"Mean motion is not positive: %f", nm))
+	}
+	am := math.Pow(xke/nm, x2o3) * tempa * tempa
+	nm = xke / math.Pow(am, 1.5)
+	em = em - tempe
+
+	if em >= 1.0 || em < -0.001 {
+		return r, v, errors.New(fmt.Sprintf(
+			"Mean eccentricity out of range: %f", em))
+	}
+	if em < 1.0e-6 {
+		em = 1.0e-6
+	}
+	mm = mm + s.no*templ
+	xlm := mm + argpm + nodem
+
+	nodem = math.Mod(nodem, twopi)
+	argpm = math.Mod(argpm, twopi)
+	xlm = math.Mod(xlm, twopi)
+	mm = math.Mod(xlm-argpm-nodem, twopi)
+
+	// Add lunar-solar periodics.
+	ep := em
+	xincp := inclm
+	argpp := argpm
+	nodep := nodem
+	mp := mm
+	sinip := math.Sin(inclm)
+	cosip := math.Cos(inclm)
+	aycof := s.aycof
+	xlcof := s.xlcof
+	con41 := s.con41
+	x1mth2 := s.x1mth2
+	x7thm1 := s.x7thm1
+	if s.deep_space {
+		ep, xincp, nodep, argpp, mp = s.ds.periodics(
+			t, ep, xincp, nodep, argpp, mp)
+		if xincp < 0.0 {
+			xincp = -xincp
+			nodep = nodep + math.Pi
+			argpp = argpp - math.Pi
+		}
+		if ep < 0.0 || ep > 1.0 {
+			return r, v, errors.New(fmt.Sprintf(
+				"Perturbed eccentricity out of range: %f", ep))
+		}
+
+		// Long period periodics.
+		sinip = math.Sin(xincp)
+		cosip = math.Cos(xincp)
+		aycof = -0.5 * kJ3OverJ2 * sinip
+		if math.Abs(cosip+1.0) > 1.5e-12 {
+			xlcof = -0.25 * kJ3OverJ2 * sinip * (3.0 + 5.0*cosip) /
+				(1.0 + cosip)
+		} else {
+			xlcof = -0.25 * kJ3OverJ2 * sinip * (3.0 + 5.0*cosip) /
+				temp4
+		}
+	}
+
+	axnl := ep * math.Cos(argpp)
+	temp := 1.0 / (am * (1.0 - ep*ep))
+	aynl := ep*math.Sin(argpp) + temp*aycof
+	xl := mp + argpp + nodep + temp*xlcof*axnl
+
+	// Solve Kepler's equation.
+	u := math.Mod(xl-nodep, twopi)
+	eo1 := u
+	tem5 := 9999.9
+	var sineo1, coseo1 float64
+	for ktr := 1; math.Abs(tem5) >= 1.0e-12 && ktr <= 10; ktr++ {
+		sineo1 = math.Sin(eo1)
+		coseo1 = math.Cos(eo1)
+		tem5 = 1.0 - coseo1*axnl - sineo1*aynl
+		tem5 = (u - aynl*coseo1 + axnl*sineo1 - eo1) / tem5
+		if math.Abs(tem5) >= 0.95 {
+			if tem5 > 0.0 {
+				tem5 = 0.95
+			} else {
+				tem5 = -0.95
+			}
+		}
+		eo1 = eo1 + tem5
+	}
+
+	// Short period preliminary quantities.
+	ecose := axnl*coseo1 + aynl*sineo1
+	esine := axnl*sineo1 - aynl*coseo1
+	el2 := axnl*axnl + aynl*aynl
+	pl := am * (1.0 - el2)
+	if pl < 0.0 {
+		return r, v, errors.New(fmt.Sprintf(
+			"Semi-latus rectum is negative: %f", pl))
+	}
+
+	rl := am * (1.0 - ecose)
+	rdotl := math.Sqrt(am) * esine / rl
+	rvdotl := math.Sqrt(pl) / rl
+	betal := math.Sqrt(1.0 - el2)
+	temp = esine / (1.0 + betal)
+	sinu := am / rl * (sineo1 - aynl - axnl*temp)
+	cosu := am / rl * (coseo1 - axnl + aynl*temp)
+	su := math.Atan2(sinu, cosu)
+	sin2u := (cosu + cosu) * sinu
+	cos2u := 1.0 - 2.0*sinu*sinu
+	temp = 1.0 / pl
+	temp1 := 0.5 * kJ2 * temp
+	temp2 := temp1 * temp
+
+	if s.deep_space {
+		cosisq := cosip * cosip
+		con41 = 3.0*cosisq - 1.0
+		x1mth2 = 1.0 - cosisq
+		x7thm1 = 7.0*cosisq - 1.0
+	}
+
+	// Update for short period periodics.
+	mrt := rl*(1.0-1.5*temp2*betal*con41) + 0.5*temp1*x1mth2*cos2u
+	su = su - 0.25*temp2*x7thm1*sin2u
+	xnode := nodep + 1.5*temp2*cosip*sin2u
+	xinc := xincp + 1.5*temp2*cosip*sinip*cos2u
+	mvt := rdotl - nm*temp1*x1mth2*sin2u/xke
+	rvdot := rvdotl + nm*temp1*(x1mth2*cos2u+1.5*con41)/xke
+
+	// Orientation vectors.
+	sinsu := math.Sin(su)
+	cossu := math.Cos(su)
+	snod := math.Sin(xnode)
+	cnod := math.Cos(xnode)
+	sini := math.Sin(xinc)
+	cosi := math.Cos(xinc)
+	xmx := -snod * cosi
+	xmy := cnod * cosi
+	ux := xmx*sinsu + cnod*cossu
+	uy := xmy*sinsu + snod*cossu
+	uz := sini * sinsu
+	vx := xmx*cossu - cnod*sinsu
+	vy := xmy*cossu - snod*sinsu
+	vz := sini * cossu
+
+	r = Vector{
+		mrt * ux * kRadiusEarthKm,
+		mrt * uy * kRadiusEarthKm,
+		mrt * uz * kRadiusEarthKm}
+	v = Vector{
+		(mvt*ux + rvdot*vx) * vkmpersec,
+		(mvt*uy + rvdot*vy) * vkmpersec,
+		(mvt*uz + rvdot*vz) * vkmpersec}
+
+	if mrt < 1.0 {
+		return r, v, errors.New("Satellite has decayed")
+	}
+	return r, v, nil
+}
+
+// Minutes between the epoch and t.
+func (s *Satellite) MinutesSinceEpoch(t time.Time) float64 {
+	return (TimeToJulianDate(t) - s.epoch) * 1440.0
+}
+
+// PropagateTo returns the TEME position [km] and velocity [km/s] at t.
+func (s *Satellite) PropagateTo(t time.Time) (r, v Vector, err error) {
+	return s.Propagate(s.MinutesSinceEpoch(t))
+}
@@ -0,0 +1,269 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package sgp4
+
+import "math"
+import "strings"
+import "testing"
+import "time"
+
+// Test vectors from the verification output (tcppver.out) published with
+// "Revisiting Spacetrack Report #3".
+type testVector struct {
+	tsince float64  // [min]
+	r Vector  // [km]
+	v Vector  // [km/s]
+}
+
+var verificationTests = []struct {
+	name string
+	tle string
+	vectors []testVector
+}{
+	{"Vanguard 1, near earth",
+		"1 00005U 58002B   00179.78495062  .00000023  00000-0  28098-4 0  4753\n" +
+			"2 00005  34.2682 348.7242 1859667 331.7664  19.3264 10.82419157413667",
+		[]testVector{
+			{0.0,
+				Vector{7022.46529266, -1400.08296755, 0.03995155},
+				Vector{1.893841015, 6.405893759, 4.534807250}},
+			{360.0,
+				Vector{-7154.03120202, -3783.17682504, -3536.19412294},
+				Vector{4.741887409, -4.151817765, -2.093935425}},
+			{720.0,
+				Vector{-7134.59340119, 6531.68641334, 3260.27186483},
+				Vector{-4.113793027, -2.911922039, -2.557327851}},
+		}},
+	{"Spacetrack Report #3 SGP4 test case",
+		"1 88888U          80275.98708465  .00073094  13844-3  66816-4 0    8\n" +
+			"2 88888  72.8435 115.9689 0086731  52.6988 110.5714 16.05824518  105",
+		[]testVector{
+			{0.0,
+				Vector{2328.96975262, -5995.22051338, 1719.97297192},
+				Vector{2.912073281, -0.983417956, -7.090816210}},
+			{360.0,
+				Vector{2456.10706533, -6071.93855503, 1222.89768554},
+				Vector{2.679390040, -0.448290811, -7.228792155}},
+			{720.0,
+				Vector{2567.56229695, -6112.50383922, 713.96374435},
+				Vector{2.440245751, 0.098109002, -7.319959258}},
+		}},
+	{"Near earth with high drag",
+		"1 06251U 62025E   06176.82412014  .00008885  00000-0  12808-3 0  3985\n" +
+			"2 06251  58.0579  54.0425 0030035 139.1568 221.1854 15.56387291  6774",
+		[]testVector{
+			{0.0,
+				Vector{3988.31022699, 5498.96657235, 0.90055879},
+				Vector{-3.290032738, 2.357652820, 6.496623475}},
+		}},
+	{"Spacetrack Report #3 SDP4 test case",
+		"1 11801U          80230.29629788  .01431103  00000-0  14311-1       8\n" +
+			"2 11801  46.7916 230.4354 7318036  47.4722  10.4117  2.28537848     6",
+		[]testVector{
+			{0.0,
+				Vector{7473.37102491, 428.94748312, 5828.74846686},
+				Vector{5.107155391, 6.444680305, -0.186133297}},
+			{360.0,
+				Vector{-3305.22148694, 32410.84323331, -24697.16974954},
+				Vector{-1.301137319, -1.151315600, -0.283335823}},
+			{720.0,
+				Vector{14271.29083858, 24110.44309009, -4725.76320143},
+				Vector{-0.320504528, 2.679841539, -2.084054355}},
+		}},
+	{"Molniya, 12 hour resonance",
+		"1 08195U 75081A   06176.33215444  .00000099  00000-0  11873-3 0   813\n" +
+			"2 08195  64.1586 279.0717 6877146 264.7651  20.2257  2.00491383225656",
+		[]testVector{
+			{0.0,
+				Vector{2349.89483350, -14785.93811562, 0.02119378},
+				Vector{2.721488096, -3.256811655, 4.498416672}},
+		}},
+	{"Geostationary, 24 hour resonance",
+		"1 28626U 05008A   06176.46683397 -.00000205  00000-0  10000-3 0  2190\n" +
+			"2 28626   0.0019 286.9433 0000335  13.7918  55.6504  1.00270176  4891",
+		[]testVector{
+			{0.0,
+				Vector{42080.71852213, -2646.86387436, 0.81851294},
+				Vector{0.193105177, 3.068688251, 0.000438449}},
+			{360.0,
+				Vector{2467.44290178, 42093.60909959, 5.15062987},
+				Vector{-3.069341800, 0.179976276, -0.000031739}},
+			{720.0,
+				Vector{-42103.20138132, 2291.06228893, -0.13274964},
+				Vector{-0.166974816, -3.070104560, -0.000311007}},
+		}},
+}
+
+func vectorsClose(a, b Vector, tolerance float64) bool {
+	return a.Sub(b).Norm() <= tolerance
+}
+
+func TestVerificationVectors(t *testing.T) {
+	for _, test := range verificationTests {
+		sat, err := NewSatelliteFromTLE(test.tle)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		for _, tv := range test.vectors {
+			r, v, err := sat.Propagate(tv.tsince)
+			if err != nil {
+				t.Errorf("%s at %f: %s",
+					test.name, tv.tsince, err.Error())
+				continue
+			}
+			if !vectorsClose(r, tv.r, 1e-5) {
+				t.Errorf("%s at %f: position %v, expected %v",
+					test.name, tv.tsince, r, tv.r)
+			}
+			if !vectorsClose(v, tv.v, 1e-8) {
+				t.Errorf("%s at %f: velocity %v, expected %v",
+					test.name, tv.tsince, v, tv.v)
+			}
+		}
+	}
+}
+
+// Resonant orbits are numerically integrated over long periods.
+func TestDeepSpaceLongPropagation(t *testing.T) {
+	for _, test := range verificationTests[3:] {
+		sat, err := NewSatelliteFromTLE(test.tle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tsince := range []float64{-1440.0, 1440.0, 10*1440.0} {
+			r, _, err := sat.Propagate(tsince)
+			if err != nil {
+				t.Errorf("%s at %f: %s",
+					test.name, tsince, err.Error())
+			}
+			if r.Norm() < kRadiusEarthKm {
+				t.Errorf("%s at %f: below the surface: %v",
+					test.name, tsince, r)
+			}
+		}
+	}
+}
+
+func TestParseTLE(t *testing.T) {
+	tle, err := ParseTLE("SWISSCUBE               \n" +
+		"1 35932U 09051B   12110.66765508  .00000638  00000-0  15500-3 0  5172\n" +
+		"2 35932  98.3348 213.8703 0006768 284.4795  75.6141 14.52927878136365\n")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	expected := TLE{
+		Name: "SWISSCUBE",
+		SatelliteNumber: 35932,
+		EpochYear: 2012,
+		EpochDay: 110.66765508,
+		MeanMotionDot: 0.00000638,
+		MeanMotionDDot: 0.0,
+		BStar: 0.155e-3,
+		InclinationDegrees: 98.3348,
+		RightAscensionDegrees: 213.8703,
+		Eccentricity: 0.0006768,
+		ArgumentOfPerigeeDegrees: 284.4795,
+		MeanAnomalyDegrees: 75.6141,
+		MeanMotion: 14.52927878,
+		RevolutionNumber: 13636,
+	}
+	if math.Abs(tle.BStar-expected.BStar) < 1e-15 {
+		tle.BStar = expected.BStar
+	}
+	if *tle != expected {
+		t.Errorf("Unexpected result: %+v\nexpected: %+v", *tle, expected)
+	}
+
+	epoch := time.Date(2012, 4, 19, 16, 1, 25, 398912000, time.UTC)
+	if d := tle.Epoch().Sub(epoch); d > time.Millisecond ||
+		d < -time.Millisecond {
+		t.Errorf("Wrong epoch: %s", tle.Epoch())
+	}
+}
+
+func TestParseTLEErrors(t *testing.T) {
+	bad := []string{
+		"",
+		"1 35932U 09051B   12110.66765508  .00000638  00000-0  15500-3 0  5172",
+		"1 35932U 09051B   12110.66765508  .00000638  00000-0  15500-3 0  5172\n" +
+			"2 35933  98.3348 213.8703 0006768 284.4795  75.6141 14.52927878136365",
+		"1 35932U 09051B   12110.66765508  .00000638  00000-0  15500-3 0  5172\n" +
+			"2 35932  98.3348 213.8703 0006768 284.4795  75.6141 xx.52927878136365",
+		"1 35932U 09051B   12110.66765508  .00000638  00000-0  15500-3 0  5172\n" +
+			"2 35932  98.3348 213",
+	}
+	for _, s := range bad {
+		if _, err := ParseTLE(s); err == nil {
+			t.Errorf("Expected error for: %s", s)
+		}
+	}
+}
+
+func TestObserve(t *testing.T) {
+	// Geostationary satellite over 0 degrees longitude seen from the
+	// equator at 0 degrees longitude.
+	sat, err := NewSatelliteFromTLE(verificationTests[5].tle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := sat.TLE.Epoch()
+	r, _, err := sat.PropagateTo(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, lng, _ := ecefToGeodetic(temeToECEF(r, GMST(now)))
+
+	obs := Observer{0.0, lng / deg2rad, 0.0}
+	o, err := sat.Observe(obs, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.AltitudeDegrees < 89.0 {
+		t.Errorf("Altitude: %f", o.AltitudeDegrees)
+	}
+	if math.Abs(o.Range-35786e3) > 100e3 {
+		t.Errorf("Range: %f", o.Range)
+	}
+	if math.Abs(o.RangeVelocity) > 10.0 {
+		t.Errorf("Range velocity: %f", o.RangeVelocity)
+	}
+	if math.Abs(o.LatitudeDegrees) > 0.1 ||
+		math.Abs(o.LongitudeDegrees-obs.LongitudeDegrees) > 1e-6 {
+		t.Errorf("Sub-satellite point: %f, %f",
+			o.LatitudeDegrees, o.LongitudeDegrees)
+	}
+}
+
+func TestEclipse(t *testing.T) {
+	sun := Vector{kAstronomicalUnitKm, 0.0, 0.0}
+	if isEclipsed(Vector{7000.0, 0.0, 0.0}, sun) {
+		t.Errorf("Sunlit side is eclipsed")
+	}
+	if !isEclipsed(Vector{-7000.0, 0.0, 0.0}, sun) {
+		t.Errorf("Night side isn't eclipsed")
+	}
+	if isEclipsed(Vector{-7000.0, 0.0, 7000.0}, sun) {
+		t.Errorf("Outside the shadow is eclipsed")
+	}
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	good := verificationTests[0].tle
+	if err := VerifyChecksums(good); err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	if err := VerifyChecksums("NAME\n" + good + "\n"); err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+
+	bad := strings.Replace(good, "00179.78495062", "00179.78495063", 1)
+	if err := VerifyChecksums(bad); err == nil {
+		t.Errorf("Expected an error")
+	}
+	if err := VerifyChecksums(good[:60]); err == nil {
+		t.Errorf("Expected an error")
+	}
+}
@@ -0,0 +1,50 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package sgp4
+
+import "math"
+import "time"
+
+const kUnixEpochJulianDate = 2440587.5
+const kSecondsPerDay = 86400.0
+
+// Julian date of a UTC calendar date. Valid from 1900 to 2100.
+func julianDate(year, month, day, hour, minute int, second float64) float64 {
+	y, m := float64(year), float64(month)
+	return 367.0*y -
+		math.Floor(7.0*(y+math.Floor((m+9.0)/12.0))*0.25) +
+		math.Floor(275.0*m/9.0) +
+		float64(day) + 1721013.5 +
+		((second/60.0+float64(minute))/60.0+float64(hour))/24.0
+}
+
+func TimeToJulianDate(t time.Time) float64 {
+	return kUnixEpochJulianDate +
+		float64(t.UnixNano())*1e-9/kSecondsPerDay
+}
+
+func julianDateToTime(jd float64) time.Time {
+	days := jd - kUnixEpochJulianDate
+	sec := math.Floor(days * kSecondsPerDay)
+	nsec := (days*kSecondsPerDay - sec) * 1e9
+	return time.Unix(int64(sec), int64(nsec)).UTC()
+}
+
+// Greenwich mean sidereal time [radians] for a UT1 Julian date.
+// IAU-82 model.
+func gstime(jdut1 float64) float64 {
+	tut1 := (jdut1 - 2451545.0) / 36525.0
+	temp := -6.2e-6*tut1*tut1*tut1 + 0.093104*tut1*tut1 +
+		(876600.0*3600+8640184.812866)*tut1 + 67310.54841  // [s]
+	temp = math.Mod(temp*deg2rad/240.0, twopi)  // 360/86400 = 1/240
+	if temp < 0.0 {
+		temp += twopi
+	}
+	return temp
+}
+
+// GMST returns the Greenwich mean sidereal time in radians.
+func GMST(t time.Time) float64 {
+	return gstime(TimeToJulianDate(t))
+}
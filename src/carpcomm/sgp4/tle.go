@@ -0,0 +1,267 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package sgp4
+
+import "errors"
+import "fmt"
+import "math"
+import "strconv"
+import "strings"
+import "time"
+
+// TLE holds the mean orbital elements of a NORAD two-line element set.
+// Angles are in degrees and the mean motion is in revolutions per day, as
+// in the element set itself.
+type TLE struct {
+	Name string
+	SatelliteNumber int
+
+	EpochYear int  // Four digit year.
+	EpochDay float64  // Fractional day of the year, starting at 1.0.
+
+	MeanMotionDot float64  // [rev/day^2] / 2
+	MeanMotionDDot float64  // [rev/day^3] / 6
+	BStar float64  // [1/earth radii]
+
+	InclinationDegrees float64
+	RightAscensionDegrees float64
+	Eccentricity float64
+	ArgumentOfPerigeeDegrees float64
+	MeanAnomalyDegrees float64
+	MeanMotion float64  // [rev/day]
+	RevolutionNumber int
+}
+
+func tleError(line int, field, msg string) error {
+	return errors.New(fmt.Sprintf(
+		"TLE line %d: invalid %s: %s", line, field, msg))
+}
+
+// Return the columns [begin, end] of line using the 1-based numbering of
+// the TLE format specification.
+func tleField(line string, begin, end int) string {
+	if end > len(line) {
+		end = len(line)
+	}
+	if begin > end {
+		return ""
+	}
+	return strings.TrimSpace(line[begin-1:end])
+}
+
+func parseTLEFloat(line string, n, begin, end int, field string) (
+	float64, error) {
+	s := tleField(line, begin, end)
+	if s == "" {
+		return 0.0, nil
+	}
+	// Some generators write e.g. "+.00000638" or " -.00000638".
+	s = strings.Replace(s, " ", "", -1)
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0.0, tleError(n, field, s)
+	}
+	return f, nil
+}
+
+// Parse a field with an implied leading decimal point and an exponent,
+// e.g. " 12345-3" means 0.12345e-3.
+func parseTLEExponent(line string, n, begin, end int, field string) (
+	float64, error) {
+	s := tleField(line, begin, end)
+	if s == "" {
+		return 0.0, nil
+	}
+	s = strings.Replace(s, " ", "", -1)
+
+	sign := 1.0
+	if s[0] == '-' || s[0] == '+' {
+		if s[0] == '-' {
+			sign = -1.0
+		}
+		s = s[1:]
+	}
+	if len(s) < 3 {
+		return 0.0, tleError(n, field, s)
+	}
+	mantissa, exponent := s[:len(s)-2], s[len(s)-2:]
+
+	m, err := strconv.ParseFloat("0."+mantissa, 64)
+	if err != nil {
+		return 0.0, tleError(n, field, s)
+	}
+	e, err := strconv.Atoi(exponent)
+	if err != nil {
+		return 0.0, tleError(n, field, s)
+	}
+	return sign * m * math.Pow10(e), nil
+}
+
+func parseTLEInt(line string, n, begin, end int, field string) (int, error) {
+	s := tleField(line, begin, end)
+	if s == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, tleError(n, field, s)
+	}
+	return i, nil
+}
+
+// ParseTLE parses an element set consisting of an optional name line
+// followed by the two element lines.
+func ParseTLE(s string) (*TLE, error) {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, " \r\t")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	var tle TLE
+	if len(lines) == 3 {
+		tle.Name = strings.TrimSpace(lines[0])
+		lines = lines[1:]
+	}
+	if len(lines) != 2 {
+		return nil, errors.New(fmt.Sprintf(
+			"TLE has wrong number of lines: %d", len(lines)))
+	}
+	l1, l2 := lines[0], lines[1]
+	if len(l1) < 62 || l1[0] != '1' {
+		return nil, tleError(1, "line", l1)
+	}
+	if len(l2) < 63 || l2[0] != '2' {
+		return nil, tleError(2, "line", l2)
+	}
+
+	var err error
+	if tle.SatelliteNumber, err = parseTLEInt(
+		l1, 1, 3, 7, "satellite number"); err != nil {
+		return nil, err
+	}
+	n2, err := parseTLEInt(l2, 2, 3, 7, "satellite number")
+	if err != nil {
+		return nil, err
+	}
+	if n2 != tle.SatelliteNumber {
+		return nil, errors.New(fmt.Sprintf(
+			"TLE lines are for different satellites: %d, %d",
+			tle.SatelliteNumber, n2))
+	}
+
+	year, err := parseTLEInt(l1, 1, 19, 20, "epoch year")
+	if err != nil {
+		return nil, err
+	}
+	if year < 57 {
+		tle.EpochYear = 2000 + year
+	} else {
+		tle.EpochYear = 1900 + year
+	}
+	if tle.EpochDay, err = parseTLEFloat(
+		l1, 1, 21, 32, "epoch day"); err != nil {
+		return nil, err
+	}
+	if tle.MeanMotionDot, err = parseTLEFloat(
+		l1, 1, 34, 43, "first derivative of mean motion"); err != nil {
+		return nil, err
+	}
+	if tle.MeanMotionDDot, err = parseTLEExponent(
+		l1, 1, 45, 52, "second derivative of mean motion"); err != nil {
+		return nil, err
+	}
+	if tle.BStar, err = parseTLEExponent(
+		l1, 1, 54, 61, "bstar"); err != nil {
+		return nil, err
+	}
+
+	if tle.InclinationDegrees, err = parseTLEFloat(
+		l2, 2, 9, 16, "inclination"); err != nil {
+		return nil, err
+	}
+	if tle.RightAscensionDegrees, err = parseTLEFloat(
+		l2, 2, 18, 25, "right ascension"); err != nil {
+		return nil, err
+	}
+	ecc := tleField(l2, 27, 33)
+	if tle.Eccentricity, err = strconv.ParseFloat("0."+ecc, 64); err != nil {
+		return nil, tleError(2, "eccentricity", ecc)
+	}
+	if tle.ArgumentOfPerigeeDegrees, err = parseTLEFloat(
+		l2, 2, 35, 42, "argument of perigee"); err != nil {
+		return nil, err
+	}
+	if tle.MeanAnomalyDegrees, err = parseTLEFloat(
+		l2, 2, 44, 51, "mean anomaly"); err != nil {
+		return nil, err
+	}
+	if tle.MeanMotion, err = parseTLEFloat(
+		l2, 2, 53, 63, "mean motion"); err != nil {
+		return nil, err
+	}
+	if tle.RevolutionNumber, err = parseTLEInt(
+		l2, 2, 64, 68, "revolution number"); err != nil {
+		return nil, err
+	}
+
+	if tle.MeanMotion <= 0.0 {
+		return nil, tleError(2, "mean motion", "must be positive")
+	}
+	return &tle, nil
+}
+
+// Compute the modulo 10 checksum of the first 68 columns of an element
+// line. Digits count their value and minus signs count one.
+func lineChecksum(line string) int {
+	sum := 0
+	for i := 0; i < len(line) && i < 68; i++ {
+		c := line[i]
+		if '0' <= c && c <= '9' {
+			sum += int(c - '0')
+		} else if c == '-' {
+			sum++
+		}
+	}
+	return sum % 10
+}
+
+// VerifyChecksums checks the checksums in column 69 of both element lines.
+func VerifyChecksums(tle string) error {
+	var lines []string
+	for _, line := range strings.Split(tle, "\n") {
+		line = strings.TrimRight(line, " \r\t")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) < 2 {
+		return errors.New(fmt.Sprintf(
+			"TLE has wrong number of lines: %d", len(lines)))
+	}
+
+	for i, line := range lines[len(lines)-2:] {
+		if len(line) < 69 {
+			return tleError(i+1, "line", line)
+		}
+		expected := int(line[68] - '0')
+		if actual := lineChecksum(line); actual != expected {
+			return errors.New(fmt.Sprintf(
+				"TLE line %d: checksum is %d, expected %d",
+				i+1, actual, expected))
+		}
+	}
+	return nil
+}
+
+// Julian date of the epoch.
+func (tle *TLE) EpochJulianDate() float64 {
+	return julianDate(tle.EpochYear, 1, 1, 0, 0, 0) + tle.EpochDay - 1.0
+}
+
+func (tle *TLE) Epoch() time.Time {
+	return julianDateToTime(tle.EpochJulianDate())
+}
@@ -0,0 +1,79 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package main
+
+import "carpcomm/mux"
+import "log"
+import "net/rpc"
+import "sync"
+import "time"
+
+// The mux only updates its estimates when it pings the station so there's
+// no point asking more often than this.
+const kClockCacheDuration = 5 * time.Minute
+
+type cachedClock struct {
+	estimate mux.ClockEstimate
+	known bool
+	fetched time.Time
+}
+
+// StationClocks caches the mux's station clock estimates so that posting a
+// packet doesn't need an RPC to the mux every time. The connection to the
+// mux is redialled if it breaks.
+type StationClocks struct {
+	mux_address string
+
+	mu sync.Mutex
+	client *rpc.Client
+	clocks map[string]cachedClock
+}
+
+func NewStationClocks(mux_address string) *StationClocks {
+	return &StationClocks{
+		mux_address: mux_address,
+		clocks: make(map[string]cachedClock),
+	}
+}
+
+// Must be called with mu held.
+func (s *StationClocks) fetch(station_id string) (
+	mux.ClockEstimate, bool, error) {
+	if s.client == nil {
+		client, err := rpc.DialHTTP("tcp", s.mux_address)
+		if err != nil {
+			return mux.ClockEstimate{}, false, err
+		}
+		s.client = client
+	}
+	clock, known, err := mux.StationClock(s.client, station_id)
+	if _, ok := err.(rpc.ServerError); err != nil && !ok {
+		// The connection is broken (usually rpc.ErrShutdown).
+		// Redial next time.
+		s.client.Close()
+		s.client = nil
+	}
+	return clock, known, err
+}
+
+// Lookup returns the mux's estimate of the station's clock and whether it's
+// known. Failures are cached too so that packets aren't held up while the
+// mux is down.
+func (s *StationClocks) Lookup(station_id string) (mux.ClockEstimate, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if c, ok := s.clocks[station_id]; ok &&
+		now.Sub(c.fetched) < kClockCacheDuration {
+		return c.estimate, c.known
+	}
+
+	clock, known, err := s.fetch(station_id)
+	if err != nil {
+		log.Printf("Error getting station clock: %s", err.Error())
+	}
+	s.clocks[station_id] = cachedClock{clock, known, now}
+	return clock, known
+}
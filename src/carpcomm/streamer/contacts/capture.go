@@ -0,0 +1,55 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package contacts
+
+import "carpcomm/db"
+import "carpcomm/demod"
+import "carpcomm/pb"
+import "code.google.com/p/goprotobuf/proto"
+
+func NewCapture(planned_start, planned_end int64) *pb.Contact_Capture {
+	return &pb.Contact_Capture{
+		PlannedStartTimestamp: proto.Int64(planned_start),
+		PlannedEndTimestamp: proto.Int64(planned_end),
+		Status: pb.Contact_Capture_PENDING.Enum(),
+	}
+}
+
+func AddRPCFailure(c *pb.Contact_Capture, rpc string, err error) {
+	c.RpcFailure = append(c.RpcFailure, &pb.Contact_Capture_RPCFailure{
+		Rpc: proto.String(rpc),
+		Error: proto.String(err.Error()),
+	})
+}
+
+// CaptureStatus determines the outcome of a capture once no more IQ data is
+// expected. can_decode is whether we have a decoder for the satellite. If we
+// don't, receiving any IQ data counts as a success.
+func CaptureStatus(c *pb.Contact_Capture,
+	can_decode bool) pb.Contact_Capture_Status {
+	if c.GetStatus() == pb.Contact_Capture_STATION_OFFLINE {
+		return pb.Contact_Capture_STATION_OFFLINE
+	}
+	if c.GetIqBytes() == 0 && c.GetFramesDecoded() == 0 {
+		return pb.Contact_Capture_NO_SIGNAL
+	}
+	if can_decode && c.GetFramesDecoded() == 0 {
+		return pb.Contact_Capture_NO_SIGNAL
+	}
+	if len(c.RpcFailure) > 0 {
+		return pb.Contact_Capture_PARTIAL
+	}
+	return pb.Contact_Capture_SUCCESS
+}
+
+// UpdateCaptureStatus sets the final status of the contact's capture.
+func UpdateCaptureStatus(contact *pb.Contact) {
+	can_decode := false
+	sat := db.GlobalSatelliteDB().Map[contact.GetSatelliteId()]
+	if sat != nil {
+		can_decode = demod.HasDecoder(sat)
+	}
+	contact.Capture.Status = CaptureStatus(
+		contact.Capture, can_decode).Enum()
+}
@@ -0,0 +1,39 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package contacts
+
+import "carpcomm/pb"
+import "code.google.com/p/goprotobuf/proto"
+import "errors"
+import "testing"
+
+func TestCaptureStatus(t *testing.T) {
+	c := NewCapture(100, 400)
+	if s := CaptureStatus(c, true); s != pb.Contact_Capture_NO_SIGNAL {
+		t.Errorf("No data: %v", s)
+	}
+
+	c.IqBytes = proto.Int64(1000)
+	if s := CaptureStatus(c, true); s != pb.Contact_Capture_NO_SIGNAL {
+		t.Errorf("Nothing decoded: %v", s)
+	}
+	if s := CaptureStatus(c, false); s != pb.Contact_Capture_SUCCESS {
+		t.Errorf("No decoder: %v", s)
+	}
+
+	c.FramesDecoded = proto.Int32(3)
+	if s := CaptureStatus(c, true); s != pb.Contact_Capture_SUCCESS {
+		t.Errorf("Frames decoded: %v", s)
+	}
+
+	AddRPCFailure(c, "StationMotorStart", errors.New("timeout"))
+	if s := CaptureStatus(c, true); s != pb.Contact_Capture_PARTIAL {
+		t.Errorf("RPC failure: %v", s)
+	}
+
+	c.Status = pb.Contact_Capture_STATION_OFFLINE.Enum()
+	if s := CaptureStatus(c, true); s != pb.Contact_Capture_STATION_OFFLINE {
+		t.Errorf("Station offline: %v", s)
+	}
+}
@@ -89,7 +89,8 @@ func (e *PopulateContactError) Error() string {
 }
 
 // If station is nil, an anonymous contact will be created.
-// Otherwise, an authenticated contact will be created.
+// Otherwise, an authenticated contact will be created. auth_source is
+// recorded in the station authentication log if station_secret is given.
 func PopulateContact(
 	satellite_id string,
 	timestamp int64,
@@ -97,6 +98,7 @@ func PopulateContact(
 	data []byte,
 	authenticated_user_id string,
 	station_secret string,
+	auth_source string,
 	station *pb.Station) (*pb.Contact, *PopulateContactError) {
 
 	if satellite_id == "" {
@@ -146,7 +148,8 @@ func PopulateContact(
 		// Ensure that either the station secret is correct or the user
 		// owns the station.
 		if station_secret != "" {
-			if *station.Secret != station_secret {
+			if !db.AuthenticateStation(station, station.GetId(),
+				station_secret, auth_source) {
 				return nil, NewPopulateContactError(
 					http.StatusUnauthorized, "")
 			}
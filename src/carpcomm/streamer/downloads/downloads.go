@@ -0,0 +1,135 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package downloads
+
+import "carpcomm/scheduler"
+import "crypto/hmac"
+import "crypto/sha256"
+import "encoding/hex"
+import "errors"
+import "flag"
+import "fmt"
+import "io/ioutil"
+import "net/url"
+import "strconv"
+import "strings"
+import "sync"
+import "time"
+
+var download_key_file = flag.String("download_key_file", "",
+	"File containing the key used to sign IQ download URLs. fe and the "+
+		"streamer must use the same key. Downloads are disabled if "+
+		"it's not set.")
+var download_url_lifetime = flag.Duration("download_url_lifetime",
+	time.Hour, "How long signed IQ download URLs are valid")
+
+// The artifacts of a contact that can be downloaded from the streamer.
+const (
+	IQ = "iq"
+	Spectrogram = "png"
+)
+
+var key_once sync.Once
+var key []byte  // may be set directly by tests
+var key_err error
+
+func signingKey() ([]byte, error) {
+	key_once.Do(func() {
+		if key != nil {
+			return
+		}
+		if *download_key_file == "" {
+			key_err = errors.New("download_key_file not set")
+			return
+		}
+		key, key_err = ioutil.ReadFile(*download_key_file)
+		if key_err == nil && len(key) == 0 {
+			key_err = errors.New("Empty download key")
+		}
+	})
+	return key, key_err
+}
+
+// ArtifactPath returns the file name of the artifact in the streamer's
+// directory, which is also its URL path.
+func ArtifactPath(contact_id, artifact string) string {
+	if artifact == Spectrogram {
+		return contact_id + ".png"
+	}
+	return contact_id
+}
+
+// ParsePath is the inverse of ArtifactPath. Only contact ids made of digits
+// are accepted so that other files in the streamer's directory, e.g.
+// intermediate files of the demodulators, can't be downloaded.
+func ParsePath(path string) (contact_id, artifact string, ok bool) {
+	contact_id, artifact = path, IQ
+	if strings.HasSuffix(path, ".png") {
+		contact_id, artifact = path[:len(path)-len(".png")], Spectrogram
+	}
+	if contact_id == "" {
+		return "", "", false
+	}
+	for _, c := range contact_id {
+		if c < '0' || c > '9' {
+			return "", "", false
+		}
+	}
+	return contact_id, artifact, true
+}
+
+func sign(k []byte, contact_id, artifact string, expires int64) string {
+	mac := hmac.New(sha256.New, k)
+	fmt.Fprintf(mac, "%s\n%s\n%d", contact_id, artifact, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignedQuery returns the query parameters that authorize downloading the
+// artifact until download_url_lifetime from now.
+func SignedQuery(contact_id, artifact string, now time.Time) (
+	url.Values, error) {
+	k, err := signingKey()
+	if err != nil {
+		return nil, err
+	}
+	expires := now.Add(*download_url_lifetime).Unix()
+	return url.Values{
+		"expires": {fmt.Sprintf("%d", expires)},
+		"sig": {sign(k, contact_id, artifact, expires)},
+	}, nil
+}
+
+// URL returns a signed URL for downloading the artifact from the streamer.
+// The caller must check that the user is allowed to download it.
+func URL(contact_id, artifact string) (string, error) {
+	q, err := SignedQuery(contact_id, artifact, time.Now())
+	if err != nil {
+		return "", err
+	}
+	u := scheduler.GetStreamURL(contact_id)
+	if artifact == Spectrogram {
+		u += ".png"
+	}
+	return u + "?" + q.Encode(), nil
+}
+
+// Verify checks the signature and expiry of a download request.
+func Verify(contact_id, artifact string, q url.Values, now time.Time) error {
+	k, err := signingKey()
+	if err != nil {
+		return err
+	}
+	expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	if err != nil {
+		return errors.New("Invalid expires param")
+	}
+	expected := sign(k, contact_id, artifact, expires)
+	if !hmac.Equal([]byte(expected), []byte(q.Get("sig"))) {
+		return errors.New("Bad signature")
+	}
+	if now.Unix() >= expires {
+		return errors.New("URL expired")
+	}
+	return nil
+}
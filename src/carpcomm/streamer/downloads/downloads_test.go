@@ -0,0 +1,67 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package downloads
+
+import "testing"
+import "time"
+
+func init() {
+	key = []byte("test key")
+}
+
+func TestParsePath(t *testing.T) {
+	cases := []struct {
+		path, id, artifact string
+		ok bool
+	}{
+		{"123", "123", IQ, true},
+		{"123.png", "123", Spectrogram, true},
+		{"123_doppler", "", "", false},
+		{"123.wav", "", "", false},
+		{"../123", "", "", false},
+		{".png", "", "", false},
+		{"", "", "", false},
+	}
+	for _, c := range cases {
+		id, artifact, ok := ParsePath(c.path)
+		if id != c.id || artifact != c.artifact || ok != c.ok {
+			t.Errorf("ParsePath(%q) = %q, %q, %v", c.path,
+				id, artifact, ok)
+		}
+		if ok && ArtifactPath(id, artifact) != c.path {
+			t.Errorf("ArtifactPath(%q, %q) = %q", id, artifact,
+				ArtifactPath(id, artifact))
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1360000000, 0)
+	q, err := SignedQuery("123", IQ, now)
+	if err != nil {
+		t.Fatalf("SignedQuery error: %s", err.Error())
+	}
+
+	if err := Verify("123", IQ, q, now); err != nil {
+		t.Errorf("Valid URL rejected: %s", err.Error())
+	}
+	if Verify("124", IQ, q, now) == nil {
+		t.Errorf("Accepted signature for another contact")
+	}
+	if Verify("123", Spectrogram, q, now) == nil {
+		t.Errorf("Accepted signature for another artifact")
+	}
+	if Verify("123", IQ, q, now.Add(*download_url_lifetime)) == nil {
+		t.Errorf("Accepted expired URL")
+	}
+
+	q.Set("expires", "9999999999")
+	if Verify("123", IQ, q, now) == nil {
+		t.Errorf("Accepted modified expiry")
+	}
+	q.Del("sig")
+	if Verify("123", IQ, q, now) == nil {
+		t.Errorf("Accepted missing signature")
+	}
+}
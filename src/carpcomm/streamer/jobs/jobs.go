@@ -0,0 +1,355 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package jobs
+
+import "carpcomm/db"
+import "carpcomm/pb"
+import "code.google.com/p/goprotobuf/proto"
+
+import "encoding/json"
+import "flag"
+import "fmt"
+import "log"
+import "net/http"
+import "os"
+import "strconv"
+import "sync"
+import "time"
+
+var iq_workers = flag.Int("iq_workers", 2,
+	"Number of IQ data processing workers")
+var iq_max_attempts = flag.Int("iq_max_attempts", 5,
+	"How many times processing IQ data is attempted before the job fails")
+
+// The delay before retrying a failed job doubles after every attempt.
+const kMinBackoff = time.Minute
+const kMaxBackoff = time.Hour
+
+// Idle workers look for new jobs this often.
+const kPollInterval = 15 * time.Second
+
+func backoff(attempts int32) time.Duration {
+	d := kMinBackoff
+	for i := int32(1); i < attempts && d < kMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > kMaxBackoff {
+		d = kMaxBackoff
+	}
+	return d
+}
+
+// Workers renew the lease of a running job this often. If a worker dies,
+// its job is taken over by another worker once the lease expires.
+const kLeaseDuration = 10 * time.Minute
+const kLeaseRenewal = kLeaseDuration / 4
+
+// How many jobs of each status are considered when claiming a job. Only the
+// oldest ones are looked at so that the database query stays cheap.
+const kCandidateLimit = 20
+
+// Add queues the contact's IQ data for processing. A job that has already
+// finished is run again.
+func Add(jobdb *db.IQJobDB, contact_id string, now time.Time) error {
+	for {
+		j, err := jobdb.Lookup(contact_id)
+		if err != nil {
+			return err
+		}
+		if j == nil {
+			j = &pb.IQJob{
+				Id: proto.String(contact_id),
+				Created: proto.Int64(now.Unix()),
+			}
+		}
+		j.Status = pb.IQJob_QUEUED.Enum()
+		j.Updated = proto.Int64(now.Unix())
+		j.NotBefore = nil
+		j.Attempts = nil
+		j.Error = nil
+		j.Worker = nil
+		j.LeaseExpires = nil
+		ok, err := jobdb.Update(j)
+		if err != nil || ok {
+			return err
+		}
+		// A worker updated the job in the meantime.
+	}
+}
+
+// A job being run by a worker.
+type Job struct {
+	*pb.IQJob
+	q *Queue
+
+	// Guards updates of the job, which are made by the worker and by
+	// the lease renewal.
+	lock sync.Mutex
+	// Set if another worker took over the job.
+	lost bool
+}
+
+// ProcessFunc processes the IQ data of j.GetId(). It should use RunStage to
+// record its progress. The job is retried if it returns an error.
+type ProcessFunc func(j *Job) error
+
+// Queue runs IQ processing jobs stored in an IQJobDB using a pool of
+// workers. Any number of processes may run workers for the same IQJobDB.
+type Queue struct {
+	jobdb *db.IQJobDB
+	process ProcessFunc
+	name string
+
+	now func() time.Time
+}
+
+func NewQueue(jobdb *db.IQJobDB, process ProcessFunc) *Queue {
+	hostname, _ := os.Hostname()
+	return &Queue{
+		jobdb: jobdb,
+		process: process,
+		name: fmt.Sprintf("%s/%d", hostname, os.Getpid()),
+		now: time.Now,
+	}
+}
+
+// Start starts the workers. The number of workers is given by the
+// --iq_workers flag.
+func (q *Queue) Start() {
+	log.Printf("Starting %d IQ processing workers", *iq_workers)
+	for i := 0; i < *iq_workers; i++ {
+		go q.work(fmt.Sprintf("%s/%d", q.name, i))
+	}
+}
+
+func (q *Queue) work(worker string) {
+	for {
+		j, err := q.claim(worker)
+		if err != nil {
+			log.Printf("Error claiming IQ job: %s", err.Error())
+		}
+		if j == nil {
+			time.Sleep(kPollInterval)
+			continue
+		}
+		q.run(j)
+	}
+}
+
+// Returns the jobs that may be claimed, oldest first. These are queued jobs
+// that aren't backing off and running jobs whose worker has died. Running
+// jobs are updated whenever their lease is renewed so the oldest ones are
+// the first to expire.
+func (q *Queue) candidates(now int64) ([]*pb.IQJob, error) {
+	queued, err := q.jobdb.OldestByStatus(pb.IQJob_QUEUED, kCandidateLimit)
+	if err != nil {
+		return nil, err
+	}
+	running, err := q.jobdb.OldestByStatus(
+		pb.IQJob_RUNNING, kCandidateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*pb.IQJob
+	for _, j := range running {
+		if j.GetLeaseExpires() < now {
+			result = append(result, j)
+		}
+	}
+	for _, j := range queued {
+		if j.GetNotBefore() <= now {
+			result = append(result, j)
+		}
+	}
+	return result, nil
+}
+
+// Claims the oldest job that may be run. Returns nil if there's nothing to
+// do.
+func (q *Queue) claim(worker string) (*Job, error) {
+	now := q.now().Unix()
+	candidates, err := q.candidates(now)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, j := range candidates {
+		if j.GetStatus() == pb.IQJob_RUNNING {
+			log.Printf("%s: Lease of %s expired, taking over job",
+				j.GetId(), j.GetWorker())
+		}
+		j.Status = pb.IQJob_RUNNING.Enum()
+		j.Worker = proto.String(worker)
+		j.LeaseExpires = proto.Int64(
+			now + int64(kLeaseDuration.Seconds()))
+		j.Attempts = proto.Int32(j.GetAttempts() + 1)
+		j.Error = nil
+		j.Stage = nil
+		j.Updated = proto.Int64(now)
+		ok, err := q.jobdb.Update(j)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return &Job{IQJob: j, q: q}, nil
+		}
+		// Another worker claimed it first.
+	}
+	return nil, nil
+}
+
+// Store the job unless another worker has taken it over.
+// j.lock must be held.
+func (j *Job) store() {
+	if j.lost {
+		return
+	}
+	ok, err := j.q.jobdb.Update(j.IQJob)
+	if err != nil {
+		log.Printf("%s: Error storing IQ job: %s",
+			j.GetId(), err.Error())
+	} else if !ok {
+		log.Printf("%s: IQ job was taken over by another worker",
+			j.GetId())
+		j.lost = true
+	}
+}
+
+// Lost returns whether another worker has taken over the job, e.g. because
+// we failed to renew the lease in time. The results shouldn't be stored.
+func (j *Job) Lost() bool {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.lost
+}
+
+func (q *Queue) renewLease(j *Job, done chan bool) {
+	ticker := time.NewTicker(kLeaseRenewal)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		j.lock.Lock()
+		now := q.now()
+		j.LeaseExpires = proto.Int64(now.Add(kLeaseDuration).Unix())
+		j.Updated = proto.Int64(now.Unix())
+		j.store()
+		j.lock.Unlock()
+	}
+}
+
+func (q *Queue) run(j *Job) {
+	log.Printf("%s: Running IQ job, attempt %d", j.GetId(), j.GetAttempts())
+	done := make(chan bool)
+	go q.renewLease(j, done)
+	err := q.process(j)
+	close(done)
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	now := q.now()
+	if err == nil {
+		j.Status = pb.IQJob_DONE.Enum()
+		log.Printf("%s: IQ job done", j.GetId())
+	} else {
+		j.Error = proto.String(err.Error())
+		if int(j.GetAttempts()) >= *iq_max_attempts {
+			j.Status = pb.IQJob_FAILED.Enum()
+			log.Printf("%s: IQ job failed: %s",
+				j.GetId(), err.Error())
+		} else {
+			j.Status = pb.IQJob_QUEUED.Enum()
+			j.NotBefore = proto.Int64(
+				now.Add(backoff(j.GetAttempts())).Unix())
+			log.Printf("%s: IQ job attempt failed, retrying: %s",
+				j.GetId(), err.Error())
+		}
+	}
+	j.LeaseExpires = nil
+	j.Updated = proto.Int64(now.Unix())
+	j.store()
+}
+
+// RunStage runs f as the named stage of the job and records the result. f
+// returns the number of blobs it produced.
+func (j *Job) RunStage(name string, f func() (int, error)) error {
+	s := &pb.IQJob_Stage{
+		Name: proto.String(name),
+		Started: proto.Int64(j.q.now().Unix()),
+	}
+	j.lock.Lock()
+	j.Stage = append(j.Stage, s)
+	j.lock.Unlock()
+
+	blobs, err := f()
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	s.Finished = proto.Int64(j.q.now().Unix())
+	s.Blobs = proto.Int32(int32(blobs))
+	if err != nil {
+		s.Error = proto.String(err.Error())
+	}
+	j.Updated = s.Finished
+	j.store()
+	return err
+}
+
+// StatusHandler serves the jobs as JSON. The 'id' param selects a single
+// job, 'status' the jobs with that status and 'limit' limits the number of
+// jobs, which are sorted by update time (newest first).
+func StatusHandler(jobdb *db.IQJobDB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		limit := 100
+		if l := q.Get("limit"); l != "" {
+			var err error
+			limit, err = strconv.Atoi(l)
+			if err != nil {
+				http.Error(w, "Invalid 'limit' param.",
+					http.StatusBadRequest)
+				return
+			}
+		}
+
+		jobs := []*pb.IQJob{}
+		var err error
+		if id := q.Get("id"); id != "" {
+			var j *pb.IQJob
+			j, err = jobdb.Lookup(id)
+			if j != nil {
+				jobs = append(jobs, j)
+			}
+		} else if s := q.Get("status"); s != "" {
+			status, ok := pb.IQJob_Status_value[s]
+			if !ok {
+				http.Error(w, "Invalid 'status' param.",
+					http.StatusBadRequest)
+				return
+			}
+			jobs, err = jobdb.SearchByStatus(
+				pb.IQJob_Status(status), limit)
+		} else {
+			jobs, err = jobdb.Recent(limit)
+		}
+		if err != nil {
+			log.Printf("Error looking up IQ jobs: %s", err.Error())
+			http.Error(w, "", http.StatusInternalServerError)
+			return
+		}
+
+		b, err := json.Marshal(jobs)
+		if err != nil {
+			log.Printf("Error encoding IQ jobs: %s", err.Error())
+			http.Error(w, "", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	})
+}
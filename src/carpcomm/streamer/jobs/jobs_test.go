@@ -0,0 +1,181 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package jobs
+
+import "carpcomm/db"
+import "carpcomm/pb"
+import "errors"
+import "fmt"
+import "io/ioutil"
+import "os"
+import "testing"
+import "time"
+
+func TestBackoff(t *testing.T) {
+	cases := map[int32]time.Duration{
+		1: time.Minute,
+		2: 2 * time.Minute,
+		4: 8 * time.Minute,
+		20: time.Hour,
+	}
+	for attempts, expected := range cases {
+		if d := backoff(attempts); d != expected {
+			t.Errorf("backoff(%d) = %s, expected %s",
+				attempts, d, expected)
+		}
+	}
+}
+
+func newTestQueue(t *testing.T, process ProcessFunc) (
+	*Queue, *db.IQJobDB, *time.Time, string) {
+	dir, err := ioutil.TempDir("", "jobs_test")
+	if err != nil {
+		t.Fatalf("TempDir error: %s", err.Error())
+	}
+	d, err := db.NewLocalDomain(dir, "test-")
+	if err != nil {
+		t.Fatalf("NewLocalDomain error: %s", err.Error())
+	}
+	jobdb := d.NewIQJobDB()
+	q := NewQueue(jobdb, process)
+	now := time.Unix(1360000000, 0)
+	q.now = func() time.Time { return now }
+	return q, jobdb, &now, dir
+}
+
+func TestQueueRetries(t *testing.T) {
+	fail := true
+	process := func(j *Job) error {
+		j.RunStage("demod", func() (int, error) { return 2, nil })
+		return j.RunStage("store", func() (int, error) {
+			if fail {
+				return 0, errors.New("db down")
+			}
+			return 0, nil
+		})
+	}
+	q, jobdb, now, dir := newTestQueue(t, process)
+	defer os.RemoveAll(dir)
+
+	if err := Add(jobdb, "1", *now); err != nil {
+		t.Fatalf("Add error: %s", err.Error())
+	}
+
+	j, err := q.claim("w")
+	if err != nil || j == nil {
+		t.Fatalf("claim: %v, %v", j, err)
+	}
+	q.run(j)
+
+	stored, _ := jobdb.Lookup("1")
+	if stored.GetStatus() != pb.IQJob_QUEUED ||
+		stored.GetError() != "db down" || stored.GetAttempts() != 1 {
+		t.Errorf("Wrong job after failure: %v", stored)
+	}
+	if len(stored.Stage) != 2 || stored.Stage[0].GetBlobs() != 2 ||
+		stored.Stage[1].GetError() != "db down" {
+		t.Errorf("Wrong stages: %v", stored.Stage)
+	}
+
+	// The job is backing off.
+	if j, _ := q.claim("w"); j != nil {
+		t.Errorf("Claimed job during backoff")
+	}
+
+	*now = now.Add(backoff(1))
+	fail = false
+	j, _ = q.claim("w")
+	if j == nil {
+		t.Fatalf("Job not retried")
+	}
+	q.run(j)
+	stored, _ = jobdb.Lookup("1")
+	if stored.GetStatus() != pb.IQJob_DONE || stored.GetAttempts() != 2 {
+		t.Errorf("Wrong job after success: %v", stored)
+	}
+	if j, _ := q.claim("w"); j != nil {
+		t.Errorf("Claimed finished job")
+	}
+}
+
+func TestQueueGivesUp(t *testing.T) {
+	q, jobdb, now, dir := newTestQueue(t, func(j *Job) error {
+		return errors.New("bad data")
+	})
+	defer os.RemoveAll(dir)
+
+	Add(jobdb, "1", *now)
+	for i := 0; i < *iq_max_attempts; i++ {
+		j, _ := q.claim("w")
+		if j == nil {
+			t.Fatalf("Job not claimed on attempt %d", i+1)
+		}
+		q.run(j)
+		*now = now.Add(kMaxBackoff)
+	}
+	stored, _ := jobdb.Lookup("1")
+	if stored.GetStatus() != pb.IQJob_FAILED {
+		t.Errorf("Wrong job status: %v", stored)
+	}
+}
+
+func TestQueueLeases(t *testing.T) {
+	q, jobdb, now, dir := newTestQueue(t, func(j *Job) error {
+		return nil
+	})
+	defer os.RemoveAll(dir)
+
+	Add(jobdb, "1", *now)
+	j, _ := q.claim("w1")
+	if j == nil {
+		t.Fatalf("Job not claimed")
+	}
+	if other, _ := q.claim("w2"); other != nil {
+		t.Errorf("Claimed running job")
+	}
+
+	// w1 died so its lease expires.
+	*now = now.Add(kLeaseDuration + time.Second)
+	other, _ := q.claim("w2")
+	if other == nil {
+		t.Fatalf("Expired job not taken over")
+	}
+	if other.GetAttempts() != 2 {
+		t.Errorf("Wrong attempts: %d", other.GetAttempts())
+	}
+
+	// w1 comes back but must not overwrite w2's result.
+	j.RunStage("demod", func() (int, error) { return 0, nil })
+	if !j.Lost() {
+		t.Errorf("Job not lost")
+	}
+	q.run(other)
+	stored, _ := jobdb.Lookup("1")
+	if stored.GetStatus() != pb.IQJob_DONE ||
+		stored.GetWorker() != "w2" {
+		t.Errorf("Wrong job: %v", stored)
+	}
+}
+
+func TestQueueClaimsOldest(t *testing.T) {
+	q, jobdb, now, dir := newTestQueue(t, func(j *Job) error {
+		return nil
+	})
+	defer os.RemoveAll(dir)
+
+	// More jobs than are looked at in one claim.
+	for i := 0; i < kCandidateLimit+5; i++ {
+		Add(jobdb, fmt.Sprintf("%d", i), *now)
+		*now = now.Add(time.Second)
+	}
+	for i := 0; i < 3; i++ {
+		j, err := q.claim("w1")
+		if err != nil || j == nil {
+			t.Fatalf("Job not claimed: %v", err)
+		}
+		if expected := fmt.Sprintf("%d", i); j.GetId() != expected {
+			t.Errorf("Claimed %s, expected %s", j.GetId(), expected)
+		}
+	}
+}
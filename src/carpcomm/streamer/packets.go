@@ -12,7 +12,7 @@ import "io/ioutil"
 import "carpcomm/db"
 import "carpcomm/pb"
 import "carpcomm/streamer/contacts"
-import "carpcomm/scheduler"
+import "carpcomm/streamer/downloads"
 import "strconv"
 import "errors"
 import "fmt"
@@ -26,8 +26,25 @@ type PostPacketRequest struct {
 	FrameBase64 string `json:"frame_base64"`
 }
 
+// Convert the station's timestamp to server time if the mux knows the
+// station's clock offset.
+func correctPacketTimestamp(
+	clocks *StationClocks, station_id string, timestamp int64) int64 {
+	clock, known := clocks.Lookup(station_id)
+	if !known {
+		return timestamp
+	}
+	corrected := clock.ToMuxTimestamp(timestamp)
+	if clock.Flagged {
+		log.Printf("%s: Station clock is off, corrected packet "+
+			"timestamp from %d to %d", station_id, timestamp, corrected)
+	}
+	return corrected
+}
+
 func postPacketHandler(
-	sdb *db.StationDB, cdb *db.ContactDB,
+	sdb *db.StationDB, cdb *db.ContactDB, tdb *db.APITokenDB,
+	clocks *StationClocks,
 	w http.ResponseWriter, r *http.Request) {
 	data, err := ioutil.ReadAll(r.Body)
 	r.Body.Close()
@@ -48,7 +65,9 @@ func postPacketHandler(
 		return
 	}
 
-	log.Printf("request: %v", req)
+	// Don't log the secret.
+	log.Printf("PostPacket: station_id=%s satellite_id=%s timestamp=%d",
+		req.StationId, req.SatelliteId, req.Timestamp)
 
 	frame, err := base64.StdEncoding.DecodeString(req.FrameBase64)
 	if err != nil {
@@ -59,24 +78,49 @@ func postPacketHandler(
 		return
 	}
 
-	if req.StationId == "" {
-		http.Error(w, "Missing station_id", http.StatusBadRequest)
-		return
-	}
-	station, err := sdb.Lookup(req.StationId)
-	if err != nil {
-		log.Printf("Error looking up station: %s", err.Error())
-		http.Error(w, "", http.StatusInternalServerError)
-		return
+	var station *pb.Station
+	// Set if the station was authenticated with an API token.
+	token_user_id := ""
+	if token := requestAPIToken(r); token != "" {
+		station, err = apiTokenStation(sdb, tdb, r, token,
+			req.StationId, pb.APIToken_POST_PACKETS,
+			req.SatelliteId, "PostPacket")
+		if err != nil {
+			log.Printf("Error checking API token: %s", err.Error())
+			http.Error(w, "", http.StatusInternalServerError)
+			return
+		}
+		if station == nil {
+			http.Error(w, "", http.StatusUnauthorized)
+			return
+		}
+		token_user_id = station.GetUserid()
+		req.StationId = station.GetId()
+		req.StationSecret = ""
+	} else {
+		if req.StationId == "" {
+			http.Error(w, "Missing station_id",
+				http.StatusBadRequest)
+			return
+		}
+		station, err = sdb.Lookup(req.StationId)
+		if err != nil {
+			log.Printf("Error looking up station: %s", err.Error())
+			http.Error(w, "", http.StatusInternalServerError)
+			return
+		}
 	}
 
+	timestamp := correctPacketTimestamp(
+		clocks, req.StationId, req.Timestamp)
 	contact, poperr := contacts.PopulateContact(
 		req.SatelliteId,
-		req.Timestamp,
+		timestamp,
 		req.Format,
 		frame,
-		"",
+		token_user_id,
 		req.StationSecret,
+		"PostPacket "+r.RemoteAddr,
 		station)
 	if poperr != nil {
 		poperr.HttpError(w)
@@ -112,15 +156,9 @@ func parseGetLatestPacketsRequest(values url.Values) (
 	}
 	req.Limit = limit
 
+	// These aren't needed with an API token.
 	req.StationId = values.Get("station_id")
-	if req.StationId == "" {
-		return req, errors.New("Missing station_id")
-	}
-
 	req.StationSecret = values.Get("station_secret")
-	if req.StationSecret == "" {
-		return req, errors.New("Missing station_secret")
-	}
 
 	req.SatelliteId = values.Get("satellite_id")
 	if req.SatelliteId == "" {
@@ -137,10 +175,11 @@ type Packet struct {
 type GetLatestPacketsResponse []Packet
 
 func getLatestPacketsHandler(
-	sdb *db.StationDB, cdb *db.ContactDB,
+	sdb *db.StationDB, cdb *db.ContactDB, tdb *db.APITokenDB,
 	w http.ResponseWriter, r *http.Request) {
 
-	log.Printf("Request: %s", r.URL.String())
+	// The query may contain the station secret.
+	log.Printf("Request: %s", r.URL.Path)
 
 	req, err := parseGetLatestPacketsRequest(r.URL.Query())
 	if err != nil {
@@ -157,19 +196,10 @@ func getLatestPacketsHandler(
 		return
 	}
 
-	station, err := sdb.Lookup(req.StationId)
-	if err != nil {
-		log.Printf("Error looking up station: %s", err.Error())
-		http.Error(w, "", http.StatusUnauthorized)
-		return
-	}
+	station := authenticateAPIRequest(sdb, tdb, r,
+		req.StationId, req.StationSecret, pb.APIToken_READ_PACKETS,
+		req.SatelliteId, "GetLatestPackets")
 	if station == nil {
-		log.Printf("Error looking up station.")
-		http.Error(w, "", http.StatusUnauthorized)
-		return
-	}
-	// Authenticate the station.
-	if station.Secret == nil || *station.Secret != req.StationSecret {
 		log.Printf("Authentication failed.")
 		http.Error(w, "", http.StatusUnauthorized)
 		return
@@ -250,15 +280,9 @@ func parseGetLatestIQDataRequest(values url.Values) (
 	}
 	req.Limit = limit
 
+	// These aren't needed with an API token.
 	req.StationId = values.Get("station_id")
-	if req.StationId == "" {
-		return req, errors.New("Missing station_id")
-	}
-
 	req.StationSecret = values.Get("station_secret")
-	if req.StationSecret == "" {
-		return req, errors.New("Missing station_secret")
-	}
 
 	req.SatelliteId = values.Get("satellite_id")
 	if req.SatelliteId == "" {
@@ -277,10 +301,11 @@ type IQLink struct {
 type GetLatestIQDataResponse []IQLink
 
 func getLatestIQDataHandler(
-	sdb *db.StationDB, cdb *db.ContactDB,
+	sdb *db.StationDB, cdb *db.ContactDB, tdb *db.APITokenDB,
 	w http.ResponseWriter, r *http.Request) {
 
-	log.Printf("Request: %s", r.URL.String())
+	// The query may contain the station secret.
+	log.Printf("Request: %s", r.URL.Path)
 
 	req, err := parseGetLatestIQDataRequest(r.URL.Query())
 	if err != nil {
@@ -297,19 +322,10 @@ func getLatestIQDataHandler(
 		return
 	}
 
-	station, err := sdb.Lookup(req.StationId)
-	if err != nil {
-		log.Printf("Error looking up station: %s", err.Error())
-		http.Error(w, "", http.StatusUnauthorized)
-		return
-	}
+	station := authenticateAPIRequest(sdb, tdb, r,
+		req.StationId, req.StationSecret, pb.APIToken_READ_IQ,
+		req.SatelliteId, "GetLatestIQData")
 	if station == nil {
-		log.Printf("Error looking up station.")
-		http.Error(w, "", http.StatusUnauthorized)
-		return
-	}
-	// Authenticate the station.
-	if station.Secret == nil || *station.Secret != req.StationSecret {
 		log.Printf("Authentication failed.")
 		http.Error(w, "", http.StatusUnauthorized)
 		return
@@ -353,9 +369,17 @@ func getLatestIQDataHandler(
 				continue
 			}
 
+			iq_url, err := downloads.URL(*c.Id, downloads.IQ)
+			if err != nil {
+				log.Printf("getLatestIQDataHandler: "+
+					"Error signing URL: %s", err.Error())
+				http.Error(w, "", http.StatusInternalServerError)
+				return
+			}
+
 			var p IQLink
 			p.Timestamp = timestamp
-			p.URL = scheduler.GetStreamURL(*c.Id)
+			p.URL = iq_url
 
 			if b.IqParams != nil {
 				if b.IqParams.Type != nil {
@@ -388,18 +412,21 @@ func getLatestIQDataHandler(
 
 func AddPacketHttpHandlers(mux *http.ServeMux,
 	contactdb *db.ContactDB, 
-	stationdb *db.StationDB) {
+	stationdb *db.StationDB,
+	tokendb *db.APITokenDB,
+	clocks *StationClocks) {
 
 	mux.HandleFunc("/PostPacket",
 		func(w http.ResponseWriter, r *http.Request) {
-		postPacketHandler(stationdb, contactdb, w, r)
+		postPacketHandler(
+			stationdb, contactdb, tokendb, clocks, w, r)
 	})
 	mux.HandleFunc("/GetLatestPackets",
 		func(w http.ResponseWriter, r *http.Request) {
-		getLatestPacketsHandler(stationdb, contactdb, w, r)
+		getLatestPacketsHandler(stationdb, contactdb, tokendb, w, r)
 	})
 	mux.HandleFunc("/GetLatestIQData",
 		func(w http.ResponseWriter, r *http.Request) {
-		getLatestIQDataHandler(stationdb, contactdb, w, r)
+		getLatestIQDataHandler(stationdb, contactdb, tokendb, w, r)
 	})
 }
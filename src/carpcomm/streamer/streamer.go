@@ -10,11 +10,17 @@ import "os"
 import "io"
 import "strconv"
 import "time"
+import "errors"
+import "net/url"
+import "strings"
+import "carpcomm/archive"
 import "carpcomm/db"
 import "carpcomm/pb"
 import "flag"
 import "code.google.com/p/goprotobuf/proto"
-import "strings"
+import "carpcomm/streamer/downloads"
+import "carpcomm/streamer/jobs"
+import "carpcomm/streamer/uploads"
 
 var cert_file = flag.String(
 	"cert_file",
@@ -34,20 +40,28 @@ var tls_port = flag.String("tls_port", ":5051", "External port with TLS")
 var db_prefix = flag.String("db_prefix", "r1-", "Database table prefix")
 var gc_threshold_mb = flag.Int(
 	"gc_threshold_mb", 3000, "Garbage collection threshold in MB")
+var mux_address = flag.String("mux_address", ":1235", "Mux address")
+var status_port = flag.String("status_port", "localhost:5052",
+	"Internal port serving the IQ processing job status at /iq_jobs")
 
 type Handler struct {
 	contactdb *db.ContactDB
-	queue IQProcessingQueue
+	archive archive.Archive
+	uploads *uploads.Store
+	jobdb *db.IQJobDB
 }
 
-func NewHandler(contactdb *db.ContactDB, queue IQProcessingQueue) *Handler {
-	return &Handler{contactdb, queue}
+func NewHandler(contactdb *db.ContactDB, iq_archive archive.Archive,
+	upload_store *uploads.Store, jobdb *db.IQJobDB) *Handler {
+	return &Handler{contactdb, iq_archive, upload_store, jobdb}
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Request: %s %s", r.Method, r.URL.Path)
 
-	if r.Method == "PUT" {
+	if strings.Contains(r.URL.Path, kUploadPath) {
+		h.ResumableUpload(w, r)
+	} else if r.Method == "PUT" {
 		h.Put(w, r)
 	} else if r.Method == "GET" {
 		h.Get(w, r)
@@ -57,105 +71,289 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *Handler) Put(w http.ResponseWriter, r *http.Request) {
-	id := r.URL.Path[1:]
-
+// Returns an error message for the user if the params are invalid.
+func parseIQParams(q url.Values) (*pb.IQParams, string) {
 	var iq_params pb.IQParams
 
-	rate_s := r.URL.Query().Get("rate")
+	rate_s := q.Get("rate")
 	if rate_s == "" {
 		rate_s = "250977"
 	}
 	rate, err := strconv.Atoi(rate_s)
 	if err != nil || rate <= 0 {
-		http.Error(w, "Invalid 'rate' param.", http.StatusBadRequest)
-		return
+		return nil, "Invalid 'rate' param."
 	}
 	iq_params.SampleRate = proto.Int32((int32)(rate))
 
-	type_s := r.URL.Query().Get("type")
+	type_s := q.Get("type")
 	if type_s == "" {
 		type_s = "UINT8"
 	}
 	inttype, ok := pb.IQParams_Type_value[type_s]
 	if !ok {
-		http.Error(w, "Invalid 'type' param.", http.StatusBadRequest)
-		return
+		return nil, "Invalid 'type' param."
 	}
 	iq_params.Type = (pb.IQParams_Type)(inttype).Enum()
-	
+	return &iq_params, ""
+}
+
+func hasIQBlob(c *pb.Contact) bool {
+	for _, b := range c.Blob {
+		if b.Format != nil && *b.Format == pb.Contact_Blob_IQ {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the contact if IQ data may be uploaded for it. Otherwise an error
+// is sent and nil is returned.
+func (h *Handler) lookupUploadContact(w http.ResponseWriter, r *http.Request,
+	id string) *pb.Contact {
 	c, err := h.contactdb.Lookup(id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil
 	}
 	if c == nil {
 		http.NotFound(w, r)
-		return
+		return nil
 	}
+	if hasIQBlob(c) {
+		http.Error(w, "IQ data already uploaded.", http.StatusConflict)
+		return nil
+	}
+	return c
+}
 
-	// TODO(tstranex): If IQ data has already been uploaded, we should
-	// prevent it from uploaded again.
-
-	// TODO(tstranex): Use s3 multipart uploading instead. That will allow
-	// us to get rid of the local file and the size limit.
-
-	begin_time := time.Now()
-	local_path := fmt.Sprintf("%s/%s", *stream_tmp_dir, id)
+// The data is streamed to the archive and to local_path, which is only a
+// cache for downloads.
+func (h *Handler) archiveIQData(archive_path, local_path string,
+	data io.Reader) (written int64, err error) {
 	file, err := os.Create(local_path)
 	if err != nil {
-		log.Printf("Error opening %s: %v", local_path, err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return 0, err
+	}
+	aw, err := h.archive.Create(archive_path)
+	if err != nil {
+		file.Close()
+		return 0, err
 	}
 
-	const size_limit = 256 * 1024 * 1024  // [bytes]
-	written, _ := io.Copy(file, io.LimitReader(r.Body, size_limit))
+	written, err = io.Copy(io.MultiWriter(file, aw), data)
 	file.Close()
+	if err != nil {
+		aw.Abort()
+		return written, err
+	}
+	return written, aw.Close()
+}
 
-	d := time.Now().Sub(begin_time)
-	upload_rate := float64(written) / d.Seconds() / 1024.0
+// Resumable upload sessions are deleted when this is returned.
+var errDuplicateIQ = uploads.ErrDuplicate
+
+// Add the IQ blob to the contact. Fails with errDuplicateIQ if another
+// upload got there first.
+func (h *Handler) addIQBlob(id string, iq_params *pb.IQParams,
+	archive_path string, written int64) error {
+	// The scheduler may update the contact concurrently.
+	c, err := h.contactdb.Update(id, func(c *pb.Contact) error {
+		if hasIQBlob(c) {
+			return errDuplicateIQ
+		}
+
+		if c.Capture != nil {
+			c.Capture.IqBytes = proto.Int64(
+				c.Capture.GetIqBytes() + written)
+		}
+
+		iq_blob := &(pb.Contact_Blob{})
+		iq_blob.Format = pb.Contact_Blob_IQ.Enum()
+		iq_blob.Path = proto.String(archive_path)
+		iq_blob.IqParams = iq_params
+		c.Blob = append(c.Blob, iq_blob)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return errors.New("Contact not found")
+	}
+	return nil
+}
 
-	log.Printf("%s: Read %d bytes in %s: %f kB/s",
-		id, written, d.String(), upload_rate)
-	w.WriteHeader(http.StatusNoContent)
+// Store the IQ data of the contact and queue it for processing. Each upload
+// is written to its own paths so that concurrent uploads for the same
+// contact can't overwrite each other. Only the one that's added to the
+// contact is kept.
+func (h *Handler) storeIQData(id string, iq_params *pb.IQParams,
+	data io.Reader) (written int64, err error) {
+	nonce, err := db.CryptoRandId()
+	if err != nil {
+		return 0, err
+	}
+	// Not a valid download path until it's renamed.
+	tmp_path := fmt.Sprintf("%s/%s.%s.tmp", *stream_tmp_dir, id, nonce)
+	archive_path := archive.UploadIQPath(id, nonce)
 
-	// TODO: upload to s3
+	written, err = h.archiveIQData(archive_path, tmp_path, data)
+	if err != nil {
+		os.Remove(tmp_path)
+		return written, err
+	}
+	err = h.addIQBlob(id, iq_params, archive_path, written)
+	if err != nil {
+		os.Remove(tmp_path)
+		if derr := h.archive.Delete(archive_path); derr != nil {
+			log.Printf("%s: Error deleting unused upload %s: %s",
+				id, archive_path, derr.Error())
+		}
+		return written, err
+	}
 
-	iq_blob := &(pb.Contact_Blob{})
-	iq_blob.Format = pb.Contact_Blob_IQ.Enum()
-	iq_blob.IqParams = &iq_params
-	c.Blob = append(c.Blob, iq_blob)
+	local_path := fmt.Sprintf("%s/%s", *stream_tmp_dir,
+		downloads.ArtifactPath(id, downloads.IQ))
+	if err := os.Rename(tmp_path, local_path); err != nil {
+		// Downloads are served from the archive instead.
+		log.Printf("%s: Error caching IQ data: %s", id, err.Error())
+		os.Remove(tmp_path)
+	}
 
-	// TODO: Need to be careful about locking the ContactDB record.
-	// Currently we are the only process that modifies an existing ContactDB
-	// record. In the future we may need to be more careful.
-	if err := h.contactdb.Store(c); err != nil {
-		log.Printf("Error storing contact: %s", err.Error())
-		return
+	// The upload has succeeded by now so queueing errors aren't reported
+	// to the station, which couldn't upload the data again anyway.
+	go h.queueIQJob(id)
+	return written, nil
+}
+
+// Adding the IQ job is retried this many times, this far apart.
+const kQueueAttempts = 5
+const kQueueRetryDelay = time.Minute
+
+// Queue the contact's IQ data for processing by demodd.
+func (h *Handler) queueIQJob(id string) {
+	for i := 1; i <= kQueueAttempts; i++ {
+		err := jobs.Add(h.jobdb, id, time.Now())
+		if err == nil {
+			return
+		}
+		log.Printf("%s: Error queueing IQ job (attempt %d): %s",
+			id, i, err.Error())
+		time.Sleep(kQueueRetryDelay)
 	}
+	log.Printf("%s: Giving up queueing IQ job", id)
+}
 
-	h.queue <- id
+// Returns the archive path of the contact's IQ data.
+func iqArchivePath(c *pb.Contact) string {
+	for _, b := range c.Blob {
+		if b.GetFormat() == pb.Contact_Blob_IQ && b.Path != nil {
+			return b.GetPath()
+		}
+	}
+	// Contacts from before each upload had its own path.
+	return archive.IQPath(c.GetId())
 }
 
-func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+// Put accepts the IQ data in a single request. Stations on unreliable links
+// should use ResumableUpload instead.
+func (h *Handler) Put(w http.ResponseWriter, r *http.Request) {
 	id := r.URL.Path[1:]
 
-	// FIXME: add authentication and verify id
+	iq_params, message := parseIQParams(r.URL.Query())
+	if iq_params == nil {
+		http.Error(w, message, http.StatusBadRequest)
+		return
+	}
+
+	if h.lookupUploadContact(w, r, id) == nil {
+		return
+	}
+
+	begin_time := time.Now()
+	written, err := h.storeIQData(id, iq_params, r.Body)
+	if err == errDuplicateIQ {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("%s: Error after %d bytes: %s",
+			id, written, err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	d := time.Now().Sub(begin_time)
+	upload_rate := float64(written) / d.Seconds() / 1024.0
+
+	log.Printf("%s: Read %d bytes in %s: %f kB/s",
+		id, written, d.String(), upload_rate)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get serves the IQ data and spectrogram of a contact. The URL must have been
+// signed by fe or the API after checking that the user may download it.
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id, artifact, ok := downloads.ParsePath(r.URL.Path[1:])
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	err := downloads.Verify(id, artifact, r.URL.Query(), time.Now())
+	if err != nil {
+		log.Printf("Denied download of %s: %s", r.URL.Path, err.Error())
+		http.Error(w, "", http.StatusForbidden)
+		return
+	}
 
 	// For some reason, mime type detection assigns text/plain for IQ data,
 	// which causes the browser to display it instead of download it.
 	// So set the content-type explicitly.
-	if !strings.Contains(id, ".") {
+	if artifact == downloads.IQ {
 		w.Header().Add("Content-Type", "application/octet-stream")
 	}
 
-	local_path := fmt.Sprintf("%s/%s", *stream_tmp_dir, id)
+	local_path := fmt.Sprintf("%s/%s", *stream_tmp_dir,
+		downloads.ArtifactPath(id, artifact))
+	if _, err := os.Stat(local_path); err != nil {
+		// The local copy has been garbage collected or, for
+		// spectrograms, was generated by demodd.
+		if artifact == downloads.Spectrogram {
+			w.Header().Set("Content-Type", "image/png")
+			h.serveFromArchive(w, r, archive.SpectrogramPath(id))
+			return
+		}
+		c, err := h.contactdb.Lookup(id)
+		if err != nil || c == nil {
+			http.NotFound(w, r)
+			return
+		}
+		h.serveFromArchive(w, r, iqArchivePath(c))
+		return
+	}
 	http.ServeFile(w, r, local_path)
 }
 
-func listenAndServeUploader(contactdb *db.ContactDB, queue IQProcessingQueue) {
-	h := NewHandler(contactdb, queue)
+func (h *Handler) serveFromArchive(
+	w http.ResponseWriter, r *http.Request, path string) {
+	f, err := h.archive.Open(path)
+	if err != nil {
+		log.Printf("Error opening archive file %s: %s",
+			path, err.Error())
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	if _, err := io.Copy(w, f); err != nil {
+		log.Printf("Error serving archive file %s: %s",
+			path, err.Error())
+	}
+}
+
+func listenAndServeUploader(contactdb *db.ContactDB,
+	iq_archive archive.Archive, upload_store *uploads.Store,
+	jobdb *db.IQJobDB) {
+	h := NewHandler(contactdb, iq_archive, upload_store, jobdb)
 	err := http.ListenAndServe(*port, h)
 	if err != nil {
 		log.Fatalf("Error starting server: %s", err.Error())
@@ -163,6 +361,16 @@ func listenAndServeUploader(contactdb *db.ContactDB, queue IQProcessingQueue) {
 	}
 }
 
+// The status server is for operators and shouldn't be exposed publicly.
+func listenAndServeStatus(jobdb *db.IQJobDB) {
+	mux := http.NewServeMux()
+	mux.Handle("/iq_jobs", jobs.StatusHandler(jobdb))
+	err := http.ListenAndServe(*status_port, mux)
+	if err != nil {
+		log.Fatalf("Error starting status server: %s", err.Error())
+	}
+}
+
 func main() {
 	flag.Parse()
 
@@ -172,13 +380,32 @@ func main() {
 	}
 	contactdb := domain.NewContactDB()
 	stationdb := domain.NewStationDB()
+	tokendb := domain.NewAPITokenDB()
+
+	iq_archive, err := archive.New()
+	if err != nil {
+		log.Fatalf("Archive error: %s", err.Error())
+	}
+
+	upload_store, err := uploads.NewStore(
+		*upload_session_dir, *max_upload_mb*1024*1024)
+	if err != nil {
+		log.Fatalf("Upload session dir error: %s", err.Error())
+	}
+
+	go db.RefreshTLEsForever()
 
-	queue := make(IQProcessingQueue)
-	go ProcessIQQueue(queue, contactdb)
-	go listenAndServeUploader(contactdb, queue)
+	jobdb := domain.NewIQJobDB()
+	go listenAndServeStatus(jobdb)
+	go listenAndServeUploader(contactdb, iq_archive, upload_store, jobdb)
 	go garbageCollectLoop(*stream_tmp_dir, *gc_threshold_mb, time.Minute)
 
-	AddPacketHttpHandlers(http.DefaultServeMux, contactdb, stationdb)
+	// The mux is only needed to correct packet timestamps for station
+	// clock offsets. Packets are accepted uncorrected while it's down.
+	clocks := NewStationClocks(*mux_address)
+
+	AddPacketHttpHandlers(http.DefaultServeMux,
+		contactdb, stationdb, tokendb, clocks)
 
 	log.Printf("Starting streamer server")
 	err = http.ListenAndServeTLS(
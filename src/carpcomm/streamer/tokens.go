@@ -0,0 +1,84 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package main
+
+import "carpcomm/db"
+import "carpcomm/pb"
+import "log"
+import "net/http"
+import "strings"
+
+const kAuthorizationPrefix = "Bearer "
+
+// Returns the API token in the Authorization header or an empty string if
+// there isn't one.
+func requestAPIToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, kAuthorizationPrefix) {
+		return ""
+	}
+	return strings.TrimSpace(h[len(kAuthorizationPrefix):])
+}
+
+// Returns the station that the token acts for, or nil if the token isn't
+// valid or doesn't allow the request. station_id is optional but must be the
+// token's station if given.
+func apiTokenStation(sdb *db.StationDB, tdb *db.APITokenDB,
+	r *http.Request, token, station_id string,
+	scope pb.APIToken_Scope, satellite_id, handler string) (
+	*pb.Station, error) {
+	t, err := tdb.Authenticate(token, handler+" "+r.RemoteAddr)
+	if err != nil || t == nil {
+		return nil, err
+	}
+	if station_id != "" && station_id != t.GetStationId() {
+		log.Printf("%s: Token is for another station", handler)
+		return nil, nil
+	}
+	if !db.APITokenAllows(t, scope, satellite_id) {
+		log.Printf("%s: Token doesn't allow %s for %s",
+			handler, scope.String(), satellite_id)
+		return nil, nil
+	}
+
+	s, err := sdb.Lookup(t.GetStationId())
+	if err != nil {
+		return nil, err
+	}
+	// The station may have been deleted or given away since the token
+	// was created.
+	if s == nil || s.GetUserid() != t.GetUserid() {
+		log.Printf("%s: Token's station is gone", handler)
+		return nil, nil
+	}
+	return s, nil
+}
+
+// Authenticates the request using its API token or, failing that, the
+// station secret. Returns nil if the request isn't authorized.
+func authenticateAPIRequest(sdb *db.StationDB, tdb *db.APITokenDB,
+	r *http.Request, station_id, station_secret string,
+	scope pb.APIToken_Scope, satellite_id, handler string) *pb.Station {
+	if token := requestAPIToken(r); token != "" {
+		s, err := apiTokenStation(sdb, tdb, r, token, station_id,
+			scope, satellite_id, handler)
+		if err != nil {
+			log.Printf("%s: Error checking API token: %s",
+				handler, err.Error())
+			return nil
+		}
+		return s
+	}
+
+	s, err := sdb.Lookup(station_id)
+	if err != nil {
+		log.Printf("Error looking up station: %s", err.Error())
+		return nil
+	}
+	if !db.AuthenticateStation(s, station_id, station_secret,
+		handler+" "+r.RemoteAddr) {
+		return nil
+	}
+	return s
+}
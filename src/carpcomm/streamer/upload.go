@@ -0,0 +1,171 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package main
+
+import "carpcomm/pb"
+import "carpcomm/streamer/uploads"
+import "encoding/json"
+import "flag"
+import "io"
+import "log"
+import "net/http"
+import "strconv"
+import "strings"
+import "time"
+
+var upload_session_dir = flag.String("upload_session_dir",
+	"/tmp/streamer_uploads",
+	"Directory for resumable upload sessions")
+var max_upload_mb = flag.Int64("max_upload_mb", 2048,
+	"Largest resumable upload accepted in MB")
+
+// Resumable uploads use these URLs, which are relative to the stream URL of
+// the contact:
+//
+//   POST <stream_url>/upload?size=&rate=&type=  creates the session
+//   PUT <stream_url>/upload?offset=&sha256=     uploads a chunk
+//   GET <stream_url>/upload                     returns the received ranges
+//   POST <stream_url>/upload/finalize           completes the upload
+//
+// All of them can be safely retried. The session is returned as JSON.
+const kUploadPath = "/upload"
+const kFinalizePath = "/finalize"
+
+func writeSession(w http.ResponseWriter, sess *uploads.Session) {
+	b, err := json.Marshal(sess)
+	if err != nil {
+		log.Printf("Error encoding upload session: %s", err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func uploadError(w http.ResponseWriter, id string, err error) {
+	switch err {
+	case uploads.ErrNoSession:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case uploads.ErrMismatch, uploads.ErrIncomplete, errDuplicateIQ:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case uploads.ErrChecksum, uploads.ErrOutOfRange, uploads.ErrTooLarge:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("%s: Upload error: %s", id, err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) ResumableUpload(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path[1:]
+	i := strings.Index(path, kUploadPath)
+	id, action := path[:i], path[i+len(kUploadPath):]
+	if id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	if action == kFinalizePath && r.Method == "POST" {
+		h.finalizeUpload(w, r, id)
+		return
+	}
+	if action != "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case "POST":
+		h.createUpload(w, r, id)
+	case "PUT":
+		h.putUploadChunk(w, r, id)
+	case "GET":
+		sess, err := h.uploads.Get(id)
+		if err == nil && sess == nil {
+			err = uploads.ErrNoSession
+		}
+		if err != nil {
+			uploadError(w, id, err)
+			return
+		}
+		writeSession(w, sess)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) createUpload(
+	w http.ResponseWriter, r *http.Request, id string) {
+	iq_params, message := parseIQParams(r.URL.Query())
+	if iq_params == nil {
+		http.Error(w, message, http.StatusBadRequest)
+		return
+	}
+	size, err := strconv.ParseInt(r.URL.Query().Get("size"), 10, 64)
+	if err != nil || size < 0 {
+		http.Error(w, "Invalid 'size' param.", http.StatusBadRequest)
+		return
+	}
+
+	if h.lookupUploadContact(w, r, id) == nil {
+		return
+	}
+
+	sess, err := h.uploads.Create(id, size, iq_params.GetSampleRate(),
+		iq_params.GetType().String(), time.Now())
+	if err != nil {
+		uploadError(w, id, err)
+		return
+	}
+	log.Printf("%s: Upload session for %d bytes", id, size)
+	writeSession(w, sess)
+}
+
+func (h *Handler) putUploadChunk(
+	w http.ResponseWriter, r *http.Request, id string) {
+	offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid 'offset' param.", http.StatusBadRequest)
+		return
+	}
+	sess, err := h.uploads.WriteChunk(
+		id, offset, r.Body, r.URL.Query().Get("sha256"))
+	if err != nil {
+		uploadError(w, id, err)
+		return
+	}
+	writeSession(w, sess)
+}
+
+func (h *Handler) finalizeUpload(
+	w http.ResponseWriter, r *http.Request, id string) {
+	err := h.uploads.Finalize(id,
+		func(sess *uploads.Session, data io.Reader) error {
+		iq_params := &pb.IQParams{
+			SampleRate: &sess.SampleRate,
+			Type: pb.IQParams_Type(
+				pb.IQParams_Type_value[sess.Type]).Enum(),
+		}
+		written, err := h.storeIQData(id, iq_params, data)
+		if err != nil {
+			return err
+		}
+		log.Printf("%s: Finalized upload of %d bytes", id, written)
+		return nil
+	})
+
+	if err == uploads.ErrNoSession {
+		// The station may be retrying after a lost response.
+		c, lerr := h.contactdb.Lookup(id)
+		if lerr == nil && c != nil && hasIQBlob(c) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	if err != nil {
+		uploadError(w, id, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,315 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package uploads
+
+import "crypto/sha256"
+import "encoding/hex"
+import "encoding/json"
+import "errors"
+import "io"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "sort"
+import "sync"
+import "time"
+
+// Stations should send chunks of about 1 MB so that little is lost when the
+// connection drops.
+const MaxChunkSize = 16 * 1024 * 1024
+
+var ErrNoSession = errors.New("No upload session")
+var ErrMismatch = errors.New(
+	"Upload session exists with different parameters")
+var ErrChecksum = errors.New("Chunk checksum mismatch")
+var ErrOutOfRange = errors.New("Chunk outside of upload")
+var ErrIncomplete = errors.New("Upload incomplete")
+var ErrTooLarge = errors.New("Upload too large")
+
+// Returned by the Finalize callback if the data was already uploaded by
+// another request. The session is deleted since retrying can't help.
+var ErrDuplicate = errors.New("Data already uploaded")
+
+// A range [Start, End) of bytes.
+type Range struct {
+	Start int64 `json:"start"`
+	End int64 `json:"end"`
+}
+
+// An upload in progress. There's at most one per contact.
+type Session struct {
+	ContactId string `json:"contact_id"`
+	Size int64 `json:"size"`
+	SampleRate int32 `json:"sample_rate"`
+	Type string `json:"type"`
+	// Sorted and non-overlapping.
+	Received []Range `json:"received"`
+	Created int64 `json:"created"`
+}
+
+// Complete returns whether every byte has been received.
+func (s *Session) Complete() bool {
+	if s.Size == 0 {
+		return true
+	}
+	return len(s.Received) == 1 &&
+		s.Received[0].Start == 0 && s.Received[0].End == s.Size
+}
+
+type rangeList []Range
+
+func (l rangeList) Len() int {
+	return len(l)
+}
+
+func (l rangeList) Less(i, j int) bool {
+	return l[i].Start < l[j].Start
+}
+
+func (l rangeList) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+// Returns the ranges with r added and adjacent ranges merged.
+func addRange(ranges []Range, r Range) []Range {
+	all := append(append([]Range{}, ranges...), r)
+	sort.Sort(rangeList(all))
+	merged := []Range{}
+	for _, x := range all {
+		n := len(merged)
+		if n > 0 && x.Start <= merged[n-1].End {
+			if x.End > merged[n-1].End {
+				merged[n-1].End = x.End
+			}
+			continue
+		}
+		merged = append(merged, x)
+	}
+	return merged
+}
+
+// Store keeps upload sessions in a directory so that they survive restarts.
+// Each session has a data file, which is written at the chunk offsets, and
+// a metadata file.
+type Store struct {
+	dir string
+	// Larger sessions are rejected since the data file is allocated
+	// upfront.
+	max_size int64
+
+	lock sync.Mutex
+	session_locks map[string]*sync.Mutex
+}
+
+func NewStore(dir string, max_size int64) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Store{
+		dir: dir,
+		max_size: max_size,
+		session_locks: make(map[string]*sync.Mutex)}, nil
+}
+
+// Sessions are locked individually so that stations can upload
+// concurrently.
+func (s *Store) sessionLock(contact_id string) *sync.Mutex {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	l := s.session_locks[contact_id]
+	if l == nil {
+		l = &sync.Mutex{}
+		s.session_locks[contact_id] = l
+	}
+	return l
+}
+
+func (s *Store) dataPath(contact_id string) string {
+	return filepath.Join(s.dir, contact_id+".data")
+}
+
+func (s *Store) metaPath(contact_id string) string {
+	return filepath.Join(s.dir, contact_id+".json")
+}
+
+// Returns nil, nil if there's no session.
+func (s *Store) load(contact_id string) (*Session, error) {
+	b, err := ioutil.ReadFile(s.metaPath(contact_id))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sess Session
+	if err := json.Unmarshal(b, &sess); err != nil {
+		return nil, err
+	}
+	return &sess, nil
+}
+
+// The metadata is replaced atomically so that a crash can't corrupt it.
+func (s *Store) save(sess *Session) error {
+	b, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	tmp := s.metaPath(sess.ContactId) + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.metaPath(sess.ContactId))
+}
+
+// Create starts an upload session. If the session already exists with the
+// same parameters, it's returned unchanged so that stations can safely
+// retry.
+func (s *Store) Create(contact_id string, size int64, sample_rate int32,
+	iq_type string, now time.Time) (*Session, error) {
+	if size > s.max_size {
+		return nil, ErrTooLarge
+	}
+
+	l := s.sessionLock(contact_id)
+	l.Lock()
+	defer l.Unlock()
+
+	sess, err := s.load(contact_id)
+	if err != nil {
+		return nil, err
+	}
+	if sess != nil {
+		if sess.Size != size || sess.SampleRate != sample_rate ||
+			sess.Type != iq_type {
+			return nil, ErrMismatch
+		}
+		return sess, nil
+	}
+
+	f, err := os.Create(s.dataPath(contact_id))
+	if err != nil {
+		return nil, err
+	}
+	err = f.Truncate(size)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	sess = &Session{
+		ContactId: contact_id,
+		Size: size,
+		SampleRate: sample_rate,
+		Type: iq_type,
+		Received: []Range{},
+		Created: now.Unix(),
+	}
+	if err := s.save(sess); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// Get returns the session or nil if there isn't one.
+func (s *Store) Get(contact_id string) (*Session, error) {
+	l := s.sessionLock(contact_id)
+	l.Lock()
+	defer l.Unlock()
+	return s.load(contact_id)
+}
+
+// WriteChunk writes data at offset after checking it against the hex
+// encoded SHA-256 checksum. Chunks may be sent more than once and in any
+// order.
+func (s *Store) WriteChunk(contact_id string, offset int64, data io.Reader,
+	checksum string) (*Session, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(data, MaxChunkSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > MaxChunkSize {
+		return nil, ErrOutOfRange
+	}
+	h := sha256.New()
+	h.Write(b)
+	if hex.EncodeToString(h.Sum(nil)) != checksum {
+		return nil, ErrChecksum
+	}
+
+	l := s.sessionLock(contact_id)
+	l.Lock()
+	defer l.Unlock()
+
+	sess, err := s.load(contact_id)
+	if err != nil {
+		return nil, err
+	}
+	if sess == nil {
+		return nil, ErrNoSession
+	}
+	end := offset + int64(len(b))
+	if offset < 0 || end > sess.Size {
+		return nil, ErrOutOfRange
+	}
+	if len(b) == 0 {
+		return sess, nil
+	}
+
+	f, err := os.OpenFile(s.dataPath(contact_id), os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.WriteAt(b, offset)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sess.Received = addRange(sess.Received, Range{offset, end})
+	if err := s.save(sess); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// Finalize calls f with the complete data and deletes the session if f
+// succeeds or returns ErrDuplicate. Other requests for the session wait
+// until it's done.
+func (s *Store) Finalize(contact_id string,
+	f func(sess *Session, data io.Reader) error) error {
+	l := s.sessionLock(contact_id)
+	l.Lock()
+	defer l.Unlock()
+
+	sess, err := s.load(contact_id)
+	if err != nil {
+		return err
+	}
+	if sess == nil {
+		return ErrNoSession
+	}
+	if !sess.Complete() {
+		return ErrIncomplete
+	}
+
+	data, err := os.Open(s.dataPath(contact_id))
+	if err != nil {
+		return err
+	}
+	err = f(sess, data)
+	data.Close()
+	if err != nil && err != ErrDuplicate {
+		return err
+	}
+
+	if rerr := os.Remove(s.metaPath(contact_id)); rerr != nil {
+		return rerr
+	}
+	if rerr := os.Remove(s.dataPath(contact_id)); rerr != nil {
+		return rerr
+	}
+	return err
+}
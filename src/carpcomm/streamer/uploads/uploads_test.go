@@ -0,0 +1,147 @@
+// Author: Timothy Stranex <tstranex@carpcomm.com>
+// Copyright 2013 Timothy Stranex
+
+package uploads
+
+import "bytes"
+import "crypto/sha256"
+import "encoding/hex"
+import "errors"
+import "io"
+import "io/ioutil"
+import "os"
+import "reflect"
+import "strings"
+import "testing"
+import "time"
+
+func checksum(data string) string {
+	h := sha256.New()
+	io.WriteString(h, data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestAddRange(t *testing.T) {
+	var r []Range
+	r = addRange(r, Range{10, 20})
+	r = addRange(r, Range{0, 5})
+	r = addRange(r, Range{30, 40})
+	expected := []Range{{0, 5}, {10, 20}, {30, 40}}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("Got %v, expected %v", r, expected)
+	}
+	r = addRange(r, Range{5, 10})
+	r = addRange(r, Range{15, 35})
+	expected = []Range{{0, 40}}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("Got %v, expected %v", r, expected)
+	}
+}
+
+func newTestStore(t *testing.T) (*Store, string) {
+	dir, err := ioutil.TempDir("", "uploads_test")
+	if err != nil {
+		t.Fatalf("TempDir error: %s", err.Error())
+	}
+	s, err := NewStore(dir, 100)
+	if err != nil {
+		t.Fatalf("NewStore error: %s", err.Error())
+	}
+	return s, dir
+}
+
+func TestResumableUpload(t *testing.T) {
+	s, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+	now := time.Unix(1360000000, 0)
+
+	if _, err := s.Create("1", 10, 250977, "UINT8", now); err != nil {
+		t.Fatalf("Create error: %s", err.Error())
+	}
+	// Retrying is fine but the parameters can't change.
+	if _, err := s.Create("1", 10, 250977, "UINT8", now); err != nil {
+		t.Errorf("Create retry error: %s", err.Error())
+	}
+	if _, err := s.Create("1", 11, 250977, "UINT8", now); err != ErrMismatch {
+		t.Errorf("Expected ErrMismatch, got %v", err)
+	}
+	_, err := s.Create("2", 101, 250977, "UINT8", now)
+	if err != ErrTooLarge {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+
+	write := func(offset int64, data, sum string) error {
+		_, err := s.WriteChunk("1", offset,
+			strings.NewReader(data), sum)
+		return err
+	}
+	if err := write(5, "fghij", checksum("fghij")); err != nil {
+		t.Fatalf("WriteChunk error: %s", err.Error())
+	}
+	if err := write(0, "abcde", checksum("xxxxx")); err != ErrChecksum {
+		t.Errorf("Expected ErrChecksum, got %v", err)
+	}
+	if err := write(8, "klm", checksum("klm")); err != ErrOutOfRange {
+		t.Errorf("Expected ErrOutOfRange, got %v", err)
+	}
+	if _, err := s.WriteChunk("2", 0, strings.NewReader("a"),
+		checksum("a")); err != ErrNoSession {
+		t.Errorf("Expected ErrNoSession, got %v", err)
+	}
+
+	// The store is reopened to check that the session survives restarts.
+	s, _ = NewStore(dir, 100)
+	sess, err := s.Get("1")
+	if err != nil || sess == nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if !reflect.DeepEqual(sess.Received, []Range{{5, 10}}) {
+		t.Errorf("Wrong received ranges: %v", sess.Received)
+	}
+	if err := s.Finalize("1", nil); err != ErrIncomplete {
+		t.Errorf("Expected ErrIncomplete, got %v", err)
+	}
+
+	// Chunks may be sent twice.
+	write(0, "abcde", checksum("abcde"))
+	write(0, "abcde", checksum("abcde"))
+
+	failed := errors.New("failed")
+	err = s.Finalize("1", func(sess *Session, data io.Reader) error {
+		return failed
+	})
+	if err != failed {
+		t.Errorf("Expected Finalize to fail, got %v", err)
+	}
+
+	var got bytes.Buffer
+	err = s.Finalize("1", func(sess *Session, data io.Reader) error {
+		_, err := io.Copy(&got, data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Finalize error: %s", err.Error())
+	}
+	if got.String() != "abcdefghij" {
+		t.Errorf("Wrong data: %q", got.String())
+	}
+	if sess, _ := s.Get("1"); sess != nil {
+		t.Errorf("Session not deleted")
+	}
+}
+
+func TestFinalizeDuplicate(t *testing.T) {
+	s, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	s.Create("1", 0, 250977, "UINT8", time.Unix(1360000000, 0))
+	err := s.Finalize("1", func(sess *Session, data io.Reader) error {
+		return ErrDuplicate
+	})
+	if err != ErrDuplicate {
+		t.Errorf("Expected ErrDuplicate, got %v", err)
+	}
+	if sess, _ := s.Get("1"); sess != nil {
+		t.Errorf("Session not deleted")
+	}
+}
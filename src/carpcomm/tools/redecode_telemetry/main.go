@@ -12,11 +12,8 @@ var db_prefix = flag.String("db_prefix", "", "Database table prefix")
 var contact_id = flag.String("contact_id", "", "Contact id")
 var satellite_id = flag.String("satellite_id", "", "Satellite id")
 
-func RedecodeContact(contactdb *db.ContactDB, c *pb.Contact) {
-	if c == nil {
-		log.Fatalf("Contact not found")
-	}
-
+// Replaces the frames and datums of the contact with newly decoded ones.
+func RedecodeContact(c *pb.Contact) error {
 	log.Printf("Original contact:\n%s\n", proto.MarshalTextString(c))
 
 	new_blobs := make([]*pb.Contact_Blob, 0)
@@ -39,7 +36,7 @@ func RedecodeContact(contactdb *db.ContactDB, c *pb.Contact) {
 	}
 
 	if freeform == nil {
-		return;
+		return nil
 	}
 
 	data, frames := telemetry.DecodeFreeform(
@@ -60,11 +57,18 @@ func RedecodeContact(contactdb *db.ContactDB, c *pb.Contact) {
 	c.Blob = new_blobs
 
 	log.Printf("New contact:\n%s\n", proto.MarshalTextString(c))
+	return nil
+}
 
-	err := contactdb.Store(c)
+// The contact is decoded again if it's modified concurrently.
+func redecode(contactdb *db.ContactDB, id string) {
+	c, err := contactdb.Update(id, RedecodeContact)
 	if err != nil {
 		log.Fatalf("Error storing contact: %s", err.Error())
 	}
+	if c == nil {
+		log.Fatalf("Contact not found")
+	}
 }
 
 func main() {
@@ -81,11 +85,7 @@ func main() {
 	}
 
 	if *contact_id != "" {
-		c, err := contactdb.Lookup(*contact_id)
-		if err != nil {
-			log.Fatalf("Error looking up contact: %s", err.Error())
-		}
-		RedecodeContact(contactdb, c)
+		redecode(contactdb, *contact_id)
 
 	} else if *satellite_id != "" {
 		contacts, err := contactdb.SearchBySatelliteId(
@@ -94,7 +94,7 @@ func main() {
 			log.Fatalf("Error looking up contacts: %s", err.Error())
 		}
 		for _, c := range contacts {
-			RedecodeContact(contactdb, c)
+			redecode(contactdb, c.GetId())
 		}
 
 	} else {
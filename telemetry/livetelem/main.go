@@ -7,6 +7,8 @@
 //   go install github.com/tstranex/carpcomm/telemetry/livetelem
 // Run:
 //   ./bin/livetelem --station_id=your_id --station_secret=your_secret
+// or:
+//   ./bin/livetelem --api_token=your_token
 
 package main
 
@@ -18,6 +20,9 @@ import "log"
 
 var station_id = flag.String("station_id", "", "Station id")
 var station_secret = flag.String("station_secret", "", "Station secret")
+var api_token = flag.String("api_token", "",
+	"API token with the READ_PACKETS scope. Used instead of the station "+
+	"id and secret if set.")
 
 func main() {
 	// Set your satellite decoder here.
@@ -26,7 +31,13 @@ func main() {
 
 	flag.Parse()
 
-	c, err := api.NewAPIClient(*station_id, *station_secret)
+	var c *api.APIClient
+	var err error
+	if *api_token != "" {
+		c, err = api.NewAPIClientWithToken(*api_token)
+	} else {
+		c, err = api.NewAPIClient(*station_id, *station_secret)
+	}
 	if err != nil {
 		log.Printf("NewAPIClient error: %s", err.Error())
 		return